@@ -0,0 +1,277 @@
+package asset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Ostsol/engine/meshfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// ChannelPath is the part of a joint's Transform animated by a ClipChannel.
+type ChannelPath int
+
+const (
+	PathTranslation ChannelPath = iota
+	PathRotation
+	PathScale
+)
+
+// Interpolation is how a ClipChannel is sampled between keyframes.
+type Interpolation int
+
+const (
+	InterpolateLinear Interpolation = iota // spherical for rotations
+	InterpolateStep                        // holding each keyframe until the next
+	InterpolateCubic                       // Hermite spline through the keyframes
+)
+
+// ClipChannel is the keyframes of one part of a joint's Transform.
+type ClipChannel struct {
+	Joint         int
+	Path          ChannelPath
+	Interpolation Interpolation
+
+	// Times are the increasing times of the keyframes in seconds.
+	Times []float32
+
+	// Values are the keyframes, three components each for translations and
+	// scales, and four (X, Y, Z, W) for rotations. Cubic keyframes each hold an
+	// in-tangent, a value and an out-tangent.
+	Values []float32
+}
+
+// components returns the number of components of each value of the channel.
+func (c *ClipChannel) components() int {
+	if c.Path == PathRotation {
+		return 4
+	}
+	return 3
+}
+
+// Clip is an animation of the joints of a Skeleton.
+type Clip struct {
+	Name     string
+	Duration float32 // time of the last keyframe
+	Channels []ClipChannel
+}
+
+// NewClip creates a Clip from its channels. An error is returned if a channel
+// has no keyframes, or not the right number of values for them.
+func NewClip(name string, channels []ClipChannel) (*Clip, error) {
+	var c = &Clip{Name: name, Channels: channels}
+	for i := range channels {
+		var (
+			ch   = &channels[i]
+			want = ch.components() * len(ch.Times)
+		)
+		if ch.Interpolation == InterpolateCubic {
+			want *= 3
+		}
+		if len(ch.Times) == 0 || len(ch.Values) != want {
+			return nil, fmt.Errorf("asset.NewClip error: Clip '%s' channel %d has %d values for %d keyframes", name, i, len(ch.Values), len(ch.Times))
+		}
+		c.Duration = max(c.Duration, ch.Times[len(ch.Times)-1])
+	}
+	return c, nil
+}
+
+// Wrap returns a time brought within the Clip by looping it.
+func (c *Clip) Wrap(t float32) float32 {
+	if c.Duration <= 0 {
+		return 0
+	}
+	t = float32(math.Mod(float64(t), float64(c.Duration)))
+	if t < 0 {
+		t += c.Duration
+	}
+	return t
+}
+
+// Sample sets the Transforms of the joints animated by the Clip at time 't' in
+// a Pose, leaving the others as they are. Times before the first keyframe of a
+// channel or after its last hold that keyframe.
+func (c *Clip) Sample(t float32, pose Pose) {
+	for i := range c.Channels {
+		var ch = &c.Channels[i]
+		if ch.Joint < 0 || ch.Joint >= len(pose) {
+			continue
+		}
+		var v = ch.sample(t)
+		switch ch.Path {
+		case PathTranslation:
+			pose[ch.Joint].Translation = mgl.Vec3{v[0], v[1], v[2]}
+		case PathRotation:
+			pose[ch.Joint].Rotation = mgl.Quat{W: v[3], V: mgl.Vec3{v[0], v[1], v[2]}}
+		case PathScale:
+			pose[ch.Joint].Scale = mgl.Vec3{v[0], v[1], v[2]}
+		}
+	}
+}
+
+// sample returns the value of the channel at time 't'.
+func (c *ClipChannel) sample(t float32) mgl.Vec4 {
+	var (
+		n      = c.components()
+		stride = n
+		offset = 0
+	)
+	if c.Interpolation == InterpolateCubic {
+		stride, offset = 3*n, n
+	}
+	var key = func(k int, at int) (v mgl.Vec4) {
+		copy(v[:n], c.Values[k*stride+at:])
+		return v
+	}
+
+	// k is the keyframe after 't'.
+	var k = sort.Search(len(c.Times), func(i int) bool { return c.Times[i] > t })
+	if k == 0 {
+		return key(0, offset)
+	}
+	if k == len(c.Times) {
+		return key(k-1, offset)
+	}
+
+	var (
+		dt = c.Times[k] - c.Times[k-1]
+		w  = (t - c.Times[k-1]) / dt
+	)
+	switch c.Interpolation {
+	case InterpolateStep:
+		return key(k-1, offset)
+
+	case InterpolateCubic:
+		var (
+			w2, w3 = w * w, w * w * w
+			v0     = key(k-1, n).Mul(2*w3 - 3*w2 + 1)
+			b0     = key(k-1, 2*n).Mul((w3 - 2*w2 + w) * dt)
+			v1     = key(k, n).Mul(-2*w3 + 3*w2)
+			a1     = key(k, 0).Mul((w3 - w2) * dt)
+			v      = v0.Add(b0).Add(v1).Add(a1)
+		)
+		if c.Path == PathRotation {
+			v = v.Normalize()
+		}
+		return v
+	}
+
+	var a, b = key(k-1, 0), key(k, 0)
+	if c.Path == PathRotation {
+		var q = mgl.QuatSlerp(
+			mgl.Quat{W: a[3], V: a.Vec3()},
+			mgl.Quat{W: b[3], V: b.Vec3()},
+			w,
+		)
+		return mgl.Vec4{q.V[0], q.V[1], q.V[2], q.W}
+	}
+	return a.Add(b.Sub(a).Mul(w))
+}
+
+// NewSkeletonFromGLTF creates the Skeleton of skin 'skin' of a glTF document.
+// Each joint's parent is its nearest ancestor among the joints, and the
+// Skeleton's Root is the transform of the parent node of its first root joint.
+// Nodes between joints that are not joints themselves are skipped.
+func NewSkeletonFromGLTF(doc *meshfmt.GLTF, skin int) (*Skeleton, error) {
+	if skin < 0 || skin >= len(doc.Skins) {
+		return nil, fmt.Errorf("asset.NewSkeletonFromGLTF error: no skin %d", skin)
+	}
+	var (
+		s      = doc.Skins[skin]
+		index  = make(map[int]int, len(s.Joints))
+		joints = make([]Joint, len(s.Joints))
+		root   = -2
+	)
+	for j, node := range s.Joints {
+		if node < 0 || node >= len(doc.Nodes) {
+			return nil, fmt.Errorf("asset.NewSkeletonFromGLTF error: skin %d has no node %d", skin, node)
+		}
+		index[node] = j
+	}
+	for j, node := range s.Joints {
+		var n = doc.Nodes[node]
+		joints[j] = Joint{Name: n.Name, Parent: -1, Rest: decompose(n.Local())}
+		var p = n.Parent
+		for ; p >= 0; p = doc.Nodes[p].Parent {
+			if pj, ok := index[p]; ok {
+				joints[j].Parent = pj
+				break
+			}
+		}
+		if p < 0 && root == -2 {
+			root = n.Parent
+		}
+	}
+
+	var sk, err = NewSkeleton(s.Name, joints, append([]mgl.Mat4(nil), s.InverseBindMatrices...))
+	if err != nil {
+		return nil, err
+	}
+	if root >= 0 {
+		sk.Root = doc.World(root)
+	}
+	return sk, nil
+}
+
+// NewClipFromGLTF creates a Clip from animation 'anim' of a glTF document,
+// keeping the channels that animate the joints of skin 'skin'. Morph target
+// weights are not animated. The Clip is nil if it animates none of the joints.
+func NewClipFromGLTF(doc *meshfmt.GLTF, anim, skin int) (*Clip, error) {
+	if anim < 0 || anim >= len(doc.Animations) {
+		return nil, fmt.Errorf("asset.NewClipFromGLTF error: no animation %d", anim)
+	}
+	if skin < 0 || skin >= len(doc.Skins) {
+		return nil, fmt.Errorf("asset.NewClipFromGLTF error: no skin %d", skin)
+	}
+	var (
+		a     = doc.Animations[anim]
+		index = make(map[int]int)
+	)
+	for j, node := range doc.Skins[skin].Joints {
+		index[node] = j
+	}
+
+	var channels []ClipChannel
+	for _, c := range a.Channels {
+		var j, ok = index[c.Node]
+		if !ok || c.Sampler < 0 || c.Sampler >= len(a.Samplers) {
+			continue
+		}
+		var ch = ClipChannel{Joint: j}
+		switch c.Path {
+		case "translation":
+			ch.Path = PathTranslation
+		case "rotation":
+			ch.Path = PathRotation
+		case "scale":
+			ch.Path = PathScale
+		default:
+			continue
+		}
+
+		var s = a.Samplers[c.Sampler]
+		switch s.Interpolation {
+		case "STEP":
+			ch.Interpolation = InterpolateStep
+		case "CUBICSPLINE":
+			ch.Interpolation = InterpolateCubic
+		default:
+			ch.Interpolation = InterpolateLinear
+		}
+		if s.Components != ch.components() {
+			return nil, fmt.Errorf("asset.NewClipFromGLTF error: animation %d %s has %d components", anim, c.Path, s.Components)
+		}
+		ch.Times, ch.Values = s.Times, s.Values
+		channels = append(channels, ch)
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	var clip, err = NewClip(a.Name, channels)
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewClipFromGLTF error: %v", err)
+	}
+	return clip, nil
+}
@@ -0,0 +1,139 @@
+package asset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Ostsol/engine/meshfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewClip(t *testing.T) {
+	var c, err = NewClip("walk", []ClipChannel{
+		{Path: PathTranslation, Times: []float32{0, 1}, Values: make([]float32, 6)},
+		{Path: PathRotation, Times: []float32{0.5, 2}, Values: make([]float32, 8)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Duration != 2 {
+		t.Errorf("duration %v, want 2", c.Duration)
+	}
+	for _, tt := range [][2]float32{{0.5, 0.5}, {5, 1}, {-0.5, 1.5}} {
+		if got := c.Wrap(tt[0]); got != tt[1] {
+			t.Errorf("Wrap(%v) = %v, want %v", tt[0], got, tt[1])
+		}
+	}
+
+	for _, ch := range []ClipChannel{
+		{Path: PathTranslation},
+		{Path: PathRotation, Times: []float32{0}, Values: make([]float32, 3)},
+		{Path: PathScale, Interpolation: InterpolateCubic, Times: []float32{0}, Values: make([]float32, 3)},
+	} {
+		if _, err := NewClip("bad", []ClipChannel{ch}); err == nil {
+			t.Errorf("channel %+v made a Clip", ch)
+		}
+	}
+}
+
+func TestClipChannelSample(t *testing.T) {
+	var tests = []struct {
+		name string
+		ch   ClipChannel
+		t    float32
+		want mgl.Vec4
+	}{
+		{"linear", ClipChannel{Times: []float32{1, 3}, Values: []float32{0, 0, 0, 4, 2, 0}}, 2.5, mgl.Vec4{3, 1.5, 0, 0}},
+		{"before", ClipChannel{Times: []float32{1, 3}, Values: []float32{0, 0, 0, 4, 2, 0}}, 0, mgl.Vec4{}},
+		{"after", ClipChannel{Times: []float32{1, 3}, Values: []float32{0, 0, 0, 4, 2, 0}}, 9, mgl.Vec4{4, 2, 0, 0}},
+		{"step", ClipChannel{Interpolation: InterpolateStep, Times: []float32{0, 1}, Values: []float32{1, 1, 1, 2, 2, 2}}, 0.9, mgl.Vec4{1, 1, 1, 0}},
+		{
+			// Tangents of one unit per second follow the line v = t.
+			"cubic",
+			ClipChannel{Interpolation: InterpolateCubic, Times: []float32{0, 2}, Values: []float32{
+				1, 1, 1, 0, 0, 0, 1, 1, 1,
+				1, 1, 1, 2, 2, 2, 1, 1, 1,
+			}},
+			0.5, mgl.Vec4{0.5, 0.5, 0.5, 0},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.ch.sample(tt.t); !got.ApproxEqualThreshold(tt.want, 1e-6) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Rotations are interpolated spherically.
+	var (
+		s, c = float32(math.Sin(math.Pi / 4)), float32(math.Cos(math.Pi / 4))
+		rot  = ClipChannel{Path: PathRotation, Times: []float32{0, 1}, Values: []float32{0, 0, 0, 1, 0, s, 0, c}}
+		q    = rot.sample(0.5)
+		want = mgl.QuatRotate(math.Pi/4, mgl.Vec3{0, 1, 0})
+	)
+	if !q.ApproxEqualThreshold(mgl.Vec4{want.V[0], want.V[1], want.V[2], want.W}, 1e-6) {
+		t.Errorf("rotation: got %v, want %v", q, want)
+	}
+}
+
+func TestClipSample(t *testing.T) {
+	var c, err = NewClip("raise", []ClipChannel{
+		{Joint: 1, Path: PathTranslation, Times: []float32{0, 1}, Values: []float32{0, 0, 0, 0, 2, 0}},
+		{Joint: 1, Path: PathScale, Times: []float32{0}, Values: []float32{2, 2, 2}},
+		{Joint: 5, Path: PathTranslation, Times: []float32{0}, Values: []float32{9, 9, 9}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pose = Pose{translation(1, 1, 1), IdentityTransform}
+	c.Sample(0.5, pose)
+
+	if pose[0] != translation(1, 1, 1) {
+		t.Errorf("unanimated joint changed to %v", pose[0])
+	}
+	if want := (Transform{mgl.Vec3{0, 1, 0}, mgl.QuatIdent(), mgl.Vec3{2, 2, 2}}); pose[1] != want {
+		t.Errorf("animated joint is %v, want %v", pose[1], want)
+	}
+}
+
+func TestNewClipFromGLTF(t *testing.T) {
+	var doc = gltfRig()
+	doc.Animations = []*meshfmt.Animation{{
+		Name: "kick",
+		Channels: []meshfmt.Channel{
+			{Node: 3, Path: "rotation", Sampler: 0},
+			{Node: 1, Path: "translation", Sampler: 1},
+			{Node: 2, Path: "translation", Sampler: 1}, // not a joint
+			{Node: 3, Path: "weights", Sampler: 1},
+		},
+		Samplers: []meshfmt.AnimationSampler{
+			{Times: []float32{0, 1}, Values: []float32{0, 0, 0, 1, 0, 0, 1, 0}, Components: 4, Interpolation: "LINEAR"},
+			{Times: []float32{0, 1.5}, Values: []float32{0, 0, 0, 1, 0, 0}, Components: 3, Interpolation: "STEP"},
+		},
+	}}
+
+	var c, err = NewClipFromGLTF(doc, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "kick" || c.Duration != 1.5 || len(c.Channels) != 2 {
+		t.Fatalf("got Clip %q of %v seconds with %d channels", c.Name, c.Duration, len(c.Channels))
+	}
+	if ch := c.Channels[0]; ch.Joint != 0 || ch.Path != PathRotation || ch.Interpolation != InterpolateLinear {
+		t.Errorf("channel 0 %+v", ch)
+	}
+	if ch := c.Channels[1]; ch.Joint != 1 || ch.Path != PathTranslation || ch.Interpolation != InterpolateStep {
+		t.Errorf("channel 1 %+v", ch)
+	}
+
+	// A sampler of the wrong size for its path is an error.
+	doc.Animations[0].Channels[1].Sampler = 0
+	if _, err = NewClipFromGLTF(doc, 0, 0); err == nil {
+		t.Error("a translation of 4 components made a Clip")
+	}
+
+	// An animation of no joints makes no Clip.
+	doc.Animations[0].Channels = doc.Animations[0].Channels[2:]
+	if c, err = NewClipFromGLTF(doc, 0, 0); c != nil || err != nil {
+		t.Errorf("got %v, %v, want no Clip", c, err)
+	}
+}
@@ -0,0 +1,159 @@
+package asset
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// bmfontTags splits a line of a BMFont text descriptor into its tag and its
+// key=value pairs. Values may be quoted.
+func bmfontTags(line string) (tag string, values map[string]string) {
+	values = make(map[string]string)
+	line = strings.TrimSpace(line)
+
+	var i = strings.IndexByte(line, ' ')
+	if i < 0 {
+		return line, values
+	}
+	tag, line = line[:i], line[i+1:]
+
+	for {
+		line = strings.TrimLeft(line, " \t")
+		var eq = strings.IndexByte(line, '=')
+		if eq < 0 {
+			return tag, values
+		}
+		var key, rest = line[:eq], line[eq+1:]
+
+		if strings.HasPrefix(rest, `"`) {
+			var end = strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				values[key] = rest[1:]
+				return tag, values
+			}
+			values[key], line = rest[1:end+1], rest[end+2:]
+		} else {
+			var end = strings.IndexAny(rest, " \t")
+			if end < 0 {
+				values[key] = rest
+				return tag, values
+			}
+			values[key], line = rest[:end], rest[end:]
+		}
+	}
+}
+
+// bmfontInt parses an integer value, treating missing values as zero.
+func bmfontInt(values map[string]string, key string) int {
+	var v, _ = strconv.Atoi(values[key])
+	return v
+}
+
+// NewFontFromBMFont creates a Font from an AngelCode BMFont text descriptor.
+// 'open' is called to load each page image named by the descriptor. The pages
+// are stacked vertically into a single atlas; greyscale pages are treated as
+// coverage and stored as the alpha of white pixels.
+func NewFontFromBMFont(name string, desc io.Reader, open func(file string) (image.Image, error)) (*Font, error) {
+	type char struct {
+		id, page         int
+		rect             image.Rectangle
+		xoffset, yoffset int
+		xadvance         int
+	}
+
+	var (
+		scanner          = bufio.NewScanner(desc)
+		size, lineHeight int
+		base             int
+		pageW, pageH     int
+		pages            = make(map[int]string)
+		chars            []char
+		kerning          = make(map[[2]rune]float32)
+	)
+
+	for scanner.Scan() {
+		var tag, v = bmfontTags(scanner.Text())
+		switch tag {
+		case "info":
+			size = bmfontInt(v, "size")
+			if size < 0 {
+				size = -size // negative sizes match the cell height
+			}
+		case "common":
+			lineHeight, base = bmfontInt(v, "lineHeight"), bmfontInt(v, "base")
+			pageW, pageH = bmfontInt(v, "scaleW"), bmfontInt(v, "scaleH")
+		case "page":
+			pages[bmfontInt(v, "id")] = v["file"]
+		case "char":
+			var x, y = bmfontInt(v, "x"), bmfontInt(v, "y")
+			chars = append(chars, char{
+				id:       bmfontInt(v, "id"),
+				page:     bmfontInt(v, "page"),
+				rect:     image.Rect(x, y, x+bmfontInt(v, "width"), y+bmfontInt(v, "height")),
+				xoffset:  bmfontInt(v, "xoffset"),
+				yoffset:  bmfontInt(v, "yoffset"),
+				xadvance: bmfontInt(v, "xadvance"),
+			})
+		case "kerning":
+			kerning[[2]rune{rune(bmfontInt(v, "first")), rune(bmfontInt(v, "second"))}] = float32(bmfontInt(v, "amount"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if pageW <= 0 || pageH <= 0 || len(pages) == 0 {
+		return nil, fmt.Errorf("asset.NewFontFromBMFont error: '%s' is not a BMFont text descriptor", name)
+	}
+
+	var atlas = image.NewRGBA(image.Rect(0, 0, pageW, pageH*len(pages)))
+	for id, file := range pages {
+		if id < 0 || id >= len(pages) {
+			return nil, fmt.Errorf("asset.NewFontFromBMFont error: '%s' has invalid page id %d", name, id)
+		}
+
+		var img, err = open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var dst = image.Rect(0, id*pageH, pageW, (id+1)*pageH)
+		if gray, ok := img.(*image.Gray); ok {
+			var b = gray.Rect.Intersect(image.Rect(0, 0, pageW, pageH).Add(gray.Rect.Min))
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					var i = atlas.PixOffset(dst.Min.X+x-b.Min.X, dst.Min.Y+y-b.Min.Y)
+					atlas.Pix[i], atlas.Pix[i+1], atlas.Pix[i+2] = 0xff, 0xff, 0xff
+					atlas.Pix[i+3] = gray.GrayAt(x, y).Y
+				}
+			}
+		} else {
+			draw.Draw(atlas, dst, img, img.Bounds().Min, draw.Src)
+		}
+	}
+
+	var f, err = newFont(name, atlas, atlas.Rect.Dy(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Size = float32(size)
+	f.LineHeight = float32(lineHeight)
+	f.Ascent = float32(base)
+	f.Kerning = kerning
+
+	for _, c := range chars {
+		f.Glyphs[rune(c.id)] = &Glyph{
+			Rect:    c.rect.Add(image.Pt(0, c.page*pageH)),
+			Offset:  image.Pt(c.xoffset, c.yoffset-base),
+			Advance: float32(c.xadvance),
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,267 @@
+package asset
+
+import (
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB is an axis-aligned bounding box. A box with any Min component greater
+// than its Max is empty.
+type AABB struct {
+	Min, Max mgl.Vec3
+}
+
+// EmptyAABB is an AABB containing nothing, which any point extends.
+var EmptyAABB = AABB{
+	Min: mgl.Vec3{float32(math.Inf(1)), float32(math.Inf(1)), float32(math.Inf(1))},
+	Max: mgl.Vec3{float32(math.Inf(-1)), float32(math.Inf(-1)), float32(math.Inf(-1))},
+}
+
+// Empty reports whether the box contains nothing.
+func (b AABB) Empty() bool {
+	return b.Min[0] > b.Max[0] || b.Min[1] > b.Max[1] || b.Min[2] > b.Max[2]
+}
+
+// Center returns the centre of the box.
+func (b AABB) Center() mgl.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Extents returns the half-size of the box along each axis.
+func (b AABB) Extents() mgl.Vec3 {
+	return b.Max.Sub(b.Min).Mul(0.5)
+}
+
+// Extend returns the box grown to contain a point.
+func (b AABB) Extend(p mgl.Vec3) AABB {
+	for c := range p {
+		b.Min[c] = min(b.Min[c], p[c])
+		b.Max[c] = max(b.Max[c], p[c])
+	}
+	return b
+}
+
+// Union returns the smallest box containing both boxes.
+func (b AABB) Union(o AABB) AABB {
+	if o.Empty() {
+		return b
+	}
+	return b.Extend(o.Min).Extend(o.Max)
+}
+
+// Contains reports whether a point lies within the box.
+func (b AABB) Contains(p mgl.Vec3) bool {
+	return p[0] >= b.Min[0] && p[0] <= b.Max[0] &&
+		p[1] >= b.Min[1] && p[1] <= b.Max[1] &&
+		p[2] >= b.Min[2] && p[2] <= b.Max[2]
+}
+
+// Intersects reports whether two boxes overlap.
+func (b AABB) Intersects(o AABB) bool {
+	return b.Min[0] <= o.Max[0] && b.Max[0] >= o.Min[0] &&
+		b.Min[1] <= o.Max[1] && b.Max[1] >= o.Min[1] &&
+		b.Min[2] <= o.Max[2] && b.Max[2] >= o.Min[2]
+}
+
+// IntersectRay returns the distance along a ray at which it enters the box, in
+// units of the length of 'dir', or zero if it starts inside. False is returned
+// if the ray misses the box.
+func (b AABB) IntersectRay(origin, dir mgl.Vec3) (float32, bool) {
+	var near, far = float32(0), float32(math.Inf(1))
+	for c := range origin {
+		if dir[c] == 0 {
+			if origin[c] < b.Min[c] || origin[c] > b.Max[c] {
+				return 0, false
+			}
+			continue
+		}
+		var t0, t1 = (b.Min[c] - origin[c]) / dir[c], (b.Max[c] - origin[c]) / dir[c]
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		near, far = max(near, t0), min(far, t1)
+		if near > far {
+			return 0, false
+		}
+	}
+	return near, true
+}
+
+// Transform returns the box bounding this box under a transform.
+func (b AABB) Transform(m mgl.Mat4) AABB {
+	if b.Empty() {
+		return b
+	}
+
+	// Each axis of the transformed box is the translation plus the least and
+	// greatest contribution of each column.
+	var out = AABB{Min: m.Col(3).Vec3(), Max: m.Col(3).Vec3()}
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			var (
+				e    = m.At(row, col)
+				a, z = e * b.Min[col], e * b.Max[col]
+			)
+			out.Min[row] += min(a, z)
+			out.Max[row] += max(a, z)
+		}
+	}
+	return out
+}
+
+// Sphere is a bounding sphere. A sphere with a negative Radius is empty.
+type Sphere struct {
+	Center mgl.Vec3
+	Radius float32
+}
+
+// Contains reports whether a point lies within the sphere.
+func (s Sphere) Contains(p mgl.Vec3) bool {
+	return p.Sub(s.Center).LenSqr() <= s.Radius*s.Radius
+}
+
+// Intersects reports whether two spheres overlap.
+func (s Sphere) Intersects(o Sphere) bool {
+	if s.Radius < 0 || o.Radius < 0 {
+		return false
+	}
+	var r = s.Radius + o.Radius
+	return s.Center.Sub(o.Center).LenSqr() <= r*r
+}
+
+// Transform returns the sphere bounding this sphere under a transform, scaling
+// its radius by the transform's greatest scale.
+func (s Sphere) Transform(m mgl.Mat4) Sphere {
+	if s.Radius < 0 {
+		return s
+	}
+
+	var scale float32
+	for col := 0; col < 3; col++ {
+		scale = max(scale, m.Col(col).Vec3().Len())
+	}
+	return Sphere{m.Mul4x1(s.Center.Vec4(1)).Vec3(), s.Radius * scale}
+}
+
+// Union returns the smallest sphere containing both spheres.
+func (s Sphere) Union(o Sphere) Sphere {
+	switch {
+	case o.Radius < 0:
+		return s
+	case s.Radius < 0:
+		return o
+	}
+
+	var (
+		d    = o.Center.Sub(s.Center)
+		dist = d.Len()
+	)
+	switch {
+	case dist+o.Radius <= s.Radius:
+		return s
+	case dist+s.Radius <= o.Radius:
+		return o
+	}
+	var radius = (dist + s.Radius + o.Radius) / 2
+	return Sphere{s.Center.Add(d.Mul((radius - s.Radius) / dist)), radius}
+}
+
+// Bounds are the bounding box and sphere of a set of points.
+type Bounds struct {
+	Box    AABB
+	Sphere Sphere
+}
+
+// EmptyBounds are the Bounds of no points.
+var EmptyBounds = Bounds{EmptyAABB, Sphere{Radius: -1}}
+
+// NewBounds computes the Bounds of a set of points. The sphere is found by
+// Ritter's method, so it is close to, but may be up to a few percent larger
+// than, the smallest.
+func NewBounds(points []mgl.Vec3) Bounds {
+	if len(points) == 0 {
+		return EmptyBounds
+	}
+
+	var box = EmptyAABB
+	for _, p := range points {
+		box = box.Extend(p)
+	}
+
+	var farthest = func(from mgl.Vec3) mgl.Vec3 {
+		var (
+			best mgl.Vec3
+			dist float32 = -1
+		)
+		for _, p := range points {
+			if d := p.Sub(from).LenSqr(); d > dist {
+				best, dist = p, d
+			}
+		}
+		return best
+	}
+
+	var (
+		a      = farthest(points[0])
+		b      = farthest(a)
+		center = a.Add(b).Mul(0.5)
+		radius = b.Sub(a).Len() / 2
+	)
+	for _, p := range points {
+		var d = p.Sub(center).Len()
+		if d <= radius {
+			continue
+		}
+		// Grow the sphere just enough to reach the point.
+		var grown = (radius + d) / 2
+		center = center.Add(p.Sub(center).Mul((grown - radius) / d))
+		radius = grown
+	}
+
+	return Bounds{box, Sphere{center, radius}}
+}
+
+// Transform returns the Bounds under a transform, as by AABB.Transform and
+// Sphere.Transform.
+func (b Bounds) Transform(m mgl.Mat4) Bounds {
+	return Bounds{b.Box.Transform(m), b.Sphere.Transform(m)}
+}
+
+// Union returns the Bounds containing both Bounds.
+func (b Bounds) Union(o Bounds) Bounds {
+	return Bounds{b.Box.Union(o.Box), b.Sphere.Union(o.Sphere)}
+}
+
+// rawBounds computes the Bounds of raw position data, as read by readFloats.
+// Components beyond the third are ignored, and missing ones are zero.
+func rawBounds(data []byte, typ uint32, dims, stride, offset int) Bounds {
+	var (
+		floats = readFloats(data, typ, dims, false, stride, offset)
+		points = make([]mgl.Vec3, len(floats)/dims)
+	)
+	for i := range points {
+		copy(points[i][:], floats[i*dims:i*dims+min(dims, 3)])
+	}
+	return NewBounds(points)
+}
+
+// Bounds returns the bounds of the Mesh's "pos" attribute in model space, which
+// are kept as its data is created and updated. Meshes without positions have
+// EmptyBounds.
+func (m *Mesh) Bounds() Bounds {
+	if arr, ok := m.Attribs["pos"]; ok {
+		return arr.Bounds
+	}
+	for _, vb := range m.Buffers {
+		if _, ok := vb.Layout.Attrib("pos"); ok {
+			return vb.Bounds
+		}
+	}
+	return EmptyBounds
+}
+
+// WorldBounds returns the bounds of the Mesh under a model transform.
+func (m *Mesh) WorldBounds(model mgl.Mat4) Bounds {
+	return m.Bounds().Transform(model)
+}
@@ -0,0 +1,146 @@
+package asset
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// vecNear reports whether each component of two vectors differs by at most
+// 'eps'.
+func vecNear(a, b mgl.Vec3, eps float32) bool {
+	for c := range a {
+		if a[c]-b[c] > eps || b[c]-a[c] > eps {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAABB(t *testing.T) {
+	var b = EmptyAABB.Extend(mgl.Vec3{1, 2, 3}).Extend(mgl.Vec3{-1, 0, 5})
+	if b != (AABB{mgl.Vec3{-1, 0, 3}, mgl.Vec3{1, 2, 5}}) {
+		t.Fatalf("got %v", b)
+	}
+	if !EmptyAABB.Empty() || b.Empty() {
+		t.Error("emptiness is wrong")
+	}
+	if b.Center() != (mgl.Vec3{0, 1, 4}) || b.Extents() != (mgl.Vec3{1, 1, 1}) {
+		t.Errorf("centre %v and extents %v", b.Center(), b.Extents())
+	}
+	if b.Union(EmptyAABB) != b || EmptyAABB.Union(b) != b {
+		t.Error("union with an empty box changed the box")
+	}
+	if !b.Contains(mgl.Vec3{0, 2, 3}) || b.Contains(mgl.Vec3{0, 2, 2.9}) {
+		t.Error("containment is wrong")
+	}
+	if !b.Intersects(AABB{mgl.Vec3{1, 2, 5}, mgl.Vec3{3, 3, 6}}) || b.Intersects(AABB{mgl.Vec3{1.1, 0, 3}, mgl.Vec3{2, 1, 4}}) {
+		t.Error("intersection is wrong")
+	}
+}
+
+func TestAABBIntersectRay(t *testing.T) {
+	var b = AABB{mgl.Vec3{-1, -1, -1}, mgl.Vec3{1, 1, 1}}
+	var tests = []struct {
+		origin, dir mgl.Vec3
+		hit         bool
+		dist        float32
+	}{
+		{mgl.Vec3{-5, 0, 0}, mgl.Vec3{2, 0, 0}, true, 2},
+		{mgl.Vec3{0, 0, 0}, mgl.Vec3{0, 1, 0}, true, 0},
+		{mgl.Vec3{-5, 0, 0}, mgl.Vec3{-1, 0, 0}, false, 0},
+		{mgl.Vec3{-5, 2, 0}, mgl.Vec3{1, 0, 0}, false, 0},
+		{mgl.Vec3{-5, -5, 0}, mgl.Vec3{1, 1, 0}, true, 4},
+	}
+	for _, tt := range tests {
+		if dist, hit := b.IntersectRay(tt.origin, tt.dir); hit != tt.hit || dist != tt.dist {
+			t.Errorf("ray from %v along %v: got %v %v, want %v %v", tt.origin, tt.dir, dist, hit, tt.dist, tt.hit)
+		}
+	}
+}
+
+func TestAABBTransform(t *testing.T) {
+	// A quarter turn about Z, a doubling in X and a translation.
+	var (
+		b = AABB{mgl.Vec3{0, 0, 0}, mgl.Vec3{1, 2, 3}}
+		m = mgl.Translate3D(10, 0, 0).Mul4(mgl.HomogRotate3DZ(math.Pi / 2)).Mul4(mgl.Scale3D(2, 1, 1))
+		r = b.Transform(m)
+	)
+	var want = AABB{mgl.Vec3{8, 0, 0}, mgl.Vec3{10, 2, 3}}
+	if !vecNear(r.Min, want.Min, 1e-5) || !vecNear(r.Max, want.Max, 1e-5) {
+		t.Errorf("got %v, want %v", r, want)
+	}
+	if !EmptyAABB.Transform(m).Empty() {
+		t.Error("transformed empty box is not empty")
+	}
+}
+
+func TestSphere(t *testing.T) {
+	var (
+		a     = Sphere{mgl.Vec3{0, 0, 0}, 1}
+		b     = Sphere{mgl.Vec3{4, 0, 0}, 1}
+		empty = Sphere{Radius: -1}
+	)
+	if !a.Contains(mgl.Vec3{0, 1, 0}) || a.Contains(mgl.Vec3{0, 1.1, 0}) {
+		t.Error("containment is wrong")
+	}
+	if a.Intersects(b) || !a.Intersects(Sphere{mgl.Vec3{2, 0, 0}, 1}) || a.Intersects(empty) {
+		t.Error("intersection is wrong")
+	}
+	if u := a.Union(b); u != (Sphere{mgl.Vec3{2, 0, 0}, 3}) {
+		t.Errorf("union %v", u)
+	}
+	if u := a.Union(Sphere{mgl.Vec3{0.5, 0, 0}, 0.25}); u != a {
+		t.Errorf("union with a contained sphere %v", u)
+	}
+	if a.Union(empty) != a || empty.Union(a) != a {
+		t.Error("union with an empty sphere changed the sphere")
+	}
+
+	// Non-uniform scale grows the radius by the greatest factor.
+	var s = a.Transform(mgl.Translate3D(1, 2, 3).Mul4(mgl.Scale3D(1, 3, 2)))
+	if s.Center != (mgl.Vec3{1, 2, 3}) || s.Radius != 3 {
+		t.Errorf("transformed sphere %v", s)
+	}
+}
+
+func TestNewBounds(t *testing.T) {
+	if NewBounds(nil) != EmptyBounds {
+		t.Error("bounds of no points are not empty")
+	}
+
+	var points []mgl.Vec3
+	for i := 0; i < 32; i++ {
+		var a = float64(i) * 2 * math.Pi / 32
+		points = append(points, mgl.Vec3{float32(math.Cos(a)), float32(math.Sin(a)), float32(i%3) - 1})
+	}
+	var b = NewBounds(points)
+	if !vecNear(b.Box.Min, mgl.Vec3{-1, -1, -1}, 1e-6) || !vecNear(b.Box.Max, mgl.Vec3{1, 1, 1}, 1e-6) {
+		t.Errorf("box %v", b.Box)
+	}
+	// The smallest sphere has radius sqrt(2); Ritter's may be a little
+	// larger.
+	if b.Sphere.Radius < math.Sqrt2-1e-5 || b.Sphere.Radius > math.Sqrt2*1.05 {
+		t.Errorf("sphere radius %v", b.Sphere.Radius)
+	}
+	for _, p := range points {
+		if p.Sub(b.Sphere.Center).Len() > b.Sphere.Radius*(1+1e-5) {
+			t.Errorf("point %v is outside %v", p, b.Sphere)
+		}
+	}
+}
+
+func TestRawBounds(t *testing.T) {
+	// Two 2D float positions interleaved with a 4-byte attribute.
+	var data = make([]byte, 24)
+	for i, v := range []float32{-1, 2, 3, 4} {
+		binary.NativeEndian.PutUint32(data[12*(i/2)+4+4*(i%2):], math.Float32bits(v))
+	}
+	var b = rawBounds(data, gl.FLOAT, 2, 12, 4)
+	if b.Box != (AABB{mgl.Vec3{-1, 2, 0}, mgl.Vec3{3, 4, 0}}) {
+		t.Errorf("box %v", b.Box)
+	}
+}
@@ -0,0 +1,118 @@
+package asset
+
+// builtinPrefix marks shader names that LoadShader compiles from builtinShaders
+// rather than loading from a file.
+const builtinPrefix = "builtin/"
+
+// builtinShaders holds the source code of the shaders provided by the engine.
+// Vertex attributes use the locations of DefaultAttribLocations.
+var builtinShaders = map[string]string{
+	// The sdf shaders draw distance field text and icons, such as Fonts
+	// created by NewFontFromTTFSDF and Textures created by NewSDFTexture.
+	// Uniforms:
+	//  - mvp: model-view-projection matrix
+	//  - atlas: sampler of the distance field texture
+	//  - pxRange: the distance range of the texture in texels
+	//  - multichannel: non-zero if the texture is a multi-channel field
+	//  - tint: colour multiplied by the vertex colour
+	"builtin/sdf.vert": `#version 450 core
+
+layout(location = 0) in vec4 pos;
+layout(location = 1) in vec4 color;
+layout(location = 3) in vec2 texcoord0;
+
+uniform mat4 mvp;
+
+out vec4 vColor;
+out vec2 vTexcoord;
+
+void main() {
+	vColor = color;
+	vTexcoord = texcoord0;
+	gl_Position = mvp * pos;
+}
+`,
+	"builtin/sdf.frag": `#version 450 core
+
+in vec4 vColor;
+in vec2 vTexcoord;
+
+uniform sampler2D atlas;
+uniform float pxRange;
+uniform int multichannel;
+uniform vec4 tint = vec4(1.0);
+
+out vec4 fragColor;
+
+float median(float r, float g, float b) {
+	return max(min(r, g), min(max(r, g), b));
+}
+
+// screenPxRange is the distance range in screen pixels, which sets the
+// width of the antialiased edge regardless of scale.
+float screenPxRange() {
+	vec2 unitRange = vec2(pxRange) / vec2(textureSize(atlas, 0));
+	vec2 screenTexSize = vec2(1.0) / fwidth(vTexcoord);
+	return max(0.5 * dot(unitRange, screenTexSize), 1.0);
+}
+
+void main() {
+	vec4 s = texture(atlas, vTexcoord);
+	float dist = multichannel != 0 ? median(s.r, s.g, s.b) : s.a;
+	float opacity = clamp(screenPxRange() * (dist - 0.5) + 0.5, 0.0, 1.0);
+
+	vec4 color = vColor * tint;
+	fragColor = vec4(color.rgb, color.a * opacity);
+}
+`,
+
+	// The skinned shaders draw Meshes deformed by a Skeleton, each vertex
+	// blending the matrices of up to four joints from the JointPalette bound
+	// at JointPaletteBinding, with a single directional light.
+	// Uniforms:
+	//  - model: model matrix, applied after skinning
+	//  - viewProjection: view-projection matrix
+	//  - baseColor: colour of the surface
+	//  - lightDir: direction towards the light in world space
+	"builtin/skinned.vert": `#version 450 core
+
+layout(location = 0) in vec3 pos;
+layout(location = 2) in vec3 normal;
+layout(location = 7) in uvec4 joints0;
+layout(location = 8) in vec4 weights0;
+
+layout(std430, binding = 0) readonly buffer JointPalette {
+	mat4 joints[];
+};
+
+uniform mat4 model;
+uniform mat4 viewProjection;
+
+out vec3 vNormal;
+
+void main() {
+	float total = dot(weights0, vec4(1.0));
+	vec4 w = total > 0.0 ? weights0 / total : vec4(1.0, 0.0, 0.0, 0.0);
+	mat4 skin = w.x * joints[joints0.x] + w.y * joints[joints0.y] +
+		w.z * joints[joints0.z] + w.w * joints[joints0.w];
+
+	mat4 world = model * skin;
+	vNormal = mat3(world) * normal;
+	gl_Position = viewProjection * world * vec4(pos, 1.0);
+}
+`,
+	"builtin/skinned.frag": `#version 450 core
+
+in vec3 vNormal;
+
+uniform vec4 baseColor = vec4(1.0);
+uniform vec3 lightDir = vec3(0.3, 0.8, 0.5);
+
+out vec4 fragColor;
+
+void main() {
+	float diffuse = max(dot(normalize(vNormal), normalize(lightDir)), 0.0);
+	fragColor = vec4(baseColor.rgb * (0.2 + 0.8 * diffuse), baseColor.a);
+}
+`,
+}
@@ -0,0 +1,167 @@
+package asset
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/Ostsol/engine/texfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// sRGB S3TC formats from EXT_texture_sRGB, which the core bindings omit.
+const (
+	compressedSRGBAlphaS3TCDXT1 = 0x8c4d
+	compressedSRGBAlphaS3TCDXT3 = 0x8c4e
+	compressedSRGBAlphaS3TCDXT5 = 0x8c4f
+)
+
+// glFormat describes how a texfmt.Format is uploaded to OpenGL. 'format' and
+// 'typ' are only used by uncompressed formats.
+type glFormat struct {
+	internal uint32
+	format   uint32
+	typ      uint32
+}
+
+var glFormats = map[texfmt.Format]glFormat{
+	texfmt.RGBA8:     {gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE},
+	texfmt.RGBA8SRGB: {gl.SRGB8_ALPHA8, gl.RGBA, gl.UNSIGNED_BYTE},
+	texfmt.RG8:       {gl.RG8, gl.RG, gl.UNSIGNED_BYTE},
+	texfmt.R8:        {gl.R8, gl.RED, gl.UNSIGNED_BYTE},
+	texfmt.RGBA16F:   {gl.RGBA16F, gl.RGBA, gl.HALF_FLOAT},
+	texfmt.RGB32F:    {gl.RGB32F, gl.RGB, gl.FLOAT},
+	texfmt.RGBA32F:   {gl.RGBA32F, gl.RGBA, gl.FLOAT},
+
+	texfmt.BC1:     {internal: gl.COMPRESSED_RGBA_S3TC_DXT1_EXT},
+	texfmt.BC1SRGB: {internal: compressedSRGBAlphaS3TCDXT1},
+	texfmt.BC2:     {internal: gl.COMPRESSED_RGBA_S3TC_DXT3_EXT},
+	texfmt.BC2SRGB: {internal: compressedSRGBAlphaS3TCDXT3},
+	texfmt.BC3:     {internal: gl.COMPRESSED_RGBA_S3TC_DXT5_EXT},
+	texfmt.BC3SRGB: {internal: compressedSRGBAlphaS3TCDXT5},
+	texfmt.BC4:     {internal: gl.COMPRESSED_RED_RGTC1},
+	texfmt.BC4S:    {internal: gl.COMPRESSED_SIGNED_RED_RGTC1},
+	texfmt.BC5:     {internal: gl.COMPRESSED_RG_RGTC2},
+	texfmt.BC5S:    {internal: gl.COMPRESSED_SIGNED_RG_RGTC2},
+	texfmt.BC6HU:   {internal: gl.COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT},
+	texfmt.BC6HS:   {internal: gl.COMPRESSED_RGB_BPTC_SIGNED_FLOAT},
+	texfmt.BC7:     {internal: gl.COMPRESSED_RGBA_BPTC_UNORM},
+	texfmt.BC7SRGB: {internal: gl.COMPRESSED_SRGB_ALPHA_BPTC_UNORM},
+
+	texfmt.ETC2RGB8:       {internal: gl.COMPRESSED_RGB8_ETC2},
+	texfmt.ETC2RGB8SRGB:   {internal: gl.COMPRESSED_SRGB8_ETC2},
+	texfmt.ETC2RGB8A1:     {internal: gl.COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2},
+	texfmt.ETC2RGB8A1SRGB: {internal: gl.COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2},
+	texfmt.ETC2RGBA8:      {internal: gl.COMPRESSED_RGBA8_ETC2_EAC},
+	texfmt.ETC2RGBA8SRGB:  {internal: gl.COMPRESSED_SRGB8_ALPHA8_ETC2_EAC},
+	texfmt.EACR11:         {internal: gl.COMPRESSED_R11_EAC},
+	texfmt.EACR11S:        {internal: gl.COMPRESSED_SIGNED_R11_EAC},
+	texfmt.EACRG11:        {internal: gl.COMPRESSED_RG11_EAC},
+	texfmt.EACRG11S:       {internal: gl.COMPRESSED_SIGNED_RG11_EAC},
+}
+
+// formatSupported reports whether the driver supports textures of the given
+// target and internal format.
+func formatSupported(target, internal uint32) bool {
+	var supported int32
+	gl.GetInternalformativ(target, internal, gl.INTERNALFORMAT_SUPPORTED, 1, &supported)
+	return supported == gl.TRUE
+}
+
+// imageTarget returns the texture target appropriate for an image.
+func imageTarget(img *texfmt.Image) uint32 {
+	switch {
+	case img.Faces == 6 && img.Array:
+		return gl.TEXTURE_CUBE_MAP_ARRAY
+	case img.Faces == 6:
+		return gl.TEXTURE_CUBE_MAP
+	case img.Array:
+		return gl.TEXTURE_2D_ARRAY
+	}
+	return gl.TEXTURE_2D
+}
+
+// NewTextureFromData creates a Texture from a decoded texture file, including
+// its mip chain, array layers and cube faces. Block compressed data is
+// uploaded as is if the driver supports its format, and is otherwise
+// decompressed on the CPU.
+func NewTextureFromData(name string, img *texfmt.Image) (*Texture, error) {
+	var (
+		target   = imageTarget(img)
+		glf, ok  = glFormats[img.Format]
+		err      error
+		levels   = int32(len(img.Levels))
+		internal uint32
+	)
+
+	if !ok {
+		return nil, fmt.Errorf("asset.NewTextureFromData error: '%s' has unsupported format %v", name, img.Format)
+	}
+
+	if img.Format.Compressed() && !formatSupported(target, glf.internal) {
+		Logger.Printf("asset.NewTextureFromData: %v unsupported, decompressing '%s'\n", img.Format, name)
+		if img, err = texfmt.Decompress(img); err != nil {
+			return nil, err
+		}
+		glf = glFormats[img.Format]
+	}
+	internal = glf.internal
+
+	var t = newTexture(name, target, img.Width, img.Height)
+
+	gl.BindTexture(target, t.Tex)
+	defer gl.BindTexture(target, 0)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	defer gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+
+	gl.TexParameteri(target, gl.TEXTURE_BASE_LEVEL, 0)
+	gl.TexParameteri(target, gl.TEXTURE_MAX_LEVEL, levels-1)
+	if levels > 1 {
+		gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	}
+
+	for level := range img.Levels {
+		var (
+			w, h  = img.LevelSize(level)
+			data  = img.Levels[level]
+			depth = int32(img.Layers * img.Faces)
+			lvl   = int32(level)
+		)
+
+		switch target {
+		case gl.TEXTURE_2D:
+			if img.Format.Compressed() {
+				gl.CompressedTexImage2D(target, lvl, internal, int32(w), int32(h), 0, int32(len(data)), unsafe.Pointer(&data[0]))
+			} else {
+				gl.TexImage2D(target, lvl, int32(internal), int32(w), int32(h), 0, glf.format, glf.typ, unsafe.Pointer(&data[0]))
+			}
+
+		case gl.TEXTURE_CUBE_MAP:
+			for face := 0; face < 6; face++ {
+				var (
+					faceTarget = uint32(gl.TEXTURE_CUBE_MAP_POSITIVE_X + face)
+					slice      = img.Slice(level, 0, face)
+				)
+				if img.Format.Compressed() {
+					gl.CompressedTexImage2D(faceTarget, lvl, internal, int32(w), int32(h), 0, int32(len(slice)), unsafe.Pointer(&slice[0]))
+				} else {
+					gl.TexImage2D(faceTarget, lvl, int32(internal), int32(w), int32(h), 0, glf.format, glf.typ, unsafe.Pointer(&slice[0]))
+				}
+			}
+
+		default:
+			if img.Format.Compressed() {
+				gl.CompressedTexImage3D(target, lvl, internal, int32(w), int32(h), depth, 0, int32(len(data)), unsafe.Pointer(&data[0]))
+			} else {
+				gl.TexImage3D(target, lvl, int32(internal), int32(w), int32(h), depth, 0, glf.format, glf.typ, unsafe.Pointer(&data[0]))
+			}
+		}
+	}
+
+	if e := gl.GetError(); e != gl.NO_ERROR {
+		t.Clean()
+		return nil, fmt.Errorf("asset.NewTextureFromData error: uploading '%s' failed with GL error 0x%x", name, e)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,147 @@
+package asset
+
+import (
+	"os"
+
+	"github.com/Ostsol/engine/ibl"
+	"github.com/Ostsol/engine/texfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// brdfName is the name under which the shared BRDF table is stored.
+const brdfName = "ibl:brdf"
+
+// Environment is the set of textures used for image-based lighting, baked from
+// an HDR environment map.
+type Environment struct {
+	Skybox     *Texture // the environment as a cube map
+	Irradiance *Texture // diffuse irradiance cube map
+	Specular   *Texture // prefiltered specular cube map, by roughness
+	BRDF       *Texture // split-sum BRDF table, shared by all environments
+}
+
+// EnvironmentOptions controls the resolution and quality of a baked
+// Environment.
+type EnvironmentOptions struct {
+	Size           int // face size of the skybox
+	IrradianceSize int // face size of the irradiance map
+	SpecularSize   int // face size of the base specular level
+	SpecularLevels int // number of specular roughness levels
+	Samples        int // importance samples per specular texel
+	BRDFSize       int // size of the BRDF table
+}
+
+// DefaultEnvironmentOptions is used when LoadEnvironment is given nil options.
+var DefaultEnvironmentOptions = EnvironmentOptions{
+	Size:           512,
+	IrradianceSize: 32,
+	SpecularSize:   128,
+	SpecularLevels: 6,
+	Samples:        256,
+	BRDFSize:       128,
+}
+
+// LoadEnvironment loads an HDR environment map from the file 'name' and bakes
+// the textures for image-based lighting from it on the CPU. The file may be an
+// equirectangular panorama in any format LoadTexture reads, or a cube map.
+// The textures are added to the Manager as 'name' followed by ":skybox",
+// ":irradiance" and ":specular", and are reused if they already exist.
+// Seamless cube map filtering is enabled, since prefiltered levels are
+// visibly seamed without it.
+func (am *Manager) LoadEnvironment(name string, opts *EnvironmentOptions) (*Environment, error) {
+	if opts == nil {
+		opts = &DefaultEnvironmentOptions
+	}
+
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	var (
+		env Environment
+		err error
+	)
+
+	if env.BRDF, err = am.brdfTexture(opts.BRDFSize); err != nil {
+		return nil, err
+	}
+
+	var (
+		skybox, ok1     = am.GetTexture(name + ":skybox")
+		irradiance, ok2 = am.GetTexture(name + ":irradiance")
+		specular, ok3   = am.GetTexture(name + ":specular")
+	)
+	if ok1 && ok2 && ok3 {
+		env.Skybox, env.Irradiance, env.Specular = skybox, irradiance, specular
+		return &env, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadEnvironment: baking Environment '%s'\n", name)
+
+	var (
+		f   *os.File
+		src *texfmt.Image
+	)
+
+	if f, err = os.Open("assets/textures/" + name); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if src, err = texfmt.Decode(f); err != nil {
+		return nil, err
+	}
+
+	var cube = src
+	if src.Faces != 6 {
+		if cube, err = ibl.EquirectToCube(src, opts.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	irr, err := ibl.Irradiance(cube, opts.IrradianceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := ibl.Prefilter(cube, opts.SpecularSize, opts.SpecularLevels, opts.Samples)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range []struct {
+		dst    **Texture
+		suffix string
+		img    *texfmt.Image
+	}{
+		{&env.Skybox, ":skybox", cube},
+		{&env.Irradiance, ":irradiance", irr},
+		{&env.Specular, ":specular", spec},
+	} {
+		if tex, ok := am.GetTexture(name + t.suffix); ok {
+			*t.dst = tex
+			continue
+		}
+		if *t.dst, err = NewTextureFromData(name+t.suffix, t.img); err != nil {
+			return nil, err
+		}
+		am.AddTexture(*t.dst)
+	}
+
+	Logger.Print("asset.Manager.LoadEnvironment: Environment baked")
+
+	return &env, nil
+}
+
+// brdfTexture returns the shared BRDF table, computing it if necessary.
+func (am *Manager) brdfTexture(size int) (*Texture, error) {
+	if tex, ok := am.GetTexture(brdfName); ok {
+		return tex, nil
+	}
+
+	var tex, err = NewTextureFromData(brdfName, ibl.BRDFLUT(size, 512))
+	if err != nil {
+		return nil, err
+	}
+	am.AddTexture(tex)
+
+	return tex, nil
+}
@@ -0,0 +1,366 @@
+package asset
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxAtlasSize limits the height to which a Font's atlas may grow.
+const maxAtlasSize = 4096
+
+// Glyph locates a character in a Font's atlas and describes its metrics. All
+// values are in atlas pixels, with y pointing down.
+type Glyph struct {
+	Rect    image.Rectangle // area of the atlas holding the glyph
+	Offset  image.Point     // from the pen position on the baseline to Rect.Min
+	Advance float32         // distance from this pen position to the next
+}
+
+// glyphSource produces the images of glyphs inserted into a Font's atlas.
+type glyphSource interface {
+	// glyph renders a character. An empty image is valid for glyphs such as
+	// spaces that have an advance but nothing to draw.
+	glyph(r rune) (img *image.RGBA, offset image.Point, advance float32, ok bool)
+	// kern returns the kerning adjustment between two characters.
+	kern(a, b rune) float32
+	close()
+}
+
+// Font is a set of glyphs packed into an atlas Texture. Fonts loaded from
+// TrueType or OpenType files rasterize a glyph the first time it is requested
+// and add it to the atlas, which grows when full. BMFont fonts are limited to
+// the glyphs in their pages.
+type Font struct {
+	Name       string
+	Size       float32 // nominal size in pixels
+	LineHeight float32 // distance between baselines
+	Ascent     float32 // distance from the top of a line to the baseline
+	Atlas      *Texture
+	Glyphs     map[rune]*Glyph
+	Kerning    map[[2]rune]float32
+
+	// DistanceRange is the width in atlas pixels of the band of distances
+	// stored by distance field fonts, and zero for other fonts. MultiChannel
+	// is set if the field has red, green and blue channels.
+	DistanceRange float32
+	MultiChannel  bool
+
+	// Generation is incremented whenever the atlas grows. Glyph rectangles
+	// remain valid, but texture coordinates computed earlier do not.
+	Generation int
+
+	src    glyphSource
+	img    *image.RGBA
+	packer shelfPacker
+}
+
+// shelfPacker allocates rectangles in rows of increasing y.
+type shelfPacker struct {
+	w, h      int
+	x, y, row int
+}
+
+// pack reserves a w by h rectangle, returning false if it does not fit.
+func (p *shelfPacker) pack(w, h int) (image.Point, bool) {
+	if w > p.w {
+		return image.Point{}, false
+	}
+	if p.x+w > p.w {
+		p.x, p.y, p.row = 0, p.y+p.row, 0
+	}
+	if p.y+h > p.h {
+		return image.Point{}, false
+	}
+
+	var pt = image.Pt(p.x, p.y)
+	p.x += w
+	if h > p.row {
+		p.row = h
+	}
+	return pt, true
+}
+
+// glyphPadding is the empty border kept around each glyph in the atlas, so
+// that filtering does not bleed neighbouring glyphs into each other.
+const glyphPadding = 1
+
+// newFont creates a Font and uploads its initial atlas image. Glyphs inserted
+// later are packed below 'used' rows of the image.
+func newFont(name string, img *image.RGBA, used int, src glyphSource) (*Font, error) {
+	var (
+		w, h = img.Rect.Dx(), img.Rect.Dy()
+		f    = &Font{
+			Name:    name,
+			Glyphs:  make(map[rune]*Glyph),
+			Kerning: make(map[[2]rune]float32),
+			src:     src,
+			img:     img,
+			packer:  shelfPacker{w: w, h: h, y: used},
+		}
+	)
+
+	f.Atlas = NewTexture(name, w, h)
+	if err := f.Atlas.LoadRGBA(img, 0); err != nil {
+		f.Atlas.Clean()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// NewFontFromTTF creates a Font from a TrueType or OpenType file, rasterized
+// at 'size' pixels per em. The printable ASCII characters are added to the
+// atlas immediately; others are added when first requested.
+func NewFontFromTTF(name string, data []byte, size float64) (*Font, error) {
+	var otf, err = opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewFontFromTTF error: '%s': %v", name, err)
+	}
+
+	face, err := opentype.NewFace(otf, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewFontFromTTF error: '%s': %v", name, err)
+	}
+
+	var metrics = face.Metrics()
+
+	f, err := newFont(name, image.NewRGBA(image.Rect(0, 0, 256, 256)), 0, &faceSource{face: face})
+	if err != nil {
+		face.Close()
+		return nil, err
+	}
+
+	f.Size = float32(size)
+	f.LineHeight = fixedToFloat(metrics.Height)
+	f.Ascent = fixedToFloat(metrics.Ascent)
+
+	if err = f.Preload(asciiPrintable); err != nil {
+		f.Clean()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// asciiPrintable holds the characters preloaded into outline fonts.
+var asciiPrintable = func() string {
+	var b = make([]byte, 0, 95)
+	for c := byte(' '); c <= '~'; c++ {
+		b = append(b, c)
+	}
+	return string(b)
+}()
+
+func fixedToFloat(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
+
+// faceSource renders glyphs from a font.Face.
+type faceSource struct {
+	face font.Face
+}
+
+func (s *faceSource) glyph(r rune) (*image.RGBA, image.Point, float32, bool) {
+	var dr, mask, maskp, advance, ok = s.face.Glyph(fixed.Point26_6{}, r)
+	if !ok {
+		return nil, image.Point{}, 0, false
+	}
+
+	// The mask is owned by the face, so its coverage is copied out as the
+	// alpha of white pixels.
+	var img = image.NewRGBA(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			var (
+				_, _, _, a = mask.At(maskp.X+x, maskp.Y+y).RGBA()
+				i          = img.PixOffset(x, y)
+			)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 0xff, 0xff, 0xff, uint8(a>>8)
+		}
+	}
+
+	return img, dr.Min, fixedToFloat(advance), true
+}
+
+func (s *faceSource) kern(a, b rune) float32 {
+	return fixedToFloat(s.face.Kern(a, b))
+}
+
+func (s *faceSource) close() {
+	s.face.Close()
+}
+
+// Glyph returns the glyph for a character, adding it to the atlas if the Font
+// can render it. If the Font has no glyph for the character, the glyph for
+// U+FFFD or '?' is returned instead, and false if neither exists.
+func (f *Font) Glyph(r rune) (*Glyph, bool) {
+	if g, ok := f.Glyphs[r]; ok {
+		return g, true
+	}
+
+	if f.src != nil {
+		var dirty, grown, err = f.insert(r)
+		if err != nil {
+			Logger.Printf("asset.Font.Glyph: %v\n", err)
+		}
+		f.upload(dirty, grown)
+		if g, ok := f.Glyphs[r]; ok {
+			return g, true
+		}
+	}
+
+	for _, fallback := range []rune{utf8.RuneError, '?'} {
+		if g, ok := f.Glyphs[fallback]; ok && fallback != r {
+			return g, true
+		}
+	}
+
+	return nil, false
+}
+
+// Preload adds the glyphs of every character in 's' to the atlas, uploading
+// the atlas once at the end.
+func (f *Font) Preload(s string) error {
+	if f.src == nil {
+		return nil
+	}
+
+	var (
+		dirty image.Rectangle
+		grown bool
+		err   error
+	)
+
+	for _, r := range s {
+		if _, ok := f.Glyphs[r]; ok {
+			continue
+		}
+		var d, g, e = f.insert(r)
+		dirty, grown = dirty.Union(d), grown || g
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	f.upload(dirty, grown)
+
+	return err
+}
+
+// insert renders a glyph into the atlas image, growing it if necessary. It
+// returns the area of the atlas that changed and whether the atlas grew.
+func (f *Font) insert(r rune) (dirty image.Rectangle, grown bool, err error) {
+	var img, offset, advance, ok = f.src.glyph(r)
+	if !ok {
+		return image.Rectangle{}, false, nil
+	}
+
+	var g = &Glyph{Offset: offset, Advance: advance}
+	f.Glyphs[r] = g
+
+	var w, h = img.Rect.Dx(), img.Rect.Dy()
+	if w == 0 || h == 0 {
+		return image.Rectangle{}, false, nil
+	}
+
+	var pt, fits = f.packer.pack(w+2*glyphPadding, h+2*glyphPadding)
+	for !fits {
+		if f.packer.h*2 > maxAtlasSize {
+			delete(f.Glyphs, r)
+			return image.Rectangle{}, grown, fmt.Errorf("asset.Font error: atlas of '%s' is full", f.Name)
+		}
+		f.grow()
+		grown = true
+		pt, fits = f.packer.pack(w+2*glyphPadding, h+2*glyphPadding)
+	}
+
+	g.Rect = image.Rect(0, 0, w, h).Add(pt).Add(image.Pt(glyphPadding, glyphPadding))
+	draw.Draw(f.img, g.Rect, img, img.Rect.Min, draw.Src)
+
+	return g.Rect, grown, nil
+}
+
+// grow doubles the height of the atlas image.
+func (f *Font) grow() {
+	var img = image.NewRGBA(image.Rect(0, 0, f.packer.w, f.packer.h*2))
+	copy(img.Pix, f.img.Pix)
+	f.img = img
+	f.packer.h *= 2
+	f.Generation++
+}
+
+// upload sends changes to the atlas image to its Texture.
+func (f *Font) upload(dirty image.Rectangle, grown bool) {
+	var err error
+	if grown {
+		f.Atlas.W, f.Atlas.H = f.img.Rect.Dx(), f.img.Rect.Dy()
+		err = f.Atlas.LoadRGBA(f.img, 0)
+	} else if !dirty.Empty() {
+		err = f.Atlas.LoadSubRGBA(f.img.SubImage(dirty).(*image.RGBA), dirty.Min, 0)
+	}
+	if err != nil {
+		Logger.Printf("asset.Font.upload: '%s': %v\n", f.Name, err)
+	}
+}
+
+// Kern returns the kerning adjustment to add to the advance of 'a' when it is
+// followed by 'b'.
+func (f *Font) Kern(a, b rune) float32 {
+	var pair = [2]rune{a, b}
+	if k, ok := f.Kerning[pair]; ok {
+		return k
+	}
+	if f.src == nil {
+		return 0
+	}
+
+	var k = f.src.kern(a, b)
+	f.Kerning[pair] = k
+	return k
+}
+
+// TexCoords returns the texture coordinates of a glyph's corners in the
+// atlas.
+func (f *Font) TexCoords(g *Glyph) (u0, v0, u1, v1 float32) {
+	var w, h = float32(f.Atlas.W), float32(f.Atlas.H)
+	return float32(g.Rect.Min.X) / w, float32(g.Rect.Min.Y) / h,
+		float32(g.Rect.Max.X) / w, float32(g.Rect.Max.Y) / h
+}
+
+// Measure returns the width of a single line of text, including kerning.
+func (f *Font) Measure(s string) float32 {
+	var (
+		w    float32
+		prev rune = -1
+	)
+	for _, r := range s {
+		var g, ok = f.Glyph(r)
+		if !ok {
+			continue
+		}
+		if prev >= 0 {
+			w += f.Kern(prev, r)
+		}
+		w += g.Advance
+		prev = r
+	}
+	return w
+}
+
+// Clean deletes the Font's atlas and releases its glyph source.
+func (f *Font) Clean() {
+	if f.src != nil {
+		f.src.close()
+	}
+	f.Atlas.Clean()
+}
@@ -0,0 +1,45 @@
+package asset
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestShelfPacker(t *testing.T) {
+	var (
+		p     = shelfPacker{w: 10, h: 8}
+		tests = []struct {
+			w, h int
+			want image.Point
+			ok   bool
+		}{
+			{4, 3, image.Pt(0, 0), true},
+			{4, 2, image.Pt(4, 0), true},
+			{4, 4, image.Pt(0, 3), true}, // the next shelf is as tall as the first
+			{6, 1, image.Pt(4, 3), true},
+			{11, 1, image.Point{}, false},
+			{10, 2, image.Point{}, false}, // a third shelf overflows
+		}
+	)
+	for i, tt := range tests {
+		var pt, ok = p.pack(tt.w, tt.h)
+		if ok != tt.ok || ok && pt != tt.want {
+			t.Errorf("rectangle %d: got %v %v, want %v %v", i, pt, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBMFontTags(t *testing.T) {
+	var tag, values = bmfontTags(`info face="Sans Serif" size=32  bold=0 charset=""`)
+	var want = map[string]string{"face": "Sans Serif", "size": "32", "bold": "0", "charset": ""}
+	if tag != "info" || !reflect.DeepEqual(values, want) {
+		t.Errorf("got %q %v, want info %v", tag, values, want)
+	}
+	if bmfontInt(values, "size") != 32 || bmfontInt(values, "missing") != 0 {
+		t.Errorf("bmfontInt read %d and %d", bmfontInt(values, "size"), bmfontInt(values, "missing"))
+	}
+	if tag, values = bmfontTags("chars"); tag != "chars" || len(values) != 0 {
+		t.Errorf("got %q %v", tag, values)
+	}
+}
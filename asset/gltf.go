@@ -0,0 +1,301 @@
+package asset
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // for jpeg textures embedded in glTF files
+	"os"
+	"path/filepath"
+
+	"github.com/Ostsol/engine/meshfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Scene is the content of a glTF file. Its meshes, materials and textures are
+// loaded as engine assets, and its skins and animations as Skeletons and
+// Clips, while the node hierarchy and cameras are left in the GLTF
+// description for the caller to use.
+type Scene struct {
+	Name      string
+	GLTF      *meshfmt.GLTF
+	Models    []*Model    // for each glTF mesh, indexed as in GLTF.Meshes
+	Materials []*Material // for each glTF material
+	Textures  []*Texture  // for each glTF texture, or nil if only used for colours
+	Skeletons []*Skeleton // for each glTF skin
+
+	// SRGBTextures are, for each glTF texture used as a base colour or
+	// emissive map, the Texture with its colours stored as sRGB, or nil.
+	SRGBTextures []*Texture
+
+	// Clips are, for each glTF skin, a Clip of each glTF animation, or nil if
+	// the animation moves none of the skin's joints.
+	Clips [][]*Clip
+}
+
+// LoadGLTF attempts to load a Scene from the glTF file 'name', which may be
+// either a .gltf file or a binary .glb file. Each glTF mesh becomes a Model
+// named 'name:mesh', holding one Mesh of the same name with a Submesh and a
+// material slot for each primitive. Materials are named 'name:material', and
+// embedded textures 'name:texture%d'. Base colour and emissive maps are
+// loaded as sRGB, named with ':srgb' appended, and other maps as linear data.
+// If the Scene already exists, it is returned.
+//
+// Material factors are stored as the Params "baseColor" (mgl.Vec4),
+// "metallic", "roughness", "normalScale", "occlusionStrength" and
+// "alphaCutoff" (float32), and "emissive" (mgl.Vec3). Textures are bound to
+// the samplers "baseColorMap", "metallicRoughnessMap", "normalMap",
+// "occlusionMap" and "emissiveMap", each through a sampler object of the
+// Material holding the glTF sampler's filters and wrap modes, so the Textures
+// themselves are left as they are for other Materials. The Materials have no
+// shader program, which must be set before drawing.
+//
+// Skinned primitives keep their joint indices and weights in the attributes
+// "joints0" and "weights0", and may be drawn with "builtin/skinned.vert" and a
+// JointPalette updated from the Scene's Skeletons.
+func (am *Manager) LoadGLTF(name string) (*Scene, error) {
+	if s, ok := am.GetScene(name); ok {
+		return s, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadGLTF: loading Scene '%s'\n", name)
+
+	var (
+		path = "assets/models/" + name
+		dir  = filepath.Dir(path)
+		doc  *meshfmt.GLTF
+	)
+
+	var f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var open = func(uri string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(uri)))
+	}
+	if doc, err = meshfmt.DecodeGLTF(f, open); err != nil {
+		Logger.Print("asset.Manager.LoadGLTF: failed")
+		return nil, err
+	}
+
+	var scene = &Scene{Name: name, GLTF: doc}
+
+	// Colour maps are loaded as sRGB, and every other use as linear data.
+	var colour, linear = make([]bool, len(doc.Textures)), make([]bool, len(doc.Textures))
+	for i := range linear {
+		linear[i] = true
+	}
+	for _, m := range doc.Materials {
+		for _, t := range []int{m.BaseColorTexture.Texture, m.EmissiveTexture.Texture} {
+			if t >= 0 {
+				colour[t], linear[t] = true, false
+			}
+		}
+	}
+	for _, m := range doc.Materials {
+		for _, t := range []int{m.MetallicRoughnessTexture.Texture, m.NormalTexture.Texture, m.OcclusionTexture.Texture} {
+			if t >= 0 {
+				linear[t] = true
+			}
+		}
+	}
+
+	scene.Textures = make([]*Texture, len(doc.Textures))
+	scene.SRGBTextures = make([]*Texture, len(doc.Textures))
+	for i := range doc.Textures {
+		var err error
+		if linear[i] {
+			scene.Textures[i], err = am.loadGLTFTexture(name, dir, doc, i, false)
+		}
+		if colour[i] && err == nil {
+			scene.SRGBTextures[i], err = am.loadGLTFTexture(name, dir, doc, i, true)
+		}
+		if err != nil {
+			Logger.Print("asset.Manager.LoadGLTF: failed")
+			return nil, err
+		}
+	}
+
+	for i, m := range doc.Materials {
+		var matName = fmt.Sprintf("%s:material%d", name, i)
+		if m.Name != "" {
+			matName = name + ":" + m.Name
+		}
+		if _, ok := am.GetMaterial(matName); ok {
+			matName = fmt.Sprintf("%s#%d", matName, i)
+		}
+
+		var mat = NewMaterial(matName)
+		mat.Params["baseColor"] = m.BaseColor
+		mat.Params["metallic"] = m.Metallic
+		mat.Params["roughness"] = m.Roughness
+		mat.Params["normalScale"] = m.NormalTexture.Scale
+		mat.Params["occlusionStrength"] = m.OcclusionTexture.Scale
+		mat.Params["emissive"] = m.Emissive
+		if m.AlphaMode == "MASK" {
+			mat.Params["alphaCutoff"] = m.AlphaCutoff
+		}
+
+		for _, t := range []struct {
+			info     meshfmt.TextureInfo
+			sampler  string
+			textures []*Texture
+		}{
+			{m.BaseColorTexture, "baseColorMap", scene.SRGBTextures},
+			{m.MetallicRoughnessTexture, "metallicRoughnessMap", scene.Textures},
+			{m.NormalTexture, "normalMap", scene.Textures},
+			{m.OcclusionTexture, "occlusionMap", scene.Textures},
+			{m.EmissiveTexture, "emissiveMap", scene.SRGBTextures},
+		} {
+			if t.info.Texture < 0 || t.textures[t.info.Texture] == nil {
+				continue
+			}
+			mat.Params[t.sampler] = int32(len(mat.Textures))
+			mat.AddSamplerObject(len(mat.Textures), newGLTFSampler(doc.Textures[t.info.Texture]))
+			mat.AddTextures(t.textures[t.info.Texture])
+			mat.AddSamplers(t.sampler)
+		}
+
+		am.AddMaterial(mat)
+		scene.Materials = append(scene.Materials, mat)
+	}
+
+	for i, group := range doc.Meshes {
+		var modelName = fmt.Sprintf("%s:mesh%d", name, i)
+		if group.Name != "" {
+			modelName = name + ":" + group.Name
+		}
+		if _, ok := am.GetModel(modelName); ok {
+			modelName = fmt.Sprintf("%s#%d", modelName, i)
+		}
+
+		// Each primitive is a Submesh, with a slot for its material.
+		var (
+			model = &Model{Name: modelName}
+			slots = make([]int, len(group.Primitives))
+		)
+		for p := range group.Primitives {
+			var mat *Material
+			if m := group.Materials[p]; m >= 0 {
+				mat = scene.Materials[m]
+			}
+			slots[p] = p
+			model.Materials = append(model.Materials, mat)
+		}
+
+		if len(group.Primitives) > 0 {
+			var meshName = modelName
+			if _, ok := am.GetMesh(meshName); ok {
+				meshName = fmt.Sprintf("%s#%d", meshName, i)
+			}
+
+			var mesh, err = NewMeshFromParts(meshName, group.Primitives, slots, am.AttribLocations)
+			if err != nil {
+				Logger.Print("asset.Manager.LoadGLTF: failed")
+				return nil, err
+			}
+			am.AddMesh(mesh)
+			model.Meshes = append(model.Meshes, mesh)
+		}
+
+		am.AddModel(model)
+		scene.Models = append(scene.Models, model)
+	}
+
+	for i := range doc.Skins {
+		var skel, err = NewSkeletonFromGLTF(doc, i)
+		if err != nil {
+			Logger.Print("asset.Manager.LoadGLTF: failed")
+			return nil, err
+		}
+		var clips = make([]*Clip, len(doc.Animations))
+		for a := range doc.Animations {
+			if clips[a], err = NewClipFromGLTF(doc, a, i); err != nil {
+				Logger.Print("asset.Manager.LoadGLTF: failed")
+				return nil, err
+			}
+		}
+		scene.Skeletons = append(scene.Skeletons, skel)
+		scene.Clips = append(scene.Clips, clips)
+	}
+
+	am.AddScene(scene)
+
+	return scene, nil
+}
+
+// loadGLTFTexture loads the image of texture 'i' of a glTF file, with mipmaps
+// for the Materials' sampler objects, as sRGB if 'srgb' is set. Embedded images are decoded directly,
+// while external ones are loaded as by loadTextureFile. Nil is returned if the
+// texture has no image.
+func (am *Manager) loadGLTFTexture(name, dir string, doc *meshfmt.GLTF, i int, srgb bool) (*Texture, error) {
+	var t = doc.Textures[i]
+	if t.Image < 0 {
+		return nil, nil
+	}
+
+	var img = doc.Images[t.Image]
+	if img.Data == nil {
+		var tex, err = am.loadTextureFile(filepath.Join(dir, filepath.FromSlash(img.URI)), srgb)
+		if err != nil {
+			return nil, err
+		}
+		generateMipmaps(tex)
+		return tex, nil
+	}
+
+	var texName = fmt.Sprintf("%s:texture%d", name, i)
+	if srgb {
+		texName += ":srgb"
+	}
+	if tex, ok := am.GetTexture(texName); ok {
+		return tex, nil
+	}
+
+	var decoded, _, err = image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, fmt.Errorf("asset.Manager.LoadGLTF error: image %d: %v", t.Image, err)
+	}
+
+	var tex *Texture
+	if tex, err = newTextureFromImage(texName, decoded, srgb); err != nil {
+		return nil, err
+	}
+	generateMipmaps(tex)
+	am.AddTexture(tex)
+
+	return tex, nil
+}
+
+// newGLTFSampler creates a sampler object with the filters and wrap modes of a
+// glTF texture. Wrap modes default to repeating, and the minifying filter to
+// trilinear filtering.
+func newGLTFSampler(t *meshfmt.Texture) uint32 {
+	var orDefault = func(v int, def int32) int32 {
+		if v == 0 {
+			return def
+		}
+		return int32(v)
+	}
+
+	var sampler uint32
+	gl.CreateSamplers(1, &sampler)
+	gl.SamplerParameteri(sampler, gl.TEXTURE_WRAP_S, orDefault(t.WrapS, gl.REPEAT))
+	gl.SamplerParameteri(sampler, gl.TEXTURE_WRAP_T, orDefault(t.WrapT, gl.REPEAT))
+	gl.SamplerParameteri(sampler, gl.TEXTURE_MAG_FILTER, orDefault(t.MagFilter, gl.LINEAR))
+	gl.SamplerParameteri(sampler, gl.TEXTURE_MIN_FILTER, orDefault(t.MinFilter, gl.LINEAR_MIPMAP_LINEAR))
+	return sampler
+}
+
+// generateMipmaps generates the mipmaps of a Texture uploaded with only its
+// base level, so that it may be sampled with a mipmap filter. Textures whose
+// levels were given, as by NewTextureFromData, are left as they are.
+func generateMipmaps(tex *Texture) {
+	var maxLevel int32
+	gl.GetTextureParameteriv(tex.Tex, gl.TEXTURE_MAX_LEVEL, &maxLevel)
+	if maxLevel == 1000 { // the default
+		gl.GenerateTextureMipmap(tex.Tex)
+	}
+}
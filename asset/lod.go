@@ -0,0 +1,226 @@
+package asset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ostsol/engine/meshfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Simplify reduces the data of a triangle mesh to about 'target' triangles,
+// as by meshfmt.Simplify, keeping the shape of UV seams and borders. Each
+// Submesh is simplified apart, keeping its share of the triangles, so that
+// the borders between them are kept too. Vertices that are no longer used are
+// dropped, and the elements become a list of triangles of the smallest type
+// that can index every vertex. It returns the error of the result as a
+// fraction of the size of the mesh.
+func (d *MeshData) Simplify(target int, maxError float32) (float32, error) {
+	var pos, tris, err = d.triangleGeometry("")
+	if err == nil {
+		var parts [][]uint32
+		if parts, err = d.splitSubmeshes(tris); err == nil {
+			var worst float32
+			for i, part := range parts {
+				var (
+					share   = int(math.Round(float64(target) * float64(len(part)) / float64(max(len(tris), 1))))
+					ratio   = extent(pos, tris) / max(extent(pos, part), math.SmallestNonzeroFloat64)
+					limit   = float64(maxError) * ratio
+					partErr float64
+				)
+				parts[i], partErr = meshfmt.Simplify(part, pos, share, limit)
+				worst = max(worst, float32(partErr/ratio))
+			}
+
+			d.remap(meshfmt.OptimizeVertexFetch(d.joinSubmeshes(parts), d.Vertices))
+			if d.Elements != nil && d.Elements.Type != indexType(d.Vertices) {
+				d.setElements(d.Indices(), indexType(d.Vertices))
+			}
+			return worst, nil
+		}
+	}
+	return 0, fmt.Errorf("asset.MeshData.Simplify error: %v", err)
+}
+
+// extent returns the greatest side of the bounding box of the positions of the
+// vertices of 'tris'.
+func extent(pos []float32, tris []uint32) float64 {
+	if len(tris) == 0 {
+		return 0
+	}
+	var points = make([]mgl.Vec3, len(tris))
+	for i, v := range tris {
+		copy(points[i][:], pos[3*v:3*v+3])
+	}
+	var b = NewBounds(points).Box.Extents()
+	return 2 * float64(max(b[0], b[1], b[2]))
+}
+
+// Simplify reduces the Mesh to about 'target' triangles, as by
+// MeshData.Simplify, recreating its arrays. See GenerateNormals.
+func (m *Mesh) Simplify(target int, maxError float32) (float32, error) {
+	var e float32
+	var err = m.generate("", func(d *MeshData) (err error) {
+		e, err = d.Simplify(target, maxError)
+		return err
+	})
+	return e, err
+}
+
+// copy returns a copy of the data whose arrays may be replaced without
+// changing the original. The bytes themselves are shared.
+func (d *MeshData) copy() *MeshData {
+	var out = *d
+	out.Attribs = make(map[string]*AttribData, len(d.Attribs))
+	for name, a := range d.Attribs {
+		var c = *a
+		out.Attribs[name] = &c
+	}
+	out.Instanced = make(map[string]*AttribData, len(d.Instanced))
+	for name, a := range d.Instanced {
+		var c = *a
+		out.Instanced[name] = &c
+	}
+	out.Buffers = make([]*BufferData, len(d.Buffers))
+	for i, b := range d.Buffers {
+		var c = *b
+		out.Buffers[i] = &c
+	}
+	if d.Elements != nil {
+		var c = *d.Elements
+		out.Elements = &c
+	}
+	out.Submeshes = append([]Submesh(nil), d.Submeshes...)
+	return &out
+}
+
+// LODLevel describes a level of detail to be generated by NewLODGroup.
+type LODLevel struct {
+	Ratio      float32 // fraction of the original triangles to keep
+	ScreenSize float32 // projected size below which the level is drawn
+}
+
+// LODGroup is a Mesh with simplified versions of it, drawn in its place as it
+// covers less of the screen.
+type LODGroup struct {
+	Name string
+
+	// Levels are the Meshes of the group in decreasing detail, the first
+	// being the original.
+	Levels []*Mesh
+
+	// ScreenSizes are the projected sizes, as fractions of the viewport's
+	// height, below which each Level after the first is drawn.
+	ScreenSizes []float32
+
+	// Errors are the errors of each Level, as fractions of the size of the
+	// Mesh.
+	Errors []float32
+}
+
+// NewLODGroup generates a LODGroup from a Mesh of triangles. Each level is
+// simplified from the one before, as by MeshData.Simplify, to about its Ratio of
+// the Mesh's triangles, and no further than 'maxError', unless it is zero.
+// Levels must be given in decreasing Ratio and ScreenSize. The Mesh becomes the
+// first Level, and is not changed. Its data is read back from its buffers,
+// unless it retains all of it.
+func NewLODGroup(name string, m *Mesh, levels []LODLevel, maxError float32) (*LODGroup, error) {
+	var (
+		d   = m.Data
+		err error
+	)
+	if d == nil || d.Geometry {
+		if d, err = NewMeshData(m, RetainAll); err != nil {
+			return nil, fmt.Errorf("asset.NewLODGroup error: %v", err)
+		}
+	}
+	var triangles = len(d.Triangles())
+
+	var g = &LODGroup{
+		Name:   name,
+		Levels: []*Mesh{m},
+		Errors: []float32{0},
+	}
+	for i, l := range levels {
+		if i > 0 && (l.Ratio > levels[i-1].Ratio || l.ScreenSize > levels[i-1].ScreenSize) {
+			g.Clean()
+			return nil, fmt.Errorf("asset.NewLODGroup error: level %d is more detailed than level %d", i+1, i)
+		}
+
+		d = d.copy()
+		var e float32
+		if e, err = d.Simplify(int(float64(l.Ratio)*float64(triangles)), maxError); err == nil {
+			var level *Mesh
+			if level, err = d.NewMesh(fmt.Sprintf("%s:%d", m.Name, i+1)); err == nil {
+				level.Locations = m.Locations
+				g.Levels = append(g.Levels, level)
+				g.ScreenSizes = append(g.ScreenSizes, l.ScreenSize)
+				g.Errors = append(g.Errors, max(e, g.Errors[i]))
+			}
+		}
+		if err != nil {
+			g.Clean()
+			return nil, fmt.Errorf("asset.NewLODGroup error: %v", err)
+		}
+	}
+
+	return g, nil
+}
+
+// ScreenSize returns the height of the bounding sphere of the first Level, as
+// a fraction of the viewport's height, when drawn with the given model, view
+// and projection transforms. It is infinite if the camera is within the sphere.
+func (g *LODGroup) ScreenSize(model, view, projection mgl.Mat4) float32 {
+	var s = g.Levels[0].Bounds().Sphere.Transform(view.Mul4(model))
+	if s.Radius < 0 {
+		return 0
+	}
+
+	// An orthographic projection scales by the same amount at any depth.
+	if projection.At(3, 3) == 1 {
+		return s.Radius * projection.At(1, 1)
+	}
+	var dist = s.Center.Len()
+	if dist <= s.Radius {
+		return float32(math.Inf(1))
+	}
+	return s.Radius * projection.At(1, 1) / dist
+}
+
+// Select returns the Level to draw at a projected size.
+func (g *LODGroup) Select(size float32) *Mesh {
+	var level = 0
+	for i, s := range g.ScreenSizes {
+		if size < s {
+			level = i + 1
+		}
+	}
+	return g.Levels[level]
+}
+
+// DrawUniforms draws the Level chosen for the group's projected size, given a
+// Material, a set of uniforms and the transforms they hold.
+func (g *LODGroup) DrawUniforms(material *Material, uniforms Uniforms, model, view, projection mgl.Mat4) {
+	g.Select(g.ScreenSize(model, view, projection)).DrawUniforms(material, uniforms)
+}
+
+// Init initializes every Level of the group.
+func (g *LODGroup) Init() error {
+	for _, m := range g.Levels {
+		if err := m.Init(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clean deletes the generated Levels of the group. The first Level, which the
+// group did not create, is left alone.
+func (g *LODGroup) Clean() {
+	for _, m := range g.Levels[1:] {
+		m.Clean()
+	}
+	g.Levels = g.Levels[:1]
+	g.ScreenSizes = nil
+	g.Errors = g.Errors[:1]
+}
@@ -6,7 +6,11 @@ import (
 	"image"
 	_ "image/png" // for png textures
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/Ostsol/engine/sdf"
+	"github.com/Ostsol/engine/texfmt"
 	"github.com/go-gl/gl/v4.5-core/gl"
 )
 
@@ -17,31 +21,205 @@ type ShaderSet struct {
 	Gs uint32
 }
 
-// Manager stores Materials, Meshes, Shaders, and Textures.
+// Manager stores Fonts, LODGroups, Materials, Meshes, Models, Scenes, Shaders,
+// and Textures.
 type Manager struct {
+	Fonts     map[string]*Font
+	LODGroups map[string]*LODGroup
 	Materials map[string]*Material
 	Meshes    map[string]*Mesh
+	Models    map[string]*Model
+	Scenes    map[string]*Scene
 	Shaders   map[string]uint32
 	Programs  map[ShaderSet]uint32
 	Textures  map[string]*Texture
 
+	// AttribLocations are the attribute locations given to the Meshes that
+	// the Manager loads. They are copied from the parent, or from
+	// DefaultAttribLocations, and may be extended with further attributes.
+	AttribLocations AttribLocations
+
+	// MeshRetention is the policy for keeping the data of Meshes added to the
+	// Manager. It is copied from the parent, or else RetainNone.
+	MeshRetention Retention
+
 	Parent *Manager
 }
 
 // NewManager creates and initializes a new Manager
 func NewManager(parent *Manager) *Manager {
 	var am = &Manager{
+		Fonts:     make(map[string]*Font),
+		LODGroups: make(map[string]*LODGroup),
 		Materials: make(map[string]*Material),
 		Meshes:    make(map[string]*Mesh),
+		Models:    make(map[string]*Model),
+		Scenes:    make(map[string]*Scene),
 		Shaders:   make(map[string]uint32),
 		Programs:  make(map[ShaderSet]uint32),
 		Textures:  make(map[string]*Texture),
 		Parent:    parent,
 	}
 
+	var locs = DefaultAttribLocations
+	if parent != nil {
+		locs = parent.AttribLocations
+		am.MeshRetention = parent.MeshRetention
+	}
+	am.AttribLocations = make(AttribLocations, len(locs))
+	for name, loc := range locs {
+		am.AttribLocations[name] = loc
+	}
+
 	return am
 }
 
+// AddFont adds a Font to the Manager. If the Font's name is already in use, the
+// operation fails and an error is returned.
+func (am *Manager) AddFont(f *Font) error {
+	if _, ok := am.Fonts[f.Name]; ok {
+		return fmt.Errorf("asset.Manager.AddFont error: font '%s' already exists", f.Name)
+	}
+
+	Logger.Printf("Manager: adding Font '%s'\n", f.Name)
+	am.Fonts[f.Name] = f
+
+	return nil
+}
+
+// GetFont searches for a Font. If it exists it is returned, otherwise nil and
+// false are returned.
+func (am *Manager) GetFont(name string) (*Font, bool) {
+	if f, ok := am.Fonts[name]; ok {
+		return f, true
+	}
+
+	if am.Parent != nil {
+		return am.Parent.GetFont(name)
+	}
+
+	return nil, false
+}
+
+// LoadFont attempts to load a Font from the given file 'name'. BMFont
+// descriptors (.fnt) are loaded with their page images and 'size' is ignored.
+// Other files are treated as TrueType or OpenType fonts rasterized at 'size'
+// pixels, and are named 'name:size' so that several sizes may be loaded. If
+// the Font already exists, it is returned.
+func (am *Manager) LoadFont(name string, size float64) (*Font, error) {
+	var (
+		isBMFont = strings.ToLower(filepath.Ext(name)) == ".fnt"
+		key      = name
+	)
+
+	if !isBMFont {
+		key = fmt.Sprintf("%s:%g", name, size)
+	}
+	if f, ok := am.GetFont(key); ok {
+		return f, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadFont: loading Font '%s'\n", key)
+
+	var (
+		path = "assets/fonts/" + name
+		f    *Font
+		err  error
+	)
+
+	if isBMFont {
+		var file *os.File
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		f, err = NewFontFromBMFont(key, file, func(page string) (image.Image, error) {
+			var pf, err = os.Open(filepath.Join(filepath.Dir(path), page))
+			if err != nil {
+				return nil, err
+			}
+			defer pf.Close()
+
+			var img, _, derr = image.Decode(pf)
+			return img, derr
+		})
+	} else {
+		var data []byte
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		f, err = NewFontFromTTF(key, data, size)
+	}
+	if err != nil {
+		Logger.Print("asset.Manager.LoadFont: failed")
+		return nil, err
+	}
+
+	am.AddFont(f)
+
+	return f, nil
+}
+
+// LoadSDFFont attempts to load a distance field Font from the TrueType or
+// OpenType file 'name', as by NewFontFromTTFSDF. It is named
+// 'name:sdf:size'. If the Font already exists, it is returned.
+func (am *Manager) LoadSDFFont(name string, opts *SDFOptions) (*Font, error) {
+	if opts == nil {
+		opts = &DefaultSDFOptions
+	}
+
+	var key = fmt.Sprintf("%s:sdf:%g", name, opts.Size)
+	if f, ok := am.GetFont(key); ok {
+		return f, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadSDFFont: loading Font '%s'\n", key)
+
+	var data, err = os.ReadFile("assets/fonts/" + name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := NewFontFromTTFSDF(key, data, *opts)
+	if err != nil {
+		Logger.Print("asset.Manager.LoadSDFFont: failed")
+		return nil, err
+	}
+
+	am.AddFont(f)
+
+	return f, nil
+}
+
+// AddLODGroup adds a LODGroup to the Manager. If the LODGroup's name is already
+// in use, the operation fails and an error is returned. The Manager cleans the
+// Levels the LODGroup generated, but its first Level is not added.
+func (am *Manager) AddLODGroup(g *LODGroup) error {
+	if _, ok := am.LODGroups[g.Name]; ok {
+		return fmt.Errorf("asset.Manager.AddLODGroup error: LODGroup '%s' already exists", g.Name)
+	}
+
+	Logger.Printf("Manager: adding LODGroup '%s'\n", g.Name)
+	am.LODGroups[g.Name] = g
+
+	return nil
+}
+
+// GetLODGroup searches for a LODGroup. If it exists it is returned, otherwise
+// nil and false are returned.
+func (am *Manager) GetLODGroup(name string) (*LODGroup, bool) {
+	if g, ok := am.LODGroups[name]; ok {
+		return g, true
+	}
+
+	if am.Parent != nil {
+		return am.Parent.GetLODGroup(name)
+	}
+
+	return nil, false
+}
+
 // AddMaterial adds a Material to the manager. If the Material's name is already
 // in use, the operation fails and an error is returned.
 func (am *Manager) AddMaterial(m *Material) error {
@@ -70,12 +248,19 @@ func (am *Manager) GetMaterial(name string) (*Material, bool) {
 }
 
 // AddMesh adds a Mesh to the Manager. If the Mesh's name is already in use, the
-// operation fails and an error is returned.
+// operation fails and an error is returned. Meshes that retain no data are made
+// to retain it according to the Manager's MeshRetention.
 func (am *Manager) AddMesh(m *Mesh) error {
 	if _, ok := am.Meshes[m.Name]; ok {
 		return fmt.Errorf("asset.Manager.AddMesh error: Mesh '%s' already exists", m.Name)
 	}
 
+	if m.Data == nil && am.MeshRetention != RetainNone {
+		if err := m.Retain(am.MeshRetention); err != nil {
+			return err
+		}
+	}
+
 	Logger.Printf("Manager: adding Mesh '%s'\n", m.Name)
 	am.Meshes[m.Name] = m
 
@@ -96,6 +281,62 @@ func (am *Manager) GetMesh(name string) (*Mesh, bool) {
 	return nil, false
 }
 
+// AddModel adds a Model to the Manager. If the Model's name is already in use,
+// the operation fails and an error is returned. The Model's Meshes and
+// Materials are not added.
+func (am *Manager) AddModel(m *Model) error {
+	if _, ok := am.Models[m.Name]; ok {
+		return fmt.Errorf("asset.Manager.AddModel error: Model '%s' already exists", m.Name)
+	}
+
+	Logger.Printf("Manager: adding Model '%s'\n", m.Name)
+	am.Models[m.Name] = m
+
+	return nil
+}
+
+// GetModel searches for a Model. If it exists it is returned, otherwise nil and
+// false are returned.
+func (am *Manager) GetModel(name string) (*Model, bool) {
+	if m, ok := am.Models[name]; ok {
+		return m, true
+	}
+
+	if am.Parent != nil {
+		return am.Parent.GetModel(name)
+	}
+
+	return nil, false
+}
+
+// AddScene adds a Scene to the Manager. If the Scene's name is already in use,
+// the operation fails and an error is returned. The Scene's Models, Materials
+// and Textures are not added.
+func (am *Manager) AddScene(s *Scene) error {
+	if _, ok := am.Scenes[s.Name]; ok {
+		return fmt.Errorf("asset.Manager.AddScene error: Scene '%s' already exists", s.Name)
+	}
+
+	Logger.Printf("Manager: adding Scene '%s'\n", s.Name)
+	am.Scenes[s.Name] = s
+
+	return nil
+}
+
+// GetScene searches for a Scene. If it exists it is returned, otherwise nil and
+// false are returned.
+func (am *Manager) GetScene(name string) (*Scene, bool) {
+	if s, ok := am.Scenes[name]; ok {
+		return s, true
+	}
+
+	if am.Parent != nil {
+		return am.Parent.GetScene(name)
+	}
+
+	return nil, false
+}
+
 // AddShader adds a Shader to the Manager. If the Shader's name is already in
 // use, the operation fails and an error is returned.
 func (am *Manager) AddShader(name string, shader uint32) error {
@@ -126,7 +367,8 @@ func (am *Manager) GetShader(name string) (uint32, bool) {
 // LoadShader loads a shader from the file 'name' and returns it. If it already
 // exists, it and a nil error is returned. 'typ' indicates the type of shader
 // and must be either gl.VERTEX_SHADER, gl.FRAGMENT_SHADER, or
-// gl.GEOMETRY_SHADER.
+// gl.GEOMETRY_SHADER. Names beginning with "builtin/" refer to shaders
+// provided by the engine, such as "builtin/sdf.vert" and "builtin/sdf.frag".
 func (am *Manager) LoadShader(typ uint32, name string) (uint32, error) {
 	if shader, ok := am.GetShader(name); ok {
 		return shader, nil
@@ -134,7 +376,20 @@ func (am *Manager) LoadShader(typ uint32, name string) (uint32, error) {
 
 	Logger.Printf("asset.Manager.LoadShader: loading Shader '%s'\n", name)
 
-	var shader, err = newShader("assets/shaders/"+name, typ)
+	var (
+		shader uint32
+		err    error
+	)
+
+	if strings.HasPrefix(name, builtinPrefix) {
+		var source, ok = builtinShaders[name]
+		if !ok {
+			return 0, fmt.Errorf("asset.Manager.LoadShader error: no builtin Shader '%s'", name)
+		}
+		shader, err = compileShader(name, source, typ)
+	} else {
+		shader, err = newShader("assets/shaders/"+name, typ)
+	}
 	if err != nil {
 		Logger.Print("asset.Manager.LoadShader: failed")
 		return 0, err
@@ -252,36 +507,75 @@ func (am *Manager) GetTexture(name string) (*Texture, bool) {
 }
 
 // LoadTexture attempts to load a Texture from the given file 'name'. If it
-// already exists, it is returned.
+// already exists, it is returned. DDS, KTX and KTX2 files are loaded with
+// their mip chains, array layers and cube faces, and Radiance HDR files as
+// float textures; other files are decoded with the image package.
 func (am *Manager) LoadTexture(name string) (*Texture, error) {
-	if tex, ok := am.GetTexture(name); ok {
+	return am.loadTextureAs(name, false)
+}
+
+// loadTextureAs loads a Texture as by LoadTexture, storing its colours as sRGB
+// if 'srgb' is set. sRGB Textures are named 'name:srgb', so that a file used
+// for both colours and other data is loaded once for each.
+func (am *Manager) loadTextureAs(name string, srgb bool) (*Texture, error) {
+	var key = name
+	if srgb {
+		key += ":srgb"
+	}
+	if tex, ok := am.GetTexture(key); ok {
 		return tex, nil
 	}
 
+	var tex, err = loadTexture(key, "assets/textures/"+name, srgb)
+	if err != nil {
+		return nil, err
+	}
+
+	am.AddTexture(tex)
+
+	return tex, nil
+}
+
+// loadTexture creates a Texture named 'name' from the file at 'path', choosing
+// a decoder by the file's extension. If 'srgb' is set, 8-bit colour formats
+// are stored as sRGB.
+func loadTexture(name, path string, srgb bool) (*Texture, error) {
 	var (
 		err error
 		f   *os.File
 	)
 
-	if f, err = os.Open("assets/textures/" + name); err != nil {
+	if f, err = os.Open(path); err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	var img image.Image
+	var tex *Texture
 
-	if img, _, err = image.Decode(f); err != nil {
-		return nil, err
-	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dds", ".ktx", ".ktx2", ".hdr":
+		var data *texfmt.Image
 
-	var tex *Texture
+		if data, err = texfmt.Decode(f); err != nil {
+			return nil, err
+		}
+		if srgb {
+			data.Format = data.Format.ToSRGB()
+		}
+		if tex, err = NewTextureFromData(name, data); err != nil {
+			return nil, err
+		}
+	default:
+		var img image.Image
 
-	if tex, err = NewTextureFromImage(name, img); err != nil {
-		return nil, err
+		if img, _, err = image.Decode(f); err != nil {
+			return nil, err
+		}
+		if tex, err = newTextureFromImage(name, img, srgb); err != nil {
+			return nil, err
+		}
 	}
 
-	am.AddTexture(tex)
-
 	return tex, nil
 }
 
@@ -303,9 +597,57 @@ func (am *Manager) LoadTextures(names ...string) ([]*Texture, error) {
 	return textures, nil
 }
 
+// LoadIcon attempts to load an SVG icon from the file 'name' and render it
+// into a distance field Texture of 'opts.Size' pixels square, as by
+// NewSDFTexture. The icon's viewBox is fitted to the Texture. It is named
+// 'name:sdf:size'. If the Texture already exists, it is returned.
+func (am *Manager) LoadIcon(name string, opts *SDFOptions) (*Texture, error) {
+	if opts == nil {
+		opts = &DefaultSDFOptions
+	}
+
+	var key = fmt.Sprintf("%s:sdf:%g", name, opts.Size)
+	if tex, ok := am.GetTexture(key); ok {
+		return tex, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadIcon: loading icon '%s'\n", key)
+
+	var f, err = os.Open("assets/icons/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	shape, frame, err := sdf.ParseSVG(f)
+	if err != nil {
+		return nil, err
+	}
+
+	tex, err := NewSDFTexture(key, shape, frame, int(opts.Size), opts.Range, opts.MultiChannel)
+	if err != nil {
+		Logger.Print("asset.Manager.LoadIcon: failed")
+		return nil, err
+	}
+
+	am.AddTexture(tex)
+
+	return tex, nil
+}
+
 // Clean ensures that all objects themselves cleaned and are removed from
 // memory.
 func (am *Manager) Clean() {
+	for name, f := range am.Fonts {
+		Logger.Printf("Manager: deleting Font '%s'\n", name)
+		f.Clean()
+		delete(am.Fonts, name)
+	}
+	for name, g := range am.LODGroups {
+		Logger.Printf("Manager: deleting LODGroup '%s'\n", name)
+		g.Clean()
+		delete(am.LODGroups, name)
+	}
 	for name, m := range am.Materials {
 		Logger.Printf("Manager: deleting Material '%s'\n", name)
 		m.Clean()
@@ -316,6 +658,14 @@ func (am *Manager) Clean() {
 		m.Clean()
 		delete(am.Meshes, name)
 	}
+	for name := range am.Models {
+		Logger.Printf("Manager: deleting Model '%s'\n", name)
+		delete(am.Models, name)
+	}
+	for name := range am.Scenes {
+		Logger.Printf("Manager: deleting Scene '%s'\n", name)
+		delete(am.Scenes, name)
+	}
 	for set, prog := range am.Programs {
 		Logger.Printf("Manager: deleting Program '%v'\n", set)
 		gl.DeleteProgram(prog)
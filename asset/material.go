@@ -2,6 +2,7 @@ package asset
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 )
@@ -12,10 +13,21 @@ type Material struct {
 	Textures []*Texture // list of textures
 	Samplers []string   // list of sampler uniform names
 
+	// SamplerObjects are GL sampler objects overriding the filtering and
+	// wrapping of the texture of the same index, or 0 to keep the texture's
+	// own. They belong to the Material, so Textures shared with other
+	// Materials are sampled differently by each.
+	SamplerObjects []uint32
+
 	Prog uint32 // shader program
 
 	AttribLocs  map[string]uint32 // vertex attrib locations
 	UniformLocs map[string]int32  // other uniform locations
+
+	// Params are uniform values set whenever the Material is drawn, such as
+	// colours loaded from a material file. Only those with a location in
+	// UniformLocs are set.
+	Params Uniforms
 }
 
 // NewMaterial creates an empty Material.
@@ -24,6 +36,7 @@ func NewMaterial(name string) *Material {
 		Name:        name,
 		AttribLocs:  make(map[string]uint32),
 		UniformLocs: make(map[string]int32),
+		Params:      make(Uniforms),
 	}
 }
 
@@ -37,6 +50,15 @@ func (mat *Material) AddSamplers(samplers ...string) {
 	mat.Samplers = append(mat.Samplers, samplers...)
 }
 
+// AddSamplerObject sets the sampler object of texture unit 'i', which the
+// Material then owns.
+func (mat *Material) AddSamplerObject(i int, sampler uint32) {
+	for len(mat.SamplerObjects) <= i {
+		mat.SamplerObjects = append(mat.SamplerObjects, 0)
+	}
+	mat.SamplerObjects[i] = sampler
+}
+
 // SetProgram sets the shader program
 func (mat *Material) SetProgram(prog uint32) {
 	mat.Prog = prog
@@ -60,6 +82,44 @@ func (mat *Material) BindAttribLoc(attrib string, loc uint32) error {
 	}
 }
 
+// InitAttribLocs initializes the table of vertex attrib locations from the
+// active attributes of the shader program. Built-in attributes, whose names
+// begin with "gl_", are left out.
+func (mat *Material) InitAttribLocs() error {
+	if mat.Prog == 0 {
+		return fmt.Errorf("Material error: material '%s' has no shader program from which to get attrib locations", mat.Name)
+	}
+
+	var count, maxLen int32
+	gl.GetProgramiv(mat.Prog, gl.ACTIVE_ATTRIBUTES, &count)
+	gl.GetProgramiv(mat.Prog, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLen)
+
+	var buf = make([]uint8, maxLen+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var (
+			length, size int32
+			typ          uint32
+		)
+		gl.GetActiveAttrib(mat.Prog, i, int32(len(buf)), &length, &size, &typ, &buf[0])
+
+		var name = string(buf[:length])
+		if strings.HasPrefix(name, "gl_") {
+			continue
+		}
+
+		var (
+			bytes = ([]uint8)(name + "\x00")
+			loc   = gl.GetAttribLocation(mat.Prog, &bytes[0])
+		)
+		if loc < 0 {
+			continue
+		}
+		mat.AttribLocs[name] = uint32(loc)
+	}
+
+	return nil
+}
+
 // InitUniformLocs initializes a table of uniform location handles, given a
 // set of uniform names.
 func (mat *Material) InitUniformLocs(uniforms ...string) error {
@@ -86,6 +146,9 @@ func (mat *Material) Use() {
 	for i, tex := range mat.Textures {
 		tex.Use(uint32(i))
 	}
+	for i, sampler := range mat.SamplerObjects {
+		gl.BindSampler(uint32(i), sampler)
+	}
 	gl.UseProgram(mat.Prog)
 }
 
@@ -96,9 +159,20 @@ func (mat *Material) Release() {
 	for _, tex := range mat.Textures {
 		tex.Release()
 	}
+	for i := range mat.SamplerObjects {
+		gl.BindSampler(uint32(i), 0)
+	}
 }
 
-// Clean deassociates the shader program from the material
+// Clean deassociates the shader program from the material and deletes its
+// sampler objects
 func (mat *Material) Clean() {
 	mat.Prog = 0
+
+	for _, sampler := range mat.SamplerObjects {
+		if sampler != 0 {
+			gl.DeleteSamplers(1, &sampler)
+		}
+	}
+	mat.SamplerObjects = nil
 }
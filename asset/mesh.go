@@ -7,36 +7,72 @@ import (
 	mgl "github.com/go-gl/mathgl/mgl32"
 )
 
-// attribMap defines the handles for each attribute name.
-// TODO: Make it so I don't have to define a handle for each texcoord.
-var attribMap = map[string]uint32{
+// AttribLocations maps vertex attribute names to shader attribute locations.
+type AttribLocations map[string]uint32
+
+// DefaultAttribLocations are the attribute locations used by Meshes that have
+// no Locations of their own. Shaders written for them, such as the built-in
+// shaders, declare the same locations.
+var DefaultAttribLocations = AttribLocations{
 	"pos":       0,
 	"color":     1,
 	"normal":    2,
 	"texcoord0": 3,
 	"texcoord1": 4,
 	"texcoord2": 5,
+	"tangent":   6,
+	"joints0":   7,
+	"weights0":  8,
+
+	"instanceColor":  11,
+	"instanceMatrix": 12, // a mat4 occupying locations 12 to 15
 }
 
 // Uniforms is a map of uniform names with their values
 type Uniforms map[string]interface{}
 
-// Mesh is a collection of AttribArrays
+// Mesh is a collection of AttribArrays and interleaved VertexBuffers
 type Mesh struct {
 	Name      string                  // Mesh name
 	Attribs   map[string]*AttribArray // map of vertex attribute arrays
+	Buffers   []*VertexBuffer         // interleaved vertex buffers
+	Instanced map[string]*AttribArray // map of per-instance attribute arrays
 	Elements  *ElementArray
 	Array     uint32 // OpenGL vertex array handle
 	Primitive uint32 // OpenGL primitive
 	Vertices  int    // number of vertex attribute sets
+
+	// Locations are the attribute locations used by Init, or nil to use
+	// DefaultAttribLocations.
+	Locations AttribLocations
+
+	// Data is a copy of the Mesh's data kept in main memory, or nil. See
+	// Retain.
+	Data *MeshData
+
+	// Submeshes divide the Mesh into ranges drawn with different Materials,
+	// or are empty if it is drawn whole. See DrawSlots.
+	Submeshes []Submesh
+}
+
+// Submesh is a range of a Mesh's elements, or of its vertices if it has no
+// ElementArray, drawn with the Material in one of a set of material slots,
+// such as those of a Model.
+type Submesh struct {
+	Name       string // name of the group or primitive it was loaded from
+	First      int    // first element
+	Count      int    // number of elements
+	BaseVertex int    // added to each index before vertices are fetched
+	Slot       int    // index of the material slot
 }
 
 // NewMesh returns an empty Mesh
 func NewMesh(name string) *Mesh {
 	return &Mesh{
-		Name:     name,
-		Attribs:  make(map[string]*AttribArray),
-		Vertices: -1,
+		Name:      name,
+		Attribs:   make(map[string]*AttribArray),
+		Instanced: make(map[string]*AttribArray),
+		Vertices:  -1,
 	}
 }
 
@@ -59,19 +95,165 @@ func (m *Mesh) AddArrays(arrays ...*AttribArray) error {
 	return nil
 }
 
-// Init creates a vertex array and attaches each vertex attribute array to it.
+// AddBuffers adds VertexBuffers to the Mesh. Each VertexBuffer must have the
+// same number of vertices as the Mesh's other arrays.
+func (m *Mesh) AddBuffers(buffers ...*VertexBuffer) error {
+	for _, vb := range buffers {
+		if vb == nil {
+			continue
+		}
+
+		if m.Vertices == -1 {
+			m.Vertices = vb.Attribs()
+		} else if vb.Attribs() != m.Vertices {
+			return fmt.Errorf("Mesh '%s' error: VertexBuffer sizes are inconsistent.", m.Name)
+		}
+		m.Buffers = append(m.Buffers, vb)
+	}
+
+	return nil
+}
+
+// AddInstanceArrays adds per-instance AttribArrays to the Mesh. Arrays with no
+// Divisor are given a Divisor of one, advancing once per instance. Instance
+// arrays need not match the number of vertices of the Mesh, but must hold an
+// attribute for each instance drawn.
+func (m *Mesh) AddInstanceArrays(arrays ...*AttribArray) {
+	for _, arr := range arrays {
+		if arr == nil {
+			continue
+		}
+		if arr.Divisor == 0 {
+			arr.Divisor = 1
+		}
+		m.Instanced[arr.Name] = arr
+	}
+}
+
+// HasAttrib reports whether the Mesh has an attribute, in a vertex or instance
+// AttribArray or in a VertexBuffer.
+func (m *Mesh) HasAttrib(name string) bool {
+	if _, ok := m.Attribs[name]; ok {
+		return true
+	}
+	if _, ok := m.Instanced[name]; ok {
+		return true
+	}
+	for _, vb := range m.Buffers {
+		if _, ok := vb.Layout.Attrib(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Init creates a vertex array and attaches each vertex attribute array to it,
+// at the location given by the Mesh's Locations. Attributes without a location
+// are not attached. If the Mesh was already initialized, its old vertex array
+// is replaced.
 func (m *Mesh) Init() error {
+	var locs = m.Locations
+	if locs == nil {
+		locs = DefaultAttribLocations
+	}
+
+	for _, name := range m.bind(locs) {
+		Logger.Printf("asset.Mesh.Init: Mesh '%s' attribute '%s' has no location\n", m.Name, name)
+	}
+
+	return nil
+}
+
+// InitMaterial initializes the Mesh for drawing with a Material, taking the
+// attribute locations from the Material's program. An error is returned if the
+// program uses an attribute that the Mesh lacks. Attributes that the program
+// does not use are not attached.
+func (m *Mesh) InitMaterial(mat *Material) error {
+	if len(mat.AttribLocs) == 0 {
+		if err := mat.InitAttribLocs(); err != nil {
+			return err
+		}
+	}
+	for name := range mat.AttribLocs {
+		if !m.HasAttrib(name) {
+			return fmt.Errorf("asset.Mesh.InitMaterial error: Mesh '%s' has no attribute '%s' required by Material '%s'", m.Name, name, mat.Name)
+		}
+	}
+
+	// The locations are copied so that the Mesh and Material do not share
+	// a map that either may later change.
+	m.Locations = make(AttribLocations, len(mat.AttribLocs))
+	for name, loc := range mat.AttribLocs {
+		m.Locations[name] = loc
+	}
+	m.bind(m.Locations)
+
+	return nil
+}
+
+// Validate checks that the Mesh can be drawn with a Material: that it has
+// every attribute the Material's program uses, at the location the program
+// expects.
+func (m *Mesh) Validate(mat *Material) error {
+	if len(mat.AttribLocs) == 0 {
+		if err := mat.InitAttribLocs(); err != nil {
+			return err
+		}
+	}
+
+	var locs = m.Locations
+	if locs == nil {
+		locs = DefaultAttribLocations
+	}
+	for name, want := range mat.AttribLocs {
+		if !m.HasAttrib(name) {
+			return fmt.Errorf("asset.Mesh.Validate error: Mesh '%s' has no attribute '%s' required by Material '%s'", m.Name, name, mat.Name)
+		}
+		if loc, ok := locs[name]; !ok || loc != want {
+			return fmt.Errorf("asset.Mesh.Validate error: Mesh '%s' attribute '%s' is not at location %d required by Material '%s'", m.Name, name, want, mat.Name)
+		}
+	}
+
+	return nil
+}
+
+// bind creates the vertex array, attaching each attribute with a location in
+// 'locs', and returns the names of those without one.
+func (m *Mesh) bind(locs AttribLocations) []string {
+	if m.Array != 0 {
+		gl.DeleteVertexArrays(1, &m.Array)
+	}
 	gl.GenVertexArrays(1, &m.Array)
 	gl.BindVertexArray(m.Array)
 	defer gl.BindVertexArray(0)
 
-	for _, arr := range m.Attribs {
-		arr.Init(attribMap[arr.Name])
+	var (
+		skipped []string
+		lookup  = func(name string) (uint32, bool) {
+			var loc, ok = locs[name]
+			return loc, ok
+		}
+	)
+
+	for _, attribs := range []map[string]*AttribArray{m.Attribs, m.Instanced} {
+		for _, arr := range attribs {
+			var loc, ok = lookup(arr.Name)
+			if !ok {
+				skipped = append(skipped, arr.Name)
+				continue
+			}
+			arr.Init(loc)
+		}
+	}
+	for _, vb := range m.Buffers {
+		skipped = append(skipped, vb.Init(lookup)...)
 	}
 
-	m.Elements.Init()
+	if m.Elements != nil {
+		m.Elements.Init()
+	}
 
-	return nil
+	return skipped
 }
 
 // Clean deletes the vertex array and all attached attribute arrays.
@@ -81,42 +263,132 @@ func (m *Mesh) Clean() {
 	for _, attr := range m.Attribs {
 		attr.Clean()
 	}
+	for _, vb := range m.Buffers {
+		vb.Clean()
+	}
+	for _, attr := range m.Instanced {
+		attr.Clean()
+	}
 	m.Elements.Clean()
 }
 
-// DrawUniforms draws the Mesh, given a Material and a set of uniforms.
+// DrawUniforms draws the Mesh, given a Material and a set of uniforms. Meshes
+// without an ElementArray draw their vertices in order.
 func (m *Mesh) DrawUniforms(material *Material, uniforms Uniforms) {
+	var count = m.Vertices
+	if m.Elements != nil {
+		count = m.Elements.Len
+	}
+	m.DrawRange(material, uniforms, 0, count, 0)
+}
+
+// DrawRange draws 'count' elements of the Mesh starting at element 'first',
+// given a Material and a set of uniforms. 'baseVertex' is added to each index
+// before vertices are fetched. For Meshes without an ElementArray, 'count'
+// vertices are drawn starting at vertex 'first' + 'baseVertex'.
+func (m *Mesh) DrawRange(material *Material, uniforms Uniforms, first, count, baseVertex int) {
+	m.draw(material, uniforms, first, count, baseVertex, -1)
+}
+
+// DrawSubmesh draws Submesh 'i' of the Mesh, given a Material and a set of
+// uniforms.
+func (m *Mesh) DrawSubmesh(i int, material *Material, uniforms Uniforms) {
+	var s = m.Submeshes[i]
+	m.DrawRange(material, uniforms, s.First, s.Count, s.BaseVertex)
+}
+
+// DrawSlots draws each Submesh of the Mesh with the Material in its slot of
+// 'materials', given a set of uniforms, binding each Material in turn.
+// Submeshes whose slot holds no Material are skipped. A Mesh without Submeshes
+// is drawn whole with the Material of the first slot.
+func (m *Mesh) DrawSlots(materials []*Material, uniforms Uniforms) {
+	if len(m.Submeshes) == 0 {
+		if len(materials) > 0 && materials[0] != nil {
+			m.DrawUniforms(materials[0], uniforms)
+		}
+		return
+	}
+	for i, s := range m.Submeshes {
+		if s.Slot >= 0 && s.Slot < len(materials) && materials[s.Slot] != nil {
+			m.DrawSubmesh(i, materials[s.Slot], uniforms)
+		}
+	}
+}
+
+// DrawInstanced draws 'instances' instances of the Mesh in a single call,
+// given a Material and a set of uniforms. Each instance reads the next
+// attribute of the Mesh's instance arrays.
+func (m *Mesh) DrawInstanced(material *Material, uniforms Uniforms, instances int) {
+	var count = m.Vertices
+	if m.Elements != nil {
+		count = m.Elements.Len
+	}
+	m.draw(material, uniforms, 0, count, 0, instances)
+}
+
+// draw draws a range of the Mesh, instanced unless 'instances' is negative.
+func (m *Mesh) draw(material *Material, uniforms Uniforms, first, count, baseVertex, instances int) {
 	material.Use()
 
+	for name, value := range material.Params {
+		if loc, ok := material.UniformLocs[name]; ok {
+			setUniform(loc, value)
+		}
+	}
 	for name, value := range uniforms {
 		var loc = material.UniformLocs[name]
 		if loc < 0 {
 			continue
 		}
-
-		switch val := value.(type) {
-		case int32:
-			gl.Uniform1i(loc, val)
-		case float32:
-			gl.Uniform1f(loc, val)
-		case mgl.Vec2:
-			gl.Uniform2fv(loc, 1, &val[0])
-		case mgl.Vec3:
-			gl.Uniform3fv(loc, 1, &val[0])
-		case mgl.Vec4:
-			gl.Uniform4fv(loc, 1, &val[0])
-		case mgl.Mat4:
-			gl.UniformMatrix4fv(loc, 1, false, &val[0])
-		default:
-			panic("Mesh.DrawUniforms: unhandled uniform type")
-		}
+		setUniform(loc, value)
 	}
 
 	gl.BindVertexArray(m.Array)
 
-	gl.DrawElements(m.Primitive, int32(m.Elements.Len), m.Elements.Type, nil)
+	switch {
+	case m.Elements == nil && instances < 0:
+		gl.DrawArrays(m.Primitive, int32(first+baseVertex), int32(count))
+	case m.Elements == nil:
+		gl.DrawArraysInstanced(m.Primitive, int32(first+baseVertex), int32(count), int32(instances))
+	default:
+		if m.Elements.Restart {
+			gl.Enable(gl.PRIMITIVE_RESTART_FIXED_INDEX)
+		}
+		var offset = gl.PtrOffset(first * typeSize(m.Elements.Type))
+		switch {
+		case instances >= 0:
+			gl.DrawElementsInstancedBaseVertex(m.Primitive, int32(count), m.Elements.Type, offset, int32(instances), int32(baseVertex))
+		case baseVertex != 0:
+			gl.DrawElementsBaseVertex(m.Primitive, int32(count), m.Elements.Type, offset, int32(baseVertex))
+		default:
+			gl.DrawElements(m.Primitive, int32(count), m.Elements.Type, offset)
+		}
+		if m.Elements.Restart {
+			gl.Disable(gl.PRIMITIVE_RESTART_FIXED_INDEX)
+		}
+	}
 
 	gl.BindVertexArray(0)
 
 	material.Release()
 }
+
+// setUniform sets the value of a uniform of the program in use.
+func setUniform(loc int32, value interface{}) {
+	switch val := value.(type) {
+	case int32:
+		gl.Uniform1i(loc, val)
+	case float32:
+		gl.Uniform1f(loc, val)
+	case mgl.Vec2:
+		gl.Uniform2fv(loc, 1, &val[0])
+	case mgl.Vec3:
+		gl.Uniform3fv(loc, 1, &val[0])
+	case mgl.Vec4:
+		gl.Uniform4fv(loc, 1, &val[0])
+	case mgl.Mat4:
+		gl.UniformMatrix4fv(loc, 1, false, &val[0])
+	default:
+		panic("Mesh.DrawUniforms: unhandled uniform type")
+	}
+}
@@ -0,0 +1,504 @@
+package asset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Retention is a policy for keeping a copy of a Mesh's data in main memory.
+type Retention int
+
+const (
+	// RetainNone keeps no data.
+	RetainNone Retention = iota
+
+	// RetainGeometry keeps the positions and elements, enough to compute
+	// bounds, pick triangles or build colliders.
+	RetainGeometry
+
+	// RetainAll keeps every array, so that the Mesh can also be recreated,
+	// such as after the OpenGL context is lost.
+	RetainAll
+)
+
+// MeshData is a copy of the vertex and element data of a Mesh held in main
+// memory. Arrays keep the datatype and layout they have in their buffers.
+type MeshData struct {
+	Primitive uint32
+	Vertices  int
+	Attribs   map[string]*AttribData // per-vertex attribute arrays
+	Instanced map[string]*AttribData // per-instance attribute arrays
+	Buffers   []*BufferData          // interleaved vertex buffers
+	Elements  *ElementData           // nil if the Mesh draws its vertices in order
+	Submeshes []Submesh
+
+	// Geometry is true if only the positions and elements were kept, in
+	// which case the Mesh cannot be recreated from the data.
+	Geometry bool
+}
+
+// AttribData is the data of an AttribArray.
+type AttribData struct {
+	Dims       int
+	Type       uint32
+	Normalized bool
+	Divisor    uint32
+	Usage      uint32
+	Data       []byte
+}
+
+// BufferData is the data of a VertexBuffer.
+type BufferData struct {
+	Layout *VertexLayout
+	Data   []byte
+}
+
+// ElementData is the data of an ElementArray.
+type ElementData struct {
+	Type    uint32
+	Restart bool
+	Data    []byte
+}
+
+// NewMeshData reads the data of a Mesh back from its buffers according to a
+// Retention policy, returning nil for RetainNone. With RetainGeometry, only the
+// "pos" attribute and the elements are read, and a position in an interleaved
+// buffer is copied out into an attribute array.
+func NewMeshData(m *Mesh, policy Retention) (*MeshData, error) {
+	if policy == RetainNone {
+		return nil, nil
+	}
+
+	var d = &MeshData{
+		Primitive: m.Primitive,
+		Vertices:  m.Vertices,
+		Attribs:   make(map[string]*AttribData),
+		Instanced: make(map[string]*AttribData),
+		Submeshes: append([]Submesh(nil), m.Submeshes...),
+		Geometry:  policy == RetainGeometry,
+	}
+
+	var readAttrib = func(arr *AttribArray) *AttribData {
+		return &AttribData{
+			Dims:       arr.Dims,
+			Type:       arr.Type,
+			Normalized: arr.Normalized,
+			Divisor:    arr.Divisor,
+			Usage:      arr.Usage,
+			Data:       readBuffer(arr.Buf, dataSize(arr.Type, arr.Len)),
+		}
+	}
+
+	for name, arr := range m.Attribs {
+		if !d.Geometry || name == "pos" {
+			d.Attribs[name] = readAttrib(arr)
+		}
+	}
+	if !d.Geometry {
+		for name, arr := range m.Instanced {
+			d.Instanced[name] = readAttrib(arr)
+		}
+	}
+
+	for _, vb := range m.Buffers {
+		var data = readBuffer(vb.Buf, vb.Len*vb.Layout.Stride)
+		if !d.Geometry {
+			d.Buffers = append(d.Buffers, &BufferData{vb.Layout, data})
+			continue
+		}
+
+		var a, ok = vb.Layout.Attrib("pos")
+		if !ok {
+			continue
+		}
+		d.Attribs["pos"] = &AttribData{Dims: a.Dims, Type: a.Type, Normalized: a.Normalized, Usage: gl.STATIC_DRAW, Data: extractAttrib(data, vb.Layout.Stride, a)}
+	}
+
+	if d.Geometry && d.Attribs["pos"] == nil {
+		return nil, fmt.Errorf("asset.NewMeshData error: Mesh '%s' has no 'pos' attribute", m.Name)
+	}
+
+	if e := m.Elements; e != nil {
+		d.Elements = &ElementData{
+			Type:    e.Type,
+			Restart: e.Restart,
+			Data:    readBuffer(e.Buf, dataSize(e.Type, e.Len)),
+		}
+	}
+
+	return d, nil
+}
+
+// extractAttrib copies one attribute of each vertex out of interleaved data.
+func extractAttrib(data []byte, stride int, a VertexAttrib) []byte {
+	var (
+		size = attribSize(a.Type, a.Dims)
+		n    = len(data) / stride
+		out  = make([]byte, 0, n*size)
+	)
+	for v := 0; v < n; v++ {
+		var offset = v*stride + a.Offset
+		out = append(out, data[offset:offset+size]...)
+	}
+	return out
+}
+
+// splice returns a copy of 'data' with 'raw' written at byte 'offset', which
+// ends with 'raw' if 'truncate' is set. The data is copied rather than
+// written in place, as copies of a MeshData share their bytes.
+func splice(data []byte, offset int, raw []byte, truncate bool) []byte {
+	var n = offset + len(raw)
+	if !truncate {
+		n = max(n, len(data))
+	}
+	var out = make([]byte, n)
+	copy(out, data[:min(offset, len(data))])
+	copy(out[offset:], raw)
+	if n > offset+len(raw) {
+		copy(out[offset+len(raw):], data[offset+len(raw):])
+	}
+	return out
+}
+
+// readBuffer reads the first 'size' bytes of a buffer.
+func readBuffer(buf uint32, size int) []byte {
+	var data = make([]byte, size)
+	if size > 0 {
+		gl.GetNamedBufferSubData(buf, 0, size, unsafe.Pointer(&data[0]))
+	}
+	return data
+}
+
+// NewMesh creates an uninitialized Mesh from the data, with new buffers.
+func (d *MeshData) NewMesh(name string) (*Mesh, error) {
+	if d.Geometry {
+		return nil, fmt.Errorf("asset.MeshData.NewMesh error: Mesh '%s' data holds only geometry", name)
+	}
+
+	var (
+		mesh = NewMesh(name)
+		err  error
+	)
+	defer func() {
+		if err != nil {
+			mesh.Clean()
+		}
+	}()
+
+	for name, a := range d.Attribs {
+		var arr *AttribArray
+		if arr, err = a.newArray(name); err != nil {
+			return nil, err
+		}
+		if err = mesh.AddArrays(arr); err != nil {
+			arr.Clean()
+			return nil, err
+		}
+	}
+	for name, a := range d.Instanced {
+		var arr *AttribArray
+		if arr, err = a.newArray(name); err != nil {
+			return nil, err
+		}
+		mesh.AddInstanceArrays(arr)
+	}
+	for _, b := range d.Buffers {
+		var vb *VertexBuffer
+		if len(b.Data) == 0 {
+			vb = newEmptyVertexBuffer(b.Layout, gl.STATIC_DRAW)
+		} else if vb, err = NewVertexBuffer(b.Layout, b.Data, gl.STATIC_DRAW); err != nil {
+			return nil, err
+		}
+		if err = mesh.AddBuffers(vb); err != nil {
+			vb.Clean()
+			return nil, err
+		}
+	}
+
+	if e := d.Elements; e != nil {
+		var l = len(e.Data) / typeSize(e.Type)
+		if l == 0 {
+			mesh.Elements = newEmptyElementArray(e.Type, gl.STATIC_DRAW)
+		} else if mesh.Elements, err = newElementArray(e.Data, e.Type, l, l, gl.STATIC_DRAW); err != nil {
+			return nil, err
+		}
+		mesh.Elements.Restart = e.Restart
+	}
+
+	mesh.Primitive = d.Primitive
+	mesh.Submeshes = append([]Submesh(nil), d.Submeshes...)
+	if mesh.Vertices == -1 {
+		mesh.Vertices = d.Vertices
+	}
+
+	return mesh, nil
+}
+
+// newArray creates an AttribArray holding the data, which may be empty, as
+// for instance arrays that are refilled each frame.
+func (a *AttribData) newArray(name string) (*AttribArray, error) {
+	var l = len(a.Data)
+	if !isPacked(a.Type) {
+		l /= typeSize(a.Type)
+	}
+
+	var arr *AttribArray
+	if l == 0 {
+		arr = newEmptyAttribArray(name, a.Dims, a.Type, a.Usage)
+	} else {
+		var err error
+		if arr, err = newAttribArray(name, a.Dims, a.Data, a.Type, l, a.Usage); err != nil {
+			return nil, err
+		}
+	}
+	arr.Normalized, arr.Divisor = a.Normalized, a.Divisor
+	return arr, nil
+}
+
+// Floats returns the components of an attribute as float32s, along with its
+// number of dimensions. Normalized integers are scaled as OpenGL reads them and
+// other integers converted. Matrices are returned column by column.
+func (d *MeshData) Floats(name string) ([]float32, int, error) {
+	var (
+		typ        uint32
+		dims       int
+		normalized bool
+		data       []byte
+		stride     int
+		offset     int
+	)
+
+	if a, ok := d.Attribs[name]; ok {
+		typ, dims, normalized, data = a.Type, a.Dims, a.Normalized, a.Data
+	} else if a, ok = d.Instanced[name]; ok {
+		typ, dims, normalized, data = a.Type, a.Dims, a.Normalized, a.Data
+	} else {
+		for _, b := range d.Buffers {
+			if a, ok := b.Layout.Attrib(name); ok {
+				typ, dims, normalized, data = a.Type, a.Dims, a.Normalized, b.Data
+				stride, offset = b.Layout.Stride, a.Offset
+				break
+			}
+		}
+		if data == nil {
+			return nil, 0, fmt.Errorf("asset.MeshData.Floats error: no attribute '%s'", name)
+		}
+	}
+
+	return readFloats(data, typ, dims, normalized, stride, offset), dims, nil
+}
+
+// readFloats reads the components of an attribute as float32s, as described by
+// MeshData.Floats. Each attribute is 'stride' bytes after the last, starting at
+// 'offset', or packed together if 'stride' is zero.
+func readFloats(data []byte, typ uint32, dims int, normalized bool, stride, offset int) []float32 {
+	if stride == 0 {
+		stride = attribSize(typ, dims)
+	}
+
+	var (
+		n   = len(data) / stride
+		out = make([]float32, 0, n*dims)
+	)
+	for v := 0; v < n; v++ {
+		var src = data[v*stride+offset:]
+		switch typ {
+		case gl.INT_2_10_10_10_REV:
+			var p = Int2101010(binary.NativeEndian.Uint32(src)).Unpack(normalized)
+			out = append(out, p[:]...)
+		case gl.UNSIGNED_INT_2_10_10_10_REV:
+			var p = Uint2101010(binary.NativeEndian.Uint32(src)).Unpack(normalized)
+			out = append(out, p[:]...)
+		default:
+			for c := 0; c < dims; c++ {
+				out = append(out, getComponent(src[c*typeSize(typ):], typ, normalized))
+			}
+		}
+	}
+
+	return out
+}
+
+// Positions returns the "pos" attribute as 3D vectors. The Z of 2D positions
+// is zero.
+func (d *MeshData) Positions() ([]mgl.Vec3, error) {
+	var data, dims, err = d.Floats("pos")
+	if err != nil {
+		return nil, err
+	}
+	if dims < 2 || dims > 4 {
+		return nil, fmt.Errorf("asset.MeshData.Positions error: 'pos' has %d dimensions", dims)
+	}
+
+	var pos = make([]mgl.Vec3, len(data)/dims)
+	for i := range pos {
+		copy(pos[i][:], data[i*dims:i*dims+min(dims, 3)])
+	}
+	return pos, nil
+}
+
+// Indices returns the elements as uint32s, or the index of each vertex in
+// order if there are none.
+func (d *MeshData) Indices() []uint32 {
+	if d.Elements == nil {
+		var idx = make([]uint32, d.Vertices)
+		for i := range idx {
+			idx[i] = uint32(i)
+		}
+		return idx
+	}
+
+	var (
+		e    = d.Elements
+		size = typeSize(e.Type)
+		idx  = make([]uint32, len(e.Data)/size)
+	)
+	for i := range idx {
+		switch e.Type {
+		case gl.UNSIGNED_BYTE:
+			idx[i] = uint32(e.Data[i])
+		case gl.UNSIGNED_SHORT:
+			idx[i] = uint32(binary.NativeEndian.Uint16(e.Data[2*i:]))
+		default:
+			idx[i] = binary.NativeEndian.Uint32(e.Data[4*i:])
+		}
+	}
+	return idx
+}
+
+// Triangles returns the vertex indices of each triangle drawn, unrolling strips
+// and fans and splitting them at restart indices. Strips keep the winding of
+// their first triangle. Nil is returned for primitives other than triangles.
+func (d *MeshData) Triangles() [][3]uint32 {
+	var (
+		idx     = d.Indices()
+		restart = uint32(0xffffffff)
+		tris    [][3]uint32
+	)
+	if d.Elements != nil && d.Elements.Restart {
+		restart = uint32(1)<<(8*typeSize(d.Elements.Type)) - 1
+	}
+
+	switch d.Primitive {
+	case gl.TRIANGLES:
+		tris = make([][3]uint32, 0, len(idx)/3)
+		for i := 0; i+2 < len(idx); i += 3 {
+			tris = append(tris, [3]uint32{idx[i], idx[i+1], idx[i+2]})
+		}
+	case gl.TRIANGLE_STRIP, gl.TRIANGLE_FAN:
+		var start = 0
+		for i := 0; i <= len(idx); i++ {
+			if i < len(idx) && idx[i] != restart {
+				continue
+			}
+			var run = idx[start:i]
+			for k := 2; k < len(run); k++ {
+				switch {
+				case d.Primitive == gl.TRIANGLE_FAN:
+					tris = append(tris, [3]uint32{run[0], run[k-1], run[k]})
+				case k%2 == 0:
+					tris = append(tris, [3]uint32{run[k-2], run[k-1], run[k]})
+				default:
+					tris = append(tris, [3]uint32{run[k-1], run[k-2], run[k]})
+				}
+			}
+			start = i + 1
+		}
+	}
+
+	return tris
+}
+
+// Retain keeps a copy of the Mesh's data in its Data field, read back from its
+// buffers according to a Retention policy. RetainNone discards any copy. The
+// copy is kept up to date as the Mesh's arrays and buffers are updated,
+// refilled or streamed. Arrays added to the Mesh later are not retained until
+// Retain is called again.
+func (m *Mesh) Retain(policy Retention) error {
+	var d, err = NewMeshData(m, policy)
+	if err != nil {
+		return err
+	}
+	m.Data = d
+	m.track()
+	return nil
+}
+
+// track links each array and buffer of the Mesh to its copy in the retained
+// Data, if any, so that writing to them keeps the copy up to date.
+func (m *Mesh) track() {
+	var d = m.Data
+	if d == nil {
+		d = &MeshData{}
+	}
+	for name, arr := range m.Attribs {
+		arr.retained = d.Attribs[name]
+	}
+	for name, arr := range m.Instanced {
+		arr.retained = d.Instanced[name]
+	}
+	for i, vb := range m.Buffers {
+		vb.retained, vb.retainedPos = nil, nil
+		if _, ok := vb.Layout.Attrib("pos"); ok && d.Geometry {
+			vb.retainedPos = d.Attribs["pos"]
+		} else if i < len(d.Buffers) {
+			vb.retained = d.Buffers[i]
+		}
+	}
+	if m.Elements != nil {
+		m.Elements.retained = d.Elements
+	}
+}
+
+// Restore recreates the buffers and vertex array of the Mesh from its retained
+// Data, such as after the OpenGL context is lost. The old handles are not
+// deleted. The Mesh is initialized with its Locations.
+func (m *Mesh) Restore() error {
+	if m.Data == nil {
+		return fmt.Errorf("asset.Mesh.Restore error: Mesh '%s' has no retained data", m.Name)
+	}
+
+	var restored, err = m.Data.NewMesh(m.Name)
+	if err != nil {
+		return err
+	}
+	m.adopt(restored)
+	m.track()
+
+	return m.Init()
+}
+
+// adopt takes the arrays and primitive of another Mesh, leaving the Mesh
+// uninitialized. Its old arrays are not deleted.
+func (m *Mesh) adopt(other *Mesh) {
+	m.Attribs = other.Attribs
+	m.Buffers = other.Buffers
+	m.Instanced = other.Instanced
+	m.Elements = other.Elements
+	m.Primitive = other.Primitive
+	m.Vertices = other.Vertices
+	m.Submeshes = other.Submeshes
+	m.Array = 0
+}
+
+// RestoreMeshes restores every Mesh of the Manager that retains all of its
+// data, as by Mesh.Restore. Meshes retaining only geometry, or nothing, are
+// skipped and must be recreated by the caller.
+func (am *Manager) RestoreMeshes() error {
+	var errs []error
+	for _, m := range am.Meshes {
+		if m.Data == nil || m.Data.Geometry {
+			Logger.Printf("asset.Manager.RestoreMeshes: Mesh '%s' cannot be restored\n", m.Name)
+			continue
+		}
+		if err := m.Restore(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
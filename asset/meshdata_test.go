@@ -0,0 +1,116 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSplice(t *testing.T) {
+	var tests = []struct {
+		data     []byte
+		offset   int
+		raw      []byte
+		truncate bool
+		want     []byte
+	}{
+		{[]byte{1, 2, 3, 4}, 0, []byte{9, 9}, true, []byte{9, 9}},
+		{[]byte{1, 2, 3, 4}, 1, []byte{9, 9}, false, []byte{1, 9, 9, 4}},
+		{[]byte{1, 2, 3, 4}, 3, []byte{9, 9}, false, []byte{1, 2, 3, 9, 9}},
+		{[]byte{1, 2, 3, 4}, 4, []byte{9}, false, []byte{1, 2, 3, 4, 9}},
+		{nil, 0, []byte{9}, false, []byte{9}},
+	}
+	for _, tt := range tests {
+		var orig = append([]byte(nil), tt.data...)
+		var got = splice(tt.data, tt.offset, tt.raw, tt.truncate)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("splice(%v, %d, %v, %v) = %v, want %v", orig, tt.offset, tt.raw, tt.truncate, got, tt.want)
+		}
+		if !bytes.Equal(tt.data, orig) {
+			t.Errorf("splice changed its input to %v", tt.data)
+		}
+	}
+}
+
+func TestExtractAttrib(t *testing.T) {
+	// Vertices of a 2D byte position followed by a byte of padding.
+	var (
+		data = []byte{1, 2, 0, 3, 4, 0, 5, 6, 0}
+		a    = VertexAttrib{Name: "pos", Dims: 2, Type: gl.UNSIGNED_BYTE}
+		want = []byte{1, 2, 3, 4, 5, 6}
+	)
+	if got := extractAttrib(data, 3, a); !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMeshDataTriangles(t *testing.T) {
+	var tests = []struct {
+		primitive uint32
+		elements  *ElementData
+		want      [][3]uint32
+	}{
+		{gl.TRIANGLES, nil, [][3]uint32{{0, 1, 2}, {3, 4, 5}}},
+		{gl.TRIANGLE_STRIP, nil, [][3]uint32{{0, 1, 2}, {2, 1, 3}, {2, 3, 4}, {4, 3, 5}}},
+		{gl.TRIANGLE_FAN, nil, [][3]uint32{{0, 1, 2}, {0, 2, 3}, {0, 3, 4}, {0, 4, 5}}},
+		// Strips split at the restart index of 8-bit elements.
+		{
+			gl.TRIANGLE_STRIP,
+			&ElementData{Type: gl.UNSIGNED_BYTE, Restart: true, Data: []byte{0, 1, 2, 3, 0xff, 5, 4, 3}},
+			[][3]uint32{{0, 1, 2}, {2, 1, 3}, {5, 4, 3}},
+		},
+		// Without restart, 0xffff is an ordinary index.
+		{
+			gl.TRIANGLE_FAN,
+			&ElementData{Type: gl.UNSIGNED_SHORT, Data: binary.NativeEndian.AppendUint16(binary.NativeEndian.AppendUint16([]byte{0, 0}, 0xffff), 1)},
+			[][3]uint32{{0, 0xffff, 1}},
+		},
+		{gl.LINES, nil, nil},
+	}
+	for _, tt := range tests {
+		var (
+			d   = &MeshData{Primitive: tt.primitive, Vertices: 6, Elements: tt.elements}
+			got = d.Triangles()
+		)
+		if len(got) != len(tt.want) {
+			t.Errorf("primitive %#x: got %v, want %v", tt.primitive, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("primitive %#x: got %v, want %v", tt.primitive, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMeshDataPositions(t *testing.T) {
+	// 2D normalized short positions interleaved after a byte attribute.
+	var data = make([]byte, 16)
+	for i, v := range []int16{32767, -32767, 0, 0} {
+		binary.NativeEndian.PutUint16(data[8*(i/2)+4+2*(i%2):], uint16(v))
+	}
+	var d = &MeshData{
+		Buffers: []*BufferData{{
+			Layout: NewVertexLayout(
+				VertexAttrib{Name: "id", Dims: 1, Type: gl.UNSIGNED_BYTE},
+				VertexAttrib{Name: "pos", Dims: 2, Type: gl.SHORT, Normalized: true},
+			),
+			Data: data,
+		}},
+	}
+	var pos, err = d.Positions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pos) != 2 || pos[0] != (mgl.Vec3{1, -1, 0}) || pos[1] != (mgl.Vec3{}) {
+		t.Errorf("got %v", pos)
+	}
+	if _, _, err = d.Floats("normal"); err == nil {
+		t.Error("a missing attribute was read")
+	}
+}
@@ -0,0 +1,53 @@
+package asset
+
+import (
+	"io"
+	"os"
+
+	"github.com/Ostsol/engine/meshfmt"
+)
+
+// LoadPLY attempts to load a Mesh from the PLY file 'name'. Vertex properties
+// other than positions, normals, texture coordinates and colours become
+// attribute arrays named after them. If the Mesh already exists, it is
+// returned.
+func (am *Manager) LoadPLY(name string) (*Mesh, error) {
+	return am.loadMeshFile("LoadPLY", name, meshfmt.DecodePLY)
+}
+
+// LoadSTL attempts to load a Mesh from the STL file 'name', welding vertices
+// and computing normals. If the Mesh already exists, it is returned.
+func (am *Manager) LoadSTL(name string) (*Mesh, error) {
+	return am.loadMeshFile("LoadSTL", name, meshfmt.DecodeSTL)
+}
+
+// loadMeshFile loads a Mesh from a file in "assets/models" holding a single
+// mesh, using the given decoder.
+func (am *Manager) loadMeshFile(method, name string, decode func(io.Reader) (*meshfmt.Mesh, error)) (*Mesh, error) {
+	if m, ok := am.GetMesh(name); ok {
+		return m, nil
+	}
+
+	Logger.Printf("asset.Manager.%s: loading Mesh '%s'\n", method, name)
+
+	var f, err = os.Open("assets/models/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		data *meshfmt.Mesh
+		mesh *Mesh
+	)
+	if data, err = decode(f); err == nil {
+		mesh, err = NewMeshFromData(name, data, am.AttribLocations)
+	}
+	if err != nil {
+		Logger.Printf("asset.Manager.%s: failed\n", method)
+		return nil, err
+	}
+	am.AddMesh(mesh)
+
+	return mesh, nil
+}
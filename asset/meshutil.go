@@ -16,9 +16,13 @@ func checkSlice(name string, attr string, data interface{}) {
 	}
 }
 
-// MakeMesh creates a mesh given a set of common attributes. 'pos' and 'elems'
-// are mandatory. 'cols' must be a slice of RGBA values. 'nrms' must be a slice
-// of 3D values. Each 'texcoord' must be a slice of 2D values.
+// MakeMesh creates a mesh given a set of common attributes. 'pos' is
+// mandatory. 'cols' must be a slice of RGBA values, which are normalized if
+// they are integers. 'nrms' must be a slice of 3D values. Each 'texcoord' must
+// be a slice of 2D values. 'elems' may be a slice of uint8, uint16 or uint32
+// indices, or nil to draw the vertices in order. Primitive restart is left
+// off; strips, fans and loops split by the maximum index value must enable it
+// by setting Restart on the Mesh's Elements.
 func MakeMesh(name string, dims int, prim uint32, pos, cols, nrms interface{}, texcoords []interface{}, elems interface{}) (*Mesh, error) {
 	var (
 		posarr, colarr, nrmarr *AttribArray
@@ -59,11 +63,12 @@ func MakeMesh(name string, dims int, prim uint32, pos, cols, nrms interface{}, t
 		if err != nil {
 			return nil, err
 		}
+		colarr.Normalized = isInteger(colarr.Type)
 	}
 	if nrms != nil {
-		checkSlice(name, "normal", cols)
+		checkSlice(name, "normal", nrms)
 
-		colarr, err = NewAttribArray("normal", 3, nrms, gl.STATIC_DRAW)
+		nrmarr, err = NewAttribArray("normal", 3, nrms, gl.STATIC_DRAW)
 		if err != nil {
 			return nil, err
 		}
@@ -77,30 +82,121 @@ func MakeMesh(name string, dims int, prim uint32, pos, cols, nrms interface{}, t
 			return nil, err
 		}
 	}
-	if elems == nil {
-		panic(fmt.Errorf("MakeMesh error: no element array for Mesh '%s'", name))
-	} else {
-		checkSlice(name, "elems", elems)
+	if elemarr, err = makeElements(name, elems); err != nil {
+		return nil, err
+	}
+
+	if err = mesh.AddArrays(posarr, colarr, nrmarr); err != nil {
+		return nil, err
+	}
+	if err = mesh.AddArrays(texcarr...); err != nil {
+		return nil, err
+	}
+
+	mesh.Elements = elemarr
+	mesh.Primitive = prim
+
+	return mesh, nil
+}
 
-		elemarr, err = NewElementArray(elems, gl.STATIC_DRAW)
+// MakeInterleavedMesh creates a mesh like MakeMesh, but interleaves its
+// attributes into a single VertexBuffer. Colours are stored as normalized
+// bytes; other attributes keep the type of their data.
+func MakeInterleavedMesh(name string, dims int, prim uint32, pos, cols, nrms interface{}, texcoords []interface{}, elems interface{}) (*Mesh, error) {
+	if pos == nil {
+		panic(fmt.Errorf("MakeMesh error: Mesh '%s' must have a 'pos' attribute", name))
+	}
+	var (
+		attribs []VertexAttrib
+		arrays  = make(map[string]interface{})
+	)
+
+	var add = func(attr string, dims int, data interface{}) error {
+		checkSlice(name, attr, data)
+
+		var _, typ, _, err = sliceBytes(data)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("MakeMesh error: Mesh '%s' '%s': %v", name, attr, err)
+		}
+		var a = VertexAttrib{Name: attr, Dims: dims, Type: typ}
+		if attr == "color" {
+			a.Type, a.Normalized = gl.UNSIGNED_BYTE, true
 		}
+		attribs = append(attribs, a)
+		arrays[attr] = data
+		return nil
 	}
 
-	if err = mesh.AddArrays(posarr, colarr, nrmarr); err != nil {
+	var err = add("pos", dims, pos)
+	if err == nil && cols != nil {
+		err = add("color", 4, cols)
+	}
+	if err == nil && nrms != nil {
+		err = add("normal", 3, nrms)
+	}
+	for i := 0; err == nil && i < len(texcoords); i++ {
+		err = add(fmt.Sprintf("texcoord%d", i), 2, texcoords[i])
+	}
+	if err != nil {
 		return nil, err
 	}
-	if err = mesh.AddArrays(texcarr...); err != nil {
+
+	var (
+		vb      *VertexBuffer
+		elemarr *ElementArray
+		mesh    = NewMesh(name)
+	)
+	if vb, err = NewInterleavedBuffer(NewVertexLayout(attribs...), arrays, gl.STATIC_DRAW); err != nil {
+		return nil, err
+	}
+	if elemarr, err = makeElements(name, elems); err != nil {
+		vb.Clean()
 		return nil, err
 	}
 
+	mesh.AddBuffers(vb)
 	mesh.Elements = elemarr
 	mesh.Primitive = prim
 
 	return mesh, nil
 }
 
+// NewInstanceArray creates a per-instance AttribArray for adding to a Mesh with
+// AddInstanceArrays, such as "instanceMatrix" from a slice of mgl.Mat4
+// transforms. Its buffer is meant to be refilled often with Refill, so 'data'
+// may be empty, though it must still give the array's type.
+func NewInstanceArray(name string, dims int, data interface{}) (*AttribArray, error) {
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("AttribArray error: '%s': %v", name, err)
+	}
+	if err = checkElementDims(name, dims, data); err != nil {
+		return nil, err
+	}
+
+	var arr *AttribArray
+	if l > 0 {
+		if arr, err = newAttribArray(name, dims, raw, typ, l, gl.DYNAMIC_DRAW); err != nil {
+			return nil, err
+		}
+	} else {
+		arr = newEmptyAttribArray(name, dims, typ, gl.DYNAMIC_DRAW)
+	}
+	arr.Divisor = 1
+
+	return arr, nil
+}
+
+// makeElements creates the ElementArray of a Mesh, or nil if 'elems' is nil.
+func makeElements(name string, elems interface{}) (*ElementArray, error) {
+	if elems == nil {
+		return nil, nil
+	}
+	checkSlice(name, "elems", elems)
+
+	return NewElementArray(elems, gl.STATIC_DRAW)
+}
+
 // NewBox creates an uninitialized box Mesh with an origin offset about its
 // geometric centre
 func NewBox(name string, width, height float32, offset mgl.Vec2) (*Mesh, error) {
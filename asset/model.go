@@ -0,0 +1,111 @@
+package asset
+
+import (
+	"strings"
+
+	"github.com/Ostsol/engine/meshfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Model is a set of Meshes loaded from a single file, whose Submeshes are
+// drawn with the Materials in the Model's material slots.
+type Model struct {
+	Name      string
+	Meshes    []*Mesh
+	Materials []*Material // Material of each slot, or nil if it has none
+}
+
+// Draw draws each Mesh of the Model with the Materials of its slots, as by
+// Mesh.DrawSlots, given a set of uniforms.
+func (m *Model) Draw(uniforms Uniforms) {
+	for _, mesh := range m.Meshes {
+		mesh.DrawSlots(m.Materials, uniforms)
+	}
+}
+
+// NewMeshFromData creates and initializes a Mesh of triangles from mesh data
+// read by the meshfmt package, interleaving its attributes into a single
+// VertexBuffer. Colours are stored as normalized bytes, and joint indices as
+// unsigned shorts read as integers. Tangents are named
+// "tangent", and further attribute sets keep their names. The Mesh is bound
+// with the given attribute locations, or DefaultAttribLocations if nil.
+func NewMeshFromData(name string, data *meshfmt.Mesh, locs AttribLocations) (*Mesh, error) {
+	var attribs, arrays = meshAttribs(data)
+	var vb, err = NewInterleavedBuffer(NewVertexLayout(attribs...), arrays, gl.STATIC_DRAW)
+	if err != nil {
+		return nil, err
+	}
+
+	var mesh = NewMesh(name)
+	mesh.Locations = locs
+	mesh.AddBuffers(vb)
+	mesh.Primitive = gl.TRIANGLES
+	if mesh.Elements, err = NewElementArray(data.Indices, gl.STATIC_DRAW); err != nil {
+		mesh.Clean()
+		return nil, err
+	}
+	if err = mesh.Init(); err != nil {
+		mesh.Clean()
+		return nil, err
+	}
+
+	return mesh, nil
+}
+
+// meshAttribs returns the vertex attributes NewMeshFromData gives mesh data,
+// and the data of each by name. Attributes without data are left out.
+func meshAttribs(data *meshfmt.Mesh) ([]VertexAttrib, map[string]interface{}) {
+	var (
+		attribs []VertexAttrib
+		arrays  = make(map[string]interface{})
+	)
+
+	var add = func(attr string, dims int, values []float32) {
+		if len(values) == 0 {
+			return
+		}
+		var a = VertexAttrib{Name: attr, Dims: dims, Type: gl.FLOAT}
+		switch {
+		case attr == "color":
+			a.Type, a.Normalized = gl.UNSIGNED_BYTE, true
+		case strings.HasPrefix(attr, "joints"):
+			a.Type = gl.UNSIGNED_SHORT
+		}
+		attribs = append(attribs, a)
+		arrays[attr] = values
+	}
+
+	add("pos", 3, data.Positions)
+	add("normal", 3, data.Normals)
+	add("tangent", 4, data.Tangents)
+	add("texcoord0", 2, data.TexCoords)
+	add("color", 4, data.Colors)
+	for _, a := range data.Attribs {
+		add(a.Name, a.Components, a.Data)
+	}
+
+	return attribs, arrays
+}
+
+// NewMeshFromParts creates and initializes a Mesh from several sets of mesh
+// data, merged as by meshfmt.Merge, with a Submesh for each named after its
+// part and drawn with the material slot given in 'slots'. See
+// NewMeshFromData.
+func NewMeshFromParts(name string, parts []*meshfmt.Mesh, slots []int, locs AttribLocations) (*Mesh, error) {
+	var data, firsts = meshfmt.Merge(name, parts)
+	var mesh, err = NewMeshFromData(name, data, locs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, p := range parts {
+		mesh.Submeshes = append(mesh.Submeshes, Submesh{
+			Name:  p.Name,
+			First: firsts[i],
+			Count: len(p.Indices),
+			Slot:  slots[i],
+		})
+	}
+
+	return mesh, nil
+}
@@ -0,0 +1,277 @@
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Ostsol/engine/meshfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// ComputeNormals sets the "normal" attribute of the data to smooth normals, as
+// computed by meshfmt.SmoothNormals with a crease angle in radians. Vertices
+// are split where the normals of their triangles differ by more than the
+// crease angle, in which case every vertex array is remapped and the elements
+// become a list of triangles. The data must be of triangles, and a normal must
+// not be part of an interleaved buffer.
+func (d *MeshData) ComputeNormals(crease float32) error {
+	var pos, tris, err = d.triangleGeometry("normal")
+	if err != nil {
+		return fmt.Errorf("asset.MeshData.ComputeNormals error: %v", err)
+	}
+
+	var normals, from, indices = meshfmt.SmoothNormals(pos, tris, float64(crease))
+	d.remap(from, indices)
+	d.Attribs["normal"] = &AttribData{Dims: 3, Type: gl.FLOAT, Usage: gl.STATIC_DRAW, Data: rawBytes(normals)}
+
+	return nil
+}
+
+// ComputeFlatNormals sets the "normal" attribute of the data to the normals of
+// its triangles, splitting vertices so that they are shared only by triangles
+// in the same plane. See ComputeNormals.
+func (d *MeshData) ComputeFlatNormals() error {
+	return d.ComputeNormals(0)
+}
+
+// ComputeTangents sets the "tangent" attribute of the data to MikkTSpace-like
+// tangents, as computed by meshfmt.Tangents from the "normal" and "texcoord0"
+// attributes. Vertices whose triangles are textured with opposite orientations
+// are split as by ComputeNormals.
+func (d *MeshData) ComputeTangents() error {
+	var pos, tris, err = d.triangleGeometry("tangent")
+	if err != nil {
+		return fmt.Errorf("asset.MeshData.ComputeTangents error: %v", err)
+	}
+
+	var (
+		normals, texcoords []float32
+		dims               int
+	)
+	if normals, dims, err = d.Floats("normal"); err == nil && dims != 3 {
+		err = fmt.Errorf("'normal' has %d dimensions", dims)
+	}
+	if err == nil {
+		if texcoords, dims, err = d.Floats("texcoord0"); err == nil && dims != 2 {
+			err = fmt.Errorf("'texcoord0' has %d dimensions", dims)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("asset.MeshData.ComputeTangents error: %v", err)
+	}
+
+	var tangents, from, indices = meshfmt.Tangents(pos, normals, texcoords, tris)
+	d.remap(from, indices)
+	d.Attribs["tangent"] = &AttribData{Dims: 4, Type: gl.FLOAT, Usage: gl.STATIC_DRAW, Data: rawBytes(tangents)}
+
+	return nil
+}
+
+// triangleGeometry returns the XYZ positions and triangle indices of the data,
+// checking that the attribute 'attr' may be replaced.
+func (d *MeshData) triangleGeometry(attr string) ([]float32, []uint32, error) {
+	switch d.Primitive {
+	case gl.TRIANGLES, gl.TRIANGLE_STRIP, gl.TRIANGLE_FAN:
+	default:
+		return nil, nil, fmt.Errorf("primitive 0x%x is not of triangles", d.Primitive)
+	}
+	for _, b := range d.Buffers {
+		if _, ok := b.Layout.Attrib(attr); ok {
+			return nil, nil, fmt.Errorf("'%s' is part of an interleaved buffer", attr)
+		}
+	}
+
+	var positions, err = d.Positions()
+	if err != nil {
+		return nil, nil, err
+	}
+	var pos = make([]float32, 0, 3*len(positions))
+	for _, p := range positions {
+		pos = append(pos, p[:]...)
+	}
+
+	var (
+		triangles = d.Triangles()
+		tris      = make([]uint32, 0, 3*len(triangles))
+	)
+	for _, t := range triangles {
+		tris = append(tris, t[:]...)
+	}
+
+	// The base vertices of Submeshes are added, as remap replaces the
+	// elements with absolute indices.
+	for _, s := range d.Submeshes {
+		if s.BaseVertex == 0 {
+			continue
+		}
+		if d.Primitive != gl.TRIANGLES || s.First < 0 || s.First+s.Count > len(tris) {
+			return nil, nil, errors.New("Submeshes with base vertices are not ranges of a list of triangles")
+		}
+		for i := s.First; i < s.First+s.Count; i++ {
+			tris[i] = uint32(int(tris[i]) + s.BaseVertex)
+		}
+	}
+
+	return pos, tris, nil
+}
+
+// remap replaces the vertices of the data with copies of the vertices 'from',
+// and its elements with the list of triangles 'indices', keeping their type if
+// it can index every vertex. If the vertices and triangles are unchanged, the
+// elements are kept.
+func (d *MeshData) remap(from, indices []uint32) {
+	var identity = len(from) == d.Vertices
+	for i := 0; identity && i < len(from); i++ {
+		identity = from[i] == uint32(i)
+	}
+
+	if identity && d.Primitive == gl.TRIANGLES {
+		var old = d.Indices()
+		var same = len(old) == len(indices)
+		for i := 0; same && i < len(old); i++ {
+			same = old[i] == indices[i]
+		}
+		if same {
+			return
+		}
+	}
+
+	if !identity {
+		var dup = func(data []byte, size int) []byte {
+			var out = make([]byte, 0, len(from)*size)
+			for _, v := range from {
+				out = append(out, data[int(v)*size:int(v+1)*size]...)
+			}
+			return out
+		}
+		for _, a := range d.Attribs {
+			a.Data = dup(a.Data, attribSize(a.Type, a.Dims))
+		}
+		for _, b := range d.Buffers {
+			b.Data = dup(b.Data, b.Layout.Stride)
+		}
+		d.Vertices = len(from)
+	}
+
+	var typ = indexType(d.Vertices)
+	if d.Elements != nil && typeSize(d.Elements.Type) > typeSize(typ) {
+		typ = d.Elements.Type
+	}
+	d.Primitive = gl.TRIANGLES
+	d.setElements(indices, typ)
+	for i := range d.Submeshes {
+		d.Submeshes[i].BaseVertex = 0
+	}
+}
+
+// setElements replaces the elements of the data with 'indices', stored as the
+// index type 'typ'.
+func (d *MeshData) setElements(indices []uint32, typ uint32) {
+	var data []byte
+	switch typ {
+	case gl.UNSIGNED_BYTE:
+		var elems = make([]uint8, len(indices))
+		for i, v := range indices {
+			elems[i] = uint8(v)
+		}
+		data = rawBytes(elems)
+	case gl.UNSIGNED_SHORT:
+		var elems = make([]uint16, len(indices))
+		for i, v := range indices {
+			elems[i] = uint16(v)
+		}
+		data = rawBytes(elems)
+	default:
+		data = rawBytes(append([]uint32(nil), indices...))
+	}
+	d.Elements = &ElementData{Type: typ, Data: data}
+}
+
+// indexType returns the smallest index type that can index 'vertices'
+// vertices without using the primitive restart index.
+func indexType(vertices int) uint32 {
+	switch {
+	case vertices <= math.MaxUint8:
+		return gl.UNSIGNED_BYTE
+	case vertices <= math.MaxUint16:
+		return gl.UNSIGNED_SHORT
+	}
+	return gl.UNSIGNED_INT
+}
+
+// GenerateNormals gives the Mesh smooth normals, as by
+// MeshData.ComputeNormals, replacing any "normal" AttribArray. Its data is
+// read back from its buffers, unless it retains all of it. If vertices are
+// split, every array of the Mesh is recreated. An initialized Mesh is
+// initialized again, and retained data is updated.
+func (m *Mesh) GenerateNormals(crease float32) error {
+	return m.generate("normal", func(d *MeshData) error {
+		return d.ComputeNormals(crease)
+	})
+}
+
+// GenerateFlatNormals gives the Mesh flat normals, as by
+// MeshData.ComputeFlatNormals. See GenerateNormals.
+func (m *Mesh) GenerateFlatNormals() error {
+	return m.generate("normal", (*MeshData).ComputeFlatNormals)
+}
+
+// GenerateTangents gives the Mesh MikkTSpace-like tangents, as by
+// MeshData.ComputeTangents. See GenerateNormals.
+func (m *Mesh) GenerateTangents() error {
+	return m.generate("tangent", (*MeshData).ComputeTangents)
+}
+
+// generate changes the data of the Mesh with 'compute', updating the Mesh with
+// the result. If the vertices and elements are unchanged, only the attribute
+// 'attr', if any, is replaced.
+func (m *Mesh) generate(attr string, compute func(*MeshData) error) error {
+	var (
+		d   = m.Data
+		err error
+	)
+	if d == nil || d.Geometry {
+		if d, err = NewMeshData(m, RetainAll); err != nil {
+			return err
+		}
+	}
+
+	var vertices, elems = d.Vertices, d.Elements
+	if err = compute(d); err != nil {
+		return err
+	}
+
+	var initialized = m.Array != 0
+	switch {
+	case d.Vertices != vertices || d.Elements != elems:
+		var rebuilt *Mesh
+		if rebuilt, err = d.NewMesh(m.Name); err != nil {
+			return err
+		}
+		m.Clean()
+		m.adopt(rebuilt)
+	case attr != "":
+		// Only the attribute changed.
+		var arr *AttribArray
+		if arr, err = d.Attribs[attr].newArray(attr); err != nil {
+			return err
+		}
+		m.Attribs[attr].Clean()
+		m.Attribs[attr] = arr
+	}
+
+	switch {
+	case m.Data == nil:
+	case m.Data.Geometry:
+		err = m.Retain(RetainGeometry)
+	default:
+		m.Data = d
+		m.track()
+	}
+	if err == nil && initialized {
+		err = m.Init()
+	}
+
+	return err
+}
@@ -0,0 +1,192 @@
+package asset
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ostsol/engine/meshfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// LoadOBJ attempts to load a Model from the Wavefront OBJ file 'name', as a
+// single Mesh named 'name'. Each object or group, and each material used
+// within it, becomes a Submesh named after the group, and each material a slot
+// of the Model. Materials are read from the MTL files the OBJ file names, are
+// named 'library:material', where 'library' is the path of the MTL file
+// relative to "assets/models", and are shared between Models. If the Model
+// already exists, it is returned.
+//
+// Material colours are stored as the Params "ambient", "diffuse", "specular"
+// and "emissive" (mgl.Vec3), and "shininess" and "opacity" (float32). Texture
+// maps are loaded as Textures and bound to the samplers "diffuseMap",
+// "specularMap", "normalMap", "bumpMap", "emissiveMap" and "opacityMap", the
+// diffuse and emissive maps as sRGB. The Materials have no shader program,
+// which must be set before drawing.
+func (am *Manager) LoadOBJ(name string) (*Model, error) {
+	if m, ok := am.GetModel(name); ok {
+		return m, nil
+	}
+
+	Logger.Printf("asset.Manager.LoadOBJ: loading Model '%s'\n", name)
+
+	var (
+		path = "assets/models/" + name
+		dir  = filepath.Dir(path)
+		obj  *meshfmt.OBJ
+	)
+
+	var f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if obj, err = meshfmt.DecodeOBJ(f); err != nil {
+		Logger.Print("asset.Manager.LoadOBJ: failed")
+		return nil, err
+	}
+
+	var materials = make(map[string]*Material)
+	for _, lib := range obj.MaterialLibs {
+		if err = am.loadMTL(dir, lib, materials); err != nil {
+			Logger.Print("asset.Manager.LoadOBJ: failed")
+			return nil, err
+		}
+	}
+
+	// Each material used becomes a slot of the Model.
+	var (
+		model = &Model{Name: name}
+		slots = make([]int, len(obj.Meshes))
+		index = make(map[string]int)
+	)
+	for i, data := range obj.Meshes {
+		var slot, ok = index[data.Material]
+		if !ok {
+			var mat = materials[data.Material]
+			if mat == nil && data.Material != "" {
+				Logger.Printf("asset.Manager.LoadOBJ: group '%s' uses unknown material '%s'\n", data.Name, data.Material)
+			}
+			slot = len(model.Materials)
+			index[data.Material] = slot
+			model.Materials = append(model.Materials, mat)
+		}
+		slots[i] = slot
+	}
+
+	if len(obj.Meshes) > 0 {
+		var meshName = name
+		if _, ok := am.GetMesh(meshName); ok {
+			meshName += "#mesh"
+		}
+
+		var mesh, err = NewMeshFromParts(meshName, obj.Meshes, slots, am.AttribLocations)
+		if err != nil {
+			Logger.Print("asset.Manager.LoadOBJ: failed")
+			return nil, err
+		}
+		am.AddMesh(mesh)
+		model.Meshes = append(model.Meshes, mesh)
+	}
+
+	am.AddModel(model)
+
+	return model, nil
+}
+
+// loadMTL loads the materials of the MTL file 'lib', relative to 'dir', into
+// 'materials' by their names in the file.
+func (am *Manager) loadMTL(dir, lib string, materials map[string]*Material) error {
+	var (
+		path    = filepath.Clean(filepath.Join(dir, lib))
+		libName = filepath.ToSlash(path)
+	)
+	if rel, err := filepath.Rel("assets/models", path); err == nil && !strings.HasPrefix(rel, "..") {
+		libName = filepath.ToSlash(rel)
+	}
+
+	var f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var mtls []*meshfmt.Material
+	if mtls, err = meshfmt.DecodeMTL(f); err != nil {
+		return err
+	}
+
+	for _, mtl := range mtls {
+		var key = libName + ":" + mtl.Name
+		if mat, ok := am.GetMaterial(key); ok {
+			materials[mtl.Name] = mat
+			continue
+		}
+
+		var mat = NewMaterial(key)
+		mat.Params["ambient"] = mgl.Vec3(mtl.Ambient)
+		mat.Params["diffuse"] = mgl.Vec3(mtl.Diffuse)
+		mat.Params["specular"] = mgl.Vec3(mtl.Specular)
+		mat.Params["emissive"] = mgl.Vec3(mtl.Emissive)
+		mat.Params["shininess"] = mtl.Shininess
+		mat.Params["opacity"] = mtl.Opacity
+
+		// Colour maps are sRGB; the others hold linear data.
+		for _, m := range []struct {
+			file, sampler string
+			srgb          bool
+		}{
+			{mtl.DiffuseMap, "diffuseMap", true},
+			{mtl.SpecularMap, "specularMap", false},
+			{mtl.NormalMap, "normalMap", false},
+			{mtl.BumpMap, "bumpMap", false},
+			{mtl.EmissiveMap, "emissiveMap", true},
+			{mtl.OpacityMap, "opacityMap", false},
+		} {
+			if m.file == "" {
+				continue
+			}
+
+			var tex, err = am.loadTextureFile(filepath.Join(filepath.Dir(path), m.file), m.srgb)
+			if err != nil {
+				return err
+			}
+			mat.Params[m.sampler] = int32(len(mat.Textures))
+			mat.AddTextures(tex)
+			mat.AddSamplers(m.sampler)
+		}
+
+		am.AddMaterial(mat)
+		materials[mtl.Name] = mat
+	}
+
+	return nil
+}
+
+// loadTextureFile loads a Texture from a path relative to the working
+// directory, as sRGB if 'srgb' is set. Textures within "assets/textures" are
+// named as by LoadTexture; others are named by their path. sRGB Textures have
+// ':srgb' appended to their names.
+func (am *Manager) loadTextureFile(path string, srgb bool) (*Texture, error) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if rel, err := filepath.Rel("assets/textures", path); err == nil && !strings.HasPrefix(rel, "..") {
+		return am.loadTextureAs(filepath.ToSlash(rel), srgb)
+	}
+
+	var key = path
+	if srgb {
+		key += ":srgb"
+	}
+	if tex, ok := am.GetTexture(key); ok {
+		return tex, nil
+	}
+
+	var tex, err = loadTexture(key, path, srgb)
+	if err != nil {
+		return nil, err
+	}
+	am.AddTexture(tex)
+
+	return tex, nil
+}
@@ -0,0 +1,120 @@
+package asset
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Ostsol/engine/meshfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Optimize rearranges the data of a triangle mesh to draw faster, as by
+// meshfmt.Mesh.Optimize. Vertices are welded where every one of their
+// attributes is identical, and the elements become a list of triangles of the
+// smallest type that can index every vertex. Triangles are reordered only
+// within each Submesh. Instance arrays are unchanged.
+func (d *MeshData) Optimize(overdraw bool) error {
+	var pos, tris, err = d.triangleGeometry("")
+	if err != nil {
+		return fmt.Errorf("asset.MeshData.Optimize error: %v", err)
+	}
+
+	// A vertex's key is its bytes in every array, in order of name.
+	var names = make([]string, 0, len(d.Attribs))
+	for name := range d.Attribs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var weldFrom, remap = meshfmt.Weld(d.Vertices, func(v int) string {
+		var key []byte
+		for _, name := range names {
+			var a = d.Attribs[name]
+			var size = attribSize(a.Type, a.Dims)
+			key = append(key, a.Data[v*size:(v+1)*size]...)
+		}
+		for _, b := range d.Buffers {
+			key = append(key, b.Data[v*b.Layout.Stride:(v+1)*b.Layout.Stride]...)
+		}
+		return string(key)
+	})
+
+	var welded = make([]float32, 0, 3*len(weldFrom))
+	for _, v := range weldFrom {
+		welded = append(welded, pos[3*v:3*v+3]...)
+	}
+	for i, v := range tris {
+		tris[i] = remap[v]
+	}
+
+	// Triangles are only reordered within each Submesh.
+	var parts [][]uint32
+	if parts, err = d.splitSubmeshes(tris); err != nil {
+		return fmt.Errorf("asset.MeshData.Optimize error: %v", err)
+	}
+	for i, part := range parts {
+		part = meshfmt.RemoveDegenerate(part, welded)
+		part = meshfmt.OptimizeVertexCache(part, len(weldFrom))
+		if overdraw {
+			part = meshfmt.OptimizeOverdraw(part, welded, 1.05)
+		}
+		parts[i] = part
+	}
+	tris = d.joinSubmeshes(parts)
+
+	var fetchFrom, indices = meshfmt.OptimizeVertexFetch(tris, len(weldFrom))
+	var from = make([]uint32, len(fetchFrom))
+	for i, v := range fetchFrom {
+		from[i] = weldFrom[v]
+	}
+
+	d.remap(from, indices)
+	if d.Elements != nil && d.Elements.Type != indexType(d.Vertices) {
+		d.setElements(d.Indices(), indexType(d.Vertices))
+	}
+
+	return nil
+}
+
+// splitSubmeshes divides the triangles of the data, as returned by
+// triangleGeometry, into those of each of its Submeshes, or returns them whole
+// if it has none. Each Submesh must be a range of whole triangles of a list.
+func (d *MeshData) splitSubmeshes(tris []uint32) ([][]uint32, error) {
+	if len(d.Submeshes) == 0 {
+		return [][]uint32{tris}, nil
+	}
+	if d.Primitive != gl.TRIANGLES {
+		return nil, fmt.Errorf("Submeshes of primitive 0x%x are not lists of triangles", d.Primitive)
+	}
+
+	var parts = make([][]uint32, len(d.Submeshes))
+	for i, s := range d.Submeshes {
+		if s.First < 0 || s.First%3 != 0 || s.Count%3 != 0 || s.First+s.Count > len(tris) {
+			return nil, fmt.Errorf("Submesh %d is not a range of whole triangles", i)
+		}
+		parts[i] = append([]uint32(nil), tris[s.First:s.First+s.Count]...)
+	}
+	return parts, nil
+}
+
+// joinSubmeshes concatenates the triangles of each Submesh, as divided by
+// splitSubmeshes, setting the Submeshes' ranges to match.
+func (d *MeshData) joinSubmeshes(parts [][]uint32) []uint32 {
+	var tris []uint32
+	for i, part := range parts {
+		if i < len(d.Submeshes) {
+			d.Submeshes[i].First = len(tris)
+			d.Submeshes[i].Count = len(part)
+		}
+		tris = append(tris, part...)
+	}
+	return tris
+}
+
+// Optimize rearranges the Mesh to draw faster, as by MeshData.Optimize,
+// recreating its arrays. See GenerateNormals.
+func (m *Mesh) Optimize(overdraw bool) error {
+	return m.generate("", func(d *MeshData) error {
+		return d.Optimize(overdraw)
+	})
+}
@@ -0,0 +1,445 @@
+package asset
+
+import (
+	"math"
+
+	"github.com/Ostsol/engine/meshfmt"
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// The primitive generators below create uninitialized 3D Meshes of triangles
+// centred on the origin, with Y up. Each has "pos", "normal", "tangent" and
+// "texcoord0" attributes. Texture coordinates place V = 0 at the top of an
+// image, as do the model loaders, and tangents point along increasing U with
+// the bitangent sign in W. Subdivision counts below their minimum are raised
+// to it.
+
+// primBuilder accumulates the vertices and triangles of a primitive.
+type primBuilder struct {
+	mesh meshfmt.Mesh
+}
+
+// vertex adds a vertex and returns its index.
+func (b *primBuilder) vertex(p, n mgl.Vec3, uv mgl.Vec2) uint32 {
+	var m = &b.mesh
+	m.Positions = append(m.Positions, p[:]...)
+	m.Normals = append(m.Normals, n[:]...)
+	m.TexCoords = append(m.TexCoords, uv[:]...)
+	return uint32(m.Vertices() - 1)
+}
+
+func (b *primBuilder) pos(i uint32) mgl.Vec3 {
+	return mgl.Vec3{b.mesh.Positions[3*i], b.mesh.Positions[3*i+1], b.mesh.Positions[3*i+2]}
+}
+
+func (b *primBuilder) normal(i uint32) mgl.Vec3 {
+	return mgl.Vec3{b.mesh.Normals[3*i], b.mesh.Normals[3*i+1], b.mesh.Normals[3*i+2]}
+}
+
+// triangle adds a triangle, winding it counter-clockwise when seen from the
+// side its vertex normals face. Degenerate triangles, such as those at the
+// poles of a sphere, are dropped.
+func (b *primBuilder) triangle(i0, i1, i2 uint32) {
+	var (
+		p0     = b.pos(i0)
+		e1, e2 = b.pos(i1).Sub(p0), b.pos(i2).Sub(p0)
+		cross  = e1.Cross(e2)
+	)
+	if cross.LenSqr() <= 1e-12*e1.LenSqr()*e2.LenSqr() {
+		return
+	}
+
+	if cross.Dot(b.normal(i0).Add(b.normal(i1)).Add(b.normal(i2))) < 0 {
+		i1, i2 = i2, i1
+	}
+	b.mesh.Indices = append(b.mesh.Indices, i0, i1, i2)
+}
+
+// grid adds a grid of (cols+1) by (rows+1) vertices given by 'fn', joined by
+// two triangles per cell.
+func (b *primBuilder) grid(cols, rows int, fn func(i, j int) (p, n mgl.Vec3, uv mgl.Vec2)) {
+	var base = uint32(b.mesh.Vertices())
+	for j := 0; j <= rows; j++ {
+		for i := 0; i <= cols; i++ {
+			b.vertex(fn(i, j))
+		}
+	}
+
+	var at = func(i, j int) uint32 {
+		return base + uint32(j*(cols+1)+i)
+	}
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			b.triangle(at(i, j), at(i, j+1), at(i+1, j+1))
+			b.triangle(at(i, j), at(i+1, j+1), at(i+1, j))
+		}
+	}
+}
+
+// profilePoint is a point of a profile revolved about the Y axis, with its
+// distance from the axis, height, the radial and vertical components of its
+// normal, and its V texture coordinate.
+type profilePoint struct {
+	r, y, nr, ny, v float32
+}
+
+// lathe adds the surface made by revolving a profile about the Y axis in
+// 'segments' steps. U runs once around the axis, unless 'planar' is non-zero,
+// in which case UVs are projected from above onto a square of that radius.
+func (b *primBuilder) lathe(profile []profilePoint, segments int, planar float32) {
+	b.grid(segments, len(profile)-1, func(i, j int) (mgl.Vec3, mgl.Vec3, mgl.Vec2) {
+		var (
+			u        = float32(i) / float32(segments)
+			theta    = 2 * math.Pi * float64(u)
+			sin, cos = math.Sincos(theta)
+			pt       = profile[j]
+			s, c     = float32(sin), float32(cos)
+			p        = mgl.Vec3{pt.r * c, pt.y, -pt.r * s}
+			n        = mgl.Vec3{pt.nr * c, pt.ny, -pt.nr * s}
+			uv       = mgl.Vec2{u, pt.v}
+		)
+		if planar != 0 {
+			uv = mgl.Vec2{0.5 + p[0]/(2*planar), 0.5 + p[2]/(2*planar)}
+		}
+		return p, n, uv
+	})
+}
+
+// disc adds a flat disc of the given radius at height 'y', facing up or down.
+func (b *primBuilder) disc(radius, y float32, up bool, segments, rings int) {
+	var (
+		profile = make([]profilePoint, rings+1)
+		ny      float32
+	)
+	if ny = -1; up {
+		ny = 1
+	}
+	for k := range profile {
+		profile[k] = profilePoint{r: radius * float32(k) / float32(rings), y: y, ny: ny}
+	}
+	b.lathe(profile, segments, radius)
+}
+
+// build creates the Mesh, computing tangents and using 16-bit indices where
+// they suffice.
+func (b *primBuilder) build(name string) (*Mesh, error) {
+	var m = &b.mesh
+	m.ComputeTangents()
+
+	var elems interface{} = m.Indices
+	if m.Vertices() <= math.MaxUint16 {
+		var short = make([]uint16, len(m.Indices))
+		for i, v := range m.Indices {
+			short[i] = uint16(v)
+		}
+		elems = short
+	}
+
+	var mesh, err = MakeMesh(name, 3, gl.TRIANGLES, m.Positions, nil, m.Normals, []interface{}{m.TexCoords}, elems)
+	if err != nil {
+		return nil, err
+	}
+
+	var tangents *AttribArray
+	if tangents, err = NewAttribArray("tangent", 4, m.Tangents, gl.STATIC_DRAW); err == nil {
+		err = mesh.AddArrays(tangents)
+	}
+	if err != nil {
+		tangents.Clean()
+		mesh.Clean()
+		return nil, err
+	}
+
+	return mesh, nil
+}
+
+// NewPlane creates a flat grid in the XZ plane facing +Y, divided into
+// 'xSegments' by 'zSegments' cells. V increases towards +Z.
+func NewPlane(name string, width, depth float32, xSegments, zSegments int) (*Mesh, error) {
+	xSegments, zSegments = max(xSegments, 1), max(zSegments, 1)
+
+	var b primBuilder
+	b.grid(xSegments, zSegments, func(i, j int) (mgl.Vec3, mgl.Vec3, mgl.Vec2) {
+		var u, v = float32(i) / float32(xSegments), float32(j) / float32(zSegments)
+		return mgl.Vec3{(u - 0.5) * width, 0, (v - 0.5) * depth}, mgl.Vec3{0, 1, 0}, mgl.Vec2{u, v}
+	})
+	return b.build(name)
+}
+
+// NewDisc creates a flat disc in the XZ plane facing +Y, divided into
+// 'segments' around its rim and 'rings' from its centre. UVs are projected
+// from above, as for NewPlane.
+func NewDisc(name string, radius float32, segments, rings int) (*Mesh, error) {
+	var b primBuilder
+	b.disc(radius, 0, true, max(segments, 3), max(rings, 1))
+	return b.build(name)
+}
+
+// NewCube creates a cube with sides of length 'size', each divided into
+// 'segments' by 'segments' cells. Each face is textured with the whole of an
+// image, upright when seen from outside with +Y up, or with -Z up for the top
+// and bottom faces.
+func NewCube(name string, size float32, segments int) (*Mesh, error) {
+	segments = max(segments, 1)
+
+	var b primBuilder
+	for _, f := range []struct{ n, u, v mgl.Vec3 }{
+		{mgl.Vec3{1, 0, 0}, mgl.Vec3{0, 0, -1}, mgl.Vec3{0, -1, 0}},
+		{mgl.Vec3{-1, 0, 0}, mgl.Vec3{0, 0, 1}, mgl.Vec3{0, -1, 0}},
+		{mgl.Vec3{0, 1, 0}, mgl.Vec3{1, 0, 0}, mgl.Vec3{0, 0, 1}},
+		{mgl.Vec3{0, -1, 0}, mgl.Vec3{1, 0, 0}, mgl.Vec3{0, 0, -1}},
+		{mgl.Vec3{0, 0, 1}, mgl.Vec3{1, 0, 0}, mgl.Vec3{0, -1, 0}},
+		{mgl.Vec3{0, 0, -1}, mgl.Vec3{-1, 0, 0}, mgl.Vec3{0, -1, 0}},
+	} {
+		b.grid(segments, segments, func(i, j int) (mgl.Vec3, mgl.Vec3, mgl.Vec2) {
+			var (
+				u, v = float32(i) / float32(segments), float32(j) / float32(segments)
+				p    = f.n.Add(f.u.Mul(2*u - 1)).Add(f.v.Mul(2*v - 1)).Mul(size / 2)
+			)
+			return p, f.n, mgl.Vec2{u, v}
+		})
+	}
+	return b.build(name)
+}
+
+// NewUVSphere creates a sphere divided into 'segments' around its equator and
+// 'rings' from pole to pole. UVs are an equirectangular projection, with U
+// increasing eastwards from +X and V from the north pole.
+func NewUVSphere(name string, radius float32, segments, rings int) (*Mesh, error) {
+	segments, rings = max(segments, 3), max(rings, 2)
+
+	var profile = make([]profilePoint, rings+1)
+	for k := range profile {
+		var (
+			v        = float32(k) / float32(rings)
+			sin, cos = math.Sincos(math.Pi/2 - math.Pi*float64(v))
+		)
+		profile[k] = profilePoint{radius * float32(cos), radius * float32(sin), float32(cos), float32(sin), v}
+	}
+
+	var b primBuilder
+	b.lathe(profile, segments, 0)
+	return b.build(name)
+}
+
+// NewIcosphere creates a sphere by subdividing an icosahedron 'subdivisions'
+// times, giving triangles of nearly equal size. UVs are projected as for
+// NewUVSphere, with vertices duplicated along the seam and at the poles.
+func NewIcosphere(name string, radius float32, subdivisions int) (*Mesh, error) {
+	var (
+		t     = float32((1 + math.Sqrt(5)) / 2)
+		verts = []mgl.Vec3{
+			{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+			{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+			{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+		}
+		tris = [][3]int{
+			{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+			{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+			{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+			{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+		}
+	)
+	for i := range verts {
+		verts[i] = verts[i].Normalize()
+	}
+
+	for s := 0; s < subdivisions; s++ {
+		var (
+			mids = make(map[[2]int]int)
+			next = make([][3]int, 0, 4*len(tris))
+		)
+		var mid = func(a, b int) int {
+			if a > b {
+				a, b = b, a
+			}
+			if m, ok := mids[[2]int{a, b}]; ok {
+				return m
+			}
+			verts = append(verts, verts[a].Add(verts[b]).Normalize())
+			mids[[2]int{a, b}] = len(verts) - 1
+			return len(verts) - 1
+		}
+		for _, tri := range tris {
+			var ab, bc, ca = mid(tri[0], tri[1]), mid(tri[1], tri[2]), mid(tri[2], tri[0])
+			next = append(next,
+				[3]int{tri[0], ab, ca}, [3]int{tri[1], bc, ab},
+				[3]int{tri[2], ca, bc}, [3]int{ab, bc, ca})
+		}
+		tris = next
+	}
+
+	var (
+		b      primBuilder
+		welded = make(map[struct {
+			v int
+			u float32
+		}]uint32)
+	)
+	var corner = func(v int, u float32) uint32 {
+		var key = struct {
+			v int
+			u float32
+		}{v, u}
+		if i, ok := welded[key]; ok {
+			return i
+		}
+		var (
+			n  = verts[v]
+			vv = float32(math.Acos(float64(mgl.Clamp(n[1], -1, 1))) / math.Pi)
+			i  = b.vertex(n.Mul(radius), n, mgl.Vec2{u, vv})
+		)
+		welded[key] = i
+		return i
+	}
+
+	for _, tri := range tris {
+		var (
+			us    [3]float32
+			poles []int
+		)
+		for c, v := range tri {
+			var n = verts[v]
+			if math.Abs(float64(n[1])) > 1-1e-6 {
+				poles = append(poles, c)
+				continue
+			}
+			var u = float32(math.Atan2(float64(-n[2]), float64(n[0])) / (2 * math.Pi))
+			if u < 0 {
+				u++
+			}
+			us[c] = u
+		}
+
+		// Corners on the far side of the seam wrap past U = 1, and poles take
+		// the mean U of the triangle's other corners.
+		var lo, hi float32 = 1, 0
+		for c := range us {
+			if len(poles) == 0 || poles[0] != c {
+				lo, hi = min(lo, us[c]), max(hi, us[c])
+			}
+		}
+		if hi-lo > 0.5 {
+			for c := range us {
+				if us[c] < 0.5 {
+					us[c]++
+				}
+			}
+		}
+		for _, p := range poles {
+			var sum float32
+			for c := range us {
+				if c != p {
+					sum += us[c]
+				}
+			}
+			us[p] = sum / 2
+		}
+
+		b.triangle(corner(tri[0], us[0]), corner(tri[1], us[1]), corner(tri[2], us[2]))
+	}
+
+	return b.build(name)
+}
+
+// frustum adds the side of a truncated cone between radii 'top' and 'bottom',
+// divided into 'segments' around and 'stacks' from top to bottom, with
+// optional caps.
+func (b *primBuilder) frustum(top, bottom, height float32, segments, stacks int, capTop, capBottom bool) {
+	var (
+		profile = make([]profilePoint, stacks+1)
+		l       = float32(math.Hypot(float64(height), float64(bottom-top)))
+		nr, ny  = height / l, (bottom - top) / l
+	)
+	for k := range profile {
+		var v = float32(k) / float32(stacks)
+		profile[k] = profilePoint{top + (bottom-top)*v, height/2 - height*v, nr, ny, v}
+	}
+	b.lathe(profile, segments, 0)
+
+	if capTop {
+		b.disc(top, height/2, true, segments, 1)
+	}
+	if capBottom {
+		b.disc(bottom, -height/2, false, segments, 1)
+	}
+}
+
+// NewCylinder creates a capped cylinder divided into 'segments' around and
+// 'stacks' along its height. The side is textured with U running around it and
+// V from top to bottom; the caps are projected from above.
+func NewCylinder(name string, radius, height float32, segments, stacks int) (*Mesh, error) {
+	var b primBuilder
+	b.frustum(radius, radius, height, max(segments, 3), max(stacks, 1), true, true)
+	return b.build(name)
+}
+
+// NewCone creates a cone with its apex up and a capped base, divided into
+// 'segments' around and 'stacks' along its height, textured as for
+// NewCylinder.
+func NewCone(name string, radius, height float32, segments, stacks int) (*Mesh, error) {
+	var b primBuilder
+	b.frustum(0, radius, height, max(segments, 3), max(stacks, 1), false, true)
+	return b.build(name)
+}
+
+// NewCapsule creates a cylinder of the given total height capped with
+// hemispheres, divided into 'segments' around and 'rings' in each hemisphere.
+// Heights less than the diameter give a sphere. V runs from top to bottom in
+// proportion to the length of the surface.
+func NewCapsule(name string, radius, height float32, segments, rings int) (*Mesh, error) {
+	segments, rings = max(segments, 3), max(rings, 1)
+
+	var (
+		half    = max(height/2-radius, 0)
+		total   = math.Pi*radius + 2*half
+		profile []profilePoint
+	)
+	for k := 0; k <= rings; k++ {
+		var (
+			a        = math.Pi / 2 * (1 - float64(k)/float64(rings))
+			sin, cos = math.Sincos(a)
+		)
+		profile = append(profile, profilePoint{radius * float32(cos), half + radius*float32(sin), float32(cos), float32(sin), 0})
+	}
+	for k := 0; k <= rings; k++ {
+		var (
+			a        = -math.Pi / 2 * float64(k) / float64(rings)
+			sin, cos = math.Sincos(a)
+		)
+		profile = append(profile, profilePoint{radius * float32(cos), -half + radius*float32(sin), float32(cos), float32(sin), 0})
+	}
+
+	// V follows the length along the profile.
+	var length float32
+	for k := 1; k < len(profile); k++ {
+		length += float32(math.Hypot(float64(profile[k].r-profile[k-1].r), float64(profile[k].y-profile[k-1].y)))
+		profile[k].v = length / total
+	}
+
+	var b primBuilder
+	b.lathe(profile, segments, 0)
+	return b.build(name)
+}
+
+// NewTorus creates a torus about the Y axis, with the given distance from its
+// centre to the centre of its tube and radius of its tube, divided into
+// 'segments' around the axis and 'sides' around the tube. V runs around the
+// tube, starting down its outer edge.
+func NewTorus(name string, radius, tube float32, segments, sides int) (*Mesh, error) {
+	segments, sides = max(segments, 3), max(sides, 3)
+
+	var profile = make([]profilePoint, sides+1)
+	for k := range profile {
+		var (
+			v        = float32(k) / float32(sides)
+			sin, cos = math.Sincos(2 * math.Pi * float64(v))
+		)
+		profile[k] = profilePoint{radius + tube*float32(cos), -tube * float32(sin), float32(cos), -float32(sin), v}
+	}
+
+	var b primBuilder
+	b.lathe(profile, segments, 0)
+	return b.build(name)
+}
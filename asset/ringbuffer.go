@@ -0,0 +1,275 @@
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// RingBuffer is a persistently mapped buffer for data rewritten every frame.
+// It is divided into a region for each frame in flight: each frame's data is
+// written into the current region while the GPU reads those of earlier
+// frames. Each region is guarded by a fence and is only reused once the GPU
+// has finished reading from it, so writing never stalls on draws in progress
+// and the buffer never needs orphaning.
+type RingBuffer struct {
+	Buf     uint32        // the OpenGL buffer handle
+	Size    int           // the size of each region in bytes
+	Timeout time.Duration // maximum time to wait for a region to be released
+
+	mem    []byte    // the mapped memory of every region
+	fences []uintptr // fence guarding each region, or 0
+	cur    int       // index of the current region
+	head   int       // bytes written to the current region
+}
+
+// NewRingBuffer creates a RingBuffer of 'regions' regions of 'size' bytes.
+// Two or three regions are usually sufficient.
+func NewRingBuffer(size, regions int) (*RingBuffer, error) {
+	if size <= 0 {
+		return nil, errors.New("asset.NewRingBuffer error: size is zero")
+	}
+	if regions < 1 {
+		regions = 1
+	}
+
+	var rb = &RingBuffer{
+		Size:    size,
+		Timeout: time.Second,
+		fences:  make([]uintptr, regions),
+	}
+
+	const flags = gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT
+	gl.CreateBuffers(1, &rb.Buf)
+	gl.NamedBufferStorage(rb.Buf, size*regions, nil, flags)
+	var ptr = gl.MapNamedBufferRange(rb.Buf, 0, size*regions, flags)
+	if ptr == nil {
+		gl.DeleteBuffers(1, &rb.Buf)
+		return nil, errors.New("asset.NewRingBuffer error: could not map buffer")
+	}
+	rb.mem = unsafe.Slice((*byte)(ptr), size*regions)
+
+	return rb, nil
+}
+
+// Write copies data into the current region, after any written earlier in the
+// frame, starting at a multiple of 'align' bytes. It returns the offset of the
+// data from the start of the buffer. An error is returned if the region has no
+// room left.
+func (rb *RingBuffer) Write(data []byte, align int) (int, error) {
+	var head = rb.head
+	if align > 1 {
+		head = (head + align - 1) / align * align
+	}
+	if head+len(data) > rb.Size {
+		return 0, fmt.Errorf("asset.RingBuffer.Write error: %d bytes do not fit in %d left of region", len(data), rb.Size-head)
+	}
+
+	var offset = rb.cur*rb.Size + head
+	copy(rb.mem[offset:], data)
+	rb.head = head + len(data)
+
+	return offset, nil
+}
+
+// Advance ends the frame, fencing the current region, and waits until the GPU
+// has released the next one, which becomes current.
+func (rb *RingBuffer) Advance() error {
+	rb.fences[rb.cur] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	rb.cur = (rb.cur + 1) % len(rb.fences)
+	rb.head = 0
+	return rb.wait(rb.cur)
+}
+
+// wait blocks until the fence guarding region 'i' has been signalled and then
+// deletes the fence.
+func (rb *RingBuffer) wait(i int) error {
+	if rb.fences[i] == 0 {
+		return nil
+	}
+
+	var deadline = time.Now().Add(rb.Timeout)
+	for {
+		switch gl.ClientWaitSync(rb.fences[i], gl.SYNC_FLUSH_COMMANDS_BIT, uint64(time.Millisecond)) {
+		case gl.ALREADY_SIGNALED, gl.CONDITION_SATISFIED:
+			gl.DeleteSync(rb.fences[i])
+			rb.fences[i] = 0
+			return nil
+		case gl.WAIT_FAILED:
+			return errors.New("asset.RingBuffer.wait error: fence wait failed")
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("asset.RingBuffer.wait error: timed out waiting for region")
+		}
+	}
+}
+
+// Clean unmaps and deletes the buffer and its fences.
+func (rb *RingBuffer) Clean() {
+	if rb == nil || rb.Buf == 0 {
+		return
+	}
+	for i, fence := range rb.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			rb.fences[i] = 0
+		}
+	}
+	gl.UnmapNamedBuffer(rb.Buf)
+	gl.DeleteBuffers(1, &rb.Buf)
+	rb.Buf = 0
+	rb.mem = nil
+}
+
+// NewStreamAttribArray creates an AttribArray of elements of type 'typ' for
+// data rewritten every frame, such as particles, in a RingBuffer of 'frames'
+// regions each holding 'capacity' elements. Its data is written by Stream and
+// the frame ended by Advance; it cannot be updated or refilled. Streamed
+// arrays hold vertices: instance arrays should be refilled instead, as draws
+// cannot start at a later instance.
+func NewStreamAttribArray(name string, dims int, typ uint32, capacity, frames int) (*AttribArray, error) {
+	if isPacked(typ) && dims != 4 {
+		return nil, fmt.Errorf("AttribArray error: packed '%s' must have 4 dimensions", name)
+	}
+	if capacity == 0 || capacity%dims != 0 {
+		return nil, AttribLenError(name, capacity, dims)
+	}
+
+	var ring, err = NewRingBuffer(dataSize(typ, capacity), frames)
+	if err != nil {
+		return nil, fmt.Errorf("AttribArray error: '%s': %v", name, err)
+	}
+
+	return &AttribArray{
+		Name:   name,
+		Dims:   dims,
+		Type:   typ,
+		Buf:    ring.Buf,
+		Cap:    capacity,
+		Usage:  gl.STREAM_DRAW,
+		Bounds: EmptyBounds,
+		ring:   ring,
+	}, nil
+}
+
+// Stream writes data into the current frame of a streamed AttribArray, which
+// then holds only that data. It returns the index of the first attribute
+// written, to be passed to DrawRange as the base vertex, or as the first
+// vertex of Meshes without an ElementArray. The data must be of the array's
+// type and fit within what is left of the frame.
+func (arr *AttribArray) Stream(data interface{}) (int, error) {
+	if arr.ring == nil {
+		return 0, fmt.Errorf("asset.AttribArray.Stream error: '%s' is not streamed", arr.Name)
+	}
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return 0, fmt.Errorf("asset.AttribArray.Stream error: '%s': %v", arr.Name, err)
+	}
+	if l > 0 && arr.Type != typ {
+		return 0, fmt.Errorf("asset.AttribArray.Stream error: '%s' data type does not match array type", arr.Name)
+	}
+	if l%arr.Dims != 0 {
+		return 0, AttribLenError(arr.Name, l, arr.Dims)
+	}
+
+	var size = attribSize(arr.Type, arr.Dims)
+	var offset int
+	if offset, err = arr.ring.Write(raw, size); err != nil {
+		return 0, fmt.Errorf("asset.AttribArray.Stream error: '%s': %v", arr.Name, err)
+	}
+	arr.Len = l
+	arr.setBounds(raw)
+	arr.retain(0, raw, true)
+
+	return offset / size, nil
+}
+
+// Advance ends the frame of a streamed AttribArray. See RingBuffer.Advance.
+func (arr *AttribArray) Advance() error {
+	if arr.ring == nil {
+		return nil
+	}
+	return arr.ring.Advance()
+}
+
+// NewStreamElementArray creates an ElementArray of indices of type 'typ' for
+// data rewritten every frame, in a RingBuffer of 'frames' regions each holding
+// 'capacity' indices. See NewStreamAttribArray.
+func NewStreamElementArray(typ uint32, capacity, frames int) (*ElementArray, error) {
+	switch typ {
+	case gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT, gl.UNSIGNED_INT:
+	default:
+		return nil, fmt.Errorf("asset.NewStreamElementArray error: unhandled index type 0x%x", typ)
+	}
+	if capacity <= 0 {
+		return nil, errors.New("asset.NewStreamElementArray error: capacity is zero")
+	}
+
+	var ring, err = NewRingBuffer(dataSize(typ, capacity), frames)
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewStreamElementArray error: %v", err)
+	}
+
+	return &ElementArray{
+		Type:  typ,
+		Buf:   ring.Buf,
+		Cap:   capacity,
+		Usage: gl.STREAM_DRAW,
+		ring:  ring,
+	}, nil
+}
+
+// Stream writes indices into the current frame of a streamed ElementArray,
+// which then holds only those indices. It returns the index of the first one
+// written, to be passed to DrawRange as the first element. See
+// AttribArray.Stream.
+func (arr *ElementArray) Stream(data interface{}) (int, error) {
+	if arr.ring == nil {
+		return 0, errors.New("asset.ElementArray.Stream error: array is not streamed")
+	}
+	var raw, typ, l, err = indexBytes(data)
+	if err != nil {
+		return 0, fmt.Errorf("asset.ElementArray.Stream error: %v", err)
+	}
+	if l > 0 && arr.Type != typ {
+		return 0, errors.New("asset.ElementArray.Stream error: data type does not match array type")
+	}
+
+	var size = typeSize(arr.Type)
+	var offset int
+	if offset, err = arr.ring.Write(raw, size); err != nil {
+		return 0, fmt.Errorf("asset.ElementArray.Stream error: %v", err)
+	}
+	arr.Len = l
+	arr.retain(0, raw, true)
+
+	return offset / size, nil
+}
+
+// Advance ends the frame of a streamed ElementArray. See RingBuffer.Advance.
+func (arr *ElementArray) Advance() error {
+	if arr.ring == nil {
+		return nil
+	}
+	return arr.ring.Advance()
+}
+
+// Advance ends the frame of every streamed array of the Mesh, after the draws
+// that read them have been issued.
+func (m *Mesh) Advance() error {
+	var errs []error
+	for _, arr := range m.Attribs {
+		errs = append(errs, arr.Advance())
+	}
+	for _, arr := range m.Instanced {
+		errs = append(errs, arr.Advance())
+	}
+	if m.Elements != nil {
+		errs = append(errs, m.Elements.Advance())
+	}
+	return errors.Join(errs...)
+}
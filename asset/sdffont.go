@@ -0,0 +1,167 @@
+package asset
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/Ostsol/engine/sdf"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// SDFOptions controls the generation of distance field fonts and icons.
+type SDFOptions struct {
+	Size         float64 // pixels per em of a font, or the size of an icon
+	Range        float64 // width in pixels of the band of stored distances
+	MultiChannel bool    // generate a multi-channel field with sharp corners
+}
+
+// DefaultSDFOptions is used when LoadSDFFont or LoadIcon is given nil options.
+var DefaultSDFOptions = SDFOptions{
+	Size:         48,
+	Range:        6,
+	MultiChannel: true,
+}
+
+// NewFontFromTTFSDF creates a Font from a TrueType or OpenType file whose
+// glyphs are signed distance fields generated from their outlines, so that
+// text drawn with the "builtin/sdf" shaders stays sharp at any scale. Glyph
+// metrics are at 'opts.Size' pixels per em. Distance fields are stored in
+// alpha; multi-channel fields also store their channels in red, green and
+// blue. Glyphs are added to the atlas as by NewFontFromTTF.
+func NewFontFromTTFSDF(name string, data []byte, opts SDFOptions) (*Font, error) {
+	var otf, err = sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewFontFromTTFSDF error: '%s': %v", name, err)
+	}
+
+	var src = &sdfSource{
+		font:  otf,
+		ppem:  fixed.Int26_6(math.Round(opts.Size * 64)),
+		rng:   opts.Range,
+		multi: opts.MultiChannel,
+	}
+
+	metrics, err := otf.Metrics(&src.buf, src.ppem, font.HintingNone)
+	if err != nil {
+		return nil, fmt.Errorf("asset.NewFontFromTTFSDF error: '%s': %v", name, err)
+	}
+
+	f, err := newFont(name, image.NewRGBA(image.Rect(0, 0, 512, 512)), 0, src)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Size = float32(opts.Size)
+	f.LineHeight = fixedToFloat(metrics.Height)
+	f.Ascent = fixedToFloat(metrics.Ascent)
+	f.DistanceRange = float32(opts.Range)
+	f.MultiChannel = opts.MultiChannel
+
+	if err = f.Preload(asciiPrintable); err != nil {
+		f.Clean()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// sdfSource generates distance fields of glyph outlines.
+type sdfSource struct {
+	font  *sfnt.Font
+	buf   sfnt.Buffer
+	ppem  fixed.Int26_6
+	rng   float64
+	multi bool
+}
+
+func (s *sdfSource) glyph(r rune) (*image.RGBA, image.Point, float32, bool) {
+	var idx, err = s.font.GlyphIndex(&s.buf, r)
+	if err != nil || idx == 0 {
+		return nil, image.Point{}, 0, false
+	}
+
+	advance, err := s.font.GlyphAdvance(&s.buf, idx, s.ppem, font.HintingNone)
+	if err != nil {
+		return nil, image.Point{}, 0, false
+	}
+
+	segs, err := s.font.LoadGlyph(&s.buf, idx, s.ppem, nil)
+	if err != nil {
+		return nil, image.Point{}, 0, false
+	}
+	if len(segs) == 0 {
+		return &image.RGBA{}, image.Point{}, fixedToFloat(advance), true
+	}
+
+	var img, offset = glyphSDF(sdf.FromSegments(segs), s.rng, s.multi)
+
+	return img, offset, fixedToFloat(advance), true
+}
+
+func (s *sdfSource) kern(a, b rune) float32 {
+	var (
+		i0, err0 = s.font.GlyphIndex(&s.buf, a)
+		i1, err1 = s.font.GlyphIndex(&s.buf, b)
+	)
+	if err0 != nil || err1 != nil {
+		return 0
+	}
+
+	var k, err = s.font.Kern(&s.buf, i0, i1, s.ppem, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return fixedToFloat(k)
+}
+
+func (s *sdfSource) close() {}
+
+// glyphSDF renders a distance field of a glyph outline into an image large
+// enough to hold the outline and the band of distances around it. It returns
+// the image and the position of its top-left corner relative to the pen.
+func glyphSDF(shape *sdf.Shape, pxRange float64, multi bool) (*image.RGBA, image.Point) {
+	var (
+		b   = shape.Bounds()
+		pad = math.Ceil(pxRange/2) + 1
+		x0  = math.Floor(b.Min.X) - pad
+		y0  = math.Floor(b.Min.Y) - pad
+		w   = int(math.Ceil(b.Max.X) + pad - x0)
+		h   = int(math.Ceil(b.Max.Y) + pad - y0)
+		tr  = sdf.Transform{Scale: 1, Translate: sdf.Point{X: -x0, Y: -y0}}
+	)
+
+	return sdfImage(shape, w, h, tr, pxRange, multi), image.Pt(int(x0), int(y0))
+}
+
+// sdfImage renders a distance field of a shape. Single-channel fields are
+// stored as the alpha of white pixels, like rasterized glyphs.
+func sdfImage(shape *sdf.Shape, w, h int, tr sdf.Transform, pxRange float64, multi bool) *image.RGBA {
+	if multi {
+		return shape.MSDF(w, h, tr, pxRange)
+	}
+
+	var (
+		gray = shape.SDF(w, h, tr, pxRange)
+		img  = image.NewRGBA(gray.Rect)
+	)
+	for i, v := range gray.Pix {
+		img.Pix[4*i], img.Pix[4*i+1], img.Pix[4*i+2], img.Pix[4*i+3] = 0xff, 0xff, 0xff, v
+	}
+
+	return img
+}
+
+// NewSDFTexture creates a Texture holding a distance field of 'shape', with
+// the area 'frame' of the shape fitted to a 'size' by 'size' image. Fields
+// are stored as by NewFontFromTTFSDF, and are drawn with the "builtin/sdf"
+// shaders.
+func NewSDFTexture(name string, shape *sdf.Shape, frame sdf.Rect, size int, pxRange float64, multi bool) (*Texture, error) {
+	if frame.Empty() {
+		return nil, fmt.Errorf("asset.NewSDFTexture error: '%s' has an empty frame", name)
+	}
+
+	return NewTextureFromImage(name, sdfImage(shape, size, size, sdf.Fit(frame, size, size, 0), pxRange, multi))
+}
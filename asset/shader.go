@@ -12,7 +12,6 @@ func newShader(file string, typ uint32) (uint32, error) {
 	var (
 		f      *os.File
 		err    error
-		s      uint32
 		buf    []uint8
 		//bufptr *uint8
 		//ln     int32
@@ -27,14 +26,15 @@ func newShader(file string, typ uint32) (uint32, error) {
 		return 0, err
 	}
 
-	buf = append(buf, 0)
+	return compileShader(file, string(buf), typ)
+}
 
-	var source, free = gl.Strs(string(buf))
-	//bufptr = &buf[0]
-	//ln = int32(len(buf))
+// compileShader compiles shader source code. 'name' is used in errors.
+func compileShader(name, source string, typ uint32) (uint32, error) {
+	var csource, free = gl.Strs(source + "\x00")
 
-	s = gl.CreateShader(typ)
-	gl.ShaderSource(s, 1, source, nil)
+	var s = gl.CreateShader(typ)
+	gl.ShaderSource(s, 1, csource, nil)
 	free()
 	gl.CompileShader(s)
 
@@ -42,7 +42,7 @@ func newShader(file string, typ uint32) (uint32, error) {
 	gl.GetShaderiv(s, gl.INFO_LOG_LENGTH, &infoLogLen)
 
 	if infoLogLen > 1 {
-		Logger.Printf("asset.newShader error: error compiling '%s'", file)
+		Logger.Printf("asset.newShader error: error compiling '%s'", name)
 		var log = make([]byte, infoLogLen)
 		gl.GetShaderInfoLog(s, infoLogLen, nil, &log[0])
 		return 0, errors.New(string(log))
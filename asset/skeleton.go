@@ -0,0 +1,233 @@
+package asset
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Transform is a translation, rotation and scale, applied to a point in
+// reverse order.
+type Transform struct {
+	Translation mgl.Vec3
+	Rotation    mgl.Quat
+	Scale       mgl.Vec3
+}
+
+// IdentityTransform is the Transform that changes nothing.
+var IdentityTransform = Transform{Rotation: mgl.QuatIdent(), Scale: mgl.Vec3{1, 1, 1}}
+
+// Mat4 returns the matrix of the Transform.
+func (t Transform) Mat4() mgl.Mat4 {
+	return mgl.Translate3D(t.Translation[0], t.Translation[1], t.Translation[2]).
+		Mul4(t.Rotation.Mat4()).
+		Mul4(mgl.Scale3D(t.Scale[0], t.Scale[1], t.Scale[2]))
+}
+
+// Lerp interpolates between two Transforms, spherically for the rotation.
+func (t Transform) Lerp(o Transform, w float32) Transform {
+	return Transform{
+		Translation: t.Translation.Add(o.Translation.Sub(t.Translation).Mul(w)),
+		Rotation:    mgl.QuatSlerp(t.Rotation, o.Rotation, w),
+		Scale:       t.Scale.Add(o.Scale.Sub(t.Scale).Mul(w)),
+	}
+}
+
+// decompose returns the Transform of a matrix without shear.
+func decompose(m mgl.Mat4) Transform {
+	var t = Transform{Translation: m.Col(3).Vec3()}
+	var rot mgl.Mat3
+	for c := 0; c < 3; c++ {
+		var col = m.Col(c).Vec3()
+		t.Scale[c] = col.Len()
+		if t.Scale[c] != 0 {
+			col = col.Mul(1 / t.Scale[c])
+		}
+		rot.SetCol(c, col)
+	}
+	// A reflection is kept as a negative scale.
+	if rot.Det() < 0 {
+		t.Scale[0] = -t.Scale[0]
+		rot.SetCol(0, rot.Col(0).Mul(-1))
+	}
+	t.Rotation = mgl.Mat4ToQuat(rot.Mat4()).Normalize()
+	return t
+}
+
+// Pose is the Transform of each joint of a Skeleton relative to its parent.
+type Pose []Transform
+
+// Blend moves each Transform of the Pose towards that of another by 'w', as
+// by Transform.Lerp, such as to cross-fade between animation clips.
+func (p Pose) Blend(o Pose, w float32) {
+	for j := range p {
+		if j < len(o) {
+			p[j] = p[j].Lerp(o[j], w)
+		}
+	}
+}
+
+// Joint is a bone of a Skeleton.
+type Joint struct {
+	Name   string
+	Parent int       // index of the parent joint, or -1
+	Rest   Transform // transform relative to the parent in the rest pose
+}
+
+// Skeleton is a hierarchy of joints that deform a skinned Mesh. Each vertex of
+// the Mesh names up to four joints in its "joints0" attribute, with weights in
+// "weights0", and is moved by the palette matrices of those joints.
+type Skeleton struct {
+	Name   string
+	Joints []Joint
+
+	// InverseBind are, for each joint, the inverse of its transform in model
+	// space when the Mesh was bound to the Skeleton.
+	InverseBind []mgl.Mat4
+
+	// Root is the transform of the space the root joints are in, such as that
+	// of their ancestors outside the Skeleton.
+	Root mgl.Mat4
+
+	order []int // joints with each parent before its children
+}
+
+// NewSkeleton creates a Skeleton from its joints and the inverse bind matrix
+// of each. An error is returned if the joints' parents do not form a
+// hierarchy.
+func NewSkeleton(name string, joints []Joint, inverseBind []mgl.Mat4) (*Skeleton, error) {
+	if len(inverseBind) != len(joints) {
+		return nil, fmt.Errorf("asset.NewSkeleton error: Skeleton '%s' has %d joints but %d inverse bind matrices", name, len(joints), len(inverseBind))
+	}
+
+	var s = &Skeleton{
+		Name:        name,
+		Joints:      joints,
+		InverseBind: inverseBind,
+		Root:        mgl.Ident4(),
+	}
+
+	// Joints are ordered by depth, which fails if a parent is missing or
+	// the parents form a loop.
+	var depth = make([]int, len(joints))
+	for j := range joints {
+		var d, p = 0, joints[j].Parent
+		for ; p >= 0 && d <= len(joints); p = joints[p].Parent {
+			if p >= len(joints) {
+				return nil, fmt.Errorf("asset.NewSkeleton error: Skeleton '%s' joint %d has no parent %d", name, j, p)
+			}
+			d++
+		}
+		if d > len(joints) {
+			return nil, fmt.Errorf("asset.NewSkeleton error: Skeleton '%s' joint %d is its own ancestor", name, j)
+		}
+		depth[j] = d
+	}
+	for d := 0; len(s.order) < len(joints); d++ {
+		for j := range joints {
+			if depth[j] == d {
+				s.order = append(s.order, j)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Joint returns the index of the joint named 'name'.
+func (s *Skeleton) Joint(name string) (int, bool) {
+	for j := range s.Joints {
+		if s.Joints[j].Name == name {
+			return j, true
+		}
+	}
+	return -1, false
+}
+
+// RestPose returns a new Pose holding the rest transform of each joint.
+func (s *Skeleton) RestPose() Pose {
+	var pose = make(Pose, len(s.Joints))
+	for j := range s.Joints {
+		pose[j] = s.Joints[j].Rest
+	}
+	return pose
+}
+
+// Transforms returns the model-space transform of each joint in a Pose,
+// reusing 'out' if it is large enough.
+func (s *Skeleton) Transforms(pose Pose, out []mgl.Mat4) []mgl.Mat4 {
+	if cap(out) < len(s.Joints) {
+		out = make([]mgl.Mat4, len(s.Joints))
+	}
+	out = out[:len(s.Joints)]
+
+	for _, j := range s.order {
+		var local = pose[j].Mat4()
+		if p := s.Joints[j].Parent; p >= 0 {
+			out[j] = out[p].Mul4(local)
+		} else {
+			out[j] = s.Root.Mul4(local)
+		}
+	}
+	return out
+}
+
+// Palette returns the skinning matrix of each joint in a Pose, which moves a
+// vertex from its bound position to its posed one, reusing 'out' if it is
+// large enough. The palette is uploaded to a JointPalette for drawing.
+func (s *Skeleton) Palette(pose Pose, out []mgl.Mat4) []mgl.Mat4 {
+	out = s.Transforms(pose, out)
+	for j := range out {
+		out[j] = out[j].Mul4(s.InverseBind[j])
+	}
+	return out
+}
+
+// JointPaletteBinding is the shader storage buffer binding at which skinning
+// shaders, such as "builtin/skinned.vert", read the joint palette.
+const JointPaletteBinding = 0
+
+// JointPalette is a shader storage buffer holding the skinning matrices of a
+// Skeleton, rewritten each frame as it is animated.
+type JointPalette struct {
+	Buf uint32 // the OpenGL buffer handle
+	Len int    // the number of matrices
+	Cap int    // the capacity of the buffer in matrices
+}
+
+// NewJointPalette creates a JointPalette with room for 'joints' matrices.
+func NewJointPalette(joints int) *JointPalette {
+	var p = &JointPalette{Cap: max(joints, 1)}
+	gl.CreateBuffers(1, &p.Buf)
+	gl.NamedBufferData(p.Buf, p.Cap*16*4, nil, gl.STREAM_DRAW)
+	return p
+}
+
+// Update replaces the matrices of the JointPalette, growing it if needed. The
+// old contents are orphaned, so that updating it each frame does not wait on
+// draws still reading them.
+func (p *JointPalette) Update(palette []mgl.Mat4) {
+	if len(palette) > p.Cap {
+		p.Cap = len(palette)
+	}
+	gl.NamedBufferData(p.Buf, p.Cap*16*4, nil, gl.STREAM_DRAW)
+	if len(palette) > 0 {
+		gl.NamedBufferSubData(p.Buf, 0, len(palette)*16*4, gl.Ptr(palette))
+	}
+	p.Len = len(palette)
+}
+
+// Bind binds the JointPalette at JointPaletteBinding for the following draws.
+func (p *JointPalette) Bind() {
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, JointPaletteBinding, p.Buf)
+}
+
+// Clean deletes the buffer.
+func (p *JointPalette) Clean() {
+	if p == nil {
+		return
+	}
+	gl.DeleteBuffers(1, &p.Buf)
+	p.Buf = 0
+}
@@ -0,0 +1,202 @@
+package asset
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/Ostsol/engine/meshfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// translation returns a Transform that only moves by (x, y, z).
+func translation(x, y, z float32) Transform {
+	var t = IdentityTransform
+	t.Translation = mgl.Vec3{x, y, z}
+	return t
+}
+
+// matNear reports whether each element of two matrices differs by at most
+// 'eps'.
+func matNear(a, b mgl.Mat4, eps float32) bool {
+	for i := range a {
+		if a[i]-b[i] > eps || b[i]-a[i] > eps {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDecompose(t *testing.T) {
+	var tests = []Transform{
+		{mgl.Vec3{1, 2, 3}, mgl.QuatRotate(0.7, mgl.Vec3{1, 2, 2}.Normalize()), mgl.Vec3{2, 0.5, 3}},
+		{mgl.Vec3{0, -1, 0}, mgl.QuatRotate(math.Pi/2, mgl.Vec3{0, 0, 1}), mgl.Vec3{-1, 1, 1}},
+	}
+	for _, tt := range tests {
+		var got = decompose(tt.Mat4())
+		if !matNear(got.Mat4(), tt.Mat4(), 1e-5) {
+			t.Errorf("%v decomposed to %v", tt, got)
+		}
+		if got.Scale[1] < 0 || got.Scale[2] < 0 {
+			t.Errorf("%v decomposed with scale %v, want only X negative", tt, got.Scale)
+		}
+	}
+}
+
+func TestNewSkeleton(t *testing.T) {
+	var ident = []mgl.Mat4{mgl.Ident4(), mgl.Ident4()}
+	for _, joints := range [][]Joint{
+		{{Parent: -1}, {Parent: 2}},
+		{{Parent: 1}, {Parent: 0}},
+		{{Parent: 0}, {Parent: -1}},
+	} {
+		if _, err := NewSkeleton("bad", joints, ident); err == nil {
+			t.Errorf("joints %v made a Skeleton", joints)
+		}
+	}
+	if _, err := NewSkeleton("bad", []Joint{{Parent: -1}}, ident); err == nil {
+		t.Error("a Skeleton was made with too many inverse bind matrices")
+	}
+}
+
+func TestSkeletonPalette(t *testing.T) {
+	// A chain listed child first: a hip at (1, 0, 0) in the Root's space and
+	// a knee one unit below it.
+	var joints = []Joint{
+		{Name: "knee", Parent: 1, Rest: translation(0, -1, 0)},
+		{Name: "hip", Parent: -1, Rest: translation(1, 0, 0)},
+	}
+	var inverseBind = []mgl.Mat4{mgl.Translate3D(-1, 1, 0), mgl.Translate3D(-1, 0, 0)}
+	var s, err = NewSkeleton("legs", joints, inverseBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, ok := s.Joint("hip"); !ok || j != 1 {
+		t.Errorf("Joint(\"hip\") = %d, %v", j, ok)
+	}
+
+	// The rest pose leaves vertices where they were bound.
+	var pose = s.RestPose()
+	for j, m := range s.Palette(pose, nil) {
+		if !matNear(m, mgl.Ident4(), 1e-6) {
+			t.Errorf("joint %d: rest palette %v", j, m)
+		}
+	}
+
+	// Turning the hip a quarter about Z swings the knee out along +X, and
+	// the Root moves everything.
+	pose[1].Rotation = mgl.QuatRotate(math.Pi/2, mgl.Vec3{0, 0, 1})
+	s.Root = mgl.Translate3D(0, 0, 5)
+	var (
+		palette = s.Palette(pose, make([]mgl.Mat4, 1, 2))
+		knee    = palette[0].Mul4x1(mgl.Vec4{1, -1, 0, 1}).Vec3()
+	)
+	if !vecNear(knee, mgl.Vec3{2, 0, 5}, 1e-5) {
+		t.Errorf("knee moved to %v, want (2, 0, 5)", knee)
+	}
+}
+
+func TestPoseBlend(t *testing.T) {
+	var (
+		a = Pose{translation(0, 0, 0), IdentityTransform}
+		b = Pose{translation(2, 0, 0)}
+	)
+	b[0].Rotation = mgl.QuatRotate(math.Pi/2, mgl.Vec3{0, 1, 0})
+	b[0].Scale = mgl.Vec3{3, 1, 1}
+	a.Blend(b, 0.5)
+
+	var want = Transform{mgl.Vec3{1, 0, 0}, mgl.QuatRotate(math.Pi/4, mgl.Vec3{0, 1, 0}), mgl.Vec3{2, 1, 1}}
+	if !matNear(a[0].Mat4(), want.Mat4(), 1e-5) {
+		t.Errorf("blended to %v, want %v", a[0], want)
+	}
+	if a[1] != IdentityTransform {
+		t.Errorf("unmatched joint blended to %v", a[1])
+	}
+}
+
+// gltfNode returns a glTF node translated by (x, y, z).
+func gltfNode(name string, parent int, x, y, z float32) *meshfmt.Node {
+	return &meshfmt.Node{
+		Name:        name,
+		Parent:      parent,
+		Mesh:        -1,
+		Skin:        -1,
+		Camera:      -1,
+		Matrix:      mgl.Ident4(),
+		Translation: mgl.Vec3{x, y, z},
+		Rotation:    mgl.QuatIdent(),
+		Scale:       mgl.Vec3{1, 1, 1},
+	}
+}
+
+// gltfRig returns a document of an armature node holding a hip joint, which
+// holds a helper node holding a knee joint. The skin lists the knee first.
+func gltfRig() *meshfmt.GLTF {
+	return &meshfmt.GLTF{
+		Nodes: []*meshfmt.Node{
+			gltfNode("armature", -1, 0, 0, 5),
+			gltfNode("hip", 0, 1, 0, 0),
+			gltfNode("helper", 1, 0, 0, 0),
+			gltfNode("knee", 2, 0, -1, 0),
+		},
+		Skins: []*meshfmt.Skin{{
+			Name:                "legs",
+			Joints:              []int{3, 1},
+			InverseBindMatrices: []mgl.Mat4{mgl.Translate3D(-1, 1, -5), mgl.Translate3D(-1, 0, -5)},
+			Skeleton:            -1,
+		}},
+	}
+}
+
+func TestNewSkeletonFromGLTF(t *testing.T) {
+	var s, err = NewSkeletonFromGLTF(gltfRig(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "legs" || len(s.Joints) != 2 {
+		t.Fatalf("got Skeleton %q of %d joints", s.Name, len(s.Joints))
+	}
+	if knee := s.Joints[0]; knee.Name != "knee" || knee.Parent != 1 || knee.Rest.Translation != (mgl.Vec3{0, -1, 0}) {
+		t.Errorf("knee joint %+v", knee)
+	}
+	if hip := s.Joints[1]; hip.Parent != -1 {
+		t.Errorf("hip joint %+v", hip)
+	}
+	if s.Root != mgl.Translate3D(0, 0, 5) {
+		t.Errorf("root %v", s.Root)
+	}
+	for j, m := range s.Palette(s.RestPose(), nil) {
+		if !matNear(m, mgl.Ident4(), 1e-6) {
+			t.Errorf("joint %d: rest palette %v", j, m)
+		}
+	}
+
+	if _, err = NewSkeletonFromGLTF(gltfRig(), 1); err == nil {
+		t.Error("a missing skin made a Skeleton")
+	}
+}
+
+func TestSkinnedShaderInputs(t *testing.T) {
+	// A skinned glTF primitive with normals but no texture coordinates.
+	var prim = &meshfmt.Mesh{
+		Positions: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+		Normals:   []float32{0, 0, 1, 0, 0, 1, 0, 0, 1},
+		Attribs: []meshfmt.Attrib{
+			{Name: "joints0", Components: 4, Data: make([]float32, 12)},
+			{Name: "weights0", Components: 4, Data: []float32{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}},
+		},
+		Indices: []uint32{0, 1, 2},
+	}
+	var attribs, _ = meshAttribs(prim)
+	var layout = NewVertexLayout(attribs...)
+
+	var inputs = regexp.MustCompile(`(?m)^layout\(location = \d+\) in \w+ (\w+);`).FindAllStringSubmatch(builtinShaders["builtin/skinned.vert"], -1)
+	if len(inputs) == 0 {
+		t.Fatal("no inputs found in builtin/skinned.vert")
+	}
+	for _, in := range inputs {
+		if _, ok := layout.Attrib(in[1]); !ok {
+			t.Errorf("input '%s' is missing from a primitive without texture coordinates", in[1])
+		}
+	}
+}
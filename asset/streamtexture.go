@@ -0,0 +1,193 @@
+package asset
+
+import (
+	"errors"
+	"image"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// StreamTexture is a Texture intended for contents that change every frame,
+// such as video. Uploads are staged through a ring of pixel buffers so that
+// writing the next frame does not stall on the transfer of the previous one.
+// Each buffer is guarded by a fence and is only reused once the GPU has
+// finished reading from it.
+type StreamTexture struct {
+	*Texture
+
+	Timeout time.Duration // maximum time to wait for a buffer to be released
+
+	bufs   []uint32  // ring of pixel unpack buffers
+	fences []uintptr // fence guarding each buffer, or 0
+	cur    int       // index of the next buffer in the ring
+
+	mapped bool            // whether the current buffer is mapped
+	rect   image.Rectangle // region being written by the mapped buffer
+}
+
+// NewStreamTexture creates a StreamTexture of the given size with a ring of
+// 'buffers' pixel buffers. Two or three buffers are usually sufficient.
+func NewStreamTexture(name string, w, h, buffers int) *StreamTexture {
+	if buffers < 1 {
+		buffers = 1
+	}
+
+	var st = &StreamTexture{
+		Texture: NewTexture(name, w, h),
+		Timeout: time.Second,
+		bufs:    make([]uint32, buffers),
+		fences:  make([]uintptr, buffers),
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, st.Tex)
+	gl.TexImage2D(
+		gl.TEXTURE_2D,
+		0, gl.RGBA,
+		int32(w), int32(h), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, nil,
+	)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	gl.GenBuffers(int32(buffers), &st.bufs[0])
+	for _, buf := range st.bufs {
+		gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, buf)
+		gl.BufferData(gl.PIXEL_UNPACK_BUFFER, w*h*4, nil, gl.STREAM_DRAW)
+	}
+	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
+
+	return st
+}
+
+// wait blocks until the fence guarding buffer 'i' has been signalled and then
+// deletes the fence.
+func (st *StreamTexture) wait(i int) error {
+	if st.fences[i] == 0 {
+		return nil
+	}
+
+	var deadline = time.Now().Add(st.Timeout)
+	for {
+		switch gl.ClientWaitSync(st.fences[i], gl.SYNC_FLUSH_COMMANDS_BIT, uint64(time.Millisecond)) {
+		case gl.ALREADY_SIGNALED, gl.CONDITION_SATISFIED:
+			gl.DeleteSync(st.fences[i])
+			st.fences[i] = 0
+			return nil
+		case gl.WAIT_FAILED:
+			return errors.New("asset.StreamTexture.wait error: fence wait failed")
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("asset.StreamTexture.wait error: timed out waiting for buffer")
+		}
+	}
+}
+
+// Map maps the next buffer in the ring for writing the sub-rectangle 'rect' of
+// the texture. The returned slice holds rect.Dy() rows of 'stride' bytes of
+// RGBA pixels and is only valid until Unmap is called, which uploads it.
+func (st *StreamTexture) Map(rect image.Rectangle) (pix []uint8, stride int, err error) {
+	if st.mapped {
+		return nil, 0, errors.New("asset.StreamTexture.Map error: a buffer is already mapped")
+	}
+	if !rect.In(image.Rect(0, 0, st.W, st.H)) {
+		return nil, 0, errors.New("asset.StreamTexture.Map error: rectangle out of bounds")
+	}
+	if rect.Empty() {
+		return nil, 0, errors.New("asset.StreamTexture.Map error: rectangle is empty")
+	}
+
+	if err = st.wait(st.cur); err != nil {
+		return nil, 0, err
+	}
+
+	stride = rect.Dx() * 4
+	var size = stride * rect.Dy()
+
+	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, st.bufs[st.cur])
+	var ptr = gl.MapBufferRange(
+		gl.PIXEL_UNPACK_BUFFER, 0, size,
+		gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_BUFFER_BIT|gl.MAP_UNSYNCHRONIZED_BIT,
+	)
+	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
+
+	if ptr == nil {
+		return nil, 0, errors.New("asset.StreamTexture.Map error: could not map buffer")
+	}
+
+	st.mapped = true
+	st.rect = rect
+
+	return unsafe.Slice((*uint8)(ptr), size), stride, nil
+}
+
+// Unmap unmaps the buffer returned by Map and uploads its contents into the
+// mapped rectangle. The buffer is fenced and the ring advances.
+func (st *StreamTexture) Unmap() error {
+	if !st.mapped {
+		return errors.New("asset.StreamTexture.Unmap error: no buffer is mapped")
+	}
+	st.mapped = false
+
+	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, st.bufs[st.cur])
+	defer gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
+
+	if !gl.UnmapBuffer(gl.PIXEL_UNPACK_BUFFER) {
+		return errors.New("asset.StreamTexture.Unmap error: buffer contents were lost")
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, st.Tex)
+	gl.TexSubImage2D(
+		gl.TEXTURE_2D,
+		0,
+		int32(st.rect.Min.X), int32(st.rect.Min.Y),
+		int32(st.rect.Dx()), int32(st.rect.Dy()),
+		gl.RGBA, gl.UNSIGNED_BYTE, nil,
+	)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	st.fences[st.cur] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	st.cur = (st.cur + 1) % len(st.bufs)
+
+	return nil
+}
+
+// Upload copies an RGBA image into the texture at 'offset' through the buffer
+// ring.
+func (st *StreamTexture) Upload(img *image.RGBA, offset image.Point) error {
+	var (
+		bounds = img.Bounds()
+		rect   = image.Rectangle{offset, offset.Add(bounds.Size())}
+	)
+
+	var pix, stride, err = st.Map(rect)
+	if err != nil {
+		return err
+	}
+
+	for y := 0; y < bounds.Dy(); y++ {
+		var j = img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		copy(pix[y*stride:], img.Pix[j:j+stride])
+	}
+
+	return st.Unmap()
+}
+
+// Clean deletes the buffer ring, its fences, and the texture state.
+func (st *StreamTexture) Clean() {
+	if st.mapped {
+		gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, st.bufs[st.cur])
+		gl.UnmapBuffer(gl.PIXEL_UNPACK_BUFFER)
+		gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
+		st.mapped = false
+	}
+	for i, fence := range st.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			st.fences[i] = 0
+		}
+	}
+	gl.DeleteBuffers(int32(len(st.bufs)), &st.bufs[0])
+	st.Texture.Clean()
+}
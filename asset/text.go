@@ -0,0 +1,387 @@
+package asset
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Align is the horizontal alignment of lines of text.
+type Align int
+
+// Alignments of lines of text within the width of a layout.
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// TextOptions controls the layout of text.
+type TextOptions struct {
+	Size        float32   // height of an em in layout units; 0 uses the Font's size
+	Width       float32   // width at which lines wrap; 0 disables wrapping
+	Align       Align     // alignment of lines within Width, or the widest line
+	LineSpacing float32   // multiple of the Font's line height; 0 means 1
+	TabStops    []float32 // positions of tab stops, in increasing order
+	TabWidth    float32   // spacing of tab stops after TabStops; 0 means four spaces
+	Color       mgl.Vec4  // colour of text outside of any ColorSpan; zero means white
+}
+
+// DefaultTextOptions is used when text is laid out with nil options.
+var DefaultTextOptions = TextOptions{}
+
+// ColorSpan colours the characters of text between the byte offsets Start and
+// End. Later spans take precedence over earlier ones.
+type ColorSpan struct {
+	Start, End int
+	Color      mgl.Vec4
+}
+
+// TextLayout is text laid out as a quad for each visible glyph. Positions are
+// 2D with y pointing down and the origin at the top-left of the text, so the
+// first baseline is at the Font's ascent.
+type TextLayout struct {
+	Pos       []float32 // 2D vertex positions
+	Colors    []float32 // RGBA vertex colours
+	TexCoords []float32 // vertex texture coordinates in the Font's atlas
+	Elems     []uint32  // triangle indices
+
+	Glyphs        int     // number of quads
+	Lines         int     // number of lines
+	Width, Height float32 // size of the laid out text
+
+	// Generation is the Font's atlas generation for which TexCoords were
+	// computed.
+	Generation int
+}
+
+// placed is a character positioned on a line.
+type placed struct {
+	glyph *Glyph
+	x     float32
+	index int // index of the character in the text being laid out
+	space bool
+}
+
+// textLine is a line of placed characters.
+type textLine struct {
+	items []placed
+	width float32 // width excluding trailing spaces
+}
+
+// LayoutText lays out the UTF-8 string 's' with a Font. Lines are broken at
+// newlines and, when wrapping, at spaces and tabs; words wider than the wrap
+// width are broken between characters. Characters the Font cannot render are
+// skipped.
+func LayoutText(f *Font, s string, spans []ColorSpan, opts *TextOptions) *TextLayout {
+	if opts == nil {
+		opts = &DefaultTextOptions
+	}
+
+	var (
+		scale   = float32(1)
+		spacing = opts.LineSpacing
+	)
+	if opts.Size > 0 && f.Size > 0 {
+		scale = opts.Size / f.Size
+	}
+	if spacing == 0 {
+		spacing = 1
+	}
+
+	var color = opts.Color
+	if color == (mgl.Vec4{}) {
+		color = mgl.Vec4{1, 1, 1, 1}
+	}
+
+	type char struct {
+		r     rune
+		index int
+	}
+	var chars []char
+	for i, r := range s {
+		chars = append(chars, char{r, i})
+	}
+
+	var (
+		lines []textLine
+		cur   textLine
+		x     float32
+		prev  rune = -1
+		word       = -1 // index in cur.items at which the current word begins
+	)
+
+	var nextTab = func(x float32) float32 {
+		for _, stop := range opts.TabStops {
+			if stop > x {
+				return stop
+			}
+		}
+		var tw = opts.TabWidth
+		if tw <= 0 {
+			if g, ok := f.Glyph(' '); ok {
+				tw = 4 * g.Advance * scale
+			}
+		}
+		if tw <= 0 {
+			return x
+		}
+		return float32(math.Floor(float64(x/tw))+1) * tw
+	}
+
+	var endLine = func() {
+		cur.width = 0
+		for i := len(cur.items) - 1; i >= 0; i-- {
+			if it := cur.items[i]; !it.space {
+				cur.width = it.x + it.glyph.Advance*scale
+				break
+			}
+		}
+		lines = append(lines, cur)
+		cur, x, prev, word = textLine{}, 0, -1, -1
+	}
+
+	for i := 0; i < len(chars); i++ {
+		var c = chars[i]
+
+		switch c.r {
+		case '\n':
+			endLine()
+			continue
+		case '\t':
+			x = nextTab(x)
+			prev, word = -1, len(cur.items)
+			continue
+		}
+
+		var g, ok = f.Glyph(c.r)
+		if !ok {
+			continue
+		}
+
+		var kern float32
+		if prev >= 0 {
+			kern = f.Kern(prev, c.r) * scale
+		}
+
+		var space = c.r == ' '
+		if !space && opts.Width > 0 && len(cur.items) > 0 && x+kern+g.Advance*scale > opts.Width {
+			if word > 0 && word < len(cur.items) {
+				// Move the current word to the next line.
+				i = cur.items[word].index - 1
+				cur.items = cur.items[:word]
+			} else {
+				// Break before this character, which either begins a word or
+				// is part of a word too wide for a line.
+				i--
+			}
+			endLine()
+			continue
+		}
+
+		x += kern
+		cur.items = append(cur.items, placed{glyph: g, x: x, index: i, space: space})
+		x += g.Advance * scale
+		prev = c.r
+		if space {
+			word = len(cur.items)
+		}
+	}
+	endLine()
+
+	var (
+		layout = &TextLayout{
+			Lines:      len(lines),
+			Height:     f.LineHeight * scale * spacing * float32(len(lines)),
+			Generation: f.Generation,
+		}
+		box = opts.Width
+	)
+	for _, l := range lines {
+		if l.width > layout.Width {
+			layout.Width = l.width
+		}
+	}
+	if box <= 0 {
+		box = layout.Width
+	}
+
+	for n, l := range lines {
+		var (
+			baseline = (f.Ascent + f.LineHeight*spacing*float32(n)) * scale
+			offset   float32
+		)
+		switch opts.Align {
+		case AlignCenter:
+			offset = (box - l.width) / 2
+		case AlignRight:
+			offset = box - l.width
+		}
+
+		for _, it := range l.items {
+			var g = it.glyph
+			if g.Rect.Empty() {
+				continue
+			}
+
+			var (
+				x0             = offset + it.x + float32(g.Offset.X)*scale
+				y0             = baseline + float32(g.Offset.Y)*scale
+				x1             = x0 + float32(g.Rect.Dx())*scale
+				y1             = y0 + float32(g.Rect.Dy())*scale
+				u0, v0, u1, v1 = f.TexCoords(g)
+				col            = color
+				base           = uint32(layout.Glyphs * 4)
+			)
+			for _, span := range spans {
+				if idx := chars[it.index].index; idx >= span.Start && idx < span.End {
+					col = span.Color
+				}
+			}
+
+			layout.Pos = append(layout.Pos, x0, y0, x0, y1, x1, y1, x1, y0)
+			layout.TexCoords = append(layout.TexCoords, u0, v0, u0, v1, u1, v1, u1, v0)
+			for k := 0; k < 4; k++ {
+				layout.Colors = append(layout.Colors, col[:]...)
+			}
+			layout.Elems = append(layout.Elems, base, base+1, base+2, base, base+2, base+3)
+			layout.Glyphs++
+		}
+	}
+
+	return layout
+}
+
+// Text is a Mesh displaying a string laid out with a Font. Changing the text
+// updates the Mesh's arrays in place while it fits in their capacity. The Mesh
+// is initialized and may be drawn with the Font's atlas, for example with the
+// "builtin/sdf" shaders for distance field fonts.
+type Text struct {
+	Name    string
+	Font    *Font
+	Options TextOptions
+	Mesh    *Mesh
+	Layout  *TextLayout
+
+	str      string
+	spans    []ColorSpan
+	capacity int // glyphs that fit in the Mesh's arrays
+}
+
+// NewText creates a Text displaying 's'.
+func NewText(name string, f *Font, s string, opts *TextOptions, spans ...ColorSpan) (*Text, error) {
+	if opts == nil {
+		opts = &DefaultTextOptions
+	}
+
+	var t = &Text{
+		Name:    name,
+		Font:    f,
+		Options: *opts,
+	}
+
+	if err := t.Set(s, spans...); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Set changes the string displayed, and its colour spans.
+func (t *Text) Set(s string, spans ...ColorSpan) error {
+	t.str, t.spans = s, spans
+	return t.update()
+}
+
+// Refresh lays the text out again if the Font's atlas has grown since, which
+// invalidates the texture coordinates. It should be called before drawing
+// when other text may have added glyphs to the Font.
+func (t *Text) Refresh() error {
+	if t.Layout != nil && t.Layout.Generation == t.Font.Generation {
+		return nil
+	}
+	return t.update()
+}
+
+func (t *Text) update() error {
+	var layout = LayoutText(t.Font, t.str, t.spans, &t.Options)
+	t.Layout = layout
+
+	if t.Mesh == nil || layout.Glyphs > t.capacity {
+		var capacity = layout.Glyphs
+		if capacity < 2*t.capacity {
+			capacity = 2 * t.capacity
+		}
+		if capacity < 16 {
+			capacity = 16
+		}
+		if err := t.makeMesh(capacity); err != nil {
+			return err
+		}
+	}
+
+	var m = t.Mesh
+	if layout.Glyphs == 0 {
+		m.Elements.Len = 0
+		return nil
+	}
+
+	for _, u := range []struct {
+		name string
+		data []float32
+	}{
+		{"pos", layout.Pos},
+		{"color", layout.Colors},
+		{"texcoord0", layout.TexCoords},
+	} {
+		if err := m.Attribs[u.name].Update(u.data); err != nil {
+			return err
+		}
+	}
+	if err := m.Elements.Update(layout.Elems); err != nil {
+		return err
+	}
+	m.Vertices = layout.Glyphs * 4
+
+	return nil
+}
+
+// makeMesh replaces the Mesh with one whose arrays hold 'capacity' glyphs.
+func (t *Text) makeMesh(capacity int) error {
+	var (
+		verts = capacity * 4
+		elems = make([]uint32, capacity*6)
+	)
+
+	var m, err = MakeMesh(
+		t.Name, 2, gl.TRIANGLES,
+		make([]float32, verts*2),
+		make([]float32, verts*4),
+		nil,
+		[]interface{}{make([]float32, verts*2)},
+		elems,
+	)
+	if err != nil {
+		return err
+	}
+	if err = m.Init(); err != nil {
+		m.Clean()
+		return err
+	}
+
+	if t.Mesh != nil {
+		t.Mesh.Clean()
+	}
+	t.Mesh, t.capacity = m, capacity
+
+	return nil
+}
+
+// Clean deletes the Text's Mesh.
+func (t *Text) Clean() {
+	if t.Mesh != nil {
+		t.Mesh.Clean()
+		t.Mesh = nil
+	}
+}
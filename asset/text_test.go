@@ -0,0 +1,151 @@
+package asset
+
+import (
+	"image"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// monoFont returns a Font of fixed width glyphs for the lowercase letters and
+// space, without an atlas texture or glyph source.
+func monoFont() *Font {
+	var f = &Font{
+		Size:       10,
+		LineHeight: 12,
+		Ascent:     8,
+		Atlas:      &Texture{W: 100, H: 100},
+		Glyphs:     make(map[rune]*Glyph),
+		Kerning:    map[[2]rune]float32{{'a', 'v'}: -2},
+	}
+	for r := 'a'; r <= 'z'; r++ {
+		var i = int(r - 'a')
+		f.Glyphs[r] = &Glyph{
+			Rect:    image.Rect(i*4, 0, i*4+4, 6),
+			Offset:  image.Pt(1, -6),
+			Advance: 5,
+		}
+	}
+	f.Glyphs[' '] = &Glyph{Advance: 5}
+	return f
+}
+
+// quadX returns the top left corner of quad 'q' of a layout.
+func quadX(l *TextLayout, q int) (x, y float32) {
+	return l.Pos[q*8], l.Pos[q*8+1]
+}
+
+func TestLayoutText(t *testing.T) {
+	var l = LayoutText(monoFont(), "ab c", nil, nil)
+	if l.Glyphs != 3 || l.Lines != 1 || l.Width != 20 || l.Height != 12 {
+		t.Fatalf("got %d glyphs on %d lines in %vx%v", l.Glyphs, l.Lines, l.Width, l.Height)
+	}
+	// Quads are offset from the pen on the baseline at the ascent.
+	for q, want := range []float32{1, 6, 16} {
+		if x, y := quadX(l, q); x != want || y != 2 {
+			t.Errorf("quad %d at (%v, %v), want (%v, 2)", q, x, y, want)
+		}
+	}
+	if len(l.Elems) != 18 || l.Elems[6] != 4 {
+		t.Errorf("got elements %v", l.Elems)
+	}
+	if u0, v0 := l.TexCoords[8], l.TexCoords[9]; u0 != 0.04 || v0 != 0 {
+		t.Errorf("quad 1 texture coordinates start at (%v, %v)", u0, v0)
+	}
+}
+
+func TestLayoutTextKerning(t *testing.T) {
+	var l = LayoutText(monoFont(), "av", nil, nil)
+	if x, _ := quadX(l, 1); x != 4 {
+		t.Errorf("kerned glyph at %v, want 4", x)
+	}
+}
+
+func TestLayoutTextWrap(t *testing.T) {
+	var tests = []struct {
+		s     string
+		width float32
+		lines [][2]float32 // top left of the first quad of each line
+	}{
+		// Words move to the next line whole.
+		{"ab cd ef", 27, [][2]float32{{1, 2}, {1, 14}}},
+		// Newlines always break.
+		{"ab\ncd", 0, [][2]float32{{1, 2}, {1, 14}}},
+		// Words wider than a line are broken between characters.
+		{"abcdef", 12, [][2]float32{{1, 2}, {1, 14}, {1, 26}}},
+	}
+	for _, tt := range tests {
+		var l = LayoutText(monoFont(), tt.s, nil, &TextOptions{Width: tt.width})
+		if l.Lines != len(tt.lines) {
+			t.Errorf("%q: got %d lines, want %d", tt.s, l.Lines, len(tt.lines))
+			continue
+		}
+		var q = 0
+		for n, want := range tt.lines {
+			var x, y = quadX(l, q)
+			if x != want[0] || y != want[1] {
+				t.Errorf("%q line %d: starts at (%v, %v), want %v", tt.s, n, x, y, want)
+			}
+			for q < l.Glyphs {
+				if _, next := quadX(l, q); next != y {
+					break
+				}
+				q++
+			}
+		}
+	}
+}
+
+func TestLayoutTextAlign(t *testing.T) {
+	var tests = []struct {
+		align Align
+		want  float32
+	}{
+		{AlignLeft, 1},
+		{AlignCenter, 6},
+		{AlignRight, 11},
+	}
+	for _, tt := range tests {
+		var l = LayoutText(monoFont(), "ab", nil, &TextOptions{Width: 20, Align: tt.align})
+		if x, _ := quadX(l, 0); x != tt.want {
+			t.Errorf("align %d: starts at %v, want %v", tt.align, x, tt.want)
+		}
+	}
+}
+
+func TestLayoutTextTabs(t *testing.T) {
+	var l = LayoutText(monoFont(), "a\tb\tc", nil, &TextOptions{TabStops: []float32{8}})
+	// The first tab stops at 8 and the next at a multiple of four spaces.
+	for q, want := range []float32{1, 9, 21} {
+		if x, _ := quadX(l, q); x != want {
+			t.Errorf("quad %d at %v, want %v", q, x, want)
+		}
+	}
+}
+
+func TestLayoutTextSpans(t *testing.T) {
+	var (
+		red   = mgl.Vec4{1, 0, 0, 1}
+		green = mgl.Vec4{0, 1, 0, 1}
+		l     = LayoutText(monoFont(), "abc", []ColorSpan{{0, 2, red}, {1, 3, green}}, &TextOptions{Size: 20})
+	)
+	for q, want := range []mgl.Vec4{red, green, green} {
+		if got := (mgl.Vec4{l.Colors[q*16], l.Colors[q*16+1], l.Colors[q*16+2], l.Colors[q*16+3]}); got != want {
+			t.Errorf("quad %d: colour %v, want %v", q, got, want)
+		}
+	}
+	// Size scales the layout by 20/10.
+	if l.Width != 30 || l.Height != 24 {
+		t.Errorf("scaled layout is %vx%v, want 30x24", l.Width, l.Height)
+	}
+}
+
+func TestLayoutTextMissingGlyphs(t *testing.T) {
+	var l = LayoutText(monoFont(), "aXb", nil, nil)
+	if l.Glyphs != 2 {
+		t.Fatalf("got %d glyphs, want 2", l.Glyphs)
+	}
+	if x, _ := quadX(l, 1); x != 6 {
+		t.Errorf("glyph after a missing one at %v, want 6", x)
+	}
+}
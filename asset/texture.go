@@ -4,7 +4,6 @@ import (
 	"errors"
 	"image"
 	"image/draw"
-	"reflect"
 	"unsafe"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
@@ -12,45 +11,65 @@ import (
 
 // Texture encapsulates texture state
 type Texture struct {
-	Name string
-	Tex  uint32
-	Buf  uint32
-	W, H int
+	Name   string
+	Tex    uint32
+	Buf    uint32
+	Target uint32 // OpenGL texture target
+	W, H   int
+
+	// SRGB makes LoadImage and LoadRGBA store colours as sRGB, which is
+	// converted to linear when sampled.
+	SRGB bool
+
+	bufSize int // allocated size of Buf in bytes
 }
 
 // NewTexture creates a new texture, but does no GL allocation
 func NewTexture(name string, w, h int) *Texture {
+	return newTexture(name, gl.TEXTURE_2D, w, h)
+}
+
+// newTexture creates a new texture bound to the given target
+func newTexture(name string, target uint32, w, h int) *Texture {
 	var tex, buf uint32
 
 	gl.GenTextures(1, &tex)
 	gl.GenBuffers(1, &buf)
 
 	var t = &Texture{
-		Name: name,
-		Tex:  tex,
-		Buf:  buf,
-		W:    w, H: h,
+		Name:   name,
+		Tex:    tex,
+		Buf:    buf,
+		Target: target,
+		W:      w, H: h,
 	}
 
-	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.BindTexture(target, tex)
 
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
 
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindTexture(target, 0)
 
 	return t
 }
 
 // NewTextureFromImage creates a new Texture from Image data
 func NewTextureFromImage(name string, img image.Image) (*Texture, error) {
+	return newTextureFromImage(name, img, false)
+}
+
+// newTextureFromImage creates a new Texture from Image data, stored as sRGB
+// if 'srgb' is set.
+func newTextureFromImage(name string, img image.Image, srgb bool) (*Texture, error) {
 	var (
 		bounds = img.Bounds()
 		t      = NewTexture(name, bounds.Dx(), bounds.Dy())
 	)
+	t.SRGB = srgb
 
 	if err := t.LoadImage(img, 0); err != nil {
 		t.Clean()
@@ -98,66 +117,73 @@ func (t *Texture) LoadImage(img image.Image, level int32) error {
 
 // LoadRGBA updates a texture from a given RGBA image
 func (t *Texture) LoadRGBA(img *image.RGBA, level int32) error {
-	var bounds = img.Bounds()
+	var (
+		bounds   = img.Bounds()
+		internal = int32(gl.RGBA)
+	)
+	if t.SRGB {
+		internal = gl.SRGB8_ALPHA8
+	}
+
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(img.Stride/4))
+	defer gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
 
 	gl.BindTexture(gl.TEXTURE_2D, t.Tex)
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
-		level, gl.RGBA,
+		level, internal,
 		int32(bounds.Dx()), int32(bounds.Dy()), 0,
-		gl.RGBA, gl.UNSIGNED_BYTE, unsafe.Pointer(&img.Pix[0]),
+		gl.RGBA, gl.UNSIGNED_BYTE, unsafe.Pointer(&img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y)]),
 	)
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 
-	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, t.Buf)
-	gl.BufferData(gl.PIXEL_UNPACK_BUFFER, len(img.Pix), nil, gl.STREAM_DRAW)
-
 	return nil
 }
 
-// LoadSubRGBA updates a portion of a texture from a given RGBA image
+// LoadSubRGBA updates a portion of a texture from a given RGBA image. The
+// image is staged through the Texture's pixel buffer, which is only
+// reallocated when it is too small to hold the image.
 func (t *Texture) LoadSubRGBA(img *image.RGBA, offset image.Point, level int32) error {
-	var bounds = img.Bounds()
+	var (
+		bounds = img.Bounds()
+		row    = bounds.Dx() * 4
+		size   = row * bounds.Dy()
+	)
 
-	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, t.Buf)
-	gl.BufferData(gl.PIXEL_UNPACK_BUFFER, t.W*t.H*4, nil, gl.STREAM_DRAW)
+	if size == 0 {
+		return nil
+	}
 
-	var ln int32
-	gl.GetBufferParameteriv(gl.PIXEL_UNPACK_BUFFER, gl.BUFFER_SIZE, &ln)
+	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, t.Buf)
+	defer gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
 
-	var ptr = uintptr(gl.MapBuffer(gl.PIXEL_UNPACK_BUFFER, gl.WRITE_ONLY))
+	if size > t.bufSize {
+		gl.BufferData(gl.PIXEL_UNPACK_BUFFER, size, nil, gl.STREAM_DRAW)
+		t.bufSize = size
+	}
 
-	if ptr == 0 {
-		return errors.New("Assets.Texture.LoadSubRGBA error: could not map buffer")
+	var ptr = gl.MapBufferRange(gl.PIXEL_UNPACK_BUFFER, 0, size, gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_BUFFER_BIT)
+	if ptr == nil {
+		return errors.New("asset.Texture.LoadSubRGBA error: could not map buffer")
 	}
-	var pbuf = *(*[]uint8)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: ptr,
-		Len:  int(ln),
-		Cap:  int(ln),
-	}))
 
+	var pbuf = unsafe.Slice((*uint8)(ptr), size)
 	for y := 0; y < bounds.Dy(); y++ {
-		var (
-			i = (y+offset.Y)*t.W*4 + offset.X*4
-			j = y * img.Stride
-		)
-		copy(pbuf[i:], img.Pix[j:j+bounds.Dx()*4])
+		var j = img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		copy(pbuf[y*row:], img.Pix[j:j+row])
 	}
 
 	gl.UnmapBuffer(gl.PIXEL_UNPACK_BUFFER)
 
 	gl.BindTexture(gl.TEXTURE_2D, t.Tex)
-
 	gl.TexSubImage2D(
 		gl.TEXTURE_2D,
 		level,
-		0, 0, //offset.X, offset.Y,
+		int32(offset.X), int32(offset.Y),
 		int32(bounds.Dx()), int32(bounds.Dy()),
 		gl.RGBA, gl.UNSIGNED_BYTE, nil,
 	)
-
 	gl.BindTexture(gl.TEXTURE_2D, 0)
-	gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, 0)
 
 	return nil
 }
@@ -166,12 +192,12 @@ func (t *Texture) LoadSubRGBA(img *image.RGBA, offset image.Point, level int32)
 func (t *Texture) Use(i uint32) {
 	gl.Enable(gl.TEXTURE_2D)
 	gl.ActiveTexture(gl.TEXTURE0 + i)
-	gl.BindTexture(gl.TEXTURE_2D, t.Tex)
+	gl.BindTexture(t.Target, t.Tex)
 }
 
 // Release unbinds texture state
 func (t *Texture) Release() {
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindTexture(t.Target, 0)
 	gl.Disable(gl.TEXTURE_2D)
 }
 
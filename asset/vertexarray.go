@@ -3,12 +3,8 @@ package asset
 import (
 	"errors"
 	"fmt"
-	"reflect"
-	"unsafe"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
-	"github.com/go-gl/mathgl/mgl32"
-	"github.com/go-gl/mathgl/mgl64"
 )
 
 // AttribLenError creates an attribute length error
@@ -16,8 +12,18 @@ func AttribLenError(name string, length, Dims int) error {
 	return fmt.Errorf("AttribArray error: '%s' length %d is not a multiple of elements %d", name, length, Dims)
 }
 
+// checkElementDims returns an error if 'dims' differs from the number of
+// components of the vector or matrix elements of 'data'.
+func checkElementDims(name string, dims int, data interface{}) error {
+	if n := elementDims(data); n != 0 && n != dims {
+		return fmt.Errorf("AttribArray error: '%s' has %d dimensions, but its elements have %d", name, dims, n)
+	}
+	return nil
+}
+
 // AttribArray is a vertex attribute array. The raw attribute data is not
-// retained and must be stored separately, if at all.
+// retained and must be stored separately, if at all, such as by Mesh.Retain,
+// which the array then keeps up to date as it is written.
 type AttribArray struct {
 	Name string // attrib location name for linking with shader
 	Dims int    // number dimensions per Attribute
@@ -25,78 +31,52 @@ type AttribArray struct {
 	Buf  uint32 // the OpenGL buffer handle
 	Len  int    // the length of the buffer in elements
 	Cap  int    // the maximum capacity of the buffer in elements
+
+	// Normalized maps integer elements to [0, 1], or [-1, 1] if signed, when
+	// read as floating point. Integer elements that are not normalized are
+	// read as integers. It must be set before the array is initialized.
+	Normalized bool
+
+	// Divisor is the number of instances drawn before the array advances to
+	// its next attribute, or zero to advance once per vertex. It must be set
+	// before the array is initialized.
+	Divisor uint32
+
+	Usage uint32 // OpenGL usage hint of the buffer
+
+	// Bounds are the bounds of the data of an array named "pos", kept as the
+	// data is set.
+	Bounds Bounds
+
+	ring     *RingBuffer // the buffer of a streamed array, or nil
+	retained *AttribData // the Mesh's retained copy of the data, or nil
 }
 
-// NewAttribArray creates a new AttribArray. 'data' must be a numeric slice.
-// Currently supported types are:
-//   []float32, []float64,
-//   []uint8
+// NewAttribArray creates a new AttribArray. 'data' must be a numeric slice of
+// one of the VertexElement types. Packed types hold all four dimensions of an
+// attribute in one element, and vectors and matrices must have 'dims'
+// components. NewTypedAttribArray checks the type of the data at compile time
+// instead.
 func NewAttribArray(name string, dims int, data interface{}, usage uint32) (*AttribArray, error) {
-	var val = reflect.ValueOf(data)
-	if val.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("AttribArray error: '%s' data is not a slice", name)
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("AttribArray error: '%s': %v", name, err)
 	}
+	if err = checkElementDims(name, dims, data); err != nil {
+		return nil, err
+	}
+	return newAttribArray(name, dims, raw, typ, l, usage)
+}
 
-	var (
-		size int
-		typ  uint32
-		ptr  unsafe.Pointer
-		l    = val.Len()
-	)
-
+// newAttribArray creates an AttribArray from the memory of 'l' elements of
+// type 'typ'.
+func newAttribArray(name string, dims int, raw []byte, typ uint32, l int, usage uint32) (*AttribArray, error) {
 	if l == 0 {
 		return nil, fmt.Errorf("AttribArray error: '%s' length is zero", name)
 	}
-
-	switch v := data.(type) {
-	case []uint8:
-		size = 1
-		typ = gl.UNSIGNED_BYTE
-		ptr = unsafe.Pointer(&v[0])
-	case []float32:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-	case []mgl32.Vec2:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-		if dims != 2 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		l *= 2
-	case []mgl32.Vec3:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-		if dims != 3 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		l *= 3
-	case []float64:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-	case []mgl64.Vec2:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-		if dims != 2 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		l *= 2
-	case []mgl64.Vec3:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-		if dims != 3 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		l *= 3
-	default:
-		return nil, fmt.Errorf("AttribArray error: unhandled element type for '%s'", name)
+	if isPacked(typ) && dims != 4 {
+		return nil, fmt.Errorf("AttribArray error: packed '%s' must have 4 dimensions", name)
 	}
-
 	if l%dims != 0 {
 		return nil, AttribLenError(name, l, dims)
 	}
@@ -104,113 +84,196 @@ func NewAttribArray(name string, dims int, data interface{}, usage uint32) (*Att
 	var buf uint32
 	gl.GenBuffers(1, &buf)
 	var arr = &AttribArray{
-		Name: name,
-		Dims: dims,
-		Type: typ,
-		Buf:  buf,
-		Len:  l,
-		Cap:  l,
+		Name:  name,
+		Dims:  dims,
+		Type:  typ,
+		Buf:   buf,
+		Len:   l,
+		Cap:   l,
+		Usage: usage,
 	}
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, arr.Buf)
-	gl.BufferData(gl.ARRAY_BUFFER, arr.Len*size, ptr, usage)
+	gl.BufferData(gl.ARRAY_BUFFER, len(raw), gl.Ptr(raw), usage)
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	arr.setBounds(raw)
 
 	return arr, nil
 }
 
-// Update updates the data in the AttribArray. The data must be of the same
-// type as the original data and no longer.
+// newEmptyAttribArray creates an AttribArray of elements of type 'typ' with an
+// empty buffer, to be filled by Update or Refill.
+func newEmptyAttribArray(name string, dims int, typ uint32, usage uint32) *AttribArray {
+	var arr = &AttribArray{
+		Name:   name,
+		Dims:   dims,
+		Type:   typ,
+		Usage:  usage,
+		Bounds: EmptyBounds,
+	}
+	// The buffer object must exist for it to be written by name.
+	gl.CreateBuffers(1, &arr.Buf)
+	return arr
+}
+
+// setBounds sets the Bounds of a "pos" array from its data.
+func (arr *AttribArray) setBounds(raw []byte) {
+	if arr.Name == "pos" {
+		arr.Bounds = rawBounds(raw, arr.Type, arr.Dims, 0, 0)
+	}
+}
+
+// Update replaces the data at the start of the AttribArray, which becomes as
+// long as the data. The data must be of the same type as the original. The
+// buffer grows if the data is longer than its capacity.
 func (arr *AttribArray) Update(data interface{}) error {
-	var val = reflect.ValueOf(data)
-	if val.Kind() != reflect.Slice {
-		panic("asset.AttribArray.Update error: data is not a slice")
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.AttribArray.Update error: '%s': %v", arr.Name, err)
+	}
+	if l > 0 && arr.Type != typ {
+		return fmt.Errorf("asset.AttribArray.Update error: '%s' data type does not match array type", arr.Name)
 	}
+	return arr.update(raw, l)
+}
 
-	var (
-		size int
-		typ  uint32
-		ptr  unsafe.Pointer
-		l    = val.Len()
-	)
+// update replaces the start of the array with the memory of 'l' elements of
+// the array's type.
+func (arr *AttribArray) update(raw []byte, l int) error {
+	if arr.ring != nil {
+		return fmt.Errorf("asset.AttribArray.Update error: '%s' is streamed", arr.Name)
+	}
+	if l%arr.Dims != 0 {
+		return AttribLenError(arr.Name, l, arr.Dims)
+	}
 
+	if l > arr.Cap {
+		arr.Cap = grownCap(arr.Cap, l)
+		resizeBuffer(arr.Buf, dataSize(arr.Type, arr.Cap), 0, arr.Usage)
+	}
 	arr.Len = l
+	arr.setBounds(raw)
+	arr.retain(0, raw, true)
 	if l == 0 {
 		return nil
 	}
-	if l > arr.Cap {
-		panic("asset.AttribArray.Update error: data length is longer than buffer")
+
+	gl.NamedBufferSubData(arr.Buf, 0, len(raw), gl.Ptr(raw))
+
+	return nil
+}
+
+// retain writes the memory of elements at byte 'offset' of the retained copy
+// of the array, if any, which ends with them if 'truncate' is set.
+func (arr *AttribArray) retain(offset int, raw []byte, truncate bool) {
+	if arr.retained != nil {
+		arr.retained.Data = splice(arr.retained.Data, offset, raw, truncate)
+	}
+}
+
+// UpdateRange replaces the data of the AttribArray starting at element
+// 'offset', which must be a whole number of attributes and no further than
+// its length. The array is lengthened if the data reaches past its end, and
+// the buffer grows, keeping its contents, if it would exceed its capacity.
+func (arr *AttribArray) UpdateRange(offset int, data interface{}) error {
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.AttribArray.UpdateRange error: '%s': %v", arr.Name, err)
+	}
+	if l > 0 && arr.Type != typ {
+		return fmt.Errorf("asset.AttribArray.UpdateRange error: '%s' data type does not match array type", arr.Name)
 	}
+	return arr.updateRange(offset, raw, l)
+}
 
-	switch v := data.(type) {
-	case []uint8:
-		size = 1
-		typ = gl.UNSIGNED_BYTE
-		ptr = unsafe.Pointer(&v[0])
-	case []float32:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-	case []mgl32.Vec2:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-		if arr.Dims != 2 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		arr.Len *= 2
-	case []mgl32.Vec3:
-		size = 4
-		typ = gl.FLOAT
-		ptr = unsafe.Pointer(&v[0])
-		if arr.Dims != 3 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		arr.Len *= 3
-	case []float64:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-	case []mgl64.Vec2:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-		if arr.Dims != 2 {
-			panic("AttribArray error: dimension mismatch")
-		}
-		arr.Len *= 2
-	case []mgl64.Vec3:
-		size = 8
-		typ = gl.DOUBLE
-		ptr = unsafe.Pointer(&v[0])
-		if arr.Dims != 3 {
-			panic("AttribArray error: dimension mismatch")
+// updateRange replaces the memory of 'l' elements of the array's type starting
+// at element 'offset'.
+func (arr *AttribArray) updateRange(offset int, raw []byte, l int) error {
+	switch {
+	case arr.ring != nil:
+		return fmt.Errorf("asset.AttribArray.UpdateRange error: '%s' is streamed", arr.Name)
+	case l%arr.Dims != 0:
+		return AttribLenError(arr.Name, l, arr.Dims)
+	case offset%arr.Dims != 0:
+		return fmt.Errorf("asset.AttribArray.UpdateRange error: '%s' offset %d is not a multiple of elements %d", arr.Name, offset, arr.Dims)
+	case offset < 0 || offset > arr.Len:
+		return fmt.Errorf("asset.AttribArray.UpdateRange error: '%s' offset %d is outside length %d", arr.Name, offset, arr.Len)
+	}
+	if l == 0 {
+		return nil
+	}
+
+	var end = offset + l
+	if end > arr.Cap {
+		arr.Cap = grownCap(arr.Cap, end)
+		resizeBuffer(arr.Buf, dataSize(arr.Type, arr.Cap), dataSize(arr.Type, arr.Len), arr.Usage)
+	}
+	gl.NamedBufferSubData(arr.Buf, dataSize(arr.Type, offset), len(raw), gl.Ptr(raw))
+	arr.retain(dataSize(arr.Type, offset), raw, false)
+
+	// The bounds of a partly replaced array can only grow.
+	if arr.Name == "pos" {
+		var b = rawBounds(raw, arr.Type, arr.Dims, 0, 0)
+		if offset > 0 || end < arr.Len {
+			b = b.Union(arr.Bounds)
 		}
-		arr.Len *= 3
-	default:
-		return fmt.Errorf("AttribArray error: unhandled element type for '%s'", arr.Name)
+		arr.Bounds = b
 	}
+	arr.Len = max(arr.Len, end)
 
-	if arr.Type != typ {
-		panic("asset.AttribArray.Update error: data type does not match array type")
+	return nil
+}
+
+// Refill replaces all of the data in the AttribArray, which must be of the
+// same type as the original but may be of any length. The old contents of the
+// buffer are orphaned, so that refilling it each frame, such as with instance
+// transforms, does not wait on draws still reading them.
+func (arr *AttribArray) Refill(data interface{}) error {
+	var raw, typ, l, err = sliceBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.AttribArray.Refill error: '%s': %v", arr.Name, err)
 	}
+	if l > 0 && arr.Type != typ {
+		return fmt.Errorf("asset.AttribArray.Refill error: '%s' data type does not match array type", arr.Name)
+	}
+	return arr.refill(raw, l)
+}
 
-	if arr.Len%arr.Dims != 0 {
-		panic("asset.AttribArray.Update error: invalid data length")
+// refill replaces the array with the memory of 'l' elements of the array's
+// type, growing the buffer if needed.
+func (arr *AttribArray) refill(raw []byte, l int) error {
+	if arr.ring != nil {
+		return fmt.Errorf("asset.AttribArray.Refill error: '%s' is streamed", arr.Name)
+	}
+	if l%arr.Dims != 0 {
+		return AttribLenError(arr.Name, l, arr.Dims)
 	}
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, arr.Buf)
-	gl.BufferSubData(gl.ARRAY_BUFFER, 0, arr.Len*size, ptr)
+	if l > arr.Cap {
+		gl.BufferData(gl.ARRAY_BUFFER, len(raw), gl.Ptr(raw), arr.Usage)
+		arr.Cap = l
+	} else {
+		gl.BufferData(gl.ARRAY_BUFFER, dataSize(arr.Type, arr.Cap), nil, arr.Usage)
+		if l > 0 {
+			gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(raw), gl.Ptr(raw))
+		}
+	}
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	arr.Len = l
+	arr.setBounds(raw)
+	arr.retain(0, raw, true)
 
 	return nil
 }
 
-// Init initializes the AttribArray within the provided vertex array.
+// Init initializes the AttribArray within the provided vertex array. Doubles
+// are bound as double inputs and integers that are not Normalized as integer
+// inputs; everything else is read as floating point. Matrices of more than
+// four dimensions occupy a location for each column, starting at 'loc'.
 func (arr *AttribArray) Init(loc uint32) {
-	gl.EnableVertexAttribArray(loc)
 	gl.BindBuffer(gl.ARRAY_BUFFER, arr.Buf)
-	gl.VertexAttribPointer(loc, int32(arr.Dims), arr.Type, false, 0, nil)
+	bindAttrib(loc, arr.Dims, arr.Type, arr.Normalized, 0, 0, arr.Divisor)
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 }
 
@@ -224,97 +287,299 @@ func (arr *AttribArray) Clean() {
 	if arr == nil {
 		return
 	}
-	gl.DeleteBuffers(1, &arr.Buf)
+	if arr.ring != nil {
+		arr.ring.Clean()
+	} else {
+		gl.DeleteBuffers(1, &arr.Buf)
+	}
 	arr.Buf = 0
 }
 
+// TypedAttribArray is an AttribArray whose element type is fixed at compile
+// time, so that it cannot be updated with data of another type.
+type TypedAttribArray[T VertexElement] struct {
+	*AttribArray
+}
+
+// NewTypedAttribArray creates a new TypedAttribArray.
+func NewTypedAttribArray[T VertexElement](name string, dims int, data []T, usage uint32) (*TypedAttribArray[T], error) {
+	if err := checkElementDims(name, dims, data); err != nil {
+		return nil, err
+	}
+	var raw, typ, l = vertexBytes(data)
+	var arr, err = newAttribArray(name, dims, raw, typ, l, usage)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedAttribArray[T]{arr}, nil
+}
+
+// Update replaces the data at the start of the array, growing it if needed.
+// See AttribArray.Update.
+func (arr *TypedAttribArray[T]) Update(data []T) error {
+	var raw, _, l = vertexBytes(data)
+	return arr.update(raw, l)
+}
+
+// UpdateRange replaces the data of the array starting at element 'offset'.
+// See AttribArray.UpdateRange.
+func (arr *TypedAttribArray[T]) UpdateRange(offset int, data []T) error {
+	var raw, _, l = vertexBytes(data)
+	return arr.updateRange(offset, raw, l)
+}
+
+// Refill replaces all of the data in the array, growing it if needed. See
+// AttribArray.Refill.
+func (arr *TypedAttribArray[T]) Refill(data []T) error {
+	var raw, _, l = vertexBytes(data)
+	return arr.refill(raw, l)
+}
+
 // ElementArray is an attribute array specialized for element indices.
 type ElementArray struct {
-	Type uint32
-	Buf  uint32
-	Len  int
-	Cap  int
+	Type  uint32
+	Buf   uint32
+	Len   int
+	Cap   int
+	Usage uint32 // OpenGL usage hint of the buffer
+
+	// Restart enables primitive restart when drawing, so that the maximum
+	// value of the index type ends one strip, fan or loop and begins another.
+	// It is never inferred from the indices, as that value may also be a
+	// real vertex, and must be set by the caller.
+	Restart bool
+
+	ring     *RingBuffer  // the buffer of a streamed array, or nil
+	retained *ElementData // the Mesh's retained copy of the indices, or nil
+}
+
+// IndexElement is the set of element types of element indices.
+type IndexElement interface {
+	uint8 | uint16 | uint32
 }
 
-// NewElementArray creates an ElementArray
+// indexFormat returns the OpenGL datatype of an index element type.
+func indexFormat[T IndexElement]() uint32 {
+	var zero T
+	switch any(zero).(type) {
+	case uint8:
+		return gl.UNSIGNED_BYTE
+	case uint16:
+		return gl.UNSIGNED_SHORT
+	}
+	return gl.UNSIGNED_INT
+}
+
+// NewElementArray creates an ElementArray. 'data' must be a slice of one of
+// the IndexElement types. The buffer is allocated with the capacity of the
+// slice. NewTypedElementArray checks the type of the data at compile time
+// instead.
 func NewElementArray(data interface{}, usage uint32) (*ElementArray, error) {
-	var v = reflect.ValueOf(data)
-	if v.Kind() != reflect.Slice {
-		panic("asset.NewElementArray error: data is not a slice")
+	switch v := data.(type) {
+	case []uint8:
+		return newElementArray(rawBytes(v[:cap(v)]), gl.UNSIGNED_BYTE, len(v), cap(v), usage)
+	case []uint16:
+		return newElementArray(rawBytes(v[:cap(v)]), gl.UNSIGNED_SHORT, len(v), cap(v), usage)
+	case []uint32:
+		return newElementArray(rawBytes(v[:cap(v)]), gl.UNSIGNED_INT, len(v), cap(v), usage)
 	}
+	return nil, fmt.Errorf("asset.NewElementArray error: unhandled data type %T", data)
+}
 
-	var l = v.Len()
+// newElementArray creates an ElementArray from the memory of 'c' indices of
+// type 'typ', of which the first 'l' are used.
+func newElementArray(raw []byte, typ uint32, l, c int, usage uint32) (*ElementArray, error) {
 	if l == 0 {
 		return nil, errors.New("asset.NewElementArray error: data length is zero")
 	}
 
-	var buf uint32
-	gl.GenBuffers(1, &buf)
 	var arr = &ElementArray{
-		Buf: buf,
-		Len: l,
-		Cap: v.Cap(),
+		Type:  typ,
+		Len:   l,
+		Cap:   c,
+		Usage: usage,
 	}
 
-	var size int
+	gl.GenBuffers(1, &arr.Buf)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, arr.Buf)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(raw), gl.Ptr(raw), usage)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
+
+	return arr, nil
+}
+
+// newEmptyElementArray creates an ElementArray of indices of type 'typ' with
+// an empty buffer, to be filled by Update or Refill.
+func newEmptyElementArray(typ uint32, usage uint32) *ElementArray {
+	var arr = &ElementArray{Type: typ, Usage: usage}
+	gl.CreateBuffers(1, &arr.Buf)
+	return arr
+}
 
-	switch data.(type) {
+// indexBytes returns the memory of a slice of one of the IndexElement types,
+// its OpenGL datatype and its length.
+func indexBytes(data interface{}) ([]byte, uint32, int, error) {
+	switch v := data.(type) {
 	case []uint8:
-		arr.Type = gl.UNSIGNED_BYTE
-		size = 1
+		return rawBytes(v), gl.UNSIGNED_BYTE, len(v), nil
+	case []uint16:
+		return rawBytes(v), gl.UNSIGNED_SHORT, len(v), nil
 	case []uint32:
-		arr.Type = gl.UNSIGNED_INT
-		size = 4
-	default:
-		panic("asset.NewElementArray error: unhandled data type")
+		return rawBytes(v), gl.UNSIGNED_INT, len(v), nil
 	}
+	return nil, 0, 0, fmt.Errorf("unhandled data type %T", data)
+}
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, arr.Buf)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, arr.Len*size, unsafe.Pointer(v.Index(0).Addr().Pointer()), usage)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
-
-	return arr, nil
+// Update replaces the indices at the start of the ElementArray, which becomes
+// as long as the data. The data must be of the same type as the original. The
+// buffer grows if the data is longer than its capacity.
+func (arr *ElementArray) Update(data interface{}) error {
+	var raw, typ, l, err = indexBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.ElementArray.Update error: %v", err)
+	}
+	if l > 0 && arr.Type != typ {
+		return errors.New("asset.ElementArray.Update error: data type does not match array type")
+	}
+	return arr.update(raw, l)
 }
 
-// Update updates the data within the ElementArray. The data must be of the
-// same type as the original and no longer.
-func (arr *ElementArray) Update(data interface{}) {
-	var v = reflect.ValueOf(data)
-	if v.Kind() != reflect.Slice {
-		panic("AttribArray error: data is not a slice")
+// update replaces the start of the array with the memory of 'l' indices of
+// the array's type.
+func (arr *ElementArray) update(raw []byte, l int) error {
+	if arr.ring != nil {
+		return errors.New("asset.ElementArray.Update error: array is streamed")
 	}
 
-	var l = v.Len()
 	if l > arr.Cap {
-		panic("ElementArray error: data is larger than array")
+		arr.Cap = grownCap(arr.Cap, l)
+		resizeBuffer(arr.Buf, dataSize(arr.Type, arr.Cap), 0, arr.Usage)
 	}
-
 	arr.Len = l
+	arr.retain(0, raw, true)
+	if l == 0 {
+		return nil
+	}
 
-	var (
-		size int
-		typ  uint32
-		ptr  unsafe.Pointer
-	)
+	gl.NamedBufferSubData(arr.Buf, 0, len(raw), gl.Ptr(raw))
 
-	switch v := data.(type) {
-	case []uint8:
-		size = 1
-		typ = gl.UNSIGNED_BYTE
-		ptr = unsafe.Pointer(&v[0])
-	case []uint32:
-		size = 4
-		typ = gl.UNSIGNED_INT
-		ptr = unsafe.Pointer(&v[0])
+	return nil
+}
+
+// retain writes the memory of indices at byte 'offset' of the retained copy of
+// the array, if any, which ends with them if 'truncate' is set.
+func (arr *ElementArray) retain(offset int, raw []byte, truncate bool) {
+	if arr.retained != nil {
+		arr.retained.Data = splice(arr.retained.Data, offset, raw, truncate)
 	}
+}
 
-	if arr.Type != typ {
-		panic("asset.ElementArray.Update error: data type does not match array type")
+// UpdateRange replaces the indices of the ElementArray starting at index
+// 'offset', which must be no further than its length. The array is lengthened
+// if the data reaches past its end, and the buffer grows, keeping its
+// contents, if it would exceed its capacity.
+func (arr *ElementArray) UpdateRange(offset int, data interface{}) error {
+	var raw, typ, l, err = indexBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.ElementArray.UpdateRange error: %v", err)
 	}
+	if l > 0 && arr.Type != typ {
+		return errors.New("asset.ElementArray.UpdateRange error: data type does not match array type")
+	}
+	return arr.updateRange(offset, raw, l)
+}
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, arr.Buf)
-	gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, 0, arr.Len*size, ptr)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
+// updateRange replaces the memory of 'l' indices of the array's type starting
+// at index 'offset'.
+func (arr *ElementArray) updateRange(offset int, raw []byte, l int) error {
+	switch {
+	case arr.ring != nil:
+		return errors.New("asset.ElementArray.UpdateRange error: array is streamed")
+	case offset < 0 || offset > arr.Len:
+		return fmt.Errorf("asset.ElementArray.UpdateRange error: offset %d is outside length %d", offset, arr.Len)
+	}
+	if l == 0 {
+		return nil
+	}
+
+	var end = offset + l
+	if end > arr.Cap {
+		arr.Cap = grownCap(arr.Cap, end)
+		resizeBuffer(arr.Buf, dataSize(arr.Type, arr.Cap), dataSize(arr.Type, arr.Len), arr.Usage)
+	}
+	gl.NamedBufferSubData(arr.Buf, dataSize(arr.Type, offset), len(raw), gl.Ptr(raw))
+	arr.retain(dataSize(arr.Type, offset), raw, false)
+	arr.Len = max(arr.Len, end)
+
+	return nil
+}
+
+// Refill replaces all of the indices in the ElementArray, which must be of the
+// same type as the original but may be of any length. The old contents of the
+// buffer are orphaned, as by AttribArray.Refill.
+func (arr *ElementArray) Refill(data interface{}) error {
+	var raw, typ, l, err = indexBytes(data)
+	if err != nil {
+		return fmt.Errorf("asset.ElementArray.Refill error: %v", err)
+	}
+	if l > 0 && arr.Type != typ {
+		return errors.New("asset.ElementArray.Refill error: data type does not match array type")
+	}
+	return arr.refill(raw, l)
+}
+
+// refill replaces the array with the memory of 'l' indices of the array's
+// type, growing the buffer if needed.
+func (arr *ElementArray) refill(raw []byte, l int) error {
+	if arr.ring != nil {
+		return errors.New("asset.ElementArray.Refill error: array is streamed")
+	}
+
+	if l > arr.Cap {
+		arr.Cap = l
+	}
+	gl.NamedBufferData(arr.Buf, dataSize(arr.Type, arr.Cap), nil, arr.Usage)
+	if l > 0 {
+		gl.NamedBufferSubData(arr.Buf, 0, len(raw), gl.Ptr(raw))
+	}
+	arr.Len = l
+	arr.retain(0, raw, true)
+
+	return nil
+}
+
+// TypedElementArray is an ElementArray whose index type is fixed at compile
+// time, so that it cannot be updated with indices of another type.
+type TypedElementArray[T IndexElement] struct {
+	*ElementArray
+}
+
+// NewTypedElementArray creates a new TypedElementArray. The buffer is
+// allocated with the capacity of the slice.
+func NewTypedElementArray[T IndexElement](data []T, usage uint32) (*TypedElementArray[T], error) {
+	var arr, err = newElementArray(rawBytes(data[:cap(data)]), indexFormat[T](), len(data), cap(data), usage)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedElementArray[T]{arr}, nil
+}
+
+// Update replaces the indices at the start of the array, growing it if
+// needed. See ElementArray.Update.
+func (arr *TypedElementArray[T]) Update(data []T) error {
+	return arr.update(rawBytes(data), len(data))
+}
+
+// UpdateRange replaces the indices of the array starting at index 'offset'.
+// See ElementArray.UpdateRange.
+func (arr *TypedElementArray[T]) UpdateRange(offset int, data []T) error {
+	return arr.updateRange(offset, rawBytes(data), len(data))
+}
+
+// Refill replaces all of the indices in the array, orphaning the old ones. See
+// ElementArray.Refill.
+func (arr *TypedElementArray[T]) Refill(data []T) error {
+	return arr.refill(rawBytes(data), len(data))
 }
 
 // Init binds the element array.
@@ -327,6 +592,35 @@ func (arr *ElementArray) Clean() {
 	if arr == nil {
 		return
 	}
-	gl.DeleteBuffers(1, &arr.Buf)
+	if arr.ring != nil {
+		arr.ring.Clean()
+	} else {
+		gl.DeleteBuffers(1, &arr.Buf)
+	}
 	arr.Buf = 0
 }
+
+// grownCap returns the capacity to which a buffer of capacity 'c' grows to
+// hold 'l' elements, at least doubling it so that growing element by element
+// takes amortized constant time.
+func grownCap(c, l int) int {
+	return max(l, 2*c)
+}
+
+// resizeBuffer reallocates a mutable buffer to 'size' bytes, keeping the first
+// 'keep' bytes of its contents. The buffer keeps its handle, so vertex arrays
+// it is attached to need not be initialized again.
+func resizeBuffer(buf uint32, size, keep int, usage uint32) {
+	if keep == 0 {
+		gl.NamedBufferData(buf, size, nil, usage)
+		return
+	}
+
+	var tmp uint32
+	gl.CreateBuffers(1, &tmp)
+	gl.NamedBufferData(tmp, keep, nil, gl.STREAM_COPY)
+	gl.CopyNamedBufferSubData(buf, tmp, 0, 0, keep)
+	gl.NamedBufferData(buf, size, nil, usage)
+	gl.CopyNamedBufferSubData(tmp, buf, 0, 0, keep)
+	gl.DeleteBuffers(1, &tmp)
+}
@@ -0,0 +1,410 @@
+package asset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// VertexAttrib describes one attribute of an interleaved vertex.
+type VertexAttrib struct {
+	Name       string // attrib location name for linking with shader
+	Dims       int    // number of components
+	Type       uint32 // OpenGL datatype of the components
+	Normalized bool   // whether integer components are mapped to [0, 1] or [-1, 1]
+	Offset     int    // offset in bytes from the start of the vertex
+}
+
+// VertexLayout describes the attributes of an interleaved vertex and the
+// distance in bytes between consecutive vertices.
+type VertexLayout struct {
+	Attribs []VertexAttrib
+	Stride  int
+}
+
+// NewVertexLayout creates a VertexLayout from a set of attributes, packing them
+// in order. Each attribute is aligned to four bytes, and any Offsets given are
+// replaced.
+func NewVertexLayout(attribs ...VertexAttrib) *VertexLayout {
+	var layout = &VertexLayout{Attribs: append([]VertexAttrib(nil), attribs...)}
+
+	for i := range layout.Attribs {
+		var a = &layout.Attribs[i]
+		a.Offset = layout.Stride
+		layout.Stride += (attribSize(a.Type, a.Dims) + 3) &^ 3
+	}
+
+	return layout
+}
+
+// Attrib returns the attribute with the given name, if it exists.
+func (l *VertexLayout) Attrib(name string) (VertexAttrib, bool) {
+	for _, a := range l.Attribs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return VertexAttrib{}, false
+}
+
+// VertexBuffer is a buffer of interleaved vertices, each holding every
+// attribute of its VertexLayout. The raw vertex data is not retained, except
+// by Mesh.Retain, which the buffer then keeps up to date as it is written.
+type VertexBuffer struct {
+	Layout *VertexLayout
+	Buf    uint32 // the OpenGL buffer handle
+	Len    int    // the length of the buffer in vertices
+	Cap    int    // the maximum capacity of the buffer in vertices
+	Usage  uint32 // OpenGL usage hint of the buffer
+
+	// Bounds are the bounds of the "pos" attribute, if the layout has one,
+	// kept as the data is set.
+	Bounds Bounds
+
+	retained    *BufferData // the Mesh's retained copy of the data, or nil
+	retainedPos *AttribData // the Mesh's retained copy of only "pos", or nil
+}
+
+// NewVertexBuffer creates a VertexBuffer from interleaved vertex data, the
+// length of which must be a multiple of the layout's stride.
+func NewVertexBuffer(layout *VertexLayout, data []byte, usage uint32) (*VertexBuffer, error) {
+	if layout.Stride <= 0 {
+		return nil, errors.New("asset.NewVertexBuffer error: layout has no stride")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("asset.NewVertexBuffer error: data length is zero")
+	}
+	if len(data)%layout.Stride != 0 {
+		return nil, fmt.Errorf("asset.NewVertexBuffer error: length %d is not a multiple of stride %d", len(data), layout.Stride)
+	}
+
+	var vb = &VertexBuffer{
+		Layout: layout,
+		Len:    len(data) / layout.Stride,
+		Cap:    len(data) / layout.Stride,
+		Usage:  usage,
+	}
+
+	gl.GenBuffers(1, &vb.Buf)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vb.Buf)
+	gl.BufferData(gl.ARRAY_BUFFER, len(data), gl.Ptr(data), usage)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	vb.setBounds(data)
+
+	return vb, nil
+}
+
+// newEmptyVertexBuffer creates a VertexBuffer with an empty buffer, to be
+// filled by Update or Refill.
+func newEmptyVertexBuffer(layout *VertexLayout, usage uint32) *VertexBuffer {
+	var vb = &VertexBuffer{Layout: layout, Usage: usage, Bounds: EmptyBounds}
+	gl.CreateBuffers(1, &vb.Buf)
+	return vb
+}
+
+// setBounds sets the Bounds of the buffer from its data.
+func (vb *VertexBuffer) setBounds(data []byte) {
+	if a, ok := vb.Layout.Attrib("pos"); ok {
+		vb.Bounds = rawBounds(data, a.Type, a.Dims, vb.Layout.Stride, a.Offset)
+	}
+}
+
+// NewInterleavedBuffer interleaves per-attribute slices into a new
+// VertexBuffer. See Interleave.
+func NewInterleavedBuffer(layout *VertexLayout, arrays map[string]interface{}, usage uint32) (*VertexBuffer, error) {
+	var data, err = Interleave(layout, arrays)
+	if err != nil {
+		return nil, err
+	}
+	return NewVertexBuffer(layout, data, usage)
+}
+
+// Update replaces the vertices at the start of the VertexBuffer, which
+// becomes as long as the data. The buffer grows if the data is longer than its
+// capacity.
+func (vb *VertexBuffer) Update(data []byte) error {
+	if len(data)%vb.Layout.Stride != 0 {
+		return fmt.Errorf("asset.VertexBuffer.Update error: length %d is not a multiple of stride %d", len(data), vb.Layout.Stride)
+	}
+
+	var l = len(data) / vb.Layout.Stride
+	if l > vb.Cap {
+		vb.Cap = grownCap(vb.Cap, l)
+		resizeBuffer(vb.Buf, vb.Cap*vb.Layout.Stride, 0, vb.Usage)
+	}
+	vb.Len = l
+	vb.setBounds(data)
+	vb.retain(0, data, true)
+	if vb.Len == 0 {
+		return nil
+	}
+
+	gl.NamedBufferSubData(vb.Buf, 0, len(data), gl.Ptr(data))
+
+	return nil
+}
+
+// UpdateRange replaces the vertices of the VertexBuffer starting at vertex
+// 'offset', which must be no further than its length. The buffer is
+// lengthened if the data reaches past its end, and grows, keeping its
+// contents, if it would exceed its capacity.
+func (vb *VertexBuffer) UpdateRange(offset int, data []byte) error {
+	if len(data)%vb.Layout.Stride != 0 {
+		return fmt.Errorf("asset.VertexBuffer.UpdateRange error: length %d is not a multiple of stride %d", len(data), vb.Layout.Stride)
+	}
+	if offset < 0 || offset > vb.Len {
+		return fmt.Errorf("asset.VertexBuffer.UpdateRange error: offset %d is outside length %d", offset, vb.Len)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var end = offset + len(data)/vb.Layout.Stride
+	if end > vb.Cap {
+		vb.Cap = grownCap(vb.Cap, end)
+		resizeBuffer(vb.Buf, vb.Cap*vb.Layout.Stride, vb.Len*vb.Layout.Stride, vb.Usage)
+	}
+	gl.NamedBufferSubData(vb.Buf, offset*vb.Layout.Stride, len(data), gl.Ptr(data))
+	vb.retain(offset, data, false)
+
+	// The bounds of a partly replaced buffer can only grow.
+	if a, ok := vb.Layout.Attrib("pos"); ok {
+		var b = rawBounds(data, a.Type, a.Dims, vb.Layout.Stride, a.Offset)
+		if offset > 0 || end < vb.Len {
+			b = b.Union(vb.Bounds)
+		}
+		vb.Bounds = b
+	}
+	vb.Len = max(vb.Len, end)
+
+	return nil
+}
+
+// Refill replaces all of the vertices in the VertexBuffer, which may be of any
+// number. The old contents of the buffer are orphaned, as by
+// AttribArray.Refill.
+func (vb *VertexBuffer) Refill(data []byte) error {
+	if len(data)%vb.Layout.Stride != 0 {
+		return fmt.Errorf("asset.VertexBuffer.Refill error: length %d is not a multiple of stride %d", len(data), vb.Layout.Stride)
+	}
+
+	var l = len(data) / vb.Layout.Stride
+	if l > vb.Cap {
+		vb.Cap = l
+	}
+	gl.NamedBufferData(vb.Buf, vb.Cap*vb.Layout.Stride, nil, vb.Usage)
+	if l > 0 {
+		gl.NamedBufferSubData(vb.Buf, 0, len(data), gl.Ptr(data))
+	}
+	vb.Len = l
+	vb.setBounds(data)
+	vb.retain(0, data, true)
+
+	return nil
+}
+
+// retain writes vertices starting at vertex 'offset' of the retained copy of
+// the buffer, if any, which ends with them if 'truncate' is set. A copy of
+// only the positions receives just those.
+func (vb *VertexBuffer) retain(offset int, data []byte, truncate bool) {
+	if vb.retained != nil {
+		vb.retained.Data = splice(vb.retained.Data, offset*vb.Layout.Stride, data, truncate)
+	}
+	if vb.retainedPos != nil {
+		var a, _ = vb.Layout.Attrib("pos")
+		var size = attribSize(a.Type, a.Dims)
+		vb.retainedPos.Data = splice(vb.retainedPos.Data, offset*size, extractAttrib(data, vb.Layout.Stride, a), truncate)
+	}
+}
+
+// Init attaches each attribute of the VertexBuffer to the bound vertex array,
+// at the location found by 'loc'. Attributes without a location are skipped
+// and their names returned.
+func (vb *VertexBuffer) Init(loc func(name string) (uint32, bool)) []string {
+	var skipped []string
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, vb.Buf)
+	for _, a := range vb.Layout.Attribs {
+		var l, ok = loc(a.Name)
+		if !ok {
+			skipped = append(skipped, a.Name)
+			continue
+		}
+		bindAttrib(l, a.Dims, a.Type, a.Normalized, vb.Layout.Stride, a.Offset, 0)
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	return skipped
+}
+
+// Attribs returns the number of vertices in the buffer.
+func (vb *VertexBuffer) Attribs() int {
+	return vb.Len
+}
+
+// Clean deletes the buffer.
+func (vb *VertexBuffer) Clean() {
+	if vb == nil {
+		return
+	}
+	gl.DeleteBuffers(1, &vb.Buf)
+	vb.Buf = 0
+}
+
+// Interleave builds interleaved vertex data from a slice for each attribute of
+// a layout, keyed by attribute name. Slices are of the types accepted by
+// NewAttribArray. A slice whose type matches its attribute is copied as is;
+// float32 data is otherwise converted, scaling it for normalized integer
+// attributes and packing it for packed ones.
+func Interleave(layout *VertexLayout, arrays map[string]interface{}) ([]byte, error) {
+	type source struct {
+		VertexAttrib
+		raw []byte
+		typ uint32
+	}
+
+	var (
+		sources  = make([]source, len(layout.Attribs))
+		vertices = -1
+	)
+
+	for i, a := range layout.Attribs {
+		var data, ok = arrays[a.Name]
+		if !ok {
+			return nil, fmt.Errorf("asset.Interleave error: no data for attribute '%s'", a.Name)
+		}
+
+		var raw, typ, n, err = sliceBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("asset.Interleave error: attribute '%s': %v", a.Name, err)
+		}
+		if typ != a.Type && typ != gl.FLOAT {
+			return nil, fmt.Errorf("asset.Interleave error: attribute '%s' data cannot be converted", a.Name)
+		}
+		if isPacked(a.Type) && a.Dims != 4 {
+			return nil, fmt.Errorf("asset.Interleave error: packed attribute '%s' must have 4 components", a.Name)
+		}
+
+		if n%a.Dims != 0 {
+			return nil, AttribLenError(a.Name, n, a.Dims)
+		}
+		if vertices == -1 {
+			vertices = n / a.Dims
+		} else if n/a.Dims != vertices {
+			return nil, fmt.Errorf("asset.Interleave error: attribute '%s' has %d vertices, not %d", a.Name, n/a.Dims, vertices)
+		}
+
+		sources[i] = source{a, raw, typ}
+	}
+
+	if vertices <= 0 {
+		return nil, errors.New("asset.Interleave error: no vertices")
+	}
+
+	var (
+		out  = make([]byte, vertices*layout.Stride)
+		vec4 mgl.Vec4
+	)
+	for _, s := range sources {
+		var width = attribSize(s.typ, s.Dims)
+		for v := 0; v < vertices; v++ {
+			var (
+				src = s.raw[v*width : (v+1)*width]
+				dst = out[v*layout.Stride+s.Offset:]
+			)
+			if s.typ == s.Type {
+				copy(dst, src)
+				continue
+			}
+
+			for c := 0; c < s.Dims; c++ {
+				var f = math.Float32frombits(binary.NativeEndian.Uint32(src[4*c:]))
+				if isPacked(s.Type) {
+					vec4[c] = f
+					continue
+				}
+				putComponent(dst[c*typeSize(s.Type):], s.Type, s.Normalized, f)
+			}
+			switch s.Type {
+			case gl.INT_2_10_10_10_REV:
+				binary.NativeEndian.PutUint32(dst, uint32(PackInt2101010(vec4)))
+			case gl.UNSIGNED_INT_2_10_10_10_REV:
+				binary.NativeEndian.PutUint32(dst, uint32(PackUint2101010(vec4)))
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// putComponent writes a float32 component as the given datatype. Normalized
+// integers are scaled from [0, 1], or [-1, 1] if signed, and all integers are
+// clamped to their range.
+func putComponent(b []byte, typ uint32, normalized bool, f float32) {
+	var integer = func(lo, hi float64) float64 {
+		var v = float64(f)
+		if normalized {
+			v = math.Round(v * hi)
+		}
+		return math.Max(lo, math.Min(hi, v))
+	}
+
+	switch typ {
+	case gl.BYTE:
+		b[0] = byte(int8(integer(-127, 127)))
+	case gl.UNSIGNED_BYTE:
+		b[0] = byte(integer(0, 255))
+	case gl.SHORT:
+		binary.NativeEndian.PutUint16(b, uint16(int16(integer(-32767, 32767))))
+	case gl.UNSIGNED_SHORT:
+		binary.NativeEndian.PutUint16(b, uint16(integer(0, 65535)))
+	case gl.INT:
+		binary.NativeEndian.PutUint32(b, uint32(int32(integer(-2147483647, 2147483647))))
+	case gl.UNSIGNED_INT:
+		binary.NativeEndian.PutUint32(b, uint32(integer(0, 4294967295)))
+	case gl.HALF_FLOAT:
+		binary.NativeEndian.PutUint16(b, uint16(ToHalf(f)))
+	case gl.FLOAT:
+		binary.NativeEndian.PutUint32(b, math.Float32bits(f))
+	case gl.DOUBLE:
+		binary.NativeEndian.PutUint64(b, math.Float64bits(float64(f)))
+	}
+}
+
+// getComponent reads a component of the given datatype as a float32, scaling
+// normalized integers to [0, 1], or [-1, 1] if signed. Packed types are not
+// handled.
+func getComponent(b []byte, typ uint32, normalized bool) float32 {
+	var integer = func(v, max float64) float32 {
+		if normalized {
+			v = math.Max(-1, v/max)
+		}
+		return float32(v)
+	}
+
+	switch typ {
+	case gl.BYTE:
+		return integer(float64(int8(b[0])), 127)
+	case gl.UNSIGNED_BYTE:
+		return integer(float64(b[0]), 255)
+	case gl.SHORT:
+		return integer(float64(int16(binary.NativeEndian.Uint16(b))), 32767)
+	case gl.UNSIGNED_SHORT:
+		return integer(float64(binary.NativeEndian.Uint16(b)), 65535)
+	case gl.INT:
+		return integer(float64(int32(binary.NativeEndian.Uint32(b))), 2147483647)
+	case gl.UNSIGNED_INT:
+		return integer(float64(binary.NativeEndian.Uint32(b)), 4294967295)
+	case gl.HALF_FLOAT:
+		return Half(binary.NativeEndian.Uint16(b)).Float32()
+	case gl.FLOAT:
+		return math.Float32frombits(binary.NativeEndian.Uint32(b))
+	case gl.DOUBLE:
+		return float32(math.Float64frombits(binary.NativeEndian.Uint64(b)))
+	}
+	return 0
+}
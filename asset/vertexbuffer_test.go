@@ -0,0 +1,101 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewVertexLayout(t *testing.T) {
+	// Each attribute is aligned to four bytes.
+	var layout = NewVertexLayout(
+		VertexAttrib{Name: "pos", Dims: 3, Type: gl.FLOAT},
+		VertexAttrib{Name: "color", Dims: 3, Type: gl.UNSIGNED_BYTE, Normalized: true, Offset: 99},
+		VertexAttrib{Name: "normal", Dims: 4, Type: gl.INT_2_10_10_10_REV, Normalized: true},
+		VertexAttrib{Name: "texcoord0", Dims: 2, Type: gl.HALF_FLOAT},
+	)
+	if layout.Stride != 24 {
+		t.Errorf("stride %d, want 24", layout.Stride)
+	}
+	for i, want := range []int{0, 12, 16, 20} {
+		if got := layout.Attribs[i].Offset; got != want {
+			t.Errorf("attribute %d at offset %d, want %d", i, got, want)
+		}
+	}
+	if a, ok := layout.Attrib("normal"); !ok || a.Type != gl.INT_2_10_10_10_REV {
+		t.Errorf("Attrib(\"normal\") = %v, %v", a, ok)
+	}
+	if _, ok := layout.Attrib("tangent"); ok {
+		t.Error("found a missing attribute")
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	var layout = NewVertexLayout(
+		VertexAttrib{Name: "pos", Dims: 2, Type: gl.FLOAT},
+		VertexAttrib{Name: "color", Dims: 4, Type: gl.UNSIGNED_BYTE, Normalized: true},
+		VertexAttrib{Name: "normal", Dims: 4, Type: gl.UNSIGNED_INT_2_10_10_10_REV, Normalized: true},
+		VertexAttrib{Name: "id", Dims: 1, Type: gl.UNSIGNED_SHORT},
+	)
+	var out, err = Interleave(layout, map[string]interface{}{
+		"pos":    []mgl.Vec2{{1, 2}, {3, 4}},
+		"color":  []float32{1, 0, 0.5, 1, 0, 1, 0, 1},
+		"normal": []mgl.Vec4{{1, 0, 0, 1}, {0, 1, 0, 0}},
+		"id":     []uint16{7, 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		le   = binary.NativeEndian
+		want = make([]byte, 2*layout.Stride)
+	)
+	for v, vert := range []struct {
+		x, y   float32
+		color  [4]byte
+		normal Uint2101010
+		id     uint16
+	}{
+		{1, 2, [4]byte{255, 0, 128, 255}, PackUint2101010(mgl.Vec4{1, 0, 0, 1}), 7},
+		{3, 4, [4]byte{0, 255, 0, 255}, PackUint2101010(mgl.Vec4{0, 1, 0, 0}), 8},
+	} {
+		var b = want[v*layout.Stride:]
+		le.PutUint32(b, math.Float32bits(vert.x))
+		le.PutUint32(b[4:], math.Float32bits(vert.y))
+		copy(b[8:], vert.color[:])
+		le.PutUint32(b[12:], uint32(vert.normal))
+		le.PutUint16(b[16:], vert.id)
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+}
+
+func TestInterleaveInvalid(t *testing.T) {
+	var layout = NewVertexLayout(
+		VertexAttrib{Name: "pos", Dims: 3, Type: gl.FLOAT},
+		VertexAttrib{Name: "id", Dims: 1, Type: gl.UNSIGNED_SHORT},
+	)
+	for _, arrays := range []map[string]interface{}{
+		{"pos": []float32{0, 0, 0}},
+		{"pos": []float32{0, 0, 0}, "id": []int8{1}},
+		{"pos": []float32{0, 0}, "id": []uint16{1}},
+		{"pos": []float32{0, 0, 0, 1, 1, 1}, "id": []uint16{1}},
+		{"pos": []float32{}, "id": []uint16{}},
+		{"pos": []string{"a"}, "id": []uint16{1}},
+	} {
+		if _, err := Interleave(layout, arrays); err == nil {
+			t.Errorf("%v was interleaved", arrays)
+		}
+	}
+
+	var packed = NewVertexLayout(VertexAttrib{Name: "normal", Dims: 3, Type: gl.INT_2_10_10_10_REV})
+	if _, err := Interleave(packed, map[string]interface{}{"normal": []float32{0, 0, 1}}); err == nil {
+		t.Error("a packed attribute of 3 components was interleaved")
+	}
+}
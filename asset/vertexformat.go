@@ -0,0 +1,365 @@
+package asset
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Half is a 16-bit floating point number, for attributes of type
+// gl.HALF_FLOAT.
+type Half uint16
+
+// ToHalf converts a float32 to the nearest Half. Values too large for a Half
+// become infinite.
+func ToHalf(f float32) Half {
+	var (
+		bits = math.Float32bits(f)
+		sign = Half(bits>>16) & 0x8000
+		exp  = int(bits>>23&0xff) - 127 + 15
+		mant = bits & 0x7fffff
+	)
+
+	switch {
+	case bits&0x7fffffff > 0x7f800000: // NaN
+		return sign | 0x7e00
+	case exp >= 0x1f: // overflow or infinity
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		var (
+			shift = uint(14 - exp)
+			h     = mant >> shift
+		)
+		// Round to nearest, ties to even.
+		if rem := mant & (1<<shift - 1); rem > 1<<(shift-1) || rem == 1<<(shift-1) && h&1 == 1 {
+			h++
+		}
+		return sign | Half(h)
+	}
+
+	var h = uint32(exp)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || rem == 0x1000 && h&1 == 1 {
+		h++ // may carry into the exponent, which rounds up correctly
+	}
+	return sign | Half(h)
+}
+
+// Float32 converts the Half to a float32.
+func (h Half) Float32() float32 {
+	var (
+		sign = uint32(h&0x8000) << 16
+		exp  = uint32(h>>10) & 0x1f
+		mant = uint32(h & 0x3ff)
+	)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Normalize the subnormal.
+		exp = 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		mant &= 0x3ff
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// Int2101010 is a vertex packed into 32 bits as signed X, Y and Z of 10 bits
+// and W of 2 bits, for attributes of type gl.INT_2_10_10_10_REV.
+type Int2101010 uint32
+
+// PackInt2101010 packs a vector of values in [-1, 1] for a normalized
+// attribute.
+func PackInt2101010(v mgl32.Vec4) Int2101010 {
+	var pack = func(f float32, bits uint) uint32 {
+		var max = float64(int(1)<<(bits-1) - 1)
+		var i = int32(math.Round(math.Max(-1, math.Min(1, float64(f))) * max))
+		return uint32(i) & (1<<bits - 1)
+	}
+	return Int2101010(pack(v[0], 10) | pack(v[1], 10)<<10 | pack(v[2], 10)<<20 | pack(v[3], 2)<<30)
+}
+
+// Unpack returns the components of the vertex, mapped to [-1, 1] if
+// 'normalized' as OpenGL reads them.
+func (p Int2101010) Unpack(normalized bool) mgl32.Vec4 {
+	var unpack = func(shift, bits uint) float32 {
+		// Shift the field to the top and back to sign-extend it.
+		var i = int32(uint32(p)<<(32-shift-bits)) >> (32 - bits)
+		if !normalized {
+			return float32(i)
+		}
+		return float32(math.Max(-1, float64(i)/float64(int(1)<<(bits-1)-1)))
+	}
+	return mgl32.Vec4{unpack(0, 10), unpack(10, 10), unpack(20, 10), unpack(30, 2)}
+}
+
+// Uint2101010 is a vertex packed into 32 bits as unsigned X, Y and Z of 10
+// bits and W of 2 bits, for attributes of type
+// gl.UNSIGNED_INT_2_10_10_10_REV.
+type Uint2101010 uint32
+
+// PackUint2101010 packs a vector of values in [0, 1] for a normalized
+// attribute.
+func PackUint2101010(v mgl32.Vec4) Uint2101010 {
+	var pack = func(f float32, bits uint) uint32 {
+		var max = float64(int(1)<<bits - 1)
+		return uint32(math.Round(math.Max(0, math.Min(1, float64(f))) * max))
+	}
+	return Uint2101010(pack(v[0], 10) | pack(v[1], 10)<<10 | pack(v[2], 10)<<20 | pack(v[3], 2)<<30)
+}
+
+// Unpack returns the components of the vertex, mapped to [0, 1] if
+// 'normalized'.
+func (p Uint2101010) Unpack(normalized bool) mgl32.Vec4 {
+	var unpack = func(shift, bits uint) float32 {
+		var u = uint32(p) >> shift & (1<<bits - 1)
+		if !normalized {
+			return float32(u)
+		}
+		return float32(u) / float32(int(1)<<bits-1)
+	}
+	return mgl32.Vec4{unpack(0, 10), unpack(10, 10), unpack(20, 10), unpack(30, 2)}
+}
+
+// isPacked reports whether an OpenGL datatype packs four components into one
+// value.
+func isPacked(typ uint32) bool {
+	return typ == gl.INT_2_10_10_10_REV || typ == gl.UNSIGNED_INT_2_10_10_10_REV
+}
+
+// isInteger reports whether an OpenGL datatype is an unpacked integer type.
+func isInteger(typ uint32) bool {
+	switch typ {
+	case gl.BYTE, gl.UNSIGNED_BYTE, gl.SHORT, gl.UNSIGNED_SHORT, gl.INT, gl.UNSIGNED_INT:
+		return true
+	}
+	return false
+}
+
+// typeSize returns the size in bytes of a component of an OpenGL datatype.
+// Packed types are four bytes for all four components.
+func typeSize(typ uint32) int {
+	switch typ {
+	case gl.BYTE, gl.UNSIGNED_BYTE:
+		return 1
+	case gl.SHORT, gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2
+	case gl.INT, gl.UNSIGNED_INT, gl.FLOAT, gl.INT_2_10_10_10_REV, gl.UNSIGNED_INT_2_10_10_10_REV:
+		return 4
+	case gl.DOUBLE:
+		return 8
+	}
+	panic(fmt.Errorf("asset.typeSize error: unhandled datatype 0x%x", typ))
+}
+
+// dataSize returns the size in bytes of 'l' components of an OpenGL datatype.
+func dataSize(typ uint32, l int) int {
+	if isPacked(typ) {
+		return l
+	}
+	return l * typeSize(typ)
+}
+
+// attribSize returns the size in bytes of an attribute of 'dims' components.
+func attribSize(typ uint32, dims int) int {
+	if isPacked(typ) {
+		return 4
+	}
+	return dims * typeSize(typ)
+}
+
+// bindAttrib enables and describes an attribute of the bound array buffer at
+// 'loc', advancing once per 'divisor' instances, or per vertex if zero.
+// Attributes of more than four dimensions are matrices, whose columns of four,
+// or else three, components occupy consecutive locations.
+func bindAttrib(loc uint32, dims int, typ uint32, normalized bool, stride, offset int, divisor uint32) {
+	var cols, rows = 1, dims
+	if dims > 4 && !isPacked(typ) {
+		rows = 4
+		if dims%4 != 0 {
+			rows = 3
+		}
+		cols = dims / rows
+		if stride == 0 {
+			stride = attribSize(typ, dims)
+		}
+	}
+
+	for c := 0; c < cols; c++ {
+		var l = loc + uint32(c)
+		gl.EnableVertexAttribArray(l)
+		attribPointer(l, rows, typ, normalized, stride, offset+c*rows*typeSize(typ))
+		gl.VertexAttribDivisor(l, divisor)
+	}
+}
+
+// attribPointer describes an attribute of the bound array buffer. Doubles are
+// bound as double inputs and integers that are not normalized as integer
+// inputs; everything else is converted to floating point.
+func attribPointer(loc uint32, dims int, typ uint32, normalized bool, stride, offset int) {
+	switch {
+	case typ == gl.DOUBLE:
+		gl.VertexAttribLPointer(loc, int32(dims), typ, int32(stride), gl.PtrOffset(offset))
+	case isInteger(typ) && !normalized:
+		gl.VertexAttribIPointer(loc, int32(dims), typ, int32(stride), gl.PtrOffset(offset))
+	default:
+		gl.VertexAttribPointer(loc, int32(dims), typ, normalized, int32(stride), gl.PtrOffset(offset))
+	}
+}
+
+// VertexElement is the set of element types of vertex attribute data.
+type VertexElement interface {
+	int8 | uint8 | int16 | uint16 | int32 | uint32 |
+		Half | float32 | float64 |
+		Int2101010 | Uint2101010 |
+		mgl32.Vec2 | mgl32.Vec3 | mgl32.Vec4 | mgl32.Mat3 | mgl32.Mat4 |
+		mgl64.Vec2 | mgl64.Vec3 | mgl64.Vec4
+}
+
+// vertexFormat returns the OpenGL datatype of a vertex element type and the
+// number of components in each element.
+func vertexFormat[T VertexElement]() (uint32, int) {
+	var zero T
+	switch any(zero).(type) {
+	case int8:
+		return gl.BYTE, 1
+	case uint8:
+		return gl.UNSIGNED_BYTE, 1
+	case int16:
+		return gl.SHORT, 1
+	case uint16:
+		return gl.UNSIGNED_SHORT, 1
+	case int32:
+		return gl.INT, 1
+	case uint32:
+		return gl.UNSIGNED_INT, 1
+	case Half:
+		return gl.HALF_FLOAT, 1
+	case float32:
+		return gl.FLOAT, 1
+	case float64:
+		return gl.DOUBLE, 1
+	case Int2101010:
+		return gl.INT_2_10_10_10_REV, 4
+	case Uint2101010:
+		return gl.UNSIGNED_INT_2_10_10_10_REV, 4
+	case mgl32.Vec2:
+		return gl.FLOAT, 2
+	case mgl32.Vec3:
+		return gl.FLOAT, 3
+	case mgl32.Vec4:
+		return gl.FLOAT, 4
+	case mgl32.Mat3:
+		return gl.FLOAT, 9
+	case mgl32.Mat4:
+		return gl.FLOAT, 16
+	case mgl64.Vec2:
+		return gl.DOUBLE, 2
+	case mgl64.Vec3:
+		return gl.DOUBLE, 3
+	}
+	return gl.DOUBLE, 4
+}
+
+// elementDims returns the number of components in each element of a slice of
+// vectors or matrices, which fixes the dimensions of an attribute made from it,
+// or 0 for any other slice.
+func elementDims(data interface{}) int {
+	switch data.(type) {
+	case []mgl32.Vec2, []mgl64.Vec2:
+		return 2
+	case []mgl32.Vec3, []mgl64.Vec3:
+		return 3
+	case []mgl32.Vec4, []mgl64.Vec4:
+		return 4
+	case []mgl32.Mat3:
+		return 9
+	case []mgl32.Mat4:
+		return 16
+	}
+	return 0
+}
+
+// rawBytes returns the memory of a slice.
+func rawBytes[T any](v []T) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(v))), len(v)*int(unsafe.Sizeof(zero)))
+}
+
+// vertexBytes returns the memory of vertex data, its OpenGL datatype, and the
+// number of components it holds.
+func vertexBytes[T VertexElement](v []T) ([]byte, uint32, int) {
+	var typ, dims = vertexFormat[T]()
+	return rawBytes(v), typ, len(v) * dims
+}
+
+// sliceBytes returns the memory of a numeric slice, its OpenGL datatype, and
+// the number of components it holds.
+func sliceBytes(data interface{}) ([]byte, uint32, int, error) {
+	var (
+		raw []byte
+		typ uint32
+		n   int
+	)
+
+	switch v := data.(type) {
+	case []int8:
+		raw, typ, n = vertexBytes(v)
+	case []uint8:
+		raw, typ, n = vertexBytes(v)
+	case []int16:
+		raw, typ, n = vertexBytes(v)
+	case []uint16:
+		raw, typ, n = vertexBytes(v)
+	case []int32:
+		raw, typ, n = vertexBytes(v)
+	case []uint32:
+		raw, typ, n = vertexBytes(v)
+	case []Half:
+		raw, typ, n = vertexBytes(v)
+	case []float32:
+		raw, typ, n = vertexBytes(v)
+	case []float64:
+		raw, typ, n = vertexBytes(v)
+	case []Int2101010:
+		raw, typ, n = vertexBytes(v)
+	case []Uint2101010:
+		raw, typ, n = vertexBytes(v)
+	case []mgl32.Vec2:
+		raw, typ, n = vertexBytes(v)
+	case []mgl32.Vec3:
+		raw, typ, n = vertexBytes(v)
+	case []mgl32.Vec4:
+		raw, typ, n = vertexBytes(v)
+	case []mgl32.Mat3:
+		raw, typ, n = vertexBytes(v)
+	case []mgl32.Mat4:
+		raw, typ, n = vertexBytes(v)
+	case []mgl64.Vec2:
+		raw, typ, n = vertexBytes(v)
+	case []mgl64.Vec3:
+		raw, typ, n = vertexBytes(v)
+	case []mgl64.Vec4:
+		raw, typ, n = vertexBytes(v)
+	default:
+		return nil, 0, 0, fmt.Errorf("unhandled element type %T", data)
+	}
+
+	return raw, typ, n, nil
+}
@@ -0,0 +1,151 @@
+package asset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestToHalf(t *testing.T) {
+	var tests = []struct {
+		f    float32
+		want Half
+	}{
+		{0, 0},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65520, 0x7c00}, // rounds up to infinity
+		{float32(math.Inf(-1)), 0xfc00},
+		{float32(math.NaN()), 0x7e00},
+		{1.0 / (1 << 24), 0x0001},          // the smallest subnormal
+		{1.0 / (1 << 25), 0x0000},          // ties to even, down to zero
+		{3.0 / (1 << 25), 0x0002},          // ties to even, up
+		{1 + 1.0/(1<<11), 0x3c00},          // ties to even, down
+		{1 + 3.0/(1<<11), 0x3c02},          // ties to even, up
+		{1.0 / (1 << 14), 0x0400},          // the smallest normal
+		{1.0/(1<<14) - 1.0/(1<<24), 0x3ff}, // the largest subnormal
+	}
+	for _, tt := range tests {
+		if got := ToHalf(tt.f); got != tt.want {
+			t.Errorf("ToHalf(%v) = %#04x, want %#04x", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestHalfRoundTrip(t *testing.T) {
+	// Every Half but NaN converts to a float32 and back unchanged.
+	for h := 0; h <= 0xffff; h++ {
+		var f = Half(h).Float32()
+		if math.IsNaN(float64(f)) {
+			if h&0x7c00 != 0x7c00 || h&0x3ff == 0 {
+				t.Errorf("%#04x converted to NaN", h)
+			}
+			continue
+		}
+		if got := ToHalf(f); got != Half(h) {
+			t.Errorf("%#04x converted to %v and back to %#04x", h, f, got)
+		}
+	}
+}
+
+func TestPacked2101010(t *testing.T) {
+	var v = mgl32.Vec4{1, -1, 0.5, -1}
+	var p = PackInt2101010(v)
+	if got := p.Unpack(false); got != (mgl32.Vec4{511, -511, 256, -1}) {
+		t.Errorf("signed components %v", got)
+	}
+	if got := p.Unpack(true); !got.ApproxEqualThreshold(v, 1.0/511) {
+		t.Errorf("signed normalized %v, want %v", got, v)
+	}
+	// The most negative value is clamped to -1.
+	if got := Int2101010(0x200).Unpack(true)[0]; got != -1 {
+		t.Errorf("-512 normalized to %v", got)
+	}
+
+	var u = PackUint2101010(mgl32.Vec4{1, 0, 2, 0.4})
+	if u != 0x3ff|0x3ff<<20|1<<30 {
+		t.Errorf("unsigned packed to %#08x", uint32(u))
+	}
+	if got := u.Unpack(true); got != (mgl32.Vec4{1, 0, 1, 1.0 / 3}) {
+		t.Errorf("unsigned normalized %v", got)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	var tests = []struct {
+		typ        uint32
+		normalized bool
+		in, want   float32
+	}{
+		{gl.UNSIGNED_BYTE, true, 0.5, 128.0 / 255},
+		{gl.UNSIGNED_BYTE, true, 2, 1},
+		{gl.UNSIGNED_BYTE, false, 300, 255},
+		{gl.BYTE, true, -1, -1},
+		{gl.BYTE, false, -200, -127},
+		{gl.SHORT, true, -0.5, -16384.0 / 32767},
+		{gl.UNSIGNED_SHORT, false, 1000.4, 1000},
+		{gl.INT, false, -7, -7},
+		{gl.UNSIGNED_INT, false, -7, 0},
+		{gl.HALF_FLOAT, false, 0.1, Half(0x2e66).Float32()},
+		{gl.FLOAT, false, 0.1, 0.1},
+		{gl.DOUBLE, false, 0.1, 0.1},
+	}
+	for _, tt := range tests {
+		var b = make([]byte, 8)
+		putComponent(b, tt.typ, tt.normalized, tt.in)
+		if got := getComponent(b, tt.typ, tt.normalized); math.Abs(float64(got-tt.want)) > 1e-6 {
+			t.Errorf("type %#x, normalized %v: %v became %v, want %v", tt.typ, tt.normalized, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSliceBytes(t *testing.T) {
+	var tests = []struct {
+		data interface{}
+		typ  uint32
+		n    int
+		size int
+	}{
+		{[]uint8{1, 2, 3}, gl.UNSIGNED_BYTE, 3, 3},
+		{[]Half{1, 2}, gl.HALF_FLOAT, 2, 4},
+		{[]Uint2101010{1, 2}, gl.UNSIGNED_INT_2_10_10_10_REV, 8, 8},
+		{[]mgl32.Vec3{{}, {}}, gl.FLOAT, 6, 24},
+		{[]mgl32.Mat4{{}}, gl.FLOAT, 16, 64},
+	}
+	for _, tt := range tests {
+		var raw, typ, n, err = sliceBytes(tt.data)
+		if err != nil || typ != tt.typ || n != tt.n || len(raw) != tt.size {
+			t.Errorf("%T: got type %#x, %d components and %d bytes, %v", tt.data, typ, n, len(raw), err)
+		}
+	}
+	if _, _, _, err := sliceBytes([]string{"a"}); err == nil {
+		t.Error("a []string was accepted")
+	}
+}
+
+func TestCheckElementDims(t *testing.T) {
+	var tests = []struct {
+		dims int
+		data interface{}
+		ok   bool
+	}{
+		{3, []mgl32.Vec3{{}}, true},
+		{2, []mgl32.Vec3{{}}, false},
+		{4, []mgl32.Vec3{{}}, false},
+		{16, []mgl32.Mat4{{}}, true},
+		{4, []mgl32.Mat4{{}}, false},
+		{2, []mgl64.Vec2{{}}, true},
+		{2, []float32{0, 0}, true},
+		{4, []Int2101010{0}, true},
+	}
+	for _, tt := range tests {
+		if err := checkElementDims("a", tt.dims, tt.data); (err == nil) != tt.ok {
+			t.Errorf("%T with %d dimensions: got %v", tt.data, tt.dims, err)
+		}
+	}
+}
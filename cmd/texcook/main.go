@@ -0,0 +1,115 @@
+// Command texcook compresses images into DDS or KTX2 textures for the engine.
+//
+// Usage:
+//
+//	texcook [flags] image...
+//
+// Each input is written to the output directory with its extension replaced
+// by that of the container, which is chosen with -container.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ostsol/engine/texfmt"
+)
+
+var formats = map[string][2]texfmt.Format{
+	"bc1":   {texfmt.BC1, texfmt.BC1SRGB},
+	"bc3":   {texfmt.BC3, texfmt.BC3SRGB},
+	"bc4":   {texfmt.BC4, texfmt.BC4},
+	"bc5":   {texfmt.BC5, texfmt.BC5},
+	"bc7":   {texfmt.BC7, texfmt.BC7SRGB},
+	"rgba8": {texfmt.RGBA8, texfmt.RGBA8SRGB},
+}
+
+var writers = map[string]func(io.Writer, *texfmt.Image) error{
+	".dds":  texfmt.EncodeDDS,
+	".ktx2": texfmt.EncodeKTX2,
+}
+
+var (
+	format    = flag.String("format", "bc7", "output format: bc1, bc3, bc4, bc5, bc7 or rgba8")
+	container = flag.String("container", "ktx2", "output container: dds or ktx2")
+	outDir    = flag.String("o", ".", "output directory")
+	srgb      = flag.Bool("srgb", false, "treat colour channels as sRGB encoded")
+	noMips    = flag.Bool("nomips", false, "do not generate mip levels")
+	normal    = flag.Bool("normal", false, "renormalize mip levels of normal maps")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("texcook: ")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: texcook [flags] image...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var pair, ok = formats[strings.ToLower(*format)]
+	if !ok {
+		log.Fatalf("unknown format %q", *format)
+	}
+	var f = pair[0]
+	if *srgb {
+		f = pair[1]
+	}
+
+	var ext = "." + strings.ToLower(*container)
+	if _, ok := writers[ext]; !ok {
+		log.Fatalf("unknown container %q", *container)
+	}
+
+	var opts = &texfmt.CompressOptions{Mipmaps: !*noMips, Normalize: *normal}
+	for _, name := range flag.Args() {
+		var out = filepath.Join(*outDir, strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))+ext)
+		if err := cook(name, out, f, opts); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s -> %s (%v)", name, out, f)
+	}
+}
+
+// cook compresses one image file and writes the result to 'out'.
+func cook(name, out string, f texfmt.Format, opts *texfmt.CompressOptions) error {
+	var in, err = os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	src, _, err := image.Decode(in)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	img, err := texfmt.Compress(src, f, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	if err = writers[filepath.Ext(out)](file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %v", out, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,357 @@
+// Package ibl precomputes the textures used for image-based lighting from HDR
+// environment maps: cubemaps resampled from equirectangular panoramas, diffuse
+// irradiance maps, prefiltered specular mip chains and the split-sum BRDF
+// table. Like texfmt it does not depend on OpenGL; results are texfmt.Images
+// ready to be uploaded.
+package ibl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/Ostsol/engine/texfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// cubemap is a single level of RGB float cubemap, with faces in OpenGL order
+// and rows stored from the top.
+type cubemap struct {
+	size  int
+	faces [6][]float32
+}
+
+func newCubemap(size int) *cubemap {
+	var c = &cubemap{size: size}
+	for i := range c.faces {
+		c.faces[i] = make([]float32, size*size*3)
+	}
+	return c
+}
+
+// faceDir returns the unit direction through the point (s, t) of a face, with
+// s and t in [-1, 1], following the OpenGL cube map conventions.
+func faceDir(face int, s, t float32) mgl.Vec3 {
+	var d mgl.Vec3
+	switch face {
+	case 0:
+		d = mgl.Vec3{1, -t, -s}
+	case 1:
+		d = mgl.Vec3{-1, -t, s}
+	case 2:
+		d = mgl.Vec3{s, 1, t}
+	case 3:
+		d = mgl.Vec3{s, -1, -t}
+	case 4:
+		d = mgl.Vec3{s, -t, 1}
+	default:
+		d = mgl.Vec3{-s, -t, -1}
+	}
+	return d.Normalize()
+}
+
+// dirFace returns the face hit by a direction and the point on it, with s and
+// t in [0, 1].
+func dirFace(d mgl.Vec3) (face int, s, t float32) {
+	var (
+		ax, ay, az = abs(d[0]), abs(d[1]), abs(d[2])
+		sc, tc, ma float32
+	)
+
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if d[0] > 0 {
+			face, sc, tc = 0, -d[2], -d[1]
+		} else {
+			face, sc, tc = 1, d[2], -d[1]
+		}
+	case ay >= az:
+		ma = ay
+		if d[1] > 0 {
+			face, sc, tc = 2, d[0], d[2]
+		} else {
+			face, sc, tc = 3, d[0], -d[2]
+		}
+	default:
+		ma = az
+		if d[2] > 0 {
+			face, sc, tc = 4, d[0], -d[1]
+		} else {
+			face, sc, tc = 5, -d[0], -d[1]
+		}
+	}
+
+	return face, (sc/ma + 1) / 2, (tc/ma + 1) / 2
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// texelDir returns the direction through the centre of a texel.
+func (c *cubemap) texelDir(face, x, y int) mgl.Vec3 {
+	var (
+		s = 2*(float32(x)+0.5)/float32(c.size) - 1
+		t = 2*(float32(y)+0.5)/float32(c.size) - 1
+	)
+	return faceDir(face, s, t)
+}
+
+// sample bilinearly filters the cubemap in direction 'd'. Filtering does not
+// cross face edges.
+func (c *cubemap) sample(d mgl.Vec3) mgl.Vec3 {
+	var (
+		face, s, t = dirFace(d)
+		x, y       = s*float32(c.size) - 0.5, t*float32(c.size) - 0.5
+	)
+	return bilinear(c.faces[face], c.size, c.size, x, y, false)
+}
+
+// bilinear filters an RGB image at texel coordinates (x, y). Columns wrap if
+// 'wrap' is set, and are otherwise clamped; rows are always clamped.
+func bilinear(pix []float32, w, h int, x, y float32, wrap bool) mgl.Vec3 {
+	var (
+		x0 = int(math.Floor(float64(x)))
+		y0 = int(math.Floor(float64(y)))
+		fx = x - float32(x0)
+		fy = y - float32(y0)
+	)
+
+	var at = func(x, y int) mgl.Vec3 {
+		if wrap {
+			x = (x%w + w) % w
+		} else {
+			x = min(max(x, 0), w-1)
+		}
+		y = min(max(y, 0), h-1)
+		var p = pix[(y*w+x)*3:]
+		return mgl.Vec3{p[0], p[1], p[2]}
+	}
+
+	var (
+		top    = at(x0, y0).Mul(1 - fx).Add(at(x0+1, y0).Mul(fx))
+		bottom = at(x0, y0+1).Mul(1 - fx).Add(at(x0+1, y0+1).Mul(fx))
+	)
+	return top.Mul(1 - fy).Add(bottom.Mul(fy))
+}
+
+// downsample halves the size of the cubemap with a box filter.
+func (c *cubemap) downsample() *cubemap {
+	var out = newCubemap(max(c.size/2, 1))
+
+	for face := range c.faces {
+		var src, dst = c.faces[face], out.faces[face]
+		for y := 0; y < out.size; y++ {
+			for x := 0; x < out.size; x++ {
+				for ch := 0; ch < 3; ch++ {
+					var sum float32
+					for _, o := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+						var sx, sy = min(x*2+o[0], c.size-1), min(y*2+o[1], c.size-1)
+						sum += src[(sy*c.size+sx)*3+ch]
+					}
+					dst[(y*out.size+x)*3+ch] = sum / 4
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// resample returns the cubemap resampled to faces of size by size texels.
+func (c *cubemap) resample(size int) *cubemap {
+	var src = c
+	for src.size >= size*2 {
+		src = src.downsample()
+	}
+	if src.size == size {
+		return src
+	}
+
+	var out = newCubemap(size)
+	out.each(func(face, x, y int, d mgl.Vec3) mgl.Vec3 {
+		return src.sample(d)
+	})
+	return out
+}
+
+// each sets every texel of the cubemap to the result of 'fn', called with the
+// direction through the texel centre. Rows are processed in parallel.
+func (c *cubemap) each(fn func(face, x, y int, d mgl.Vec3) mgl.Vec3) {
+	parallel(6*c.size, func(row int) {
+		var face, y = row / c.size, row % c.size
+		for x := 0; x < c.size; x++ {
+			var v = fn(face, x, y, c.texelDir(face, x, y))
+			copy(c.faces[face][(y*c.size+x)*3:], v[:])
+		}
+	})
+}
+
+// parallel calls fn(i) for every i in [0, n) from a pool of goroutines.
+func parallel(n int, fn func(i int)) {
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int)
+	)
+
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// rgbFloats converts one surface of an image to RGB floats. Float formats are
+// used as is; RGBA8 is normalized, and RGBA8SRGB is also linearized.
+func rgbFloats(img *texfmt.Image, level, layer, face int) ([]float32, error) {
+	var (
+		src    = img.Slice(level, layer, face)
+		w, h   = img.LevelSize(level)
+		out    = make([]float32, w*h*3)
+		stride int
+		read   func(p []byte) float32
+	)
+
+	switch img.Format {
+	case texfmt.RGB32F, texfmt.RGBA32F:
+		stride = img.Format.BlockSize()
+		read = func(p []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(p)) }
+	case texfmt.RGBA16F:
+		stride = 8
+		read = func(p []byte) float32 { return halfToFloat(binary.LittleEndian.Uint16(p)) }
+	case texfmt.RGBA8:
+		stride = 4
+		read = func(p []byte) float32 { return float32(p[0]) / 255 }
+	case texfmt.RGBA8SRGB:
+		stride = 4
+		read = func(p []byte) float32 { return float32(math.Pow(float64(p[0])/255, 2.2)) }
+	default:
+		return nil, fmt.Errorf("ibl error: unsupported source format %v", img.Format)
+	}
+
+	var size = stride / 4
+	if img.Format == texfmt.RGB32F {
+		size = 4
+	}
+	for i := 0; i < w*h; i++ {
+		for c := 0; c < 3; c++ {
+			out[i*3+c] = read(src[i*stride+c*size:])
+		}
+	}
+
+	return out, nil
+}
+
+// halfToFloat converts an IEEE 754 half precision value.
+func halfToFloat(h uint16) float32 {
+	var (
+		sign = uint32(h>>15) << 31
+		exp  = int(h>>10) & 0x1f
+		man  = uint32(h) & 0x3ff
+	)
+
+	switch exp {
+	case 0:
+		var v = float32(math.Ldexp(float64(man), -24))
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | man<<13)
+	}
+	return math.Float32frombits(sign | uint32(exp-15+127)<<23 | man<<13)
+}
+
+// fromImage reads the base level of a cube map image.
+func fromImage(img *texfmt.Image) (*cubemap, error) {
+	if img.Faces != 6 || img.Width != img.Height {
+		return nil, fmt.Errorf("ibl error: image is not a cubemap")
+	}
+
+	var (
+		c   = &cubemap{size: img.Width}
+		err error
+	)
+	for face := range c.faces {
+		if c.faces[face], err = rgbFloats(img, 0, 0, face); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// toImage stores a chain of cubemap levels as an RGB32F cube map image.
+func toImage(levels ...*cubemap) *texfmt.Image {
+	var img = &texfmt.Image{
+		Format: texfmt.RGB32F,
+		Width:  levels[0].size,
+		Height: levels[0].size,
+		Layers: 1,
+		Faces:  6,
+		Levels: make([][]byte, len(levels)),
+	}
+
+	for i, c := range levels {
+		var data = make([]byte, 0, 6*c.size*c.size*12)
+		for _, face := range c.faces {
+			for _, v := range face {
+				data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+			}
+		}
+		img.Levels[i] = data
+	}
+
+	return img
+}
+
+// EquirectToCube resamples an equirectangular panorama into a cube map with
+// faces of size by size texels. The top row of the panorama is +Y and its
+// centre column faces -Z. The result is RGB32F.
+func EquirectToCube(src *texfmt.Image, size int) (*texfmt.Image, error) {
+	if src.Faces != 1 {
+		return nil, fmt.Errorf("ibl.EquirectToCube error: source is a cubemap")
+	}
+
+	var pix, err = rgbFloats(src, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out  = newCubemap(size)
+		w, h = src.Width, src.Height
+	)
+
+	// Sample a 2x2 grid within each texel so that panoramas larger than the
+	// cube are not aliased too badly.
+	out.each(func(face, x, y int, _ mgl.Vec3) mgl.Vec3 {
+		var sum mgl.Vec3
+		for _, o := range [4][2]float32{{0.25, 0.25}, {0.75, 0.25}, {0.25, 0.75}, {0.75, 0.75}} {
+			var (
+				d = faceDir(face, 2*(float32(x)+o[0])/float32(size)-1, 2*(float32(y)+o[1])/float32(size)-1)
+				u = 0.5 + float32(math.Atan2(float64(d[0]), float64(-d[2])))/(2*math.Pi)
+				v = float32(math.Acos(float64(mgl.Clamp(d[1], -1, 1)))) / math.Pi
+			)
+			sum = sum.Add(bilinear(pix, w, h, u*float32(w)-0.5, v*float32(h)-0.5, true))
+		}
+		return sum.Mul(0.25)
+	})
+
+	return toImage(out), nil
+}
@@ -0,0 +1,142 @@
+package ibl
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/Ostsol/engine/texfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// panorama returns an RGB32F equirectangular image, whose rows above the
+// horizon are 'sky' and below it 'ground'.
+func panorama(w, h int, sky, ground mgl.Vec3) *texfmt.Image {
+	var data []byte
+	for y := 0; y < h; y++ {
+		var c = ground
+		if y < h/2 {
+			c = sky
+		}
+		for x := 0; x < w; x++ {
+			for _, v := range c {
+				data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+			}
+		}
+	}
+	return &texfmt.Image{Format: texfmt.RGB32F, Width: w, Height: h, Layers: 1, Faces: 1, Levels: [][]byte{data}}
+}
+
+// texel returns the RGB of a texel of a level of an RGB32F cube map.
+func texel(img *texfmt.Image, level, face, x, y int) mgl.Vec3 {
+	var (
+		w, _ = img.LevelSize(level)
+		p    = img.Slice(level, 0, face)[(y*w+x)*12:]
+		v    mgl.Vec3
+	)
+	for c := range v {
+		v[c] = math.Float32frombits(binary.LittleEndian.Uint32(p[c*4:]))
+	}
+	return v
+}
+
+// uniform checks that every texel of every level of a cube map is 'want'.
+func uniform(t *testing.T, name string, img *texfmt.Image, want mgl.Vec3, eps float32) {
+	for level := range img.Levels {
+		var w, _ = img.LevelSize(level)
+		for face := 0; face < 6; face++ {
+			for i := 0; i < w*w; i++ {
+				if v := texel(img, level, face, i%w, i/w); !v.ApproxEqualThreshold(want, eps) {
+					t.Errorf("%s: level %d face %d texel %d is %v, want %v", name, level, face, i, v, want)
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestFaceDir(t *testing.T) {
+	for face := 0; face < 6; face++ {
+		for _, st := range [][2]float32{{0, 0}, {-0.5, 0.75}, {0.9, -0.3}} {
+			var f, s, tc = dirFace(faceDir(face, st[0], st[1]))
+			if f != face || !mgl.FloatEqualThreshold(2*s-1, st[0], 1e-5) || !mgl.FloatEqualThreshold(2*tc-1, st[1], 1e-5) {
+				t.Errorf("face %d %v: got face %d (%v, %v)", face, st, f, 2*s-1, 2*tc-1)
+			}
+		}
+	}
+}
+
+func TestEquirectToCube(t *testing.T) {
+	var (
+		sky, ground = mgl.Vec3{1, 2, 3}, mgl.Vec3{0.5, 0.25, 0}
+		cube, err   = EquirectToCube(panorama(64, 32, sky, ground), 8)
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cube.Faces != 6 || cube.Width != 8 || cube.Format != texfmt.RGB32F {
+		t.Fatalf("got %v %dx%d with %d faces", cube.Format, cube.Width, cube.Height, cube.Faces)
+	}
+	// The top of the panorama is +Y.
+	if v := texel(cube, 0, 2, 4, 4); v != sky {
+		t.Errorf("+Y is %v, want %v", v, sky)
+	}
+	if v := texel(cube, 0, 3, 4, 4); v != ground {
+		t.Errorf("-Y is %v, want %v", v, ground)
+	}
+}
+
+func TestIrradianceUniform(t *testing.T) {
+	// A uniform environment of radiance L has irradiance pi*L everywhere,
+	// which is stored divided by pi.
+	var (
+		l        = mgl.Vec3{0.2, 0.4, 0.8}
+		cube, _  = EquirectToCube(panorama(32, 16, l, l), 8)
+		irr, err = Irradiance(cube, 4)
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uniform(t, "irradiance", irr, l, 1e-3)
+}
+
+func TestPrefilterUniform(t *testing.T) {
+	var (
+		l         = mgl.Vec3{1, 0.5, 0.25}
+		cube, _   = EquirectToCube(panorama(32, 16, l, l), 16)
+		spec, err = Prefilter(cube, 16, 5, 32)
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Levels) != 5 {
+		t.Fatalf("got %d levels, want 5", len(spec.Levels))
+	}
+	uniform(t, "prefilter", spec, l, 1e-4)
+
+	if _, err = Prefilter(cube, 16, 6, 32); err == nil {
+		t.Error("6 levels were filtered for a size of 16")
+	}
+}
+
+func TestBRDFLUT(t *testing.T) {
+	var (
+		size = 16
+		lut  = BRDFLUT(size, 256)
+		at   = func(x, y int) (scale, bias float32) {
+			var p = lut.Levels[0][(y*size+x)*16:]
+			return math.Float32frombits(binary.LittleEndian.Uint32(p)), math.Float32frombits(binary.LittleEndian.Uint32(p[4:]))
+		}
+	)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if s, b := at(x, y); s < 0 || b < 0 || s+b > 1.01 {
+				t.Errorf("texel (%d, %d): scale %v and bias %v", x, y, s, b)
+			}
+		}
+	}
+	// Smooth surfaces seen head on reflect F0 itself.
+	if s, b := at(size-1, 0); s < 0.95 || b > 0.05 {
+		t.Errorf("smooth head-on: scale %v and bias %v", s, b)
+	}
+}
@@ -0,0 +1,103 @@
+package ibl
+
+import (
+	"math"
+
+	"github.com/Ostsol/engine/texfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// shBasis evaluates the nine real spherical harmonics of bands 0 to 2.
+func shBasis(d mgl.Vec3) [9]float32 {
+	var x, y, z = d[0], d[1], d[2]
+	return [9]float32{
+		0.282095,
+		0.488603 * y,
+		0.488603 * z,
+		0.488603 * x,
+		1.092548 * x * y,
+		1.092548 * y * z,
+		0.315392 * (3*z*z - 1),
+		1.092548 * x * z,
+		0.546274 * (x*x - y*y),
+	}
+}
+
+// areaElement is the integral used to compute the solid angle of a texel.
+func areaElement(x, y float64) float64 {
+	return math.Atan2(x*y, math.Sqrt(x*x+y*y+1))
+}
+
+// texelSolidAngle returns the solid angle subtended by a texel of a face of
+// size by size texels.
+func texelSolidAngle(x, y, size int) float32 {
+	var (
+		inv    = 1 / float64(size)
+		x0, y0 = 2*float64(x)*inv - 1, 2*float64(y)*inv - 1
+		x1, y1 = x0 + 2*inv, y0 + 2*inv
+	)
+	return float32(areaElement(x0, y0) - areaElement(x0, y1) - areaElement(x1, y0) + areaElement(x1, y1))
+}
+
+// Irradiance computes the diffuse irradiance of an environment cube map into a
+// cube map with faces of size by size texels. The irradiance is approximated
+// by nine spherical harmonic coefficients and divided by pi, so that diffuse
+// lighting is the albedo multiplied by the sampled value. The result is RGB32F.
+func Irradiance(env *texfmt.Image, size int) (*texfmt.Image, error) {
+	var src, err = fromImage(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// The projection needs little resolution, and larger sources are slow.
+	src = src.resample(min(src.size, 64))
+
+	var sh [9]mgl.Vec3
+	for face, pix := range src.faces {
+		for y := 0; y < src.size; y++ {
+			for x := 0; x < src.size; x++ {
+				var (
+					d     = src.texelDir(face, x, y)
+					p     = pix[(y*src.size+x)*3:]
+					c     = mgl.Vec3{p[0], p[1], p[2]}
+					basis = shBasis(d)
+					omega = texelSolidAngle(x, y, src.size)
+				)
+				for i, b := range basis {
+					sh[i] = sh[i].Add(c.Mul(b * omega))
+				}
+			}
+		}
+	}
+
+	// Convolve with the clamped cosine lobe, and divide by pi.
+	for i := range sh {
+		var band float32
+		switch {
+		case i == 0:
+			band = 1
+		case i < 4:
+			band = 2.0 / 3
+		default:
+			band = 1.0 / 4
+		}
+		sh[i] = sh[i].Mul(band)
+	}
+
+	var out = newCubemap(size)
+	out.each(func(face, x, y int, d mgl.Vec3) mgl.Vec3 {
+		var (
+			e     mgl.Vec3
+			basis = shBasis(d)
+		)
+		for i, b := range basis {
+			e = e.Add(sh[i].Mul(b))
+		}
+		for c := range e {
+			e[c] = max(e[c], 0)
+		}
+		return e
+	})
+
+	return toImage(out), nil
+}
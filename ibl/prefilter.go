@@ -0,0 +1,209 @@
+package ibl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/Ostsol/engine/texfmt"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// hammersley returns point i of an n point Hammersley sequence.
+func hammersley(i, n int) (float32, float32) {
+	return float32(i) / float32(n), float32(bits.Reverse32(uint32(i))) / (1 << 32)
+}
+
+// importanceSampleGGX returns a half vector distributed according to the GGX
+// distribution of the given roughness, in tangent space with the normal at +Z.
+func importanceSampleGGX(u, v, roughness float32) mgl.Vec3 {
+	var (
+		a      = roughness * roughness
+		phi    = 2 * math.Pi * float64(u)
+		cos    = math.Sqrt(float64((1 - v) / (1 + (a*a-1)*v)))
+		sin    = math.Sqrt(1 - cos*cos)
+		sp, cp = math.Sincos(phi)
+	)
+	return mgl.Vec3{float32(sin * cp), float32(sin * sp), float32(cos)}
+}
+
+// tangentFrame returns two vectors perpendicular to n and to each other.
+func tangentFrame(n mgl.Vec3) (mgl.Vec3, mgl.Vec3) {
+	var up = mgl.Vec3{0, 0, 1}
+	if abs(n[2]) > 0.999 {
+		up = mgl.Vec3{1, 0, 0}
+	}
+	var t = up.Cross(n).Normalize()
+	return t, n.Cross(t)
+}
+
+// specularSample is a light direction in tangent space with its weight and
+// the source level to sample it from.
+type specularSample struct {
+	l      mgl.Vec3
+	weight float32
+	lod    float32
+}
+
+// specularSamples returns the light directions used to prefilter a level of
+// the given roughness, assuming that the view direction equals the normal.
+// Each sample reads from a blurrier source level the less likely it is, which
+// removes most of the noise of a small sample count.
+func specularSamples(roughness float32, count, size int) []specularSample {
+	var (
+		out      = make([]specularSample, 0, count)
+		a2       = float64(roughness * roughness * roughness * roughness)
+		texelSA  = 4 * math.Pi / (6 * float64(size*size))
+		maxLevel = math.Log2(float64(size))
+	)
+
+	for i := 0; i < count; i++ {
+		var (
+			u, v = hammersley(i, count)
+			h    = importanceSampleGGX(u, v, roughness)
+			ndh  = float64(h[2])
+			l    = h.Mul(2 * h[2]).Sub(mgl.Vec3{0, 0, 1})
+			ndl  = l[2]
+		)
+		if ndl <= 0 {
+			continue
+		}
+
+		// With the view along the normal the pdf of l is D(h) / 4.
+		var (
+			denom    = ndh*ndh*(a2-1) + 1
+			d        = a2 / (math.Pi * denom * denom)
+			sampleSA = 1 / (float64(count) * d / 4)
+			lod      = 0.0
+		)
+		if roughness > 0 {
+			lod = math.Min(math.Max(0.5*math.Log2(sampleSA/texelSA)+1, 0), maxLevel)
+		}
+
+		out = append(out, specularSample{l, ndl, float32(lod)})
+	}
+
+	return out
+}
+
+// sampleLevel trilinearly filters a chain of cubemap levels.
+func sampleLevel(chain []*cubemap, d mgl.Vec3, lod float32) mgl.Vec3 {
+	var (
+		l0 = min(int(lod), len(chain)-1)
+		l1 = min(l0+1, len(chain)-1)
+		f  = lod - float32(l0)
+	)
+	if l0 == l1 || f == 0 {
+		return chain[l0].sample(d)
+	}
+	return chain[l0].sample(d).Mul(1 - f).Add(chain[l1].sample(d).Mul(f))
+}
+
+// Prefilter computes the specular mip chain of an environment cube map for
+// the GGX distribution, with a base level of size by size texels. Level i is
+// filtered for a roughness of i/(levels-1), so level 0 is the environment
+// itself; shaders select the level with textureLod and roughness*(levels-1).
+// 'samples' is the number of importance samples per texel. The result is
+// RGB32F.
+func Prefilter(env *texfmt.Image, size, levels, samples int) (*texfmt.Image, error) {
+	if levels < 1 || size>>uint(levels-1) < 1 {
+		return nil, fmt.Errorf("ibl.Prefilter error: %d levels do not fit a size of %d", levels, size)
+	}
+
+	var src, err = fromImage(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain = []*cubemap{src.resample(size)}
+	for chain[len(chain)-1].size > 1 {
+		chain = append(chain, chain[len(chain)-1].downsample())
+	}
+
+	var out = []*cubemap{chain[0]}
+	for level := 1; level < levels; level++ {
+		var (
+			roughness = float32(level) / float32(levels-1)
+			set       = specularSamples(roughness, samples, size)
+			c         = newCubemap(size >> uint(level))
+		)
+
+		c.each(func(face, x, y int, n mgl.Vec3) mgl.Vec3 {
+			var (
+				t, b  = tangentFrame(n)
+				sum   mgl.Vec3
+				total float32
+			)
+			for _, s := range set {
+				var l = t.Mul(s.l[0]).Add(b.Mul(s.l[1])).Add(n.Mul(s.l[2]))
+				sum = sum.Add(sampleLevel(chain, l, s.lod).Mul(s.weight))
+				total += s.weight
+			}
+			if total == 0 {
+				return mgl.Vec3{}
+			}
+			return sum.Mul(1 / total)
+		})
+
+		out = append(out, c)
+	}
+
+	return toImage(out...), nil
+}
+
+// smithGGX is the Smith geometry term for image-based lighting.
+func smithGGX(ndv, ndl, roughness float32) float32 {
+	var k = roughness * roughness / 2
+	return ndv / (ndv*(1-k) + k) * ndl / (ndl*(1-k) + k)
+}
+
+// BRDFLUT computes the split-sum table of the GGX specular term, indexed by
+// N·V along x and roughness along y, into a size by size RGBA32F image. Red
+// is the scale and green the bias applied to F0.
+func BRDFLUT(size, samples int) *texfmt.Image {
+	var data = make([]byte, size*size*16)
+
+	parallel(size, func(y int) {
+		var roughness = (float32(y) + 0.5) / float32(size)
+		for x := 0; x < size; x++ {
+			var (
+				ndv      = (float32(x) + 0.5) / float32(size)
+				v        = mgl.Vec3{float32(math.Sqrt(float64(1 - ndv*ndv))), 0, ndv}
+				scale, b float32
+			)
+
+			for i := 0; i < samples; i++ {
+				var (
+					s, t = hammersley(i, samples)
+					h    = importanceSampleGGX(s, t, roughness)
+					vdh  = v.Dot(h)
+					l    = h.Mul(2 * vdh).Sub(v)
+				)
+				if l[2] <= 0 {
+					continue
+				}
+				var (
+					vis = smithGGX(ndv, l[2], roughness) * vdh / (h[2] * ndv)
+					fc  = float32(math.Pow(float64(1-vdh), 5))
+				)
+				scale += (1 - fc) * vis
+				b += fc * vis
+			}
+
+			var p = data[(y*size+x)*16:]
+			binary.LittleEndian.PutUint32(p[0:], math.Float32bits(scale/float32(samples)))
+			binary.LittleEndian.PutUint32(p[4:], math.Float32bits(b/float32(samples)))
+			binary.LittleEndian.PutUint32(p[12:], math.Float32bits(1))
+		}
+	})
+
+	return &texfmt.Image{
+		Format: texfmt.RGBA32F,
+		Width:  size,
+		Height: size,
+		Layers: 1,
+		Faces:  1,
+		Levels: [][]byte{data},
+	}
+}
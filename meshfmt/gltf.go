@@ -0,0 +1,1203 @@
+package meshfmt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// GLTF is the content of a glTF 2.0 file: its meshes, materials, textures and
+// images, and the scene graph with cameras, skins and animations. Objects
+// refer to each other by their index in the GLTF's slices, with -1 for none.
+type GLTF struct {
+	Scenes    []*Scene
+	Scene     int // the default scene
+	Nodes     []*Node
+	Meshes    []*MeshGroup
+	Materials []*PBRMaterial
+	Textures  []*Texture
+	Images    []*Image
+	Cameras   []*Camera
+	Skins     []*Skin
+
+	Animations []*Animation
+}
+
+// Scene is a set of root nodes.
+type Scene struct {
+	Name  string
+	Nodes []int
+}
+
+// Node is an element of the scene graph. Its local transform is Matrix, or the
+// combination of Translation, Rotation and Scale; the other is the identity.
+type Node struct {
+	Name     string
+	Parent   int
+	Children []int
+	Mesh     int
+	Skin     int
+	Camera   int
+
+	Matrix      mgl.Mat4
+	Translation mgl.Vec3
+	Rotation    mgl.Quat
+	Scale       mgl.Vec3
+	Weights     []float32 // morph target weights
+}
+
+// Local returns the node's transform relative to its parent.
+func (n *Node) Local() mgl.Mat4 {
+	var trs = mgl.Translate3D(n.Translation[0], n.Translation[1], n.Translation[2]).
+		Mul4(n.Rotation.Mat4()).
+		Mul4(mgl.Scale3D(n.Scale[0], n.Scale[1], n.Scale[2]))
+	return n.Matrix.Mul4(trs)
+}
+
+// World returns the transform of a node relative to the scene.
+func (g *GLTF) World(node int) mgl.Mat4 {
+	var m = mgl.Ident4()
+	for ; node >= 0; node = g.Nodes[node].Parent {
+		m = g.Nodes[node].Local().Mul4(m)
+	}
+	return m
+}
+
+// MeshGroup is a glTF mesh: a set of primitives, each with its own material.
+type MeshGroup struct {
+	Name       string
+	Primitives []*Mesh
+	Materials  []int // material of each primitive
+}
+
+// PBRMaterial is a metallic-roughness material. Factors multiply the values
+// read from their textures.
+type PBRMaterial struct {
+	Name string
+
+	BaseColor                mgl.Vec4
+	BaseColorTexture         TextureInfo
+	Metallic, Roughness      float32
+	MetallicRoughnessTexture TextureInfo // metalness in blue, roughness in green
+	NormalTexture            TextureInfo
+	OcclusionTexture         TextureInfo // occlusion in red; Scale is the strength
+	Emissive                 mgl.Vec3
+	EmissiveTexture          TextureInfo
+
+	AlphaMode   string // "OPAQUE", "MASK" or "BLEND"
+	AlphaCutoff float32
+	DoubleSided bool
+}
+
+// TextureInfo refers to a texture from a material.
+type TextureInfo struct {
+	Texture  int     // index of the texture, or -1
+	TexCoord int     // texture coordinate set
+	Scale    float32 // normal scale or occlusion strength
+}
+
+// Texture is an image with sampling parameters. Filters and wrap modes use
+// OpenGL's constants; zero means unspecified.
+type Texture struct {
+	Name                 string
+	Image                int
+	MagFilter, MinFilter int
+	WrapS, WrapT         int
+}
+
+// Image is the encoded data of an image, such as a PNG or JPEG file. Images
+// stored outside the glTF file have their URI set and no Data.
+type Image struct {
+	Name     string
+	URI      string
+	MimeType string
+	Data     []byte
+}
+
+// Camera is a perspective or orthographic projection. It looks down its
+// node's -Z axis.
+type Camera struct {
+	Name         string
+	Orthographic bool
+	AspectRatio  float32 // 0 if unspecified
+	YFov         float32 // vertical field of view in radians
+	XMag, YMag   float32 // half extents of orthographic views
+	ZNear, ZFar  float32 // ZFar is 0 for infinite perspective projections
+}
+
+// Projection returns the camera's projection matrix. 'aspect' is used if the
+// camera does not specify an aspect ratio.
+func (c *Camera) Projection(aspect float32) mgl.Mat4 {
+	if c.Orthographic {
+		return mgl.Ortho(-c.XMag, c.XMag, -c.YMag, c.YMag, c.ZNear, c.ZFar)
+	}
+	if c.AspectRatio > 0 {
+		aspect = c.AspectRatio
+	}
+	if c.ZFar > 0 {
+		return mgl.Perspective(c.YFov, aspect, c.ZNear, c.ZFar)
+	}
+
+	var f = float32(1 / math.Tan(float64(c.YFov)/2))
+	return mgl.Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, -1, -1,
+		0, 0, -2 * c.ZNear, 0,
+	}
+}
+
+// Skin binds a mesh to a skeleton of joint nodes.
+type Skin struct {
+	Name                string
+	Joints              []int
+	InverseBindMatrices []mgl.Mat4 // for each joint
+	Skeleton            int        // common root of the joints, or -1
+}
+
+// Animation is a set of channels animating node properties.
+type Animation struct {
+	Name     string
+	Channels []Channel
+	Samplers []AnimationSampler
+}
+
+// Channel animates a property of a node: "translation", "rotation", "scale"
+// or "weights".
+type Channel struct {
+	Node    int
+	Path    string
+	Sampler int
+}
+
+// AnimationSampler holds keyframes. For cubic spline interpolation, each
+// keyframe has an in-tangent, a value and an out-tangent.
+type AnimationSampler struct {
+	Times         []float32
+	Values        []float32
+	Components    int    // values per keyframe element
+	Interpolation string // "LINEAR", "STEP" or "CUBICSPLINE"
+}
+
+// gltfDoc is the JSON structure of a glTF file, as far as it is read.
+type gltfDoc struct {
+	Asset struct {
+		Version    string
+		MinVersion string
+	}
+	ExtensionsRequired []string
+	Scene              *int
+	Scenes             []struct {
+		Name  string
+		Nodes []int
+	}
+	Nodes []struct {
+		Name        string
+		Children    []int
+		Mesh        *int
+		Skin        *int
+		Camera      *int
+		Matrix      *mgl.Mat4
+		Translation *mgl.Vec3
+		Rotation    *mgl.Vec4
+		Scale       *mgl.Vec3
+		Weights     []float32
+	}
+	Meshes []struct {
+		Name       string
+		Primitives []struct {
+			Attributes map[string]int
+			Indices    *int
+			Material   *int
+			Mode       *int
+		}
+	}
+	Accessors []struct {
+		BufferView    *int
+		ByteOffset    int
+		ComponentType int
+		Normalized    bool
+		Count         int
+		Type          string
+		Sparse        *struct {
+			Count   int
+			Indices struct {
+				BufferView    int
+				ByteOffset    int
+				ComponentType int
+			}
+			Values struct {
+				BufferView int
+				ByteOffset int
+			}
+		}
+	}
+	BufferViews []struct {
+		Buffer     int
+		ByteOffset int
+		ByteLength int
+		ByteStride int
+	}
+	Buffers []struct {
+		URI        string
+		ByteLength int
+	}
+	Materials []struct {
+		Name                 string
+		PBRMetallicRoughness *struct {
+			BaseColorFactor          *mgl.Vec4
+			BaseColorTexture         *gltfTextureInfo
+			MetallicFactor           *float32
+			RoughnessFactor          *float32
+			MetallicRoughnessTexture *gltfTextureInfo
+		}
+		NormalTexture    *gltfTextureInfo
+		OcclusionTexture *gltfTextureInfo
+		EmissiveTexture  *gltfTextureInfo
+		EmissiveFactor   mgl.Vec3
+		AlphaMode        string
+		AlphaCutoff      *float32
+		DoubleSided      bool
+	}
+	Textures []struct {
+		Name    string
+		Sampler *int
+		Source  *int
+	}
+	Images []struct {
+		Name       string
+		URI        string
+		MimeType   string
+		BufferView *int
+	}
+	Samplers []struct {
+		MagFilter, MinFilter int
+		WrapS, WrapT         int
+	}
+	Cameras []struct {
+		Name        string
+		Type        string
+		Perspective struct {
+			AspectRatio float32
+			YFov        float32
+			ZNear, ZFar float32
+		}
+		Orthographic struct {
+			XMag, YMag  float32
+			ZNear, ZFar float32
+		}
+	}
+	Skins []struct {
+		Name                string
+		InverseBindMatrices *int
+		Skeleton            *int
+		Joints              []int
+	}
+	Animations []struct {
+		Name     string
+		Channels []struct {
+			Sampler int
+			Target  struct {
+				Node *int
+				Path string
+			}
+		}
+		Samplers []struct {
+			Input, Output int
+			Interpolation string
+		}
+	}
+}
+
+type gltfTextureInfo struct {
+	Index    int
+	TexCoord int
+	Scale    *float32
+	Strength *float32
+}
+
+// gltfSupported lists the extensions that may be required by files read.
+var gltfSupported = map[string]bool{
+	"KHR_mesh_quantization": true,
+}
+
+// gltfComponents is the number of components of each accessor type.
+var gltfComponents = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+// glbMagic begins binary glTF files.
+var glbMagic = []byte("glTF")
+
+// ref converts an optional index into an index or -1.
+func ref(i *int) int {
+	if i == nil {
+		return -1
+	}
+	return *i
+}
+
+// DecodeGLTF reads a glTF 2.0 file, either JSON (.gltf) or binary (.glb).
+// 'open' is called to read buffers and images stored in external files, given
+// their URIs; data URIs are decoded directly. Images in external files are not
+// read. Primitives are converted to triangles, while points and lines are
+// skipped. Morph targets are not read.
+func DecodeGLTF(r io.Reader, open func(uri string) ([]byte, error)) (*GLTF, error) {
+	var data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		doc gltfDoc
+		bin []byte
+	)
+
+	if bytes.HasPrefix(data, glbMagic) {
+		var jsonChunk []byte
+		if jsonChunk, bin, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+		data = jsonChunk
+	}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("meshfmt.DecodeGLTF error: %v", err)
+	}
+
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("meshfmt.DecodeGLTF error: unsupported version '%s'", doc.Asset.Version)
+	}
+	for _, ext := range doc.ExtensionsRequired {
+		if !gltfSupported[ext] {
+			return nil, fmt.Errorf("meshfmt.DecodeGLTF error: unsupported required extension '%s'", ext)
+		}
+	}
+
+	var d = &gltfDecoder{doc: &doc}
+
+	// Load buffers.
+	for i, b := range doc.Buffers {
+		var buf []byte
+		switch {
+		case b.URI == "" && i == 0 && bin != nil:
+			buf = bin
+		case strings.HasPrefix(b.URI, "data:"):
+			if buf, err = decodeDataURI(b.URI); err != nil {
+				return nil, err
+			}
+		case b.URI != "":
+			if open == nil {
+				return nil, fmt.Errorf("meshfmt.DecodeGLTF error: no way to open buffer '%s'", b.URI)
+			}
+			var uri, uerr = url.PathUnescape(b.URI)
+			if uerr != nil {
+				uri = b.URI
+			}
+			if buf, err = open(uri); err != nil {
+				return nil, err
+			}
+		}
+		if len(buf) < b.ByteLength {
+			return nil, fmt.Errorf("meshfmt.DecodeGLTF error: buffer %d is %d bytes, expected %d", i, len(buf), b.ByteLength)
+		}
+		d.buffers = append(d.buffers, buf)
+	}
+
+	if err = d.decode(); err != nil {
+		return nil, err
+	}
+
+	return d.gltf, nil
+}
+
+// splitGLB returns the JSON and binary chunks of a binary glTF file.
+func splitGLB(data []byte) (jsonChunk, bin []byte, err error) {
+	if len(data) < 12 {
+		return nil, nil, errors.New("meshfmt.DecodeGLTF error: GLB file is truncated")
+	}
+
+	var (
+		version = binary.LittleEndian.Uint32(data[4:])
+		length  = int(binary.LittleEndian.Uint32(data[8:]))
+	)
+	if version != 2 {
+		return nil, nil, fmt.Errorf("meshfmt.DecodeGLTF error: unsupported GLB version %d", version)
+	}
+	if length > len(data) {
+		return nil, nil, errors.New("meshfmt.DecodeGLTF error: GLB file is truncated")
+	}
+
+	for pos := 12; pos+8 <= length; {
+		var (
+			size = int(binary.LittleEndian.Uint32(data[pos:]))
+			typ  = binary.LittleEndian.Uint32(data[pos+4:])
+		)
+		pos += 8
+		if pos+size > length {
+			return nil, nil, errors.New("meshfmt.DecodeGLTF error: GLB chunk is truncated")
+		}
+		switch typ {
+		case 0x4e4f534a: // "JSON"
+			jsonChunk = data[pos : pos+size]
+		case 0x004e4942: // "BIN\0"
+			if bin == nil {
+				bin = data[pos : pos+size]
+			}
+		}
+		pos += (size + 3) &^ 3
+	}
+
+	if jsonChunk == nil {
+		return nil, nil, errors.New("meshfmt.DecodeGLTF error: GLB file has no JSON chunk")
+	}
+
+	return jsonChunk, bin, nil
+}
+
+// decodeDataURI decodes a base64 data URI.
+func decodeDataURI(uri string) ([]byte, error) {
+	var comma = strings.IndexByte(uri, ',')
+	if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+		return nil, errors.New("meshfmt.DecodeGLTF error: unsupported data URI")
+	}
+	var data, err = base64.StdEncoding.DecodeString(uri[comma+1:])
+	if err != nil {
+		return nil, fmt.Errorf("meshfmt.DecodeGLTF error: bad data URI: %v", err)
+	}
+	return data, nil
+}
+
+// dataURIType returns the media type of a data URI.
+func dataURIType(uri string) string {
+	var end = strings.IndexAny(uri, ";,")
+	if end < 5 {
+		return ""
+	}
+	return uri[5:end]
+}
+
+// gltfDecoder converts a parsed glTF document.
+type gltfDecoder struct {
+	doc     *gltfDoc
+	buffers [][]byte
+	gltf    *GLTF
+}
+
+// view returns the bytes of a buffer view.
+func (d *gltfDecoder) view(i int) ([]byte, int, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: invalid buffer view %d", i)
+	}
+	var v = d.doc.BufferViews[i]
+	if v.Buffer < 0 || v.Buffer >= len(d.buffers) || v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteStride < 0 ||
+		v.ByteOffset > len(d.buffers[v.Buffer]) || v.ByteLength > len(d.buffers[v.Buffer])-v.ByteOffset {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: buffer view %d is out of range", i)
+	}
+	return d.buffers[v.Buffer][v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+// componentSize returns the size in bytes of an accessor component type.
+func componentSize(typ int) int {
+	switch typ {
+	case 5120, 5121: // BYTE, UNSIGNED_BYTE
+		return 1
+	case 5122, 5123: // SHORT, UNSIGNED_SHORT
+		return 2
+	case 5125, 5126: // UNSIGNED_INT, FLOAT
+		return 4
+	}
+	return 0
+}
+
+// readComponent reads a component and converts it to a float, normalizing
+// integers if requested.
+func readComponent(b []byte, typ int, normalized bool) float32 {
+	switch typ {
+	case 5120:
+		var v = float32(int8(b[0]))
+		if normalized {
+			return float32(math.Max(float64(v)/127, -1))
+		}
+		return v
+	case 5121:
+		if normalized {
+			return float32(b[0]) / 255
+		}
+		return float32(b[0])
+	case 5122:
+		var v = float32(int16(binary.LittleEndian.Uint16(b)))
+		if normalized {
+			return float32(math.Max(float64(v)/32767, -1))
+		}
+		return v
+	case 5123:
+		var v = float32(binary.LittleEndian.Uint16(b))
+		if normalized {
+			return v / 65535
+		}
+		return v
+	case 5125:
+		return float32(binary.LittleEndian.Uint32(b))
+	case 5126:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// readUint reads an integer component.
+func readUint(b []byte, typ int) uint32 {
+	switch typ {
+	case 5121:
+		return uint32(b[0])
+	case 5123:
+		return uint32(binary.LittleEndian.Uint16(b))
+	case 5125:
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// accessor reads an accessor's elements as floats, applying sparse
+// substitution. It returns the data and the number of components per element.
+func (d *gltfDecoder) accessor(i int) ([]float32, int, error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: invalid accessor %d", i)
+	}
+
+	var (
+		a     = d.doc.Accessors[i]
+		comps = gltfComponents[a.Type]
+		csize = componentSize(a.ComponentType)
+	)
+	if comps == 0 || csize == 0 {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: accessor %d has unsupported type %s/%d", i, a.Type, a.ComponentType)
+	}
+	if a.Count < 0 || a.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: accessor %d is out of range", i)
+	}
+	if s := a.Sparse; s != nil && (s.Count < 0 || s.Count > a.Count || s.Indices.ByteOffset < 0 || s.Values.ByteOffset < 0) {
+		return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: sparse accessor %d is out of range", i)
+	}
+
+	// Matrix columns are aligned to four bytes.
+	var (
+		cols, rows = 1, comps
+		colStride  = comps * csize
+	)
+	switch a.Type {
+	case "MAT2", "MAT3", "MAT4":
+		rows = int(math.Sqrt(float64(comps)))
+		cols = rows
+		colStride = (rows*csize + 3) &^ 3
+	}
+	var elemSize = cols * colStride
+	if cols == 1 {
+		elemSize = comps * csize
+	}
+
+	// fits reports whether 'count' elements 'stride' bytes apart fit in 'buf',
+	// in arithmetic that cannot overflow.
+	var fits = func(buf []byte, stride, count int) bool {
+		if stride == 0 {
+			stride = elemSize
+		}
+		return count == 0 || len(buf) >= elemSize && count-1 <= (len(buf)-elemSize)/stride
+	}
+	var read = func(buf []byte, stride, count int, dst func(e int) []float32) {
+		if stride == 0 {
+			stride = elemSize
+		}
+		for e := 0; e < count; e++ {
+			var v = dst(e)
+			for c := 0; c < cols; c++ {
+				for r := 0; r < rows; r++ {
+					var off = e*stride + c*colStride + r*csize
+					v[c*rows+r] = readComponent(buf[off:], a.ComponentType, a.Normalized)
+				}
+			}
+		}
+	}
+
+	// Every count is checked against the data it is read from before the
+	// output is allocated. Accessors without a buffer view are zero, and may
+	// have no more elements than the buffers have bytes.
+	var (
+		buf    []byte
+		stride int
+	)
+	if a.BufferView != nil {
+		var err error
+		if buf, stride, err = d.view(*a.BufferView); err != nil {
+			return nil, 0, err
+		}
+		if a.ByteOffset > len(buf) || !fits(buf[a.ByteOffset:], stride, a.Count) {
+			return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: accessor %d is out of range", i)
+		}
+		buf = buf[a.ByteOffset:]
+	} else {
+		var total int
+		for _, b := range d.buffers {
+			total += len(b)
+		}
+		if a.Count > total {
+			return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: accessor %d is out of range", i)
+		}
+	}
+
+	var (
+		ibuf, vbuf []byte
+		isize      int
+	)
+	if s := a.Sparse; s != nil && s.Count > 0 {
+		var err error
+		if ibuf, _, err = d.view(s.Indices.BufferView); err != nil {
+			return nil, 0, err
+		}
+		isize = componentSize(s.Indices.ComponentType)
+		if isize == 0 || s.Indices.ByteOffset > len(ibuf) || s.Count > (len(ibuf)-s.Indices.ByteOffset)/isize {
+			return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: sparse indices of accessor %d are invalid", i)
+		}
+		ibuf = ibuf[s.Indices.ByteOffset:]
+
+		if vbuf, _, err = d.view(s.Values.BufferView); err != nil {
+			return nil, 0, err
+		}
+		if s.Values.ByteOffset > len(vbuf) || !fits(vbuf[s.Values.ByteOffset:], 0, s.Count) {
+			return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: sparse values of accessor %d are out of range", i)
+		}
+		vbuf = vbuf[s.Values.ByteOffset:]
+	}
+
+	var out = make([]float32, a.Count*comps)
+	if buf != nil {
+		read(buf, stride, a.Count, func(e int) []float32 {
+			return out[e*comps : (e+1)*comps]
+		})
+	}
+
+	if s := a.Sparse; s != nil && s.Count > 0 {
+		var bad bool
+		read(vbuf, 0, s.Count, func(e int) []float32 {
+			var idx = int(readUint(ibuf[e*isize:], s.Indices.ComponentType))
+			if idx >= a.Count {
+				bad = true
+				return make([]float32, comps)
+			}
+			return out[idx*comps : (idx+1)*comps]
+		})
+		if bad {
+			return nil, 0, fmt.Errorf("meshfmt.DecodeGLTF error: sparse index of accessor %d is out of range", i)
+		}
+	}
+
+	return out, comps, nil
+}
+
+// indices reads an index accessor.
+func (d *gltfDecoder) indices(i int) ([]uint32, error) {
+	var data, comps, err = d.accessor(i)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 1 {
+		return nil, fmt.Errorf("meshfmt.DecodeGLTF error: index accessor %d is not scalar", i)
+	}
+	var out = make([]uint32, len(data))
+	for j, v := range data {
+		out[j] = uint32(v)
+	}
+	return out, nil
+}
+
+func (d *gltfDecoder) decode() error {
+	var (
+		doc = d.doc
+		g   = &GLTF{Scene: ref(doc.Scene)}
+	)
+	d.gltf = g
+
+	for _, s := range doc.Scenes {
+		g.Scenes = append(g.Scenes, &Scene{Name: s.Name, Nodes: s.Nodes})
+	}
+	if g.Scene < 0 && len(g.Scenes) > 0 {
+		g.Scene = 0
+	}
+	if g.Scene >= len(g.Scenes) {
+		return fmt.Errorf("meshfmt.DecodeGLTF error: invalid default scene %d", g.Scene)
+	}
+	for i, s := range g.Scenes {
+		for _, n := range s.Nodes {
+			if !inRange(n, len(doc.Nodes)) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: scene %d has invalid node %d", i, n)
+			}
+		}
+	}
+
+	for _, n := range doc.Nodes {
+		var node = &Node{
+			Name:     n.Name,
+			Parent:   -1,
+			Children: n.Children,
+			Mesh:     ref(n.Mesh),
+			Skin:     ref(n.Skin),
+			Camera:   ref(n.Camera),
+			Matrix:   mgl.Ident4(),
+			Rotation: mgl.QuatIdent(),
+			Scale:    mgl.Vec3{1, 1, 1},
+			Weights:  n.Weights,
+		}
+		if n.Matrix != nil {
+			node.Matrix = *n.Matrix
+		}
+		if n.Translation != nil {
+			node.Translation = *n.Translation
+		}
+		if n.Rotation != nil {
+			var q = *n.Rotation
+			node.Rotation = mgl.Quat{W: q[3], V: mgl.Vec3{q[0], q[1], q[2]}}
+		}
+		if n.Scale != nil {
+			node.Scale = *n.Scale
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	for i, n := range g.Nodes {
+		switch {
+		case !optRange(n.Mesh, len(doc.Meshes)):
+			return fmt.Errorf("meshfmt.DecodeGLTF error: node %d has invalid mesh %d", i, n.Mesh)
+		case !optRange(n.Skin, len(doc.Skins)):
+			return fmt.Errorf("meshfmt.DecodeGLTF error: node %d has invalid skin %d", i, n.Skin)
+		case !optRange(n.Camera, len(doc.Cameras)):
+			return fmt.Errorf("meshfmt.DecodeGLTF error: node %d has invalid camera %d", i, n.Camera)
+		}
+		for _, c := range n.Children {
+			if !inRange(c, len(g.Nodes)) || g.Nodes[c].Parent >= 0 {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: node %d has invalid child %d", i, c)
+			}
+			g.Nodes[c].Parent = i
+		}
+	}
+	// A node that is its own ancestor would make World loop forever.
+	for i := range g.Nodes {
+		var depth = 0
+		for p := g.Nodes[i].Parent; p >= 0; p = g.Nodes[p].Parent {
+			if depth++; depth > len(g.Nodes) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: node %d is its own ancestor", i)
+			}
+		}
+	}
+
+	for i := range doc.Meshes {
+		var group, err = d.mesh(i)
+		if err != nil {
+			return err
+		}
+		g.Meshes = append(g.Meshes, group)
+	}
+
+	for i, m := range doc.Materials {
+		var mat = &PBRMaterial{
+			Name:             m.Name,
+			BaseColor:        mgl.Vec4{1, 1, 1, 1},
+			BaseColorTexture: textureInfo(nil),
+			Metallic:         1,
+			Roughness:        1,
+			NormalTexture:    textureInfo(m.NormalTexture),
+			OcclusionTexture: textureInfo(m.OcclusionTexture),
+			Emissive:         m.EmissiveFactor,
+			EmissiveTexture:  textureInfo(m.EmissiveTexture),
+			AlphaMode:        "OPAQUE",
+			AlphaCutoff:      0.5,
+			DoubleSided:      m.DoubleSided,
+
+			MetallicRoughnessTexture: textureInfo(nil),
+		}
+		if pbr := m.PBRMetallicRoughness; pbr != nil {
+			if pbr.BaseColorFactor != nil {
+				mat.BaseColor = *pbr.BaseColorFactor
+			}
+			if pbr.MetallicFactor != nil {
+				mat.Metallic = *pbr.MetallicFactor
+			}
+			if pbr.RoughnessFactor != nil {
+				mat.Roughness = *pbr.RoughnessFactor
+			}
+			mat.BaseColorTexture = textureInfo(pbr.BaseColorTexture)
+			mat.MetallicRoughnessTexture = textureInfo(pbr.MetallicRoughnessTexture)
+		}
+		if m.AlphaMode != "" {
+			mat.AlphaMode = m.AlphaMode
+		}
+		if m.AlphaCutoff != nil {
+			mat.AlphaCutoff = *m.AlphaCutoff
+		}
+		for _, t := range []TextureInfo{mat.BaseColorTexture, mat.MetallicRoughnessTexture, mat.NormalTexture, mat.OcclusionTexture, mat.EmissiveTexture} {
+			if !optRange(t.Texture, len(doc.Textures)) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: material %d has invalid texture %d", i, t.Texture)
+			}
+		}
+		g.Materials = append(g.Materials, mat)
+	}
+
+	for i, t := range doc.Textures {
+		var tex = &Texture{Name: t.Name, Image: ref(t.Source)}
+		if !optRange(tex.Image, len(doc.Images)) {
+			return fmt.Errorf("meshfmt.DecodeGLTF error: texture %d has invalid image %d", i, tex.Image)
+		}
+		if !optRange(ref(t.Sampler), len(doc.Samplers)) {
+			return fmt.Errorf("meshfmt.DecodeGLTF error: texture %d has invalid sampler %d", i, *t.Sampler)
+		}
+		if s := ref(t.Sampler); s >= 0 {
+			var sampler = doc.Samplers[s]
+			tex.MagFilter, tex.MinFilter = sampler.MagFilter, sampler.MinFilter
+			tex.WrapS, tex.WrapT = sampler.WrapS, sampler.WrapT
+		}
+		g.Textures = append(g.Textures, tex)
+	}
+
+	for _, im := range doc.Images {
+		var img = &Image{Name: im.Name, MimeType: im.MimeType}
+		switch {
+		case im.BufferView != nil:
+			var buf, _, err = d.view(*im.BufferView)
+			if err != nil {
+				return err
+			}
+			img.Data = buf
+		case strings.HasPrefix(im.URI, "data:"):
+			var data, err = decodeDataURI(im.URI)
+			if err != nil {
+				return err
+			}
+			img.Data = data
+			if img.MimeType == "" {
+				img.MimeType = dataURIType(im.URI)
+			}
+		default:
+			if uri, err := url.PathUnescape(im.URI); err == nil {
+				img.URI = uri
+			} else {
+				img.URI = im.URI
+			}
+		}
+		g.Images = append(g.Images, img)
+	}
+
+	for _, c := range doc.Cameras {
+		var cam = &Camera{Name: c.Name, Orthographic: c.Type == "orthographic"}
+		if cam.Orthographic {
+			cam.XMag, cam.YMag = c.Orthographic.XMag, c.Orthographic.YMag
+			cam.ZNear, cam.ZFar = c.Orthographic.ZNear, c.Orthographic.ZFar
+		} else {
+			cam.AspectRatio, cam.YFov = c.Perspective.AspectRatio, c.Perspective.YFov
+			cam.ZNear, cam.ZFar = c.Perspective.ZNear, c.Perspective.ZFar
+		}
+		g.Cameras = append(g.Cameras, cam)
+	}
+
+	for i, s := range doc.Skins {
+		var skin = &Skin{Name: s.Name, Joints: s.Joints, Skeleton: ref(s.Skeleton)}
+		if !optRange(skin.Skeleton, len(doc.Nodes)) {
+			return fmt.Errorf("meshfmt.DecodeGLTF error: skin %d has invalid skeleton %d", i, skin.Skeleton)
+		}
+		for _, j := range s.Joints {
+			if !inRange(j, len(doc.Nodes)) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: skin %d has invalid joint %d", i, j)
+			}
+		}
+		skin.InverseBindMatrices = make([]mgl.Mat4, len(s.Joints))
+		if s.InverseBindMatrices != nil {
+			var data, comps, err = d.accessor(*s.InverseBindMatrices)
+			if err != nil {
+				return err
+			}
+			if comps != 16 || len(data) < 16*len(s.Joints) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: skin %d has invalid inverse bind matrices", i)
+			}
+			for j := range skin.InverseBindMatrices {
+				copy(skin.InverseBindMatrices[j][:], data[16*j:])
+			}
+		} else {
+			for j := range skin.InverseBindMatrices {
+				skin.InverseBindMatrices[j] = mgl.Ident4()
+			}
+		}
+		g.Skins = append(g.Skins, skin)
+	}
+
+	for i, a := range doc.Animations {
+		var anim = &Animation{Name: a.Name}
+		for _, c := range a.Channels {
+			if !optRange(ref(c.Target.Node), len(doc.Nodes)) || !inRange(c.Sampler, len(a.Samplers)) {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: animation %d has an invalid channel", i)
+			}
+			anim.Channels = append(anim.Channels, Channel{
+				Node:    ref(c.Target.Node),
+				Path:    c.Target.Path,
+				Sampler: c.Sampler,
+			})
+		}
+		for _, s := range a.Samplers {
+			var (
+				sampler   = AnimationSampler{Interpolation: s.Interpolation}
+				err       error
+				timeComps int
+			)
+			if sampler.Interpolation == "" {
+				sampler.Interpolation = "LINEAR"
+			}
+			if sampler.Times, timeComps, err = d.accessor(s.Input); err != nil {
+				return err
+			}
+			if sampler.Values, sampler.Components, err = d.accessor(s.Output); err != nil {
+				return err
+			}
+			if timeComps != 1 || len(sampler.Times) == 0 {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: animation %d has invalid keyframe times", i)
+			}
+			// Morph target weights are scalars; group them by keyframe.
+			if sampler.Components == 1 {
+				var per = len(sampler.Values) / len(sampler.Times)
+				if sampler.Interpolation == "CUBICSPLINE" {
+					per /= 3
+				}
+				sampler.Components = max(per, 1)
+			}
+			anim.Samplers = append(anim.Samplers, sampler)
+		}
+		g.Animations = append(g.Animations, anim)
+	}
+
+	return nil
+}
+
+// inRange reports whether 'i' is the index of one of 'n' objects.
+func inRange(i, n int) bool {
+	return i >= 0 && i < n
+}
+
+// optRange reports whether 'i' is the index of one of 'n' objects, or -1 for
+// none.
+func optRange(i, n int) bool {
+	return i == -1 || inRange(i, n)
+}
+
+// textureInfo converts an optional texture reference.
+func textureInfo(t *gltfTextureInfo) TextureInfo {
+	if t == nil {
+		return TextureInfo{Texture: -1, Scale: 1}
+	}
+	var info = TextureInfo{Texture: t.Index, TexCoord: t.TexCoord, Scale: 1}
+	if t.Scale != nil {
+		info.Scale = *t.Scale
+	} else if t.Strength != nil {
+		info.Scale = *t.Strength
+	}
+	return info
+}
+
+// gltfAttribs maps glTF attribute semantics to engine attribute names.
+var gltfAttribs = map[string]string{
+	"TEXCOORD": "texcoord",
+	"COLOR":    "color",
+	"JOINTS":   "joints",
+	"WEIGHTS":  "weights",
+}
+
+// gltfOrder is the order of the semantics of glTF attributes in a Mesh,
+// before any that are application-specific.
+var gltfOrder = []string{"POSITION", "NORMAL", "TANGENT", "TEXCOORD", "COLOR", "JOINTS", "WEIGHTS"}
+
+// sortSemantics returns the names of a primitive's attributes in a fixed
+// order: by semantic, as in gltfOrder, then by set index, then by name.
+func sortSemantics(attribs map[string]int) []string {
+	type key struct {
+		name      string
+		rank, set int
+	}
+	var keys = make([]key, 0, len(attribs))
+	for name := range attribs {
+		var k = key{name, len(gltfOrder), 0}
+		var prefix = name
+		if us := strings.LastIndexByte(name, '_'); us > 0 {
+			if set, err := strconv.Atoi(name[us+1:]); err == nil {
+				prefix, k.set = name[:us], set
+			}
+		}
+		for r, s := range gltfOrder {
+			if s == prefix {
+				k.rank = r
+			}
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		var a, b = keys[i], keys[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.set != b.set {
+			return a.set < b.set
+		}
+		return a.name < b.name
+	})
+
+	var names = make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.name
+	}
+	return names
+}
+
+// mesh converts the primitives of a glTF mesh.
+func (d *gltfDecoder) mesh(i int) (*MeshGroup, error) {
+	var (
+		m     = d.doc.Meshes[i]
+		group = &MeshGroup{Name: m.Name}
+	)
+
+	for p, prim := range m.Primitives {
+		var mode = 4
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode < 4 || mode > 6 {
+			continue // points and lines
+		}
+
+		var (
+			mesh = &Mesh{Name: m.Name}
+			fail = func(err error) error {
+				return fmt.Errorf("meshfmt.DecodeGLTF error: mesh %d primitive %d: %v", i, p, err)
+			}
+		)
+
+		pos, ok := prim.Attributes["POSITION"]
+		if !ok {
+			return nil, fail(errors.New("no POSITION attribute"))
+		}
+
+		var (
+			comps int
+			err   error
+		)
+		if mesh.Positions, comps, err = d.accessor(pos); err != nil {
+			return nil, err
+		}
+		if comps != 3 {
+			return nil, fail(errors.New("POSITION is not VEC3"))
+		}
+		var count = mesh.Vertices()
+
+		for _, semantic := range sortSemantics(prim.Attributes) {
+			if semantic == "POSITION" {
+				continue
+			}
+			var acc = prim.Attributes[semantic]
+
+			var data, comps, err = d.accessor(acc)
+			if err != nil {
+				return nil, err
+			}
+			if len(data) != count*comps {
+				return nil, fail(fmt.Errorf("%s has %d elements, expected %d", semantic, len(data)/comps, count))
+			}
+
+			switch semantic {
+			case "NORMAL":
+				mesh.Normals = data
+			case "TANGENT":
+				mesh.Tangents = data
+			case "TEXCOORD_0":
+				mesh.TexCoords = data
+			case "COLOR_0":
+				mesh.Colors = toRGBA(data, comps)
+			default:
+				var (
+					us   = strings.LastIndexByte(semantic, '_')
+					name = semantic
+				)
+				if us > 0 {
+					if prefix, ok := gltfAttribs[semantic[:us]]; ok {
+						name = prefix + semantic[us+1:]
+					}
+				}
+				if strings.HasPrefix(semantic, "COLOR_") {
+					data, comps = toRGBA(data, comps), 4
+				}
+				mesh.Attribs = append(mesh.Attribs, Attrib{Name: name, Components: comps, Data: data})
+			}
+		}
+
+		var indices []uint32
+		if prim.Indices != nil {
+			if indices, err = d.indices(*prim.Indices); err != nil {
+				return nil, err
+			}
+		} else {
+			indices = make([]uint32, count)
+			for v := range indices {
+				indices[v] = uint32(v)
+			}
+		}
+		for _, idx := range indices {
+			if int(idx) >= count {
+				return nil, fail(fmt.Errorf("index %d out of range", idx))
+			}
+		}
+		mesh.Indices = toTriangles(indices, mode)
+
+		if !optRange(ref(prim.Material), len(d.doc.Materials)) {
+			return nil, fail(fmt.Errorf("invalid material %d", *prim.Material))
+		}
+		group.Primitives = append(group.Primitives, mesh)
+		group.Materials = append(group.Materials, ref(prim.Material))
+	}
+
+	return group, nil
+}
+
+// toRGBA expands RGB colours to RGBA.
+func toRGBA(data []float32, comps int) []float32 {
+	if comps == 4 {
+		return data
+	}
+	var out = make([]float32, 0, len(data)/3*4)
+	for i := 0; i+2 < len(data); i += 3 {
+		out = append(out, data[i], data[i+1], data[i+2], 1)
+	}
+	return out
+}
+
+// toTriangles converts triangle strip (5) and fan (6) indices to triangles.
+func toTriangles(indices []uint32, mode int) []uint32 {
+	var tris []uint32
+	switch mode {
+	case 5:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				tris = append(tris, indices[i], indices[i+1], indices[i+2])
+			} else {
+				tris = append(tris, indices[i+1], indices[i], indices[i+2])
+			}
+		}
+	case 6:
+		for i := 1; i+1 < len(indices); i++ {
+			tris = append(tris, indices[0], indices[i], indices[i+1])
+		}
+	default:
+		tris = indices[:len(indices)/3*3]
+	}
+	return tris
+}
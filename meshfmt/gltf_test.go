@@ -0,0 +1,172 @@
+package meshfmt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// gltfTriangle returns a glTF document of one triangle with a material, a
+// texture, a skin and an animation, as decoded JSON for tests to break.
+func gltfTriangle(t *testing.T) map[string]interface{} {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	binary.Write(&buf, binary.LittleEndian, []float32{0, 1})
+
+	var src = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0]}],
+	"nodes": [{"children": [1], "mesh": 0, "skin": 0}, {}],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "material": 0}]}],
+	"materials": [{"pbrMetallicRoughness": {"baseColorTexture": {"index": 0}}}],
+	"textures": [{"source": 0, "sampler": 0}],
+	"samplers": [{}],
+	"images": [{"uri": "image.png"}],
+	"skins": [{"joints": [1]}],
+	"animations": [{
+		"channels": [{"sampler": 0, "target": {"node": 1, "path": "translation"}}],
+		"samplers": [{"input": 1, "output": 1}]
+	}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5126, "count": 2, "type": "SCALAR"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteLength": 36},
+		{"buffer": 0, "byteOffset": 36, "byteLength": 8}
+	],
+	"buffers": [{"byteLength": 44, "uri": "data:application/octet-stream;base64,` +
+		base64.StdEncoding.EncodeToString(buf.Bytes()) + `"}]
+}`
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// decodeDoc encodes a document and decodes it with DecodeGLTF.
+func decodeDoc(doc map[string]interface{}) (*GLTF, error) {
+	var data, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeGLTF(bytes.NewReader(data), nil)
+}
+
+// at returns the object at index 'i' of array 'key' of an object.
+func at(obj interface{}, key string, i int) map[string]interface{} {
+	return obj.(map[string]interface{})[key].([]interface{})[i].(map[string]interface{})
+}
+
+func TestDecodeGLTF(t *testing.T) {
+	var g, err = decodeDoc(gltfTriangle(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Meshes) != 1 || g.Meshes[0].Primitives[0].Vertices() != 3 {
+		t.Fatalf("got %d meshes", len(g.Meshes))
+	}
+	if g.Nodes[1].Parent != 0 || g.Materials[0].BaseColorTexture.Texture != 0 || g.Textures[0].Image != 0 {
+		t.Errorf("references were not decoded")
+	}
+}
+
+func TestDecodeGLTFAttribOrder(t *testing.T) {
+	var doc = gltfTriangle(t)
+	var attribs = at(at(doc, "meshes", 0), "primitives", 0)["attributes"].(map[string]interface{})
+	for _, semantic := range []string{"_CUSTOM", "WEIGHTS_0", "JOINTS_0", "COLOR_2", "COLOR_1", "TEXCOORD_10", "TEXCOORD_2", "TEXCOORD_1"} {
+		attribs[semantic] = 0
+	}
+
+	var want = []string{"texcoord1", "texcoord2", "texcoord10", "color1", "color2", "joints0", "weights0", "_CUSTOM"}
+	for run := 0; run < 10; run++ {
+		var g, err = decodeDoc(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, a := range g.Meshes[0].Primitives[0].Attribs {
+			got = append(got, a.Name)
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("got attributes %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDecodeGLTFInvalid(t *testing.T) {
+	var tests = []struct {
+		name  string
+		spoil func(doc map[string]interface{})
+	}{
+		{"default scene", func(doc map[string]interface{}) { doc["scene"] = 1 }},
+		{"scene node", func(doc map[string]interface{}) { at(doc, "scenes", 0)["nodes"] = []int{2} }},
+		{"node mesh", func(doc map[string]interface{}) { at(doc, "nodes", 1)["mesh"] = 1 }},
+		{"node skin", func(doc map[string]interface{}) { at(doc, "nodes", 1)["skin"] = -2 }},
+		{"node camera", func(doc map[string]interface{}) { at(doc, "nodes", 1)["camera"] = 0 }},
+		{"node child", func(doc map[string]interface{}) { at(doc, "nodes", 1)["children"] = []int{5} }},
+		{"node cycle", func(doc map[string]interface{}) { at(doc, "nodes", 1)["children"] = []int{0} }},
+		{"primitive material", func(doc map[string]interface{}) {
+			at(at(doc, "meshes", 0), "primitives", 0)["material"] = 3
+		}},
+		{"primitive accessor", func(doc map[string]interface{}) {
+			at(at(doc, "meshes", 0), "primitives", 0)["attributes"] = map[string]int{"POSITION": 7}
+		}},
+		{"material texture", func(doc map[string]interface{}) {
+			at(doc, "materials", 0)["normalTexture"] = map[string]int{"index": 4}
+		}},
+		{"texture image", func(doc map[string]interface{}) { at(doc, "textures", 0)["source"] = 1 }},
+		{"texture sampler", func(doc map[string]interface{}) { at(doc, "textures", 0)["sampler"] = 1 }},
+		{"skin joint", func(doc map[string]interface{}) { at(doc, "skins", 0)["joints"] = []int{2} }},
+		{"skin skeleton", func(doc map[string]interface{}) { at(doc, "skins", 0)["skeleton"] = 2 }},
+		{"channel node", func(doc map[string]interface{}) {
+			at(at(doc, "animations", 0), "channels", 0)["target"] = map[string]interface{}{"node": 9, "path": "scale"}
+		}},
+		{"channel sampler", func(doc map[string]interface{}) {
+			at(at(doc, "animations", 0), "channels", 0)["sampler"] = 1
+		}},
+		{"accessor view", func(doc map[string]interface{}) { at(doc, "accessors", 0)["bufferView"] = 2 }},
+		{"accessor count", func(doc map[string]interface{}) { at(doc, "accessors", 0)["count"] = -1 }},
+		{"accessor offset", func(doc map[string]interface{}) { at(doc, "accessors", 0)["byteOffset"] = -4 }},
+		{"accessor overrun", func(doc map[string]interface{}) { at(doc, "accessors", 0)["count"] = 4 }},
+		{"huge count", func(doc map[string]interface{}) { at(doc, "accessors", 0)["count"] = 1 << 40 }},
+		{"huge unbacked count", func(doc map[string]interface{}) {
+			delete(at(doc, "accessors", 0), "bufferView")
+			at(doc, "accessors", 0)["count"] = 1 << 40
+		}},
+		{"huge sparse count", func(doc map[string]interface{}) {
+			at(doc, "accessors", 0)["count"] = 1 << 40
+			at(doc, "accessors", 0)["sparse"] = map[string]interface{}{
+				"count":   1 << 40,
+				"indices": map[string]int{"bufferView": 1, "componentType": 5125},
+				"values":  map[string]int{"bufferView": 0},
+			}
+		}},
+		{"sparse overrun", func(doc map[string]interface{}) {
+			at(doc, "accessors", 0)["sparse"] = map[string]interface{}{
+				"count":   3,
+				"indices": map[string]int{"bufferView": 1, "componentType": 5125},
+				"values":  map[string]int{"bufferView": 0},
+			}
+		}},
+		{"view buffer", func(doc map[string]interface{}) { at(doc, "bufferViews", 0)["buffer"] = 1 }},
+		{"view offset", func(doc map[string]interface{}) { at(doc, "bufferViews", 1)["byteOffset"] = -8 }},
+		{"view stride", func(doc map[string]interface{}) { at(doc, "bufferViews", 0)["byteStride"] = -12 }},
+		{"view length", func(doc map[string]interface{}) { at(doc, "bufferViews", 1)["byteLength"] = 12 }},
+	}
+	for _, tt := range tests {
+		var doc = gltfTriangle(t)
+		tt.spoil(doc)
+		var _, err = decodeDoc(doc)
+		if err == nil {
+			t.Errorf("%s: invalid document was decoded", tt.name)
+		} else if !strings.HasPrefix(err.Error(), "meshfmt.DecodeGLTF error") {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
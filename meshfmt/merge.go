@@ -0,0 +1,79 @@
+package meshfmt
+
+// Merge concatenates meshes into one, so that they can share buffers and be
+// drawn as ranges of its indices, which are offset to refer to the merged
+// vertices. It returns the merged Mesh and the index of the first element of
+// each part. An attribute missing from some parts is given to their vertices
+// as zero, or as opaque white for Colors.
+func Merge(name string, parts []*Mesh) (*Mesh, []int) {
+	var (
+		out    = &Mesh{Name: name}
+		firsts = make([]int, len(parts))
+		has    struct{ normals, tangents, texcoords, colors bool }
+		attrs  []Attrib // name and components of every further attribute
+	)
+	for _, p := range parts {
+		has.normals = has.normals || p.Normals != nil
+		has.tangents = has.tangents || p.Tangents != nil
+		has.texcoords = has.texcoords || p.TexCoords != nil
+		has.colors = has.colors || p.Colors != nil
+	attribs:
+		for _, a := range p.Attribs {
+			for _, b := range attrs {
+				if b.Name == a.Name {
+					continue attribs
+				}
+			}
+			attrs = append(attrs, Attrib{Name: a.Name, Components: a.Components})
+		}
+	}
+
+	// join appends the data of a part, or the default value for each of its
+	// vertices.
+	var join = func(dst []float32, present bool, src []float32, comps, vertices int, def float32) []float32 {
+		if !present {
+			return dst
+		}
+		if src != nil {
+			return append(dst, src[:comps*vertices]...)
+		}
+		for i := 0; i < comps*vertices; i++ {
+			dst = append(dst, def)
+		}
+		return dst
+	}
+
+	for i, p := range parts {
+		var (
+			vertices = p.Vertices()
+			base     = uint32(out.Vertices())
+		)
+		firsts[i] = len(out.Indices)
+		for _, v := range p.Indices {
+			out.Indices = append(out.Indices, base+v)
+		}
+
+		out.Positions = append(out.Positions, p.Positions[:3*vertices]...)
+		out.Normals = join(out.Normals, has.normals, p.Normals, 3, vertices, 0)
+		out.Tangents = join(out.Tangents, has.tangents, p.Tangents, 4, vertices, 0)
+		out.TexCoords = join(out.TexCoords, has.texcoords, p.TexCoords, 2, vertices, 0)
+		out.Colors = join(out.Colors, has.colors, p.Colors, 4, vertices, 1)
+
+		for j, a := range attrs {
+			var src []float32
+			for _, b := range p.Attribs {
+				if b.Name == a.Name && b.Components == a.Components {
+					src = b.Data
+				}
+			}
+			attrs[j].Data = join(a.Data, true, src, a.Components, vertices, 0)
+		}
+	}
+	out.Attribs = attrs
+
+	if len(parts) == 1 {
+		out.Material = parts[0].Material
+	}
+
+	return out, firsts
+}
@@ -0,0 +1,60 @@
+package meshfmt
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	// A triangle with normals and a "weights0" attribute, and a quad with
+	// colours and texture coordinates.
+	var (
+		tri = &Mesh{
+			Name:      "tri",
+			Material:  "a",
+			Positions: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+			Normals:   []float32{0, 0, 1, 0, 0, 1, 0, 0, 1},
+			Attribs:   []Attrib{{Name: "weights0", Components: 1, Data: []float32{1, 2, 3}}},
+			Indices:   []uint32{0, 1, 2},
+		}
+		quad = &Mesh{
+			Name:      "quad",
+			Material:  "b",
+			Positions: []float32{0, 0, 1, 1, 0, 1, 1, 1, 1, 0, 1, 1},
+			TexCoords: []float32{0, 0, 1, 0, 1, 1, 0, 1},
+			Colors:    []float32{1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1},
+			Indices:   []uint32{0, 1, 2, 0, 2, 3},
+		}
+	)
+	var m, firsts = Merge("both", []*Mesh{tri, quad})
+
+	if m.Name != "both" || m.Material != "" {
+		t.Errorf("merged mesh %q has material %q", m.Name, m.Material)
+	}
+	if len(firsts) != 2 || firsts[0] != 0 || firsts[1] != 3 {
+		t.Errorf("first indices %v, want [0 3]", firsts)
+	}
+	if want := []uint32{0, 1, 2, 3, 4, 5, 3, 5, 6}; !equalIndices(m.Indices, want) {
+		t.Errorf("indices %v, want %v", m.Indices, want)
+	}
+	if m.Vertices() != 7 || len(m.Normals) != 21 || len(m.TexCoords) != 14 || len(m.Colors) != 28 || m.Tangents != nil {
+		t.Fatalf("got %d vertices with %d normal, %d texture coordinate and %d colour components",
+			m.Vertices(), len(m.Normals), len(m.TexCoords), len(m.Colors))
+	}
+
+	// Missing attributes are zero, and missing colours opaque white.
+	if m.Normals[9+2] != 0 || m.TexCoords[2] != 0 || m.TexCoords[8] != 1 {
+		t.Errorf("normals %v and texture coordinates %v", m.Normals, m.TexCoords)
+	}
+	if c := m.Colors[:4]; c[0] != 1 || c[1] != 1 || c[3] != 1 {
+		t.Errorf("triangle colour %v", c)
+	}
+	if c := m.Colors[12:16]; c[0] != 1 || c[1] != 0 {
+		t.Errorf("quad colour %v", c)
+	}
+	if len(m.Attribs) != 1 || len(m.Attribs[0].Data) != 7 || m.Attribs[0].Data[2] != 3 || m.Attribs[0].Data[3] != 0 {
+		t.Errorf("attributes %+v", m.Attribs)
+	}
+
+	// A single part keeps its material.
+	if m, _ = Merge("one", []*Mesh{quad}); m.Material != "b" || m.Normals != nil {
+		t.Errorf("merging one part: material %q, normals %v", m.Material, m.Normals)
+	}
+}
@@ -0,0 +1,132 @@
+// Package meshfmt reads mesh files into indexed triangle geometry held in
+// memory. Like texfmt, it has no dependency on OpenGL, so that tools which
+// only process mesh data need not link against it.
+package meshfmt
+
+import "math"
+
+// Mesh is indexed triangle geometry. Every vertex attribute present has an
+// element for each vertex.
+type Mesh struct {
+	Name     string
+	Material string // name of the material, if any
+
+	Positions []float32 // XYZ positions
+	Normals   []float32 // XYZ normals, or nil
+	Tangents  []float32 // XYZW tangents, W being the bitangent sign, or nil
+	TexCoords []float32 // UV texture coordinates, or nil
+	Colors    []float32 // RGBA colours, or nil
+	Indices   []uint32  // three vertex indices per triangle
+
+	// Attribs holds any further attribute sets, such as a second set of
+	// texture coordinates or the joints and weights of a skinned mesh.
+	Attribs []Attrib
+}
+
+// Attrib is a named vertex attribute. Names follow the engine's convention of
+// a lower case semantic followed by the set number, such as "texcoord1",
+// "color1", "joints0" and "weights0".
+type Attrib struct {
+	Name       string
+	Components int
+	Data       []float32
+}
+
+// Vertices returns the number of vertices in the Mesh.
+func (m *Mesh) Vertices() int {
+	return len(m.Positions) / 3
+}
+
+type vec3 [3]float64
+
+func (a vec3) add(b vec3) vec3      { return vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]} }
+func (a vec3) sub(b vec3) vec3      { return vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+func (a vec3) scale(s float64) vec3 { return vec3{a[0] * s, a[1] * s, a[2] * s} }
+func (a vec3) dot(b vec3) float64   { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// normalize returns the unit vector in the direction of 'a', or zero if 'a' is
+// zero.
+func (a vec3) normalize() vec3 {
+	var l = math.Sqrt(a.dot(a))
+	if l == 0 {
+		return vec3{}
+	}
+	return a.scale(1 / l)
+}
+
+// triangulate divides a simple polygon into triangles by ear clipping,
+// appending the indices of 'poly' to 'tris'. Polygons are projected onto the
+// plane of their Newell normal, so they may be concave but should be roughly
+// planar. If no ear can be found, the rest of the polygon is fanned.
+func triangulate(poly []uint32, pos func(i uint32) vec3, tris []uint32) []uint32 {
+	if len(poly) < 3 {
+		return tris
+	}
+	if len(poly) == 3 {
+		return append(tris, poly...)
+	}
+
+	var normal vec3
+	for i := range poly {
+		var a, b = pos(poly[i]), pos(poly[(i+1)%len(poly)])
+		normal[0] += (a[1] - b[1]) * (a[2] + b[2])
+		normal[1] += (a[2] - b[2]) * (a[0] + b[0])
+		normal[2] += (a[0] - b[0]) * (a[1] + b[1])
+	}
+
+	var (
+		rest = append([]uint32(nil), poly...)
+		// convex reports whether the corner at b turns the same way as the
+		// polygon.
+		convex = func(a, b, c vec3) bool {
+			return b.sub(a).cross(c.sub(b)).dot(normal) > 0
+		}
+		// inside reports whether p lies within the triangle abc.
+		inside = func(p, a, b, c vec3) bool {
+			return convex(a, b, p) && convex(b, c, p) && convex(c, a, p)
+		}
+	)
+
+	for len(rest) > 3 {
+		var found = false
+		for i := range rest {
+			var (
+				n       = len(rest)
+				ia, ib  = rest[(i+n-1)%n], rest[i]
+				ic      = rest[(i+1)%n]
+				a, b, c = pos(ia), pos(ib), pos(ic)
+			)
+			if !convex(a, b, c) {
+				continue
+			}
+
+			var ear = true
+			for _, j := range rest {
+				if j != ia && j != ib && j != ic && inside(pos(j), a, b, c) {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+
+			tris = append(tris, ia, ib, ic)
+			rest = append(rest[:i], rest[i+1:]...)
+			found = true
+			break
+		}
+
+		if !found {
+			for i := 1; i+1 < len(rest); i++ {
+				tris = append(tris, rest[0], rest[i], rest[i+1])
+			}
+			return tris
+		}
+	}
+
+	return append(tris, rest...)
+}
@@ -0,0 +1,181 @@
+package meshfmt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Material is a material read from a Wavefront MTL file. Texture maps are file
+// names relative to the MTL file, and are empty if not given.
+type Material struct {
+	Name string
+
+	Ambient   [3]float32 // Ka
+	Diffuse   [3]float32 // Kd
+	Specular  [3]float32 // Ks
+	Emissive  [3]float32 // Ke
+	Shininess float32    // Ns, the specular exponent
+	Opacity   float32    // d, or 1 - Tr
+	IOR       float32    // Ni
+	Illum     int        // illumination model
+
+	AmbientMap   string // map_Ka
+	DiffuseMap   string // map_Kd
+	SpecularMap  string // map_Ks
+	EmissiveMap  string // map_Ke
+	ShininessMap string // map_Ns
+	OpacityMap   string // map_d
+	BumpMap      string // map_Bump or bump, a height map
+	NormalMap    string // norm, a tangent-space normal map
+}
+
+// mtlOptionArgs is the number of arguments taken by each texture map option.
+// Options marked -1 take one to three numbers.
+var mtlOptionArgs = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-bm": 1, "-boost": 1, "-cc": 1, "-clamp": 1,
+	"-imfchan": 1, "-texres": 1, "-type": 1, "-mm": 2, "-o": -1, "-s": -1, "-t": -1,
+}
+
+// mtlMapFile returns the file name of a texture map statement, skipping any
+// options that precede it.
+func mtlMapFile(args []string) string {
+	for len(args) > 1 {
+		var n, ok = mtlOptionArgs[args[0]]
+		if !ok {
+			break
+		}
+		args = args[1:]
+
+		if n > 0 {
+			args = args[min(n, len(args)-1):]
+			continue
+		}
+		for n = 0; n < 3 && len(args) > 1; n++ {
+			if _, err := strconv.ParseFloat(args[0], 64); err != nil {
+				break
+			}
+			args = args[1:]
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// DecodeMTL reads the materials of a Wavefront MTL file.
+func DecodeMTL(r io.Reader) ([]*Material, error) {
+	var (
+		materials []*Material
+		cur       *Material
+	)
+
+	var err = objLines(r, func(line int, f []string) error {
+		var fail = func(err error) error {
+			return fmt.Errorf("meshfmt.DecodeMTL error: line %d: '%s': %v", line, f[0], err)
+		}
+
+		if f[0] == "newmtl" {
+			cur = &Material{
+				Name:      strings.Join(f[1:], " "),
+				Diffuse:   [3]float32{1, 1, 1},
+				Shininess: 1,
+				Opacity:   1,
+				IOR:       1,
+			}
+			materials = append(materials, cur)
+			return nil
+		}
+		if cur == nil {
+			return fail(errors.New("statement before newmtl"))
+		}
+
+		var color = func(dst *[3]float32) error {
+			if len(f) < 2 {
+				return fail(errors.New("too few values"))
+			}
+			if f[1] == "spectral" || f[1] == "xyz" {
+				return nil // unsupported colour spaces keep their default
+			}
+			if len(f) < 4 {
+				// A single value is used for all three channels.
+				if err := parseFloats(f[1:], dst[:1]); err != nil {
+					return fail(err)
+				}
+				dst[1], dst[2] = dst[0], dst[0]
+				return nil
+			}
+			if err := parseFloats(f[1:], dst[:]); err != nil {
+				return fail(err)
+			}
+			return nil
+		}
+		var scalar = func(dst *float32) error {
+			var v [1]float32
+			if len(f) < 2 {
+				return fail(errors.New("too few values"))
+			}
+			if err := parseFloats(f[1:], v[:]); err != nil {
+				return fail(err)
+			}
+			*dst = v[0]
+			return nil
+		}
+
+		switch strings.ToLower(f[0]) {
+		case "ka":
+			return color(&cur.Ambient)
+		case "kd":
+			return color(&cur.Diffuse)
+		case "ks":
+			return color(&cur.Specular)
+		case "ke":
+			return color(&cur.Emissive)
+		case "ns":
+			return scalar(&cur.Shininess)
+		case "ni":
+			return scalar(&cur.IOR)
+		case "d":
+			// "d -halo factor" is read as a plain opacity.
+			if len(f) > 2 && f[1] == "-halo" {
+				f = f[1:]
+			}
+			return scalar(&cur.Opacity)
+		case "tr":
+			var tr float32
+			if err := scalar(&tr); err != nil {
+				return err
+			}
+			cur.Opacity = 1 - tr
+		case "illum":
+			var v, err = strconv.Atoi(f[len(f)-1])
+			if err != nil {
+				return fail(err)
+			}
+			cur.Illum = v
+		case "map_ka":
+			cur.AmbientMap = mtlMapFile(f[1:])
+		case "map_kd":
+			cur.DiffuseMap = mtlMapFile(f[1:])
+		case "map_ks":
+			cur.SpecularMap = mtlMapFile(f[1:])
+		case "map_ke":
+			cur.EmissiveMap = mtlMapFile(f[1:])
+		case "map_ns":
+			cur.ShininessMap = mtlMapFile(f[1:])
+		case "map_d":
+			cur.OpacityMap = mtlMapFile(f[1:])
+		case "map_bump", "bump":
+			cur.BumpMap = mtlMapFile(f[1:])
+		case "norm", "map_kn":
+			cur.NormalMap = mtlMapFile(f[1:])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return materials, nil
+}
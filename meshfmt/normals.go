@@ -0,0 +1,343 @@
+package meshfmt
+
+import "math"
+
+// ComputeSmoothNormals sets the Normals of the Mesh as by SmoothNormals,
+// splitting vertices where needed.
+func (m *Mesh) ComputeSmoothNormals(crease float64) {
+	var normals, from, indices = SmoothNormals(m.Positions, m.Indices, crease)
+	m.remap(from, indices)
+	m.Normals = normals
+}
+
+// ComputeFlatNormals sets the Normals of the Mesh to those of its triangles,
+// splitting vertices so that they are shared only by triangles in the same
+// plane.
+func (m *Mesh) ComputeFlatNormals() {
+	m.ComputeSmoothNormals(0)
+}
+
+// ComputeTangents sets the Tangents of the Mesh as by Tangents, splitting
+// vertices where needed. The Mesh must have Normals and TexCoords, or it is
+// left unchanged.
+func (m *Mesh) ComputeTangents() {
+	var n = m.Vertices()
+	if len(m.Normals) != 3*n || len(m.TexCoords) != 2*n {
+		return
+	}
+
+	var tangents, from, indices = Tangents(m.Positions, m.Normals, m.TexCoords, m.Indices)
+	m.remap(from, indices)
+	m.Tangents = tangents
+}
+
+// remap replaces the vertices of the Mesh with copies of the vertices 'from',
+// which are referred to by 'indices'.
+func (m *Mesh) remap(from, indices []uint32) {
+	m.Indices = indices
+	if isIdentity(from, m.Vertices()) {
+		return
+	}
+
+	var dup = func(data []float32, comps int) []float32 {
+		if data == nil {
+			return nil
+		}
+		var out = make([]float32, 0, len(from)*comps)
+		for _, v := range from {
+			out = append(out, data[int(v)*comps:int(v+1)*comps]...)
+		}
+		return out
+	}
+
+	m.Positions = dup(m.Positions, 3)
+	m.Normals = dup(m.Normals, 3)
+	m.Tangents = dup(m.Tangents, 4)
+	m.TexCoords = dup(m.TexCoords, 2)
+	m.Colors = dup(m.Colors, 4)
+	for i, a := range m.Attribs {
+		m.Attribs[i].Data = dup(a.Data, a.Components)
+	}
+}
+
+// isIdentity reports whether 'from' copies each of 'vertices' vertices in order.
+func isIdentity(from []uint32, vertices int) bool {
+	if len(from) != vertices {
+		return false
+	}
+	for i, v := range from {
+		if v != uint32(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// SmoothNormals computes normals for the vertices of indexed triangles, given
+// XYZ positions. The normal at each corner of a triangle is the average of the
+// normals of the triangles meeting at its position, weighted by their angles
+// there, counting only those within 'crease' radians of its own triangle.
+// Vertices at the same position are smoothed together, even if they differ in
+// other attributes. A crease of zero gives flat normals, and one of Pi or more
+// smooths across every edge.
+//
+// Where the corners of a vertex are given different normals, the vertex is
+// split. The normals of the resulting vertices are returned, along with the
+// input vertex that each copies and the triangles' new indices. The first copy
+// of each vertex keeps its index, and further copies follow the input
+// vertices. Vertices used by no triangle are given the normal +Z.
+func SmoothNormals(pos []float32, indices []uint32, crease float64) (normals []float32, from, tris []uint32) {
+	var (
+		n       = len(indices) / 3 * 3
+		faces   = make([]vec3, n/3) // unit triangle normals, or zero
+		angles  = make([]float64, n)
+		corners = make(map[[3]float32][]int) // corners at each position
+		cos     = math.Cos(math.Min(crease, math.Pi)) - 1e-6
+	)
+
+	var key = func(i uint32) [3]float32 {
+		return [3]float32{pos[3*i], pos[3*i+1], pos[3*i+2]}
+	}
+	for t := 0; t < n; t += 3 {
+		var p [3]vec3
+		for k := range p {
+			p[k] = posVec(pos, indices[t+k])
+		}
+		faces[t/3] = p[1].sub(p[0]).cross(p[2].sub(p[0])).normalize()
+
+		for k := range p {
+			angles[t+k] = cornerAngle(p[k], p[(k+1)%3], p[(k+2)%3])
+			corners[key(indices[t+k])] = append(corners[key(indices[t+k])], t+k)
+		}
+	}
+
+	var normal = make([][3]float32, n)
+	for c := 0; c < n; c++ {
+		var f, sum = faces[c/3], vec3{}
+		for _, o := range corners[key(indices[c])] {
+			// Corners of degenerate triangles take the normal of every
+			// triangle around them.
+			var g = faces[o/3]
+			if f == (vec3{}) || f.dot(g) >= cos {
+				sum = sum.add(g.scale(angles[o]))
+			}
+		}
+
+		switch {
+		case sum.dot(sum) > 0:
+			sum = sum.normalize()
+		case f != vec3{}:
+			sum = f
+		default:
+			sum = vec3{0, 0, 1}
+		}
+		normal[c] = [3]float32{float32(sum[0]), float32(sum[1]), float32(sum[2])}
+	}
+
+	var vertices = len(pos) / 3
+	normals = make([]float32, 3*vertices)
+	for v := 0; v < vertices; v++ {
+		normals[3*v+2] = 1
+	}
+	from, tris = splitCorners(indices[:n], vertices, func(c int, out []float32) []float32 {
+		return append(out, normal[c][:]...)
+	}, 3, &normals)
+
+	return normals, from, tris
+}
+
+// Tangents computes tangents for the vertices of indexed triangles, given XYZ
+// positions, unit normals and UV texture coordinates, approximating
+// MikkTSpace. It is not a port of the reference implementation, so normal maps
+// baked against MikkTSpace may show small differences in shading, mostly
+// where a vertex's triangles are mapped unevenly. Each tangent points
+// along increasing U, perpendicular to the vertex's normal, and its W is the
+// sign of the bitangent cross(normal, tangent) * W. As in glTF, V = 0 is the top
+// of an image, so the bitangent points along decreasing V, up the image.
+//
+// As in MikkTSpace, vertices with the same position, normal and texture
+// coordinates are treated as one, and the tangent of each averages the
+// tangents of the triangles around it, projected onto the plane of its normal
+// and weighted by their angles at the vertex. Where triangles sharing a vertex
+// are mapped with opposite orientations, as across the seam of a mirrored
+// texture, the vertex is split. The results are returned as by SmoothNormals.
+func Tangents(pos, normals, texcoords []float32, indices []uint32) (tangents []float32, from, tris []uint32) {
+	var (
+		n        = len(indices) / 3 * 3
+		vertices = len(pos) / 3
+		dirs     = make([]vec3, n/3) // triangle tangents
+		orients  = make([]float32, n/3)
+		uvs      = func(i uint32) (float64, float64) {
+			// V is flipped to measure orientation with V increasing upwards.
+			return float64(texcoords[2*i]), -float64(texcoords[2*i+1])
+		}
+	)
+
+	for t := 0; t < n; t += 3 {
+		var (
+			i0, i1, i2 = indices[t], indices[t+1], indices[t+2]
+			p0         = posVec(pos, i0)
+			e1, e2     = posVec(pos, i1).sub(p0), posVec(pos, i2).sub(p0)
+			u0, v0     = uvs(i0)
+			u1, v1     = uvs(i1)
+			u2, v2     = uvs(i2)
+			du1, dv1   = u1 - u0, v1 - v0
+			du2, dv2   = u2 - u0, v2 - v0
+			r          = du1*dv2 - du2*dv1
+		)
+		if r == 0 {
+			continue // orientation is settled below
+		}
+		dirs[t/3] = e1.scale(dv2 / r).sub(e2.scale(dv1 / r))
+		if orients[t/3] = 1; r < 0 {
+			orients[t/3] = -1
+		}
+	}
+
+	// Triangles with degenerate texture coordinates take the orientation of
+	// the first triangle mapped at one of their vertices.
+	var orientOf = make([]float32, vertices)
+	for t := 0; t < n; t += 3 {
+		for k := 0; k < 3; k++ {
+			if orientOf[indices[t+k]] == 0 {
+				orientOf[indices[t+k]] = orients[t/3]
+			}
+		}
+	}
+	for t := 0; t < n; t += 3 {
+		for k := 0; orients[t/3] == 0 && k < 3; k++ {
+			orients[t/3] = orientOf[indices[t+k]]
+		}
+		if orients[t/3] == 0 {
+			orients[t/3] = 1
+		}
+	}
+
+	// Corners are grouped by the values of their vertex, welding vertices
+	// that differ only in other attributes, and by orientation.
+	type group struct {
+		weld   [8]float32
+		orient float32
+	}
+	var groupOf = func(c int) group {
+		var (
+			v = indices[c]
+			g = group{orient: orients[c/3]}
+		)
+		copy(g.weld[0:], pos[3*v:3*v+3])
+		copy(g.weld[3:], normals[3*v:3*v+3])
+		copy(g.weld[6:], texcoords[2*v:2*v+2])
+		return g
+	}
+	var sums = make(map[group]vec3)
+	for c := 0; c < n; c++ {
+		var (
+			t   = c / 3
+			v   = indices[c]
+			nrm = posVec(normals, v)
+			d   = dirs[t]
+		)
+		d = d.sub(nrm.scale(nrm.dot(d)))
+		if d.dot(d) == 0 {
+			continue
+		}
+		var (
+			p0 = posVec(pos, v)
+			p1 = posVec(pos, indices[3*t+(c+1)%3])
+			p2 = posVec(pos, indices[3*t+(c+2)%3])
+			g  = groupOf(c)
+		)
+		sums[g] = sums[g].add(d.normalize().scale(cornerAngle(p0, p1, p2)))
+	}
+
+	tangents = make([]float32, 4*vertices)
+	for v := 0; v < vertices; v++ {
+		copy(tangents[4*v:], tangentFor(posVec(normals, uint32(v)), vec3{}, 1))
+	}
+	from, tris = splitCorners(indices[:n], vertices, func(c int, out []float32) []float32 {
+		var g = groupOf(c)
+		return append(out, tangentFor(posVec(normals, indices[c]), sums[g], g.orient)...)
+	}, 4, &tangents)
+
+	return tangents, from, tris
+}
+
+// tangentFor returns the XYZW tangent with direction 't' and bitangent sign
+// 'w', or if 't' is zero, any direction perpendicular to the normal.
+func tangentFor(nrm, t vec3, w float32) []float32 {
+	if t.dot(t) < 1e-20 {
+		t = nrm.cross(vec3{1, 0, 0})
+		if t.dot(t) < 1e-6 {
+			t = nrm.cross(vec3{0, 1, 0})
+		}
+	}
+	t = t.normalize()
+	return []float32{float32(t[0]), float32(t[1]), float32(t[2]), w}
+}
+
+// splitCorners gives each triangle corner the attribute value written by
+// 'value', splitting vertices whose corners have different values. 'out' holds
+// 'comps' components for each input vertex, set for the first corner of each
+// vertex, and has the values of split vertices appended. The input vertex of
+// each output vertex and the new indices are returned.
+func splitCorners(indices []uint32, vertices int, value func(c int, out []float32) []float32, comps int, out *[]float32) (from, tris []uint32) {
+	type copyKey struct {
+		v     uint32
+		value string
+	}
+	var (
+		seen    = make([]bool, vertices)
+		copies  = make(map[copyKey]uint32)
+		scratch []float32
+	)
+
+	from = make([]uint32, vertices)
+	for v := range from {
+		from[v] = uint32(v)
+	}
+	tris = make([]uint32, len(indices))
+
+	for c, v := range indices {
+		scratch = value(c, scratch[:0])
+		var k = copyKey{v, floatKey(scratch)}
+
+		switch i, ok := copies[k]; {
+		case ok:
+			tris[c] = i
+		case !seen[v]:
+			seen[v] = true
+			copy((*out)[int(v)*comps:], scratch)
+			copies[k] = v
+			tris[c] = v
+		default:
+			var i = uint32(len(from))
+			from = append(from, v)
+			*out = append(*out, scratch...)
+			copies[k] = i
+			tris[c] = i
+		}
+	}
+
+	return from, tris
+}
+
+// floatKey returns a map key holding the exact bits of a set of floats.
+func floatKey(f []float32) string {
+	var b = make([]byte, 0, 4*len(f))
+	for _, x := range f {
+		var u = math.Float32bits(x)
+		b = append(b, byte(u), byte(u>>8), byte(u>>16), byte(u>>24))
+	}
+	return string(b)
+}
+
+// cornerAngle returns the angle at 'a' of the triangle abc.
+func cornerAngle(a, b, c vec3) float64 {
+	var e1, e2 = b.sub(a), c.sub(a)
+	var cross = e1.cross(e2)
+	return math.Atan2(math.Sqrt(cross.dot(cross)), e1.dot(e2))
+}
+
+func posVec(data []float32, i uint32) vec3 {
+	return vec3{float64(data[3*i]), float64(data[3*i+1]), float64(data[3*i+2])}
+}
@@ -0,0 +1,168 @@
+package meshfmt
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// cube returns a unit cube of 8 shared vertices and 12 outward triangles.
+func cube() ([]float32, []uint32) {
+	var pos = []float32{
+		0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0,
+		0, 0, 1, 1, 0, 1, 1, 1, 1, 0, 1, 1,
+	}
+	var indices = []uint32{
+		0, 2, 1, 0, 3, 2, // -Z
+		4, 5, 6, 4, 6, 7, // +Z
+		0, 1, 5, 0, 5, 4, // -Y
+		3, 7, 6, 3, 6, 2, // +Y
+		0, 4, 7, 0, 7, 3, // -X
+		1, 2, 6, 1, 6, 5, // +X
+	}
+	return pos, indices
+}
+
+func near(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+func TestSmoothNormalsFlat(t *testing.T) {
+	var pos, indices = cube()
+	var normals, from, tris = SmoothNormals(pos, indices, 0)
+
+	// Each corner of the cube is split into one vertex per face.
+	if len(from) != 24 {
+		t.Fatalf("got %d vertices, want 24", len(from))
+	}
+	for c, v := range tris {
+		var (
+			tri  = c / 3
+			want = [6][3]float32{{0, 0, -1}, {0, 0, 1}, {0, -1, 0}, {0, 1, 0}, {-1, 0, 0}, {1, 0, 0}}[tri/2]
+			got  = normals[3*v : 3*v+3]
+		)
+		if got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("triangle %d: normal %v, want %v", tri, got, want)
+		}
+		if pv, pf := posVec(pos, from[v]), posVec(pos, indices[c]); pv != pf {
+			t.Errorf("corner %d copies vertex %d at %v, want %v", c, from[v], pv, pf)
+		}
+	}
+}
+
+func TestSmoothNormalsSmooth(t *testing.T) {
+	var pos, indices = cube()
+	var normals, from, _ = SmoothNormals(pos, indices, math.Pi)
+	if len(from) != 8 {
+		t.Fatalf("got %d vertices, want 8", len(from))
+	}
+
+	// Each corner's normal points away from the centre along its diagonal.
+	var d = 1 / math.Sqrt(3)
+	for v := 0; v < 8; v++ {
+		var n = posVec(normals, uint32(v))
+		for c := range n {
+			var want = d
+			if pos[3*v+c] == 0 {
+				want = -d
+			}
+			if !near(n[c], want, 1e-6) {
+				t.Errorf("vertex %d: normal %v", v, n)
+				break
+			}
+		}
+	}
+}
+
+func TestTangents(t *testing.T) {
+	// A quad in the XY plane facing +Z, with V = 0 at the top, so U runs
+	// along +X. Vertices 4 and 5 duplicate 0 and 2 exactly.
+	var (
+		pos       = []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0, 0, 0, 0, 1, 1, 0}
+		normals   = []float32{0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1}
+		texcoords = []float32{0, 1, 1, 1, 1, 0, 0, 0, 0, 1, 1, 0}
+		indices   = []uint32{0, 1, 2, 4, 5, 3}
+	)
+	var tangents, from, _ = Tangents(pos, normals, texcoords, indices)
+	if len(from) != 6 {
+		t.Fatalf("got %d vertices, want 6", len(from))
+	}
+	for v := 0; v < 6; v++ {
+		var tan = tangents[4*v : 4*v+4]
+		if !near(float64(tan[0]), 1, 1e-6) || !near(float64(tan[1]), 0, 1e-6) || tan[3] != 1 {
+			t.Errorf("vertex %d: tangent %v, want [1 0 0 1]", v, tan)
+		}
+	}
+	for _, pair := range [][2]int{{0, 4}, {2, 5}} {
+		for c := 0; c < 4; c++ {
+			if tangents[4*pair[0]+c] != tangents[4*pair[1]+c] {
+				t.Errorf("welded vertices %d and %d differ: %v, %v", pair[0], pair[1], tangents[4*pair[0]:4*pair[0]+4], tangents[4*pair[1]:4*pair[1]+4])
+				break
+			}
+		}
+	}
+}
+
+func TestTangentsMirrored(t *testing.T) {
+	// Two triangles sharing the edge 1-2, the second mapped mirrored in U.
+	var (
+		pos       = []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 2, 0, 0}
+		normals   = []float32{0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1}
+		texcoords = []float32{0, 1, 1, 1, 1, 0, 0, 1}
+		indices   = []uint32{0, 1, 2, 1, 3, 2}
+	)
+	var tangents, from, tris = Tangents(pos, normals, texcoords, indices)
+
+	// The shared vertices are split by orientation.
+	if len(from) != 6 {
+		t.Fatalf("got %d vertices, want 6", len(from))
+	}
+	for c, v := range tris {
+		var want float32 = 1
+		if c >= 3 {
+			want = -1
+		}
+		if w := tangents[4*v+3]; w != want {
+			t.Errorf("corner %d: bitangent sign %v, want %v", c, w, want)
+		}
+	}
+}
+
+func TestDecodeSTLNormals(t *testing.T) {
+	// Two facets of a tent meeting along the Y axis, with welded positions
+	// smoothed together across the ridge.
+	var src = `solid tent
+facet normal 0 0 0
+outer loop
+vertex -1 0 0
+vertex 0 0 1
+vertex 0 1 1
+endloop
+endfacet
+facet normal 0 0 0
+outer loop
+vertex 1 0 0
+vertex 0 1 1
+vertex 0 0 1
+endloop
+endfacet
+endsolid tent
+`
+	var m, err = DecodeSTL(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Vertices() != 4 || len(m.Normals) != 12 {
+		t.Fatalf("got %d vertices and %d normal components", m.Vertices(), len(m.Normals))
+	}
+	for v := 0; v < 4; v++ {
+		var n = posVec(m.Normals, uint32(v))
+		if !near(math.Sqrt(n.dot(n)), 1, 1e-6) || n[2] <= 0 {
+			t.Errorf("vertex %d at %v: normal %v", v, posVec(m.Positions, uint32(v)), n)
+		}
+		// Ridge vertices are shared by both facets and face straight up.
+		if m.Positions[3*v] == 0 && !near(n[0], 0, 1e-6) {
+			t.Errorf("ridge vertex %d: normal %v", v, n)
+		}
+	}
+}
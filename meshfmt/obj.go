@@ -0,0 +1,275 @@
+package meshfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// OBJ is the geometry of a Wavefront OBJ file. Faces are split into a Mesh for
+// each object or group and material, and vertices sharing a position, texture
+// coordinate and normal are welded into one.
+type OBJ struct {
+	Meshes       []*Mesh
+	MaterialLibs []string // MTL files named by mtllib statements
+}
+
+// objLines calls fn with the fields of each statement of an OBJ or MTL file,
+// joining lines continued with a backslash and removing comments.
+func objLines(r io.Reader, fn func(line int, fields []string) error) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		n       int
+		pending string
+	)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		n++
+		var line = pending + scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			pending = line[:len(line)-1] + " "
+			continue
+		}
+		pending = ""
+
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		var fields = strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(n, fields); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseFloats parses the fields as numbers into 'v', which must not be longer
+// than 'fields'.
+func parseFloats(fields []string, v []float32) error {
+	for i := range v {
+		var f, err = strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return err
+		}
+		v[i] = float32(f)
+	}
+	return nil
+}
+
+// objBuilder accumulates the faces of one Mesh.
+type objBuilder struct {
+	mesh                 *Mesh
+	welded               map[[3]int]uint32
+	hasUV, hasN, hasCols bool
+}
+
+// DecodeOBJ reads a Wavefront OBJ file. Polygons are triangulated; points and
+// lines are ignored. Texture coordinates are flipped vertically, since OBJ
+// places the origin at the bottom of an image. Vertex colours, given as three
+// extra values of a 'v' statement, are read when present.
+func DecodeOBJ(r io.Reader) (*OBJ, error) {
+	var (
+		obj       = &OBJ{}
+		positions [][3]float32
+		colors    = make(map[int][3]float32) // by position, if given
+		texcoords [][2]float32
+		normals   [][3]float32
+
+		object, group = "", ""
+		material      string
+		builders      = make(map[[2]string]*objBuilder)
+		cur           *objBuilder
+	)
+
+	var name = func() string {
+		switch {
+		case object != "" && group != "":
+			return object + "/" + group
+		case group != "":
+			return group
+		case object != "":
+			return object
+		}
+		return "default"
+	}
+
+	// index resolves a 1-based or negative OBJ index into a slice of length n.
+	var index = func(field string, n int) (int, error) {
+		var i, err = strconv.Atoi(field)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			i += n
+		} else {
+			i--
+		}
+		if i < 0 || i >= n {
+			return 0, fmt.Errorf("index %s out of range", field)
+		}
+		return i, nil
+	}
+
+	var err = objLines(r, func(line int, f []string) error {
+		var fail = func(err error) error {
+			return fmt.Errorf("meshfmt.DecodeOBJ error: line %d: '%s': %v", line, f[0], err)
+		}
+
+		switch f[0] {
+		case "v":
+			var v [6]float32
+			if len(f) < 4 {
+				return fail(errors.New("too few values"))
+			}
+			var n = 3
+			if len(f) >= 7 {
+				n = 6
+			}
+			if err := parseFloats(f[1:], v[:n]); err != nil {
+				return fail(err)
+			}
+			if n == 6 {
+				colors[len(positions)] = [3]float32{v[3], v[4], v[5]}
+			}
+			positions = append(positions, [3]float32{v[0], v[1], v[2]})
+
+		case "vt":
+			var v [2]float32
+			if len(f) < 2 {
+				return fail(errors.New("too few values"))
+			}
+			if err := parseFloats(f[1:], v[:min(2, len(f)-1)]); err != nil {
+				return fail(err)
+			}
+			texcoords = append(texcoords, [2]float32{v[0], 1 - v[1]})
+
+		case "vn":
+			var v [3]float32
+			if len(f) < 4 {
+				return fail(errors.New("too few values"))
+			}
+			if err := parseFloats(f[1:], v[:]); err != nil {
+				return fail(err)
+			}
+			normals = append(normals, v)
+
+		case "o":
+			object, group = strings.Join(f[1:], " "), ""
+		case "g":
+			group = strings.Join(f[1:], " ")
+		case "usemtl":
+			material = strings.Join(f[1:], " ")
+		case "mtllib":
+			// File names may not contain spaces, so each field names a library.
+			obj.MaterialLibs = append(obj.MaterialLibs, f[1:]...)
+
+		case "f":
+			var key = [2]string{name(), material}
+			if cur = builders[key]; cur == nil {
+				cur = &objBuilder{
+					mesh:   &Mesh{Name: key[0], Material: key[1]},
+					welded: make(map[[3]int]uint32),
+				}
+				builders[key] = cur
+				obj.Meshes = append(obj.Meshes, cur.mesh)
+			}
+
+			var poly = make([]uint32, 0, len(f)-1)
+			for _, corner := range f[1:] {
+				var (
+					parts = strings.Split(corner, "/")
+					ref   = [3]int{-1, -1, -1}
+					err   error
+				)
+				if len(parts) > 3 {
+					return fail(fmt.Errorf("bad vertex '%s'", corner))
+				}
+				if ref[0], err = index(parts[0], len(positions)); err != nil {
+					return fail(err)
+				}
+				if len(parts) > 1 && parts[1] != "" {
+					if ref[1], err = index(parts[1], len(texcoords)); err != nil {
+						return fail(err)
+					}
+				}
+				if len(parts) > 2 && parts[2] != "" {
+					if ref[2], err = index(parts[2], len(normals)); err != nil {
+						return fail(err)
+					}
+				}
+
+				var v, ok = cur.welded[ref]
+				if !ok {
+					var m = cur.mesh
+					v = uint32(m.Vertices())
+					cur.welded[ref] = v
+
+					var p = positions[ref[0]]
+					m.Positions = append(m.Positions, p[:]...)
+
+					var uv [2]float32
+					if ref[1] >= 0 {
+						uv, cur.hasUV = texcoords[ref[1]], true
+					}
+					m.TexCoords = append(m.TexCoords, uv[:]...)
+
+					var n [3]float32
+					if ref[2] >= 0 {
+						n, cur.hasN = normals[ref[2]], true
+					}
+					m.Normals = append(m.Normals, n[:]...)
+
+					var c, ok = colors[ref[0]]
+					if ok {
+						cur.hasCols = true
+					} else {
+						c = [3]float32{1, 1, 1}
+					}
+					m.Colors = append(m.Colors, c[0], c[1], c[2], 1)
+				}
+				poly = append(poly, v)
+			}
+
+			var m = cur.mesh
+			m.Indices = triangulate(poly, func(i uint32) vec3 {
+				return vec3{float64(m.Positions[3*i]), float64(m.Positions[3*i+1]), float64(m.Positions[3*i+2])}
+			}, m.Indices)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop attributes that no vertex of a Mesh had, and Meshes without
+	// triangles.
+	var meshes = obj.Meshes[:0]
+	for _, b := range builders {
+		if !b.hasUV {
+			b.mesh.TexCoords = nil
+		}
+		if !b.hasN {
+			b.mesh.Normals = nil
+		}
+		if !b.hasCols {
+			b.mesh.Colors = nil
+		}
+	}
+	for _, m := range obj.Meshes {
+		if len(m.Indices) > 0 {
+			meshes = append(meshes, m)
+		}
+	}
+	obj.Meshes = meshes
+
+	return obj, nil
+}
@@ -0,0 +1,171 @@
+package meshfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeOBJ(t *testing.T) {
+	// A quad of two groups sharing its corners, the second with a different
+	// material, using negative indices, and a vertex colour on one corner.
+	var src = `mtllib a.mtl b.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0 1 0 0
+v 0 1 0
+vt 0 0
+vt 1 1
+vn 0 0 1
+g front
+usemtl red
+f 1/1/1 2/1/1 3/2/1 \
+  4/2/1
+# a triangle in another group
+g back
+usemtl blue
+f -1//1 -2//1 -3//1
+l 1 2
+`
+	var obj, err = DecodeOBJ(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.MaterialLibs) != 2 || obj.MaterialLibs[1] != "b.mtl" {
+		t.Errorf("material libraries %v", obj.MaterialLibs)
+	}
+	if len(obj.Meshes) != 2 {
+		t.Fatalf("got %d meshes, want 2", len(obj.Meshes))
+	}
+
+	var front, back = obj.Meshes[0], obj.Meshes[1]
+	if front.Name != "front" || front.Material != "red" || back.Name != "back" || back.Material != "blue" {
+		t.Errorf("meshes %s/%s and %s/%s", front.Name, front.Material, back.Name, back.Material)
+	}
+	if front.Vertices() != 4 || len(front.Indices) != 6 {
+		t.Errorf("front: %d vertices and %d indices", front.Vertices(), len(front.Indices))
+	}
+	// Texture coordinates are flipped vertically.
+	if front.TexCoords[1] != 1 || front.TexCoords[5] != 0 {
+		t.Errorf("front texture coordinates %v", front.TexCoords)
+	}
+	// Vertices without a colour are white.
+	if c := front.Colors[8:12]; c[0] != 1 || c[1] != 0 || c[3] != 1 {
+		t.Errorf("vertex 2 colour %v", c)
+	}
+	if c := front.Colors[0:4]; c[0] != 1 || c[1] != 1 {
+		t.Errorf("vertex 0 colour %v", c)
+	}
+
+	// The back triangle has no texture coordinates and refers to vertices
+	// 4, 3 and 2.
+	if back.TexCoords != nil || back.Normals == nil {
+		t.Errorf("back has texture coordinates %v and normals %v", back.TexCoords, back.Normals)
+	}
+	if back.Positions[0] != 0 || back.Positions[1] != 1 || back.Positions[6] != 1 || back.Positions[7] != 0 {
+		t.Errorf("back positions %v", back.Positions)
+	}
+}
+
+func TestDecodeOBJConcave(t *testing.T) {
+	// An L shaped hexagon must not be fanned from its first corner, whose
+	// fan would cover the notch.
+	var src = `v 0 0 0
+v 2 0 0
+v 2 1 0
+v 1 1 0
+v 1 2 0
+v 0 2 0
+f 3 4 5 6 1 2
+`
+	var obj, err = DecodeOBJ(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m = obj.Meshes[0]
+	if m.Name != "default" || len(m.Indices) != 12 {
+		t.Fatalf("got mesh %q with %d indices", m.Name, len(m.Indices))
+	}
+
+	// The triangles' areas add up to that of the polygon, 3, only if none
+	// overlap or leave the polygon.
+	var area float64
+	for i := 0; i < len(m.Indices); i += 3 {
+		var (
+			a = posVec(m.Positions, m.Indices[i])
+			b = posVec(m.Positions, m.Indices[i+1])
+			c = posVec(m.Positions, m.Indices[i+2])
+			n = b.sub(a).cross(c.sub(a))
+		)
+		if n[2] < 0 {
+			t.Errorf("triangle %v is wound backwards", m.Indices[i:i+3])
+		}
+		area += n[2] / 2
+	}
+	if !near(area, 3, 1e-9) {
+		t.Errorf("triangles cover an area of %v, want 3", area)
+	}
+}
+
+func TestDecodeOBJInvalid(t *testing.T) {
+	for _, src := range []string{
+		"v 0 0\n",
+		"v 0 0 x\n",
+		"v 0 0 0\nf 1 2 1\n",
+		"v 0 0 0\nf 1 1 -2\n",
+		"v 0 0 0\nf 1/1 1 1\n",
+		"v 0 0 0\nf 1/1/1/1 1 1\n",
+	} {
+		if _, err := DecodeOBJ(strings.NewReader(src)); err == nil {
+			t.Errorf("%q was decoded", src)
+		}
+	}
+}
+
+func TestDecodeMTL(t *testing.T) {
+	var src = `# two materials
+newmtl red paint
+Kd 1 0 0
+Ks 0.5
+Ns 32
+Tr 0.25
+illum 2
+map_Kd -s 2 2 -bm 0.5 textures/red paint.png
+bump -bm 2 bump.png
+newmtl plain
+Ka spectral ka.rfl
+d -halo 0.5
+norm normal.png
+`
+	var mats, err = DecodeMTL(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mats) != 2 {
+		t.Fatalf("got %d materials, want 2", len(mats))
+	}
+
+	var red, plain = mats[0], mats[1]
+	if red.Name != "red paint" || red.Diffuse != [3]float32{1, 0, 0} || red.Specular != [3]float32{0.5, 0.5, 0.5} {
+		t.Errorf("red: %+v", red)
+	}
+	if red.Shininess != 32 || red.Opacity != 0.75 || red.Illum != 2 {
+		t.Errorf("red: shininess %v, opacity %v, illum %d", red.Shininess, red.Opacity, red.Illum)
+	}
+	if red.DiffuseMap != "textures/red paint.png" || red.BumpMap != "bump.png" {
+		t.Errorf("red maps %q and %q", red.DiffuseMap, red.BumpMap)
+	}
+
+	// Defaults are kept where not given or not supported.
+	if plain.Diffuse != [3]float32{1, 1, 1} || plain.Ambient != [3]float32{} || plain.IOR != 1 {
+		t.Errorf("plain: %+v", plain)
+	}
+	if plain.Opacity != 0.5 || plain.NormalMap != "normal.png" {
+		t.Errorf("plain: opacity %v, normal map %q", plain.Opacity, plain.NormalMap)
+	}
+
+	for _, src := range []string{"Kd 1 1 1\n", "newmtl a\nKd\n", "newmtl a\nNs x\n"} {
+		if _, err := DecodeMTL(strings.NewReader(src)); err == nil {
+			t.Errorf("%q was decoded", src)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package meshfmt
+
+import (
+	"math"
+	"sort"
+)
+
+// Optimize rearranges the Mesh to draw faster. Identical vertices are welded,
+// degenerate triangles removed, triangles ordered for the post-transform vertex
+// cache, and optionally for less overdraw, and vertices ordered by first use,
+// dropping those no triangle uses.
+func (m *Mesh) Optimize(overdraw bool) {
+	var vertices = m.Vertices()
+
+	var from, remap = Weld(vertices, func(v int) string {
+		var key []float32
+		key = append(key, m.Positions[3*v:3*v+3]...)
+		for _, a := range []struct {
+			data  []float32
+			comps int
+		}{{m.Normals, 3}, {m.Tangents, 4}, {m.TexCoords, 2}, {m.Colors, 4}} {
+			if a.data != nil {
+				key = append(key, a.data[a.comps*v:a.comps*(v+1)]...)
+			}
+		}
+		for _, a := range m.Attribs {
+			key = append(key, a.Data[a.Components*v:a.Components*(v+1)]...)
+		}
+		return floatKey(key)
+	})
+	var indices = make([]uint32, len(m.Indices))
+	for i, v := range m.Indices {
+		indices[i] = remap[v]
+	}
+	m.remap(from, indices)
+
+	m.Indices = RemoveDegenerate(m.Indices, m.Positions)
+	m.Indices = OptimizeVertexCache(m.Indices, m.Vertices())
+	if overdraw {
+		m.Indices = OptimizeOverdraw(m.Indices, m.Positions, 1.05)
+	}
+	m.remap(OptimizeVertexFetch(m.Indices, m.Vertices()))
+}
+
+// Weld finds vertices that are identical, as told by their keys. It returns
+// the input vertex that each unique vertex copies, in order of first
+// appearance, and the unique vertex that each input vertex becomes.
+func Weld(vertices int, key func(v int) string) (from, remap []uint32) {
+	var unique = make(map[string]uint32)
+	remap = make([]uint32, vertices)
+	for v := range remap {
+		var k = key(v)
+		if u, ok := unique[k]; ok {
+			remap[v] = u
+			continue
+		}
+		unique[k] = uint32(len(from))
+		remap[v] = uint32(len(from))
+		from = append(from, uint32(v))
+	}
+	return from, remap
+}
+
+// RemoveDegenerate returns the triangles that use three different vertices
+// with different XYZ positions and cover some area. Positions may be nil to
+// check only the indices.
+func RemoveDegenerate(indices []uint32, pos []float32) []uint32 {
+	var out = make([]uint32, 0, len(indices))
+	for t := 0; t+2 < len(indices); t += 3 {
+		var i0, i1, i2 = indices[t], indices[t+1], indices[t+2]
+		if i0 == i1 || i1 == i2 || i2 == i0 {
+			continue
+		}
+		if pos != nil {
+			var p0 = posVec(pos, i0)
+			var n = posVec(pos, i1).sub(p0).cross(posVec(pos, i2).sub(p0))
+			if n.dot(n) == 0 {
+				continue
+			}
+		}
+		out = append(out, i0, i1, i2)
+	}
+	return out
+}
+
+// vertexCacheSize is the size of the post-transform vertex cache modelled by
+// OptimizeVertexCache and OptimizeOverdraw.
+const vertexCacheSize = 32
+
+// OptimizeVertexCache reorders triangles so that their vertices are more often
+// found in the GPU's post-transform cache, using Tom Forsyth's linear-speed
+// algorithm. Each triangle keeps its winding.
+func OptimizeVertexCache(indices []uint32, vertices int) []uint32 {
+	var n = len(indices) / 3
+	if n == 0 {
+		return nil
+	}
+
+	// Each vertex scores by its position in a modelled LRU cache and by how
+	// few triangles still use it, so that lone vertices are finished off.
+	var score = func(pos, remaining int) float64 {
+		if remaining == 0 {
+			return -1
+		}
+		var s float64
+		switch {
+		case pos < 0:
+		case pos < 3:
+			s = 0.75 // the last triangle's vertices are equally likely to hit
+		default:
+			s = math.Pow(1-float64(pos-3)/float64(vertexCacheSize-3), 1.5)
+		}
+		return s + 2/math.Sqrt(float64(remaining))
+	}
+
+	// The triangles of each vertex, those still to be added first.
+	var (
+		offsets   = make([]int, vertices+1)
+		remaining = make([]int, vertices)
+		adjacent  = make([]int, 3*n)
+	)
+	for _, v := range indices[:3*n] {
+		remaining[v]++
+	}
+	for v := 0; v < vertices; v++ {
+		offsets[v+1] = offsets[v] + remaining[v]
+	}
+	var fill = append([]int(nil), offsets[:vertices]...)
+	for i, v := range indices[:3*n] {
+		adjacent[fill[v]] = i / 3
+		fill[v]++
+	}
+
+	var (
+		cachePos = make([]int, vertices)
+		vscore   = make([]float64, vertices)
+		tscore   = make([]float64, n)
+		added    = make([]bool, n)
+		cache    []uint32
+		out      = make([]uint32, 0, 3*n)
+	)
+	for v := range cachePos {
+		cachePos[v] = -1
+		vscore[v] = score(-1, remaining[v])
+	}
+	var best = 0
+	for t := range tscore {
+		tscore[t] = vscore[indices[3*t]] + vscore[indices[3*t+1]] + vscore[indices[3*t+2]]
+		if tscore[t] > tscore[best] {
+			best = t
+		}
+	}
+
+	for cursor := 0; len(out) < 3*n; {
+		if best < 0 {
+			// Nothing in the cache has triangles left, so start afresh.
+			for added[cursor] {
+				cursor++
+			}
+			best = cursor
+		}
+
+		var tri = indices[3*best : 3*best+3]
+		added[best] = true
+		out = append(out, tri...)
+
+		for _, v := range tri {
+			var adj = adjacent[offsets[v] : offsets[v]+remaining[v]]
+			for k, t := range adj {
+				if t == best {
+					adj[k] = adj[len(adj)-1]
+					break
+				}
+			}
+			remaining[v]--
+		}
+
+		// Move the triangle's vertices to the front of the cache, letting the
+		// rest fall back and the oldest drop out.
+		var next = append(make([]uint32, 0, len(cache)+3), tri...)
+		for _, v := range cache {
+			if v != tri[0] && v != tri[1] && v != tri[2] {
+				next = append(next, v)
+			}
+		}
+		for i, v := range next {
+			if i < vertexCacheSize {
+				cachePos[v] = i
+			} else {
+				cachePos[v] = -1
+			}
+			vscore[v] = score(cachePos[v], remaining[v])
+		}
+
+		best = -1
+		var bestScore = 0.0
+		for _, v := range next {
+			for _, t := range adjacent[offsets[v] : offsets[v]+remaining[v]] {
+				tscore[t] = vscore[indices[3*t]] + vscore[indices[3*t+1]] + vscore[indices[3*t+2]]
+				if tscore[t] > bestScore {
+					best, bestScore = t, tscore[t]
+				}
+			}
+		}
+
+		if len(next) > vertexCacheSize {
+			next = next[:vertexCacheSize]
+		}
+		cache = next
+	}
+
+	return out
+}
+
+// OptimizeOverdraw reorders triangles, already ordered for the vertex cache,
+// so that those facing outwards from the centre of the mesh are drawn first and
+// hide more of those behind them. Triangles are sorted in clusters, divided
+// where the ratio of cache misses to triangles stays within 'threshold' times
+// that of the original order, such as 1.05 to give up at most 5%.
+func OptimizeOverdraw(indices []uint32, pos []float32, threshold float64) []uint32 {
+	var n = len(indices) / 3
+	if n == 0 {
+		return nil
+	}
+
+	var (
+		cachePos = make(map[uint32]int)
+		cache    []uint32
+	)
+	// misses simulates drawing a triangle through an LRU cache, returning the
+	// number of its vertices that were not in the cache.
+	var misses = func(t int) int {
+		var count = 0
+		for _, v := range indices[3*t : 3*t+3] {
+			if _, ok := cachePos[v]; !ok {
+				count++
+			}
+		}
+		var next = append(make([]uint32, 0, len(cache)+3), indices[3*t:3*t+3]...)
+		for _, v := range cache {
+			if v != next[0] && v != next[1] && v != next[2] {
+				next = append(next, v)
+			}
+		}
+		if len(next) > vertexCacheSize {
+			for _, v := range next[vertexCacheSize:] {
+				delete(cachePos, v)
+			}
+			next = next[:vertexCacheSize]
+		}
+		for i, v := range next {
+			cachePos[v] = i
+		}
+		cache = next
+		return count
+	}
+	var reset = func() {
+		clear(cachePos)
+		cache = cache[:0]
+	}
+
+	// Hard boundaries are where a triangle misses on every vertex, so that
+	// starting a cluster there costs nothing.
+	var (
+		hard        []int
+		totalMisses = 0
+	)
+	for t := 0; t < n; t++ {
+		var m = misses(t)
+		if m == 3 {
+			hard = append(hard, t)
+		}
+		totalMisses += m
+	}
+	hard = append(hard, n)
+	reset()
+
+	// Soft boundaries divide each hard cluster wherever the part drawn so
+	// far, starting with an empty cache, keeps within the threshold.
+	var (
+		acmr   = float64(totalMisses) / float64(n)
+		starts []int
+	)
+	for h := 0; h+1 < len(hard); h++ {
+		var start, count = hard[h], 0
+		starts = append(starts, start)
+		reset()
+		for t := start; t < hard[h+1]; t++ {
+			count += misses(t)
+			if t+1 < hard[h+1] && float64(count)/float64(t+1-start) <= acmr*threshold && t+1-start >= 8 {
+				start, count = t+1, 0
+				starts = append(starts, start)
+				reset()
+			}
+		}
+	}
+	starts = append(starts, n)
+
+	type cluster struct {
+		first, last int
+		sort        float64
+	}
+	var (
+		clusters = make([]cluster, len(starts)-1)
+		centres  = make([]vec3, len(clusters))
+		normals  = make([]vec3, len(clusters))
+		centroid vec3
+		area     float64
+	)
+	for c := range clusters {
+		clusters[c] = cluster{first: starts[c], last: starts[c+1]}
+		var clusterArea float64
+		for t := starts[c]; t < starts[c+1]; t++ {
+			var (
+				p0, p1, p2 = posVec(pos, indices[3*t]), posVec(pos, indices[3*t+1]), posVec(pos, indices[3*t+2])
+				cross      = p1.sub(p0).cross(p2.sub(p0))
+				a          = math.Sqrt(cross.dot(cross))
+			)
+			centres[c] = centres[c].add(p0.add(p1).add(p2).scale(a / 3))
+			normals[c] = normals[c].add(cross)
+			clusterArea += a
+		}
+		centroid = centroid.add(centres[c])
+		area += clusterArea
+		if clusterArea > 0 {
+			centres[c] = centres[c].scale(1 / clusterArea)
+		}
+	}
+	if area > 0 {
+		centroid = centroid.scale(1 / area)
+	}
+	for c := range clusters {
+		clusters[c].sort = centres[c].sub(centroid).dot(normals[c].normalize())
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].sort > clusters[j].sort
+	})
+
+	var out = make([]uint32, 0, 3*n)
+	for _, c := range clusters {
+		out = append(out, indices[3*c.first:3*c.last]...)
+	}
+	return out
+}
+
+// OptimizeVertexFetch orders vertices by their first use in the triangles, so
+// that they are read from memory more nearly in order, and drops vertices that
+// are not used. It returns the input vertex that each output vertex copies and
+// the triangles' new indices.
+func OptimizeVertexFetch(indices []uint32, vertices int) (from, tris []uint32) {
+	var remap = make([]int64, vertices)
+	for v := range remap {
+		remap[v] = -1
+	}
+
+	tris = make([]uint32, len(indices))
+	for i, v := range indices {
+		if remap[v] < 0 {
+			remap[v] = int64(len(from))
+			from = append(from, v)
+		}
+		tris[i] = uint32(remap[v])
+	}
+	return from, tris
+}
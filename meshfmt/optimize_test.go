@@ -0,0 +1,188 @@
+package meshfmt
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// grid returns a flat n by n grid of quads, each split into two triangles, with
+// the triangles shuffled.
+func grid(n int) ([]float32, []uint32) {
+	var (
+		pos     []float32
+		indices []uint32
+	)
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			pos = append(pos, float32(x), float32(y), 0)
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var v = uint32(y*(n+1) + x)
+			indices = append(indices, v, v+1, v+uint32(n)+2, v, v+uint32(n)+2, v+uint32(n)+1)
+		}
+	}
+	var r = rand.New(rand.NewSource(1))
+	r.Shuffle(len(indices)/3, func(i, j int) {
+		for c := 0; c < 3; c++ {
+			indices[3*i+c], indices[3*j+c] = indices[3*j+c], indices[3*i+c]
+		}
+	})
+	return pos, indices
+}
+
+// acmr returns the average number of vertices transformed per triangle through
+// a FIFO cache of 16 vertices.
+func acmr(indices []uint32) float64 {
+	var (
+		fifo   []uint32
+		misses int
+	)
+	for _, v := range indices {
+		var hit = false
+		for _, c := range fifo {
+			hit = hit || c == v
+		}
+		if !hit {
+			misses++
+			if fifo = append(fifo, v); len(fifo) > 16 {
+				fifo = fifo[1:]
+			}
+		}
+	}
+	return float64(misses) / float64(len(indices)/3)
+}
+
+// triangles returns the number of times each triangle appears, keyed by the
+// positions of its corners rotated to a common start so that winding counts.
+func triangles(pos []float32, indices []uint32) map[[3]vec3]int {
+	var set = make(map[[3]vec3]int)
+	for t := 0; t+2 < len(indices); t += 3 {
+		var tri = [3]vec3{posVec(pos, indices[t]), posVec(pos, indices[t+1]), posVec(pos, indices[t+2])}
+		for tri[0] != minVec(tri) {
+			tri = [3]vec3{tri[1], tri[2], tri[0]}
+		}
+		set[tri]++
+	}
+	return set
+}
+
+func minVec(tri [3]vec3) vec3 {
+	var m = tri[0]
+	for _, v := range tri[1:] {
+		if v[0] < m[0] || v[0] == m[0] && (v[1] < m[1] || v[1] == m[1] && v[2] < m[2]) {
+			m = v
+		}
+	}
+	return m
+}
+
+func sameTriangles(a, b map[[3]vec3]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, n := range a {
+		if b[k] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOptimizeVertexCache(t *testing.T) {
+	var pos, indices = grid(16)
+	var out = OptimizeVertexCache(indices, len(pos)/3)
+	if !sameTriangles(triangles(pos, indices), triangles(pos, out)) {
+		t.Fatal("triangles were changed")
+	}
+	// A shuffled grid transforms nearly three vertices per triangle; a well
+	// ordered one little more than one.
+	if before, after := acmr(indices), acmr(out); after > 0.9 || after >= before {
+		t.Errorf("ACMR %.2f before and %.2f after", before, after)
+	}
+}
+
+func TestOptimizeOverdraw(t *testing.T) {
+	// The faces of a cube facing outwards keep their triangles, and the
+	// vertex cache order is hardly worsened.
+	var pos, indices = cube()
+	indices = OptimizeVertexCache(indices, len(pos)/3)
+	var out = OptimizeOverdraw(indices, pos, 1.05)
+	if !sameTriangles(triangles(pos, indices), triangles(pos, out)) {
+		t.Fatal("triangles were changed")
+	}
+
+	var gpos, gindices = grid(16)
+	gindices = OptimizeVertexCache(gindices, len(gpos)/3)
+	var gout = OptimizeOverdraw(gindices, gpos, 1.05)
+	if !sameTriangles(triangles(gpos, gindices), triangles(gpos, gout)) {
+		t.Fatal("grid triangles were changed")
+	}
+	if before, after := acmr(gindices), acmr(gout); after > before*1.2 {
+		t.Errorf("ACMR %.2f before and %.2f after", before, after)
+	}
+}
+
+func TestOptimizeVertexFetch(t *testing.T) {
+	var from, tris = OptimizeVertexFetch([]uint32{3, 1, 4, 1, 4, 0}, 6)
+	if want := []uint32{3, 1, 4, 0}; !equalIndices(from, want) {
+		t.Errorf("from %v, want %v", from, want)
+	}
+	if want := []uint32{0, 1, 2, 1, 2, 3}; !equalIndices(tris, want) {
+		t.Errorf("triangles %v, want %v", tris, want)
+	}
+}
+
+func TestRemoveDegenerate(t *testing.T) {
+	// Vertices 0 and 3 share a position, and 0, 1 and 4 lie on a line.
+	var (
+		pos     = []float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 2, 0, 0}
+		indices = []uint32{0, 1, 2, 0, 0, 2, 1, 2, 3, 0, 3, 2, 0, 1, 4}
+	)
+	if got, want := RemoveDegenerate(indices, pos), []uint32{0, 1, 2, 1, 2, 3}; !equalIndices(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := RemoveDegenerate(indices, nil), []uint32{0, 1, 2, 1, 2, 3, 0, 3, 2, 0, 1, 4}; !equalIndices(got, want) {
+		t.Errorf("without positions: got %v, want %v", got, want)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	// A quad drawn as two unindexed triangles and an unused vertex. Vertex 3
+	// is welded with vertex 0, but vertex 4 differs from vertex 2 in its
+	// texture coordinates.
+	var m = &Mesh{
+		Positions: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 0, 0, 1, 1, 0, 0, 1, 0, 5, 5, 5},
+		TexCoords: []float32{0, 0, 1, 0, 1, 1, 0, 0, 1, 0.5, 0, 1, 0, 0},
+		Attribs:   []Attrib{{Name: "id", Components: 1, Data: []float32{1, 1, 1, 1, 1, 1, 2}}},
+		Indices:   []uint32{0, 1, 2, 3, 4, 5},
+	}
+	var before = triangles(m.Positions, m.Indices)
+	m.Optimize(true)
+
+	if m.Vertices() != 5 || len(m.TexCoords) != 10 || len(m.Attribs[0].Data) != 5 {
+		t.Fatalf("got %d vertices, %d texture coordinates and %d ids", m.Vertices(), len(m.TexCoords)/2, len(m.Attribs[0].Data))
+	}
+	if !sameTriangles(before, triangles(m.Positions, m.Indices)) {
+		t.Errorf("triangles were changed")
+	}
+	for i, v := range m.Indices[:3] {
+		if v != uint32(i) {
+			t.Errorf("vertices are not in order of first use: %v", m.Indices)
+			break
+		}
+	}
+}
+
+func equalIndices(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,328 @@
+package meshfmt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// plyProperty is a property of a PLY element. Lists have a count type as well
+// as a value type.
+type plyProperty struct {
+	name      string
+	typ       string
+	countType string // empty unless the property is a list
+}
+
+type plyElement struct {
+	name  string
+	count int
+	props []plyProperty
+}
+
+// plyTypeSizes is the size in bytes of each PLY scalar type, under both its
+// old and new names.
+var plyTypeSizes = map[string]int{
+	"char": 1, "uchar": 1, "short": 2, "ushort": 2, "int": 4, "uint": 4,
+	"float": 4, "double": 8,
+	"int8": 1, "uint8": 1, "int16": 2, "uint16": 2, "int32": 4, "uint32": 4,
+	"float32": 4, "float64": 8,
+}
+
+// plyReader reads scalar values from the body of a PLY file.
+type plyReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder // nil for ASCII files
+	words []string         // remaining values of the current ASCII line
+	buf   [8]byte
+}
+
+func (p *plyReader) value(typ string) (float64, error) {
+	if p.order == nil {
+		for len(p.words) == 0 {
+			var line, err = p.r.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			p.words = strings.Fields(line)
+		}
+		var w = p.words[0]
+		p.words = p.words[1:]
+		return strconv.ParseFloat(w, 64)
+	}
+
+	var (
+		n = plyTypeSizes[typ]
+		b = p.buf[:n]
+	)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(p.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(p.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(p.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(p.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(p.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(p.order.Uint64(b)), nil
+}
+
+// plyColorScale returns the factor bringing a colour channel of the given type
+// into the range [0, 1].
+func plyColorScale(typ string) float32 {
+	switch typ {
+	case "uchar", "uint8":
+		return 1.0 / 255
+	case "ushort", "uint16":
+		return 1.0 / 65535
+	}
+	return 1
+}
+
+// DecodePLY reads a Stanford PLY file in ASCII or binary form. The vertex
+// properties x, y and z give positions; nx, ny and nz give normals; u and v
+// (or s and t) give texture coordinates, which are flipped vertically as for
+// OBJ; and red, green, blue and alpha give colours. Any other scalar vertex
+// property is kept as an Attrib of one component named after it. Faces are
+// triangulated, and normals are computed when the file has none. Elements
+// other than vertices and faces are skipped.
+func DecodePLY(r io.Reader) (*Mesh, error) {
+	var fail = func(format string, args ...interface{}) error {
+		return fmt.Errorf("meshfmt.DecodePLY error: "+format, args...)
+	}
+
+	var (
+		br       = bufio.NewReader(r)
+		p        = &plyReader{r: br}
+		elements []*plyElement
+		format   string
+	)
+
+	var line, err = br.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, fail("not a PLY file")
+	}
+
+	for {
+		if line, err = br.ReadString('\n'); err != nil {
+			return nil, fail("unterminated header")
+		}
+		var f = strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if f[0] == "end_header" {
+			break
+		}
+
+		switch f[0] {
+		case "format":
+			if len(f) < 2 {
+				return nil, fail("bad format")
+			}
+			format = f[1]
+		case "element":
+			if len(f) < 3 {
+				return nil, fail("bad element")
+			}
+			var n, err = strconv.Atoi(f[2])
+			if err != nil || n < 0 {
+				return nil, fail("bad element count '%s'", f[2])
+			}
+			elements = append(elements, &plyElement{name: f[1], count: n})
+		case "property":
+			if len(elements) == 0 {
+				return nil, fail("property before element")
+			}
+			var prop plyProperty
+			switch {
+			case len(f) == 5 && f[1] == "list":
+				prop = plyProperty{name: f[4], typ: f[3], countType: f[2]}
+				if _, ok := plyTypeSizes[prop.countType]; !ok {
+					return nil, fail("unknown type '%s'", prop.countType)
+				}
+			case len(f) == 3:
+				prop = plyProperty{name: f[2], typ: f[1]}
+			default:
+				return nil, fail("bad property")
+			}
+			if _, ok := plyTypeSizes[prop.typ]; !ok {
+				return nil, fail("unknown type '%s'", prop.typ)
+			}
+			var e = elements[len(elements)-1]
+			e.props = append(e.props, prop)
+		}
+	}
+
+	switch format {
+	case "ascii":
+	case "binary_little_endian":
+		p.order = binary.LittleEndian
+	case "binary_big_endian":
+		p.order = binary.BigEndian
+	default:
+		return nil, fail("unsupported format '%s'", format)
+	}
+
+	var (
+		mesh        = &Mesh{}
+		hasN, hasUV bool
+		hasCol      bool
+		colorScale  [4]float32
+		extra       = make(map[int]int) // Attrib of each other vertex property
+		polys       [][]uint32
+	)
+
+	for _, e := range elements {
+		if e.name == "vertex" {
+			for i, prop := range e.props {
+				switch prop.name {
+				case "x", "y", "z":
+				case "nx", "ny", "nz":
+					hasN = true
+				case "u", "v", "s", "t", "texture_u", "texture_v":
+					hasUV = true
+				case "red", "green", "blue", "alpha":
+					var c = strings.Index("rgba", prop.name[:1])
+					colorScale[c] = plyColorScale(prop.typ)
+					hasCol = true
+				default:
+					if prop.countType == "" {
+						extra[i] = len(mesh.Attribs)
+						mesh.Attribs = append(mesh.Attribs, Attrib{Name: prop.name, Components: 1})
+					}
+				}
+			}
+		}
+
+		for n := 0; n < e.count; n++ {
+			var (
+				pos, nrm [3]float32
+				uv       = [2]float32{0, 1}
+				col      = [4]float32{1, 1, 1, 1}
+			)
+
+			for i, prop := range e.props {
+				if prop.countType != "" {
+					var count, err = p.value(prop.countType)
+					if err != nil {
+						return nil, fail("%s %d: %v", e.name, n, err)
+					}
+					if count < 0 || count > math.MaxUint32 || count != math.Trunc(count) {
+						return nil, fail("%s %d: invalid list length %v", e.name, n, count)
+					}
+
+					// The list grows as it is read, as its length may be
+					// more than the rest of the file holds.
+					var list []uint32
+					for j := 0; j < int(count); j++ {
+						var v, err = p.value(prop.typ)
+						if err != nil {
+							return nil, fail("%s %d: %v", e.name, n, err)
+						}
+						list = append(list, uint32(v))
+					}
+					if e.name == "face" && (prop.name == "vertex_indices" || prop.name == "vertex_index") {
+						polys = append(polys, list)
+					}
+					continue
+				}
+
+				var v, err = p.value(prop.typ)
+				if err != nil {
+					return nil, fail("%s %d: %v", e.name, n, err)
+				}
+				if e.name != "vertex" {
+					continue
+				}
+
+				var fv = float32(v)
+				switch prop.name {
+				case "x":
+					pos[0] = fv
+				case "y":
+					pos[1] = fv
+				case "z":
+					pos[2] = fv
+				case "nx":
+					nrm[0] = fv
+				case "ny":
+					nrm[1] = fv
+				case "nz":
+					nrm[2] = fv
+				case "u", "s", "texture_u":
+					uv[0] = fv
+				case "v", "t", "texture_v":
+					uv[1] = 1 - fv
+				case "red":
+					col[0] = fv * colorScale[0]
+				case "green":
+					col[1] = fv * colorScale[1]
+				case "blue":
+					col[2] = fv * colorScale[2]
+				case "alpha":
+					col[3] = fv * colorScale[3]
+				default:
+					if k, ok := extra[i]; ok {
+						mesh.Attribs[k].Data = append(mesh.Attribs[k].Data, fv)
+					}
+				}
+			}
+
+			if e.name == "vertex" {
+				mesh.Positions = append(mesh.Positions, pos[:]...)
+				if hasN {
+					mesh.Normals = append(mesh.Normals, nrm[:]...)
+				}
+				if hasUV {
+					mesh.TexCoords = append(mesh.TexCoords, uv[:]...)
+				}
+				if hasCol {
+					mesh.Colors = append(mesh.Colors, col[:]...)
+				}
+			}
+		}
+	}
+
+	var count = uint32(mesh.Vertices())
+	for i, poly := range polys {
+		for _, v := range poly {
+			if v >= count {
+				return nil, fail("face %d: index %d out of range", i, v)
+			}
+		}
+		mesh.Indices = triangulate(poly, func(i uint32) vec3 {
+			return vec3{float64(mesh.Positions[3*i]), float64(mesh.Positions[3*i+1]), float64(mesh.Positions[3*i+2])}
+		}, mesh.Indices)
+	}
+	if len(mesh.Indices) == 0 {
+		return nil, errors.New("meshfmt.DecodePLY error: no faces")
+	}
+
+	if !hasN {
+		mesh.ComputeSmoothNormals(math.Pi)
+	}
+
+	return mesh, nil
+}
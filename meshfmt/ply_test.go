@@ -0,0 +1,129 @@
+package meshfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodePLYASCII(t *testing.T) {
+	// A quad with 8-bit colours, texture coordinates, an extra property and a
+	// skipped element, and no normals.
+	var src = `ply
+format ascii 1.0
+comment a quad
+element vertex 4
+property float x
+property float y
+property float z
+property float s
+property float t
+property uchar red
+property uchar green
+property uchar blue
+property float quality
+element face 1
+property list uchar int vertex_indices
+element edge 1
+property int vertex1
+property int vertex2
+end_header
+0 0 0 0 0 255 0 0 0.5
+1 0 0 1 0 0 255 0 1
+1 1 0 1 1 0 0 255 1
+0 1 0 0 1 255 255 255 0
+4 0 1 2 3
+0 1
+`
+	var m, err = DecodePLY(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Vertices() != 4 || len(m.Indices) != 6 {
+		t.Fatalf("got %d vertices and %d indices", m.Vertices(), len(m.Indices))
+	}
+	if m.TexCoords[1] != 1 || m.TexCoords[5] != 0 {
+		t.Errorf("texture coordinates %v are not flipped", m.TexCoords)
+	}
+	if c := m.Colors[4:8]; c[0] != 0 || c[1] != 1 || c[2] != 0 || c[3] != 1 {
+		t.Errorf("vertex 1 colour %v", c)
+	}
+	if len(m.Attribs) != 1 || m.Attribs[0].Name != "quality" || m.Attribs[0].Data[0] != 0.5 {
+		t.Errorf("attributes %+v", m.Attribs)
+	}
+	// Normals are computed facing +Z.
+	for v := 0; v < 4; v++ {
+		if n := posVec(m.Normals, uint32(v)); !near(n[2], 1, 1e-6) {
+			t.Errorf("vertex %d: normal %v", v, n)
+		}
+	}
+}
+
+func TestDecodePLYBinary(t *testing.T) {
+	// A big-endian triangle with normals and a face list of int indices.
+	var (
+		buf bytes.Buffer
+		be  = binary.BigEndian
+	)
+	buf.WriteString(`ply
+format binary_big_endian 1.0
+element vertex 3
+property float x
+property float y
+property float z
+property double nx
+property double ny
+property double nz
+element face 1
+property list uchar uint vertex_index
+end_header
+`)
+	for _, p := range [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}} {
+		for _, c := range p {
+			binary.Write(&buf, be, math.Float32bits(c))
+		}
+		binary.Write(&buf, be, [3]float64{0, 0, -1})
+	}
+	buf.WriteByte(3)
+	binary.Write(&buf, be, [3]uint32{0, 2, 1})
+
+	var m, err = DecodePLY(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Vertices() != 3 || len(m.Indices) != 3 || m.Indices[1] != 2 {
+		t.Fatalf("got %d vertices and indices %v", m.Vertices(), m.Indices)
+	}
+	if m.Positions[3] != 1 || m.Positions[7] != 1 {
+		t.Errorf("positions %v", m.Positions)
+	}
+	// The file's normals are kept.
+	if m.Normals[2] != -1 || m.TexCoords != nil || m.Colors != nil {
+		t.Errorf("normals %v, texture coordinates %v, colours %v", m.Normals, m.TexCoords, m.Colors)
+	}
+}
+
+func TestDecodePLYInvalid(t *testing.T) {
+	var header = "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	for _, src := range []string{
+		"obj\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\n",
+		"ply\nformat ascii 1.0\nproperty float x\nend_header\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\nproperty half x\nend_header\n",
+		"ply\nformat binary_middle_endian 1.0\nend_header\n",
+		header + "0 0 0 1 0 0 0 1 0\n3 0 1 3\n",
+		header + "0 0 0 1 0 0 0 1 0\n3 0 1\n",
+		header + "0 0 0 1 0 0 0 1 0\n",
+		header + "0 0 0 1 0 0 0 1 0\n2 0 1\n",
+		header + "0 0 0 1 0 0 0 1 0\n4294967295 0 1 2\n",
+		header + "0 0 0 1 0 0 0 1 0\n1e30 0 1 2\n",
+		header + "0 0 0 1 0 0 0 1 0\n-1 0 1 2\n",
+		header + "0 0 0 1 0 0 0 1 0\n2.5 0 1 2\n",
+	} {
+		if _, err := DecodePLY(strings.NewReader(src)); err == nil {
+			t.Errorf("%q was decoded", src)
+		}
+	}
+}
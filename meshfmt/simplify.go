@@ -0,0 +1,359 @@
+package meshfmt
+
+import (
+	"math"
+	"sort"
+)
+
+// Simplify reduces the Mesh to about 'target' triangles, as by the function
+// Simplify, dropping vertices that are no longer used. It returns the error of
+// the result.
+func (m *Mesh) Simplify(target int, maxError float64) float64 {
+	var indices, err = Simplify(m.Indices, m.Positions, target, maxError)
+	m.remap(OptimizeVertexFetch(indices, m.Vertices()))
+	return err
+}
+
+// quadric is the sum of the squared distances to a set of weighted planes,
+// as the symmetric matrix of a quadratic form, with the total weight.
+type quadric struct {
+	a2, ab, ac, ad, b2, bc, bd, c2, cd, d2 float64
+	w                                      float64
+}
+
+// planeQuadric returns the quadric of the plane through 'p' with unit normal
+// 'n'.
+func planeQuadric(n, p vec3, weight float64) quadric {
+	var d = -n.dot(p)
+	return quadric{
+		n[0] * n[0] * weight, n[0] * n[1] * weight, n[0] * n[2] * weight, n[0] * d * weight,
+		n[1] * n[1] * weight, n[1] * n[2] * weight, n[1] * d * weight,
+		n[2] * n[2] * weight, n[2] * d * weight,
+		d * d * weight,
+		weight,
+	}
+}
+
+func (q *quadric) add(o quadric) {
+	q.a2 += o.a2
+	q.ab += o.ab
+	q.ac += o.ac
+	q.ad += o.ad
+	q.b2 += o.b2
+	q.bc += o.bc
+	q.bd += o.bd
+	q.c2 += o.c2
+	q.cd += o.cd
+	q.d2 += o.d2
+	q.w += o.w
+}
+
+// eval returns the mean squared distance of 'p' to the planes.
+func (q *quadric) eval(p vec3) float64 {
+	if q.w == 0 {
+		return 0
+	}
+	var x, y, z = p[0], p[1], p[2]
+	var e = q.a2*x*x + 2*q.ab*x*y + 2*q.ac*x*z + 2*q.ad*x +
+		q.b2*y*y + 2*q.bc*y*z + 2*q.bd*y +
+		q.c2*z*z + 2*q.cd*z +
+		q.d2
+	return math.Max(e/q.w, 0)
+}
+
+// borderWeight is the weight of the planes that hold borders in place,
+// relative to those of the triangles.
+const borderWeight = 10
+
+// Simplify reduces indexed triangles to about 'target' triangles by collapsing
+// edges in order of least quadric error, without moving any vertex. It returns
+// the new triangles, which use a subset of the vertices, and their error: the
+// greatest distance by which they stray from the original surface, as a
+// fraction of the size of the mesh. No collapse exceeds 'maxError', unless it
+// is zero, so fewer triangles may be removed than asked.
+//
+// Vertices at the same XYZ position are treated as one, so that seams where
+// other attributes differ are kept together: a vertex on a seam moves only
+// along it. Vertices on a border move only along it, and borders are also held
+// in place by planes through their edges. Collapses that would flip a triangle
+// are not made.
+func Simplify(indices []uint32, pos []float32, target int, maxError float64) ([]uint32, float64) {
+	var (
+		tris     = RemoveDegenerate(indices, nil)
+		vertices = len(pos) / 3
+	)
+
+	// Group the vertices, or wedges, at each position.
+	var (
+		posOf  = make([]uint32, vertices)
+		wedges [][]uint32
+		ids    = make(map[[3]float32]uint32)
+		box    = [2]vec3{{math.Inf(1), math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1), math.Inf(-1)}}
+	)
+	for v := 0; v < vertices; v++ {
+		var key = [3]float32{pos[3*v], pos[3*v+1], pos[3*v+2]}
+		var id, ok = ids[key]
+		if !ok {
+			id = uint32(len(wedges))
+			ids[key] = id
+			wedges = append(wedges, nil)
+		}
+		posOf[v] = id
+		wedges[id] = append(wedges[id], uint32(v))
+	}
+	var point = func(id uint32) vec3 {
+		return posVec(pos, wedges[id][0])
+	}
+	for _, v := range tris {
+		var p = posVec(pos, v)
+		for c := range p {
+			box[0][c], box[1][c] = math.Min(box[0][c], p[c]), math.Max(box[1][c], p[c])
+		}
+	}
+	var scale = math.Max(box[1][0]-box[0][0], math.Max(box[1][1]-box[0][1], box[1][2]-box[0][2]))
+	if len(tris) == 0 || scale == 0 {
+		return tris, 0
+	}
+
+	var maxCost = math.Inf(1)
+	if maxError > 0 {
+		maxCost = maxError * scale * maxError * scale
+	}
+
+	type edge [2]uint32
+	var edgeOf = func(a, b uint32) edge {
+		if a > b {
+			a, b = b, a
+		}
+		return edge{a, b}
+	}
+
+	// Position edges used by other than two triangles are borders.
+	var edgeCount = func() map[edge]int {
+		var counts = make(map[edge]int)
+		for t := 0; t < len(tris); t += 3 {
+			for k := 0; k < 3; k++ {
+				counts[edgeOf(posOf[tris[t+k]], posOf[tris[t+(k+1)%3]])]++
+			}
+		}
+		return counts
+	}
+
+	var quadrics = make([]quadric, len(wedges))
+	{
+		var counts = edgeCount()
+		for t := 0; t < len(tris); t += 3 {
+			var (
+				a, b, c = posOf[tris[t]], posOf[tris[t+1]], posOf[tris[t+2]]
+				pa      = point(a)
+				cross   = point(b).sub(pa).cross(point(c).sub(pa))
+				area    = math.Sqrt(cross.dot(cross)) / 2
+			)
+			if area == 0 {
+				continue
+			}
+			var (
+				n = cross.normalize()
+				q = planeQuadric(n, pa, area)
+			)
+			for _, id := range []uint32{a, b, c} {
+				quadrics[id].add(q)
+			}
+
+			for _, e := range [][2]uint32{{a, b}, {b, c}, {c, a}} {
+				if counts[edgeOf(e[0], e[1])] != 1 {
+					continue
+				}
+				var (
+					p0, p1 = point(e[0]), point(e[1])
+					dir    = p1.sub(p0)
+					length = dir.dot(dir)
+					bq     = planeQuadric(dir.cross(n).normalize(), p0, length*borderWeight)
+				)
+				quadrics[e[0]].add(bq)
+				quadrics[e[1]].add(bq)
+			}
+		}
+	}
+
+	var (
+		wedgeRemap = make([]uint32, vertices)
+		worst      float64
+	)
+	for v := range wedgeRemap {
+		wedgeRemap[v] = uint32(v)
+	}
+
+	for len(tris)/3 > target {
+		var (
+			counts    = edgeCount()
+			border    = make([]bool, len(wedges))
+			neighbors = make(map[uint32][]uint32)
+			around    = make(map[uint32][]int) // triangles at each position
+		)
+		for e, count := range counts {
+			if count != 2 {
+				border[e[0]], border[e[1]] = true, true
+			}
+		}
+		for t := 0; t < len(tris); t += 3 {
+			for k := 0; k < 3; k++ {
+				var a, b = tris[t+k], tris[t+(k+1)%3]
+				neighbors[a] = append(neighbors[a], b)
+				neighbors[b] = append(neighbors[b], a)
+				around[posOf[a]] = append(around[posOf[a]], t)
+			}
+		}
+
+		// partners finds, for each wedge at 'from' in use, a wedge at 'to'
+		// joined to it by an edge. It fails if any wedge has none, as when
+		// collapsing across a seam.
+		var partners = func(from, to uint32) (map[uint32]uint32, bool) {
+			var out = make(map[uint32]uint32)
+			for _, w := range wedges[from] {
+				var adj = neighbors[w]
+				if len(adj) == 0 {
+					continue
+				}
+				var found = false
+				for _, n := range adj {
+					if posOf[n] == to {
+						out[w], found = n, true
+						break
+					}
+				}
+				if !found {
+					return nil, false
+				}
+			}
+			return out, true
+		}
+
+		type collapse struct {
+			from, to uint32
+			cost     float64
+		}
+		var candidates []collapse
+		for e, count := range counts {
+			for _, c := range []collapse{{from: e[0], to: e[1]}, {from: e[1], to: e[0]}} {
+				if border[c.from] && count != 1 {
+					continue
+				}
+				var q = quadrics[c.from]
+				q.add(quadrics[c.to])
+				c.cost = q.eval(point(c.to))
+				candidates = append(candidates, c)
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			var a, b = candidates[i], candidates[j]
+			if a.cost != b.cost {
+				return a.cost < b.cost
+			}
+			return a.from < b.from || a.from == b.from && a.to < b.to
+		})
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		// Collapses costlier than both the cheapest one skipped for a locked
+		// neighbour and those this pass needs, allowing for rejections, wait
+		// for the next pass, where the cheaper ones may be possible.
+		var (
+			limit   = candidates[min(len(candidates)-1, (len(tris)/3-target)*3/4)].cost
+			pending = math.Inf(1)
+		)
+
+		var (
+			locked   = make([]bool, len(wedges))
+			posRemap = make(map[uint32]uint32)
+			resolve  = func(id uint32) uint32 {
+				if r, ok := posRemap[id]; ok {
+					return r
+				}
+				return id
+			}
+			removed   = 0
+			collapsed = 0
+		)
+		for _, c := range candidates {
+			if c.cost > maxCost || len(tris)/3-removed <= target || c.cost > limit && c.cost > pending {
+				break
+			}
+			if locked[c.from] || locked[c.to] {
+				pending = math.Min(pending, c.cost)
+				continue
+			}
+			var remap, ok = partners(c.from, c.to)
+			if !ok {
+				continue
+			}
+
+			// Reject collapses that would flip a triangle, counting those
+			// that would vanish.
+			var (
+				flips  = false
+				vanish = 0
+				pTo    = point(c.to)
+			)
+			for _, t := range around[c.from] {
+				var (
+					ids [3]uint32
+					has = false
+				)
+				for k := range ids {
+					ids[k] = resolve(posOf[tris[t+k]])
+					has = has || ids[k] == c.to
+				}
+				if has {
+					vanish++
+					continue
+				}
+				var p [3]vec3
+				for k := range p {
+					p[k] = point(ids[k])
+				}
+				var before = p[1].sub(p[0]).cross(p[2].sub(p[0]))
+				for k := range ids {
+					if ids[k] == c.from {
+						p[k] = pTo
+					}
+				}
+				var after = p[1].sub(p[0]).cross(p[2].sub(p[0]))
+				if before.dot(after) <= 0 {
+					flips = true
+					break
+				}
+			}
+			if flips {
+				continue
+			}
+
+			for w, to := range remap {
+				wedgeRemap[w] = to
+			}
+			posRemap[c.from] = c.to
+			quadrics[c.to].add(quadrics[c.from])
+			locked[c.from], locked[c.to] = true, true
+			worst = math.Max(worst, c.cost)
+			removed += vanish
+			collapsed++
+		}
+		if collapsed == 0 {
+			break
+		}
+
+		var next = tris[:0]
+		for t := 0; t < len(tris); t += 3 {
+			var a, b, c = wedgeRemap[tris[t]], wedgeRemap[tris[t+1]], wedgeRemap[tris[t+2]]
+			if posOf[a] == posOf[b] || posOf[b] == posOf[c] || posOf[c] == posOf[a] {
+				continue
+			}
+			next = append(next, a, b, c)
+		}
+		tris = next
+	}
+
+	return tris, math.Sqrt(worst) / scale
+}
@@ -0,0 +1,131 @@
+package meshfmt
+
+import (
+	"math"
+	"testing"
+)
+
+// area returns the total area of triangles in the XY plane, failing the test
+// if any faces -Z.
+func area(t *testing.T, pos []float32, indices []uint32) float64 {
+	var sum float64
+	for i := 0; i+2 < len(indices); i += 3 {
+		var (
+			a = posVec(pos, indices[i])
+			n = posVec(pos, indices[i+1]).sub(a).cross(posVec(pos, indices[i+2]).sub(a))
+		)
+		if n[2] <= 0 {
+			t.Errorf("triangle %v is flipped", indices[i:i+3])
+		}
+		sum += n[2] / 2
+	}
+	return sum
+}
+
+func TestSimplifyFlat(t *testing.T) {
+	// A flat grid keeps its outline, so its area, however far it is reduced.
+	var pos, indices = grid(8)
+	var out, e = Simplify(indices, pos, 8, 0)
+	if len(out)/3 > 8 {
+		t.Errorf("got %d triangles, want at most 8", len(out)/3)
+	}
+	if e > 1e-6 {
+		t.Errorf("error %v, want 0", e)
+	}
+	if a := area(t, pos, out); !near(a, 64, 1e-6) {
+		t.Errorf("area %v, want 64", a)
+	}
+}
+
+func TestSimplifyMaxError(t *testing.T) {
+	// A grid with a raised ridge along its middle can lose its flat parts
+	// within a small error, but not the ridge.
+	var pos, indices = grid(8)
+	for v := 0; v < len(pos)/3; v++ {
+		pos[3*v+2] = float32(4 - math.Abs(float64(pos[3*v])-4))
+	}
+	var out, e = Simplify(indices, pos, 0, 0.01)
+	if e > 0.01 {
+		t.Errorf("error %v exceeds 0.01", e)
+	}
+	if n := len(out) / 3; n >= 128 || n < 4 {
+		t.Errorf("got %d triangles from 128", n)
+	}
+	// Every vertex on the ridge is still used, since the ridge is not flat
+	// across it.
+	var used = make(map[float32]bool)
+	for _, v := range out {
+		if pos[3*v] == 4 {
+			used[pos[3*v+1]] = true
+		}
+	}
+	if len(used) < 2 {
+		t.Errorf("ridge vertices at y = %v remain", used)
+	}
+	for _, v := range out {
+		if x := pos[3*v]; x != 0 && x != 4 && x != 8 {
+			t.Errorf("vertex %d at %v is off the ridge and borders", v, posVec(pos, v))
+			break
+		}
+	}
+}
+
+func TestSimplifySeam(t *testing.T) {
+	// A grid whose middle column of vertices is duplicated, each half using
+	// its own copy. Triangles must keep using only their own side's copies.
+	var pos, indices = grid(8)
+	var (
+		vertices = uint32(len(pos) / 3)
+		copyOf   = make(map[uint32]uint32)
+	)
+	for v := uint32(0); v < vertices; v++ {
+		if pos[3*v] == 4 {
+			copyOf[v] = uint32(len(pos) / 3)
+			pos = append(pos, pos[3*v:3*v+3]...)
+		}
+	}
+	var right = func(tri []uint32) bool {
+		for _, v := range tri {
+			if v < vertices && pos[3*v] > 4 {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(indices); i += 3 {
+		if right(indices[i : i+3]) {
+			for k := i; k < i+3; k++ {
+				if c, ok := copyOf[indices[k]]; ok {
+					indices[k] = c
+				}
+			}
+		}
+	}
+
+	var out, _ = Simplify(indices, pos, 4, 0)
+	if len(out)/3 >= 128 {
+		t.Fatalf("no triangles were removed")
+	}
+	if a := area(t, pos, out); !near(a, 64, 1e-6) {
+		t.Errorf("area %v, want 64", a)
+	}
+	for i := 0; i < len(out); i += 3 {
+		var tri = out[i : i+3]
+		for _, v := range tri {
+			if v < vertices && pos[3*v] == 4 && right(tri) {
+				t.Errorf("right triangle %v uses left seam vertex %d", tri, v)
+			}
+		}
+	}
+}
+
+func TestMeshSimplify(t *testing.T) {
+	var pos, indices = grid(4)
+	var m = &Mesh{Positions: pos, Indices: indices}
+	m.Simplify(2, 0)
+
+	// Only the corners remain.
+	if m.Vertices() != 4 || len(m.Indices) != 6 {
+		t.Errorf("got %d vertices and %d triangles", m.Vertices(), len(m.Indices)/3)
+	}
+}
@@ -0,0 +1,136 @@
+package meshfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// stlBuilder welds the vertices of STL triangles by position.
+type stlBuilder struct {
+	mesh   *Mesh
+	welded map[[3]float32]uint32
+}
+
+func (b *stlBuilder) vertex(p [3]float32) uint32 {
+	// Negative zero is welded with positive zero.
+	for c := range p {
+		if p[c] == 0 {
+			p[c] = 0
+		}
+	}
+
+	var v, ok = b.welded[p]
+	if !ok {
+		v = uint32(b.mesh.Vertices())
+		b.welded[p] = v
+		b.mesh.Positions = append(b.mesh.Positions, p[:]...)
+	}
+	return v
+}
+
+// triangle adds a triangle, dropping it if welding has made it degenerate.
+func (b *stlBuilder) triangle(p0, p1, p2 [3]float32) {
+	var i0, i1, i2 = b.vertex(p0), b.vertex(p1), b.vertex(p2)
+	if i0 != i1 && i1 != i2 && i2 != i0 {
+		b.mesh.Indices = append(b.mesh.Indices, i0, i1, i2)
+	}
+}
+
+// DecodeSTL reads an STL file in ASCII or binary form. Vertices sharing a
+// position are welded into one, and normals are computed from the welded
+// triangles rather than read from the file's facet normals. ASCII files give
+// the Mesh the name of their solid.
+func DecodeSTL(r io.Reader) (*Mesh, error) {
+	var data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var b = &stlBuilder{mesh: &Mesh{}, welded: make(map[[3]float32]uint32)}
+
+	// Binary files may also begin with "solid", so their size is checked
+	// first.
+	if len(data) >= 84 && 84+50*int(binary.LittleEndian.Uint32(data[80:])) == len(data) {
+		for rec := data[84:]; len(rec) >= 50; rec = rec[50:] {
+			var p [3][3]float32
+			for v := range p {
+				for c := range p[v] {
+					p[v][c] = math.Float32frombits(binary.LittleEndian.Uint32(rec[12+12*v+4*c:]))
+				}
+			}
+			b.triangle(p[0], p[1], p[2])
+		}
+	} else if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		if err = b.ascii(data); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("meshfmt.DecodeSTL error: not an STL file")
+	}
+
+	if len(b.mesh.Indices) == 0 {
+		return nil, errors.New("meshfmt.DecodeSTL error: no triangles")
+	}
+	b.mesh.ComputeSmoothNormals(math.Pi)
+
+	return b.mesh, nil
+}
+
+// ascii reads the facets of an ASCII STL file. Loops of more than three
+// vertices are triangulated.
+func (b *stlBuilder) ascii(data []byte) error {
+	var (
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		n       int
+		loop    [][3]float32
+	)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		n++
+		var f = strings.Fields(scanner.Text())
+		if len(f) == 0 {
+			continue
+		}
+
+		switch f[0] {
+		case "solid":
+			if b.mesh.Name == "" {
+				b.mesh.Name = strings.Join(f[1:], " ")
+			}
+		case "outer":
+			loop = loop[:0]
+		case "vertex":
+			var v [3]float32
+			if len(f) < 4 {
+				return fmt.Errorf("meshfmt.DecodeSTL error: line %d: too few values", n)
+			}
+			if err := parseFloats(f[1:], v[:]); err != nil {
+				return fmt.Errorf("meshfmt.DecodeSTL error: line %d: %v", n, err)
+			}
+			loop = append(loop, v)
+		case "endloop":
+			if len(loop) == 3 {
+				b.triangle(loop[0], loop[1], loop[2])
+				continue
+			}
+
+			var poly = make([]uint32, len(loop))
+			for i, p := range loop {
+				poly[i] = b.vertex(p)
+			}
+			var m = b.mesh
+			m.Indices = triangulate(poly, func(i uint32) vec3 {
+				return vec3{float64(m.Positions[3*i]), float64(m.Positions[3*i+1]), float64(m.Positions[3*i+2])}
+			}, m.Indices)
+		}
+	}
+
+	return scanner.Err()
+}
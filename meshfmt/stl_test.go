@@ -0,0 +1,58 @@
+package meshfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// binarySTL returns a binary STL file of the triangles, with a header that
+// begins with "solid" as some exporters write.
+func binarySTL(tris [][3][3]float32) []byte {
+	var buf bytes.Buffer
+	var header [80]byte
+	copy(header[:], "solid exported")
+	buf.Write(header[:])
+	binary.Write(&buf, binary.LittleEndian, uint32(len(tris)))
+	for _, tri := range tris {
+		binary.Write(&buf, binary.LittleEndian, [3]float32{}) // facet normal
+		binary.Write(&buf, binary.LittleEndian, tri)
+		buf.Write([]byte{0, 0})
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeSTLBinary(t *testing.T) {
+	// Two triangles of a square sharing an edge, one given with negative
+	// zeros, and a degenerate triangle that is dropped.
+	var negZero = float32(math.Copysign(0, -1))
+	var m, err = DecodeSTL(bytes.NewReader(binarySTL([][3][3]float32{
+		{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}},
+		{{negZero, negZero, 0}, {1, 1, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {0, 0, 0}, {1, 1, 0}},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Vertices() != 4 || len(m.Indices) != 6 {
+		t.Fatalf("got %d vertices and %d indices, want 4 and 6", m.Vertices(), len(m.Indices))
+	}
+	for v := 0; v < 4; v++ {
+		if n := posVec(m.Normals, uint32(v)); !near(n[2], 1, 1e-6) {
+			t.Errorf("vertex %d: normal %v", v, n)
+		}
+	}
+}
+
+func TestDecodeSTLInvalid(t *testing.T) {
+	for _, src := range [][]byte{
+		[]byte("not an stl file"),
+		[]byte("solid empty\nendsolid empty\n"),
+		binarySTL(nil),
+	} {
+		if _, err := DecodeSTL(bytes.NewReader(src)); err == nil {
+			t.Errorf("%q was decoded", src)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package sdf
+
+import (
+	"image"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Edge colours select the channels of a multi-channel field in which an edge
+// is used. Each colour has two channels so that corners, where the colour
+// changes, are preserved by the median.
+const (
+	black   = 0
+	red     = 1
+	green   = 2
+	yellow  = red | green
+	blue    = 4
+	magenta = red | blue
+	cyan    = green | blue
+	white   = red | green | blue
+)
+
+// cornerThreshold is the sine of the smallest angle between edges treated as
+// a corner.
+var cornerThreshold = math.Sin(3)
+
+// isCorner reports whether two edge directions meet at a corner.
+func isCorner(a, b Point) bool {
+	a, b = a.normalize(), b.normalize()
+	return a.dot(b) <= 0 || math.Abs(a.cross(b)) > cornerThreshold
+}
+
+// switchColor changes an edge colour to another with two channels, avoiding
+// the channels in 'banned' where possible. 'seed' varies the choice.
+func switchColor(color *uint8, seed *uint64, banned uint8) {
+	var combined = *color & banned
+	if combined == red || combined == green || combined == blue {
+		*color = combined ^ white
+		return
+	}
+	if *color == black || *color == white {
+		*color = [3]uint8{cyan, magenta, yellow}[*seed%3]
+		*seed /= 3
+		return
+	}
+	var shifted = *color << (1 + *seed&1)
+	*color = (shifted | shifted>>3) & white
+	*seed >>= 1
+}
+
+// symmetricalTrichotomy divides n positions into three symmetric groups,
+// returning -1, 0 or 1.
+func symmetricalTrichotomy(position, n int) int {
+	return int(3+2.875*float64(position)/float64(n-1)-1.4375+0.5) - 3
+}
+
+// colorEdges assigns colours to the edges of every contour so that the edges
+// meeting at each corner share at most one channel. Contours with a single
+// corner have their edges split if necessary to provide three colours.
+func (s *Shape) colorEdges() {
+	var seed uint64
+
+	for ci, edges := range s.Contours {
+		var corners []int
+		if len(edges) > 0 {
+			var prev = edges[len(edges)-1].direction(1)
+			for i := range edges {
+				if isCorner(prev, edges[i].direction(0)) {
+					corners = append(corners, i)
+				}
+				prev = edges[i].direction(1)
+			}
+		}
+
+		switch len(corners) {
+		case 0:
+			for i := range edges {
+				edges[i].color = white
+			}
+
+		case 1:
+			// A teardrop: colour the contour in three parts around the corner.
+			var colors = [3]uint8{white, white, white}
+			switchColor(&colors[0], &seed, black)
+			colors[2] = colors[0]
+			switchColor(&colors[2], &seed, black)
+
+			var corner = corners[0]
+			if m := len(edges); m >= 3 {
+				for i := 0; i < m; i++ {
+					edges[(corner+i)%m].color = colors[1+symmetricalTrichotomy(i, m)]
+				}
+				break
+			}
+
+			var parts []Edge
+			for i := range edges {
+				var t = edges[(corner+i)%len(edges)].thirds()
+				parts = append(parts, t[:]...)
+			}
+			for i := range parts {
+				parts[i].color = colors[i*3/len(parts)]
+			}
+			s.Contours[ci] = parts
+
+		default:
+			var (
+				spline = 0
+				start  = corners[0]
+				m      = len(edges)
+				color  = uint8(white)
+			)
+			switchColor(&color, &seed, black)
+			var initial = color
+
+			for i := 0; i < m; i++ {
+				var index = (start + i) % m
+				if spline+1 < len(corners) && corners[spline+1] == index {
+					spline++
+					var banned uint8
+					if spline == len(corners)-1 {
+						banned = initial
+					}
+					switchColor(&color, &seed, banned)
+				}
+				edges[index].color = color
+			}
+		}
+	}
+}
+
+// segment is a piece of a flattened edge.
+type segment struct {
+	a, b        Point
+	edge        int
+	first, last bool // whether the segment begins or ends its edge
+}
+
+// prepared is a shape flattened for distance queries.
+type prepared struct {
+	segments []segment
+	colors   []uint8 // colour of each edge
+	polarity float64 // sign that makes the inside of the shape positive
+}
+
+func (s *Shape) prepare() *prepared {
+	var (
+		p    = &prepared{}
+		area float64
+	)
+
+	for _, edges := range s.Contours {
+		for _, e := range edges {
+			var (
+				pts  = e.flatten([]Point{e.Points[0]})
+				edge = len(p.colors)
+			)
+			p.colors = append(p.colors, e.color)
+			for i := 0; i+1 < len(pts); i++ {
+				p.segments = append(p.segments, segment{
+					a: pts[i], b: pts[i+1], edge: edge,
+					first: i == 0, last: i+2 == len(pts),
+				})
+				area += pts[i].cross(pts[i+1])
+			}
+		}
+	}
+
+	// With y pointing down, edges run clockwise around filled areas when the
+	// signed area is positive, which puts the inside to the right of each edge.
+	p.polarity = 1
+	if area < 0 {
+		p.polarity = -1
+	}
+
+	return p
+}
+
+// winding returns the non-zero winding number of the shape around 'pt'.
+func (p *prepared) winding(pt Point) int {
+	var w int
+	for _, s := range p.segments {
+		if (s.a.Y <= pt.Y) != (s.b.Y <= pt.Y) {
+			var x = s.a.X + (pt.Y-s.a.Y)/(s.b.Y-s.a.Y)*(s.b.X-s.a.X)
+			if x > pt.X {
+				if s.b.Y > s.a.Y {
+					w++
+				} else {
+					w--
+				}
+			}
+		}
+	}
+	return w
+}
+
+// edgeDistance is the distance from a point to the nearest part of an edge.
+type edgeDistance struct {
+	dist   float64 // unsigned true distance
+	ortho  float64 // how perpendicular the edge is to the point, for ties
+	pseudo float64 // signed pseudo-distance
+}
+
+// closer reports whether 'd' is nearer than 'e', preferring the more
+// perpendicular edge when both are equally near.
+func (d edgeDistance) closer(e edgeDistance) bool {
+	if math.Abs(d.dist-e.dist) < 1e-9 {
+		return d.ortho > e.ortho
+	}
+	return d.dist < e.dist
+}
+
+// query computes the signed true distance from 'pt' to the shape, and for
+// each channel the signed pseudo-distance to the nearest edge of that colour.
+// 'edges' is scratch space with an element for each edge.
+func (p *prepared) query(pt Point, edges []edgeDistance) (dist float64, channels [3]float64) {
+	var (
+		inf    = edgeDistance{dist: math.Inf(1)}
+		best   = inf
+		bestCh = [3]edgeDistance{inf, inf, inf}
+	)
+
+	for i := range edges {
+		edges[i] = inf
+	}
+
+	for _, s := range p.segments {
+		var (
+			ab = s.b.sub(s.a)
+			ap = pt.sub(s.a)
+			l2 = ab.dot(ab)
+			t  = 0.0
+		)
+		if l2 > 0 {
+			t = ap.dot(ab) / l2
+		}
+
+		var (
+			nearest = lerp(s.a, s.b, math.Max(0, math.Min(1, t)))
+			length  = pt.sub(nearest).length()
+			side    = ab.cross(ap) * p.polarity
+			d       = edgeDistance{dist: length}
+		)
+		if length > 0 && l2 > 0 {
+			d.ortho = math.Abs(ab.cross(ap)) / (math.Sqrt(l2) * ap.length())
+		}
+
+		// Beyond the ends of an edge the distance to its extended tangent
+		// keeps corners sharp.
+		d.pseudo = math.Copysign(length, side)
+		if l2 > 0 && ((t < 0 && s.first) || (t > 1 && s.last)) {
+			d.pseudo = side / math.Sqrt(l2)
+		}
+
+		if d.closer(edges[s.edge]) {
+			edges[s.edge] = d
+		}
+		if d.closer(best) {
+			best = d
+		}
+	}
+
+	for i, d := range edges {
+		for c := 0; c < 3; c++ {
+			if p.colors[i]&(1<<uint(c)) != 0 && d.closer(bestCh[c]) {
+				bestCh[c] = d
+			}
+		}
+	}
+
+	dist = best.dist
+	if p.winding(pt) == 0 {
+		dist = -dist
+	}
+	for c, d := range bestCh {
+		channels[c] = d.pseudo
+	}
+
+	return dist, channels
+}
+
+// Transform maps shape coordinates to pixels: a point p is drawn at
+// p*Scale + Translate.
+type Transform struct {
+	Scale     float64
+	Translate Point
+}
+
+// Fit returns the Transform that centres 'frame' in a w by h image, scaled
+// uniformly to leave 'margin' pixels on each side.
+func Fit(frame Rect, w, h int, margin float64) Transform {
+	var (
+		fw, fh = frame.Max.X - frame.Min.X, frame.Max.Y - frame.Min.Y
+		scale  = math.Min((float64(w)-2*margin)/math.Max(fw, 1e-9), (float64(h)-2*margin)/math.Max(fh, 1e-9))
+	)
+	return Transform{
+		Scale: scale,
+		Translate: Point{
+			(float64(w)-fw*scale)/2 - frame.Min.X*scale,
+			(float64(h)-fh*scale)/2 - frame.Min.Y*scale,
+		},
+	}
+}
+
+// encode maps a distance in pixels to a byte, with the edge at 128 and the
+// inside brighter. 'pxRange' is the width of the band of representable
+// distances.
+func encode(d, pxRange float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, (d/pxRange+0.5)*255+0.5)))
+}
+
+// SDF renders a single-channel signed distance field of the shape into a w by
+// h image. Distances are encoded so that the edge is at 0.5 and the values 0
+// and 1 are 'pxRange'/2 pixels outside and inside it.
+func (s *Shape) SDF(w, h int, tr Transform, pxRange float64) *image.Gray {
+	var (
+		img = image.NewGray(image.Rect(0, 0, w, h))
+		p   = s.prepare()
+	)
+
+	parallel(h, func(y int) {
+		var scratch = make([]edgeDistance, len(p.colors))
+		for x := 0; x < w; x++ {
+			var d, _ = p.query(Point{
+				(float64(x) + 0.5 - tr.Translate.X) / tr.Scale,
+				(float64(y) + 0.5 - tr.Translate.Y) / tr.Scale,
+			}, scratch)
+			img.Pix[y*img.Stride+x] = encode(d*tr.Scale, pxRange)
+		}
+	})
+
+	return img
+}
+
+// MSDF renders a multi-channel signed distance field of the shape into a w by
+// h image, encoded as by SDF. The median of the red, green and blue channels
+// gives the distance with sharp corners; alpha holds the true distance, which
+// is also suitable for effects such as outlines and shadows.
+func (s *Shape) MSDF(w, h int, tr Transform, pxRange float64) *image.RGBA {
+	var img = image.NewRGBA(image.Rect(0, 0, w, h))
+
+	s.colorEdges()
+	var p = s.prepare()
+
+	parallel(h, func(y int) {
+		var scratch = make([]edgeDistance, len(p.colors))
+		for x := 0; x < w; x++ {
+			var (
+				d, ch = p.query(Point{
+					(float64(x) + 0.5 - tr.Translate.X) / tr.Scale,
+					(float64(y) + 0.5 - tr.Translate.Y) / tr.Scale,
+				}, scratch)
+				i = img.PixOffset(x, y)
+			)
+
+			// Where the median disagrees with the true distance about which
+			// side of the shape the pixel is on, fall back to the latter.
+			if m := median(ch[0], ch[1], ch[2]); (m > 0) != (d > 0) {
+				ch = [3]float64{d, d, d}
+			}
+
+			for c := 0; c < 3; c++ {
+				img.Pix[i+c] = encode(ch[c]*tr.Scale, pxRange)
+			}
+			img.Pix[i+3] = encode(d*tr.Scale, pxRange)
+		}
+	})
+
+	return img
+}
+
+func median(a, b, c float64) float64 {
+	return math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+}
+
+// parallel calls fn(i) for every i in [0, n) from a pool of goroutines.
+func parallel(n int, fn func(i int)) {
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int)
+	)
+
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
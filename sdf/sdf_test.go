@@ -0,0 +1,188 @@
+package sdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// square adds a contour of the square from (x0, y0) to (x1, y1) to a shape,
+// wound clockwise, or anticlockwise if 'reverse' is set.
+func square(s *Shape, x0, y0, x1, y1 float64, reverse bool) {
+	s.MoveTo(x0, y0)
+	if reverse {
+		s.LineTo(x0, y1)
+		s.LineTo(x1, y1)
+		s.LineTo(x1, y0)
+	} else {
+		s.LineTo(x1, y0)
+		s.LineTo(x1, y1)
+		s.LineTo(x0, y1)
+	}
+	s.Close()
+}
+
+func near(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+func TestSDF(t *testing.T) {
+	var s = &Shape{}
+	square(s, 0, 0, 10, 10, false)
+
+	// The square is drawn from (5, 5) to (15, 15) in a 20x20 image.
+	var img = s.SDF(20, 20, Transform{Scale: 1, Translate: Point{5, 5}}, 8)
+	var tests = []struct {
+		x, y int
+		dist float64 // from the edge, in pixels and positive inside
+	}{
+		{10, 10, 4.5},
+		{5, 10, 0.5},
+		{3, 10, -1.5},
+		{10, 16, -1.5},
+		{0, 10, -4.5},
+	}
+	for _, tt := range tests {
+		var (
+			got  = float64(img.GrayAt(tt.x, tt.y).Y)
+			want = float64(encode(tt.dist, 8))
+		)
+		if !near(got, want, 1) {
+			t.Errorf("pixel (%d, %d): got %v, want %v", tt.x, tt.y, got, want)
+		}
+	}
+}
+
+func TestSDFHole(t *testing.T) {
+	// A hole wound against its outer contour is outside the shape.
+	var s = &Shape{}
+	square(s, 0, 0, 12, 12, false)
+	square(s, 4, 4, 8, 8, true)
+
+	var img = s.SDF(12, 12, Transform{Scale: 1}, 4)
+	if v := img.GrayAt(6, 6).Y; v >= 128 {
+		t.Errorf("hole centre is %d, want outside", v)
+	}
+	if v := img.GrayAt(1, 6).Y; v < 128 {
+		t.Errorf("ring is %d, want inside", v)
+	}
+}
+
+func TestMSDF(t *testing.T) {
+	// A triangle's sharp corners need several channels. The median of the
+	// colour channels must agree with the true distance about which side of
+	// the edge each pixel is on, and alpha holds the true distance.
+	var s = &Shape{}
+	s.MoveTo(2, 2)
+	s.LineTo(30, 6)
+	s.LineTo(8, 28)
+	s.Close()
+
+	var (
+		tr   = Transform{Scale: 1}
+		sdf  = s.SDF(32, 32, tr, 6)
+		msdf = s.MSDF(32, 32, tr, 6)
+	)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			var (
+				c = msdf.RGBAAt(x, y)
+				d = sdf.GrayAt(x, y).Y
+				m = median(float64(c.R), float64(c.G), float64(c.B))
+			)
+			if c.A != d {
+				t.Errorf("pixel (%d, %d): alpha %d, want %d", x, y, c.A, d)
+			}
+			if d > 132 && m < 128 || d < 124 && m >= 128 {
+				t.Errorf("pixel (%d, %d): median %v disagrees with distance %d", x, y, m, d)
+			}
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	var tr = Fit(Rect{Point{-1, 0}, Point{1, 4}}, 20, 20, 2)
+	if tr.Scale != 4 {
+		t.Fatalf("scale %v, want 4", tr.Scale)
+	}
+	// The frame is centred: 8 pixels wide between x = 6 and 14.
+	if tr.Translate.X != 10 || tr.Translate.Y != 2 {
+		t.Errorf("translation %v, want {10 2}", tr.Translate)
+	}
+}
+
+func TestParseSVGPath(t *testing.T) {
+	var tests = []struct {
+		d        string
+		contours int
+		bounds   Rect
+	}{
+		{"M0 0 H10 V10 H0 Z", 1, Rect{Point{0, 0}, Point{10, 10}}},
+		{"m1 1 l10 0 0 10 -10 0z m2 2 h2v2h-2z", 2, Rect{Point{1, 1}, Point{11, 11}}},
+		{"M0,0 Q5-5 10,0 T20,0 L20 5z", 1, Rect{Point{0, -5}, Point{20, 5}}},
+		{"M0 0C0 10 10 10 10 0S20-10 20 0", 1, Rect{Point{0, -10}, Point{20, 10}}},
+	}
+	for _, tt := range tests {
+		var s, err = ParseSVGPath(tt.d)
+		if err != nil {
+			t.Errorf("%q: %v", tt.d, err)
+			continue
+		}
+		if len(s.Contours) != tt.contours || s.Bounds() != tt.bounds {
+			t.Errorf("%q: got %d contours in %v, want %d in %v", tt.d, len(s.Contours), s.Bounds(), tt.contours, tt.bounds)
+		}
+	}
+
+	for _, d := range []string{"M0 0 L", "M0 0 X1 1", "M0 0 L1 1e"} {
+		if _, err := ParseSVGPath(d); err == nil {
+			t.Errorf("%q was parsed", d)
+		}
+	}
+}
+
+func TestParseSVGPathArc(t *testing.T) {
+	// Two half circle arcs form a circle of radius 5 about (5, 5).
+	var s, err = ParseSVGPath("M0 5 A5 5 0 0 0 10 5 A5 5 0 0 0 0 5Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, contour := range s.Contours {
+		for _, e := range contour {
+			for i := 0; i <= 4; i++ {
+				var p = e.point(float64(i) / 4)
+				if r := p.sub(Point{5, 5}).length(); !near(r, 5, 0.01) {
+					t.Errorf("point %v is %v from the centre, want 5", p, r)
+				}
+			}
+		}
+	}
+}
+
+func TestParseSVG(t *testing.T) {
+	var src = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24">
+	<rect x="2" y="2" width="8" height="6"/>
+	<circle cx="16" cy="16" r="4"/>
+	<polygon points="2,20 6,14 10,20"/>
+</svg>`
+	var s, frame, err = ParseSVG(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != (Rect{Point{0, 0}, Point{24, 24}}) {
+		t.Errorf("frame %v", frame)
+	}
+	if len(s.Contours) != 3 {
+		t.Fatalf("got %d contours, want 3", len(s.Contours))
+	}
+
+	// Each element is filled.
+	var img = s.SDF(24, 24, Transform{Scale: 1}, 4)
+	for _, p := range [][2]int{{5, 4}, {15, 15}, {6, 18}} {
+		if v := img.GrayAt(p[0], p[1]).Y; v < 128 {
+			t.Errorf("pixel %v is %d, want inside", p, v)
+		}
+	}
+	if v := img.GrayAt(12, 4).Y; v >= 128 {
+		t.Errorf("pixel (12, 4) is %d, want outside", v)
+	}
+}
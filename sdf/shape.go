@@ -0,0 +1,218 @@
+// Package sdf generates signed distance fields of vector shapes, such as font
+// glyph outlines and icons, on the CPU. Single-channel fields store the
+// distance to the nearest edge; multi-channel fields additionally store
+// per-channel pseudo-distances whose median preserves sharp corners. It does
+// not depend on OpenGL.
+package sdf
+
+import (
+	"math"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Point is a position in shape coordinates, with y pointing down.
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) add(q Point) Point      { return Point{p.X + q.X, p.Y + q.Y} }
+func (p Point) sub(q Point) Point      { return Point{p.X - q.X, p.Y - q.Y} }
+func (p Point) mul(s float64) Point    { return Point{p.X * s, p.Y * s} }
+func (p Point) dot(q Point) float64    { return p.X*q.X + p.Y*q.Y }
+func (p Point) cross(q Point) float64  { return p.X*q.Y - p.Y*q.X }
+func (p Point) length() float64        { return math.Hypot(p.X, p.Y) }
+func lerp(a, b Point, t float64) Point { return a.add(b.sub(a).mul(t)) }
+func (p Point) normalize() Point       { return p.mul(1 / math.Max(p.length(), 1e-12)) }
+
+// Rect is an axis-aligned rectangle in shape coordinates.
+type Rect struct {
+	Min, Max Point
+}
+
+// Empty reports whether the rectangle contains no area.
+func (r Rect) Empty() bool {
+	return r.Min.X >= r.Max.X || r.Min.Y >= r.Max.Y
+}
+
+// Edge is a line segment or a quadratic or cubic Bézier curve, given by 2, 3
+// or 4 control points.
+type Edge struct {
+	Points []Point
+	color  uint8 // channels in which the edge is used by multi-channel fields
+}
+
+// point evaluates the edge at 't' in [0, 1].
+func (e *Edge) point(t float64) Point {
+	var p = append([]Point(nil), e.Points...)
+	for n := len(p) - 1; n > 0; n-- {
+		for i := 0; i < n; i++ {
+			p[i] = lerp(p[i], p[i+1], t)
+		}
+	}
+	return p[0]
+}
+
+// direction returns the tangent of the edge at its start (t = 0) or end
+// (t = 1). Degenerate control points are skipped.
+func (e *Edge) direction(t float64) Point {
+	var n = len(e.Points) - 1
+	if t < 0.5 {
+		for i := 1; i <= n; i++ {
+			if d := e.Points[i].sub(e.Points[0]); d.length() > 1e-12 {
+				return d
+			}
+		}
+	} else {
+		for i := n - 1; i >= 0; i-- {
+			if d := e.Points[n].sub(e.Points[i]); d.length() > 1e-12 {
+				return d
+			}
+		}
+	}
+	return Point{}
+}
+
+// split divides the edge at 't' into two edges of the same degree.
+func (e *Edge) split(t float64) (Edge, Edge) {
+	var (
+		n     = len(e.Points)
+		p     = append([]Point(nil), e.Points...)
+		left  = make([]Point, n)
+		right = make([]Point, n)
+	)
+	for k := 0; k < n; k++ {
+		left[k], right[n-1-k] = p[0], p[n-1-k]
+		for i := 0; i < n-1-k; i++ {
+			p[i] = lerp(p[i], p[i+1], t)
+		}
+	}
+	return Edge{Points: left, color: e.color}, Edge{Points: right, color: e.color}
+}
+
+// thirds divides the edge into three equal parameter ranges.
+func (e *Edge) thirds() [3]Edge {
+	var a, rest = e.split(1.0 / 3)
+	var b, c = rest.split(0.5)
+	return [3]Edge{a, b, c}
+}
+
+// flatten appends the edge, approximated as a polyline, to 'pts' without its
+// start point.
+func (e *Edge) flatten(pts []Point) []Point {
+	var steps = [...]int{0, 0, 1, 8, 12}[len(e.Points)]
+	for i := 1; i <= steps; i++ {
+		pts = append(pts, e.point(float64(i)/float64(steps)))
+	}
+	return pts
+}
+
+// Shape is a set of closed contours filled with the non-zero winding rule.
+type Shape struct {
+	Contours [][]Edge
+
+	start, cur Point
+	open       bool
+}
+
+// MoveTo begins a new contour at (x, y), closing the current one.
+func (s *Shape) MoveTo(x, y float64) {
+	s.Close()
+	s.start, s.cur = Point{x, y}, Point{x, y}
+	s.Contours = append(s.Contours, nil)
+	s.open = true
+}
+
+func (s *Shape) add(pts ...Point) {
+	if !s.open {
+		s.MoveTo(s.cur.X, s.cur.Y)
+	}
+	var i = len(s.Contours) - 1
+	s.Contours[i] = append(s.Contours[i], Edge{Points: append([]Point{s.cur}, pts...)})
+	s.cur = pts[len(pts)-1]
+}
+
+// LineTo adds a line to (x, y).
+func (s *Shape) LineTo(x, y float64) {
+	s.add(Point{x, y})
+}
+
+// QuadTo adds a quadratic Bézier curve with control point (cx, cy) ending at
+// (x, y).
+func (s *Shape) QuadTo(cx, cy, x, y float64) {
+	s.add(Point{cx, cy}, Point{x, y})
+}
+
+// CubeTo adds a cubic Bézier curve with control points (c1x, c1y) and
+// (c2x, c2y) ending at (x, y).
+func (s *Shape) CubeTo(c1x, c1y, c2x, c2y, x, y float64) {
+	s.add(Point{c1x, c1y}, Point{c2x, c2y}, Point{x, y})
+}
+
+// Close closes the current contour with a line to its start if necessary.
+// Empty contours are removed.
+func (s *Shape) Close() {
+	if !s.open {
+		return
+	}
+	s.open = false
+
+	var i = len(s.Contours) - 1
+	if len(s.Contours[i]) == 0 {
+		s.Contours = s.Contours[:i]
+		return
+	}
+	if s.cur != s.start {
+		s.Contours[i] = append(s.Contours[i], Edge{Points: []Point{s.cur, s.start}})
+	}
+	s.cur = s.start
+}
+
+// Bounds returns the bounding box of the shape's control points, which
+// contains the shape.
+func (s *Shape) Bounds() Rect {
+	var r = Rect{Point{math.Inf(1), math.Inf(1)}, Point{math.Inf(-1), math.Inf(-1)}}
+	for _, contour := range s.Contours {
+		for _, e := range contour {
+			for _, p := range e.Points {
+				r.Min.X, r.Min.Y = math.Min(r.Min.X, p.X), math.Min(r.Min.Y, p.Y)
+				r.Max.X, r.Max.Y = math.Max(r.Max.X, p.X), math.Max(r.Max.Y, p.Y)
+			}
+		}
+	}
+	if r.Min.X > r.Max.X {
+		return Rect{}
+	}
+	return r
+}
+
+// FromSegments creates a Shape from a glyph outline loaded by the sfnt
+// package. Coordinates remain in pixels.
+func FromSegments(segs sfnt.Segments) *Shape {
+	var (
+		s  = &Shape{}
+		pt = func(p fixed.Point26_6) (float64, float64) { return float64(p.X) / 64, float64(p.Y) / 64 }
+	)
+
+	for _, seg := range segs {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			s.MoveTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			s.LineTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			var cx, cy = pt(seg.Args[0])
+			var x, y = pt(seg.Args[1])
+			s.QuadTo(cx, cy, x, y)
+		case sfnt.SegmentOpCubeTo:
+			var c1x, c1y = pt(seg.Args[0])
+			var c2x, c2y = pt(seg.Args[1])
+			var x, y = pt(seg.Args[2])
+			s.CubeTo(c1x, c1y, c2x, c2y, x, y)
+		}
+	}
+	s.Close()
+
+	return s
+}
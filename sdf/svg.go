@@ -0,0 +1,378 @@
+package sdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pathScanner reads commands and numbers from SVG path data.
+type pathScanner struct {
+	s   string
+	pos int
+}
+
+func (p *pathScanner) skip() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// command returns the next command letter, or 0 if a number follows.
+func (p *pathScanner) command() byte {
+	p.skip()
+	if p.pos < len(p.s) {
+		if c := p.s[p.pos]; (c|0x20) >= 'a' && (c|0x20) <= 'z' && c != 'e' && c != 'E' {
+			p.pos++
+			return c
+		}
+	}
+	return 0
+}
+
+// more reports whether a number follows.
+func (p *pathScanner) more() bool {
+	p.skip()
+	return p.pos < len(p.s) && strings.IndexByte("+-.0123456789", p.s[p.pos]) >= 0
+}
+
+func (p *pathScanner) number() (float64, error) {
+	p.skip()
+	var start, digits, dot, exp = p.pos, false, false, false
+	if p.pos < len(p.s) && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+		p.pos++
+	}
+scan:
+	for ; p.pos < len(p.s); p.pos++ {
+		var c = p.s[p.pos]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == '.' && !dot && !exp:
+			dot = true
+		case (c == 'e' || c == 'E') && digits && !exp:
+			exp = true
+			if p.pos+1 < len(p.s) && (p.s[p.pos+1] == '+' || p.s[p.pos+1] == '-') {
+				p.pos++
+			}
+		default:
+			break scan
+		}
+	}
+	var v, err = strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("sdf.ParseSVGPath error: bad number at offset %d", start)
+	}
+	return v, nil
+}
+
+// flag reads an arc flag, which may be written without a separator.
+func (p *pathScanner) flag() (bool, error) {
+	p.skip()
+	if p.pos < len(p.s) && (p.s[p.pos] == '0' || p.s[p.pos] == '1') {
+		p.pos++
+		return p.s[p.pos-1] == '1', nil
+	}
+	return false, fmt.Errorf("sdf.ParseSVGPath error: bad arc flag at offset %d", p.pos)
+}
+
+// pathArgs is the number of arguments taken by each path command.
+var pathArgs = map[byte]int{'m': 2, 'l': 2, 'h': 1, 'v': 1, 'c': 6, 's': 4, 'q': 4, 't': 2, 'a': 7, 'z': 0}
+
+// ParseSVGPath creates a Shape from the data of an SVG path element. All
+// commands are supported; arcs are approximated by cubic curves.
+func ParseSVGPath(d string) (*Shape, error) {
+	var s = &Shape{}
+	if err := s.appendPath(d); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Shape) appendPath(d string) error {
+	var (
+		p       = &pathScanner{s: d}
+		cmd     byte
+		ctrl    Point // reflected control point of smooth curves
+		lastCmd byte
+	)
+
+	for {
+		if c := p.command(); c != 0 {
+			cmd = c
+		} else if !p.more() {
+			break
+		} else if cmd == 0 {
+			return fmt.Errorf("sdf.ParseSVGPath error: path does not begin with a command")
+		}
+
+		var n, ok = pathArgs[cmd|0x20]
+		if !ok {
+			return fmt.Errorf("sdf.ParseSVGPath error: unknown command '%c'", cmd)
+		}
+
+		var (
+			rel  = cmd >= 'a'
+			base Point
+			args [7]float64
+		)
+		if rel {
+			base = s.cur
+		}
+		for i := 0; i < n; i++ {
+			var err error
+			if cmd|0x20 == 'a' && (i == 3 || i == 4) {
+				var f bool
+				if f, err = p.flag(); f {
+					args[i] = 1
+				}
+			} else {
+				args[i], err = p.number()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		var pt = func(i int) Point { return Point{base.X + args[i], base.Y + args[i+1]} }
+
+		// Smooth curves reflect the previous control point only if the
+		// previous command was of the same kind.
+		var reflected = s.cur
+		if (cmd|0x20 == 's' && strings.IndexByte("cs", lastCmd|0x20) >= 0) ||
+			(cmd|0x20 == 't' && strings.IndexByte("qt", lastCmd|0x20) >= 0) {
+			reflected = s.cur.mul(2).sub(ctrl)
+		}
+
+		switch cmd | 0x20 {
+		case 'm':
+			var e = pt(0)
+			s.MoveTo(e.X, e.Y)
+			// Further coordinate pairs are implicit lines.
+			if rel {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'l':
+			var e = pt(0)
+			s.LineTo(e.X, e.Y)
+		case 'h':
+			var x = args[0]
+			if rel {
+				x += s.cur.X
+			}
+			s.LineTo(x, s.cur.Y)
+		case 'v':
+			var y = args[0]
+			if rel {
+				y += s.cur.Y
+			}
+			s.LineTo(s.cur.X, y)
+		case 'c':
+			var c1, c2, e = pt(0), pt(2), pt(4)
+			s.CubeTo(c1.X, c1.Y, c2.X, c2.Y, e.X, e.Y)
+			ctrl = c2
+		case 's':
+			var c2, e = pt(0), pt(2)
+			s.CubeTo(reflected.X, reflected.Y, c2.X, c2.Y, e.X, e.Y)
+			ctrl = c2
+		case 'q':
+			var c, e = pt(0), pt(2)
+			s.QuadTo(c.X, c.Y, e.X, e.Y)
+			ctrl = c
+		case 't':
+			var e = pt(0)
+			s.QuadTo(reflected.X, reflected.Y, e.X, e.Y)
+			ctrl = reflected
+		case 'a':
+			s.arcTo(args[0], args[1], args[2], args[3] != 0, args[4] != 0, pt(5))
+		case 'z':
+			s.Close()
+		}
+		lastCmd = cmd
+	}
+	s.Close()
+
+	return nil
+}
+
+// arcTo adds an elliptical arc, given in SVG endpoint notation, as a series
+// of cubic curves.
+func (s *Shape) arcTo(rx, ry, angle float64, large, sweep bool, end Point) {
+	var start = s.cur
+	if start == end {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		s.LineTo(end.X, end.Y)
+		return
+	}
+
+	// Convert to centre notation, following the SVG implementation notes.
+	var (
+		sin, cos = math.Sincos(angle * math.Pi / 180)
+		h        = start.sub(end).mul(0.5)
+		x1       = cos*h.X + sin*h.Y
+		y1       = -sin*h.X + cos*h.Y
+	)
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+
+	var (
+		num = rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+		den = rx*rx*y1*y1 + ry*ry*x1*x1
+		k   = math.Sqrt(math.Max(0, num/den))
+	)
+	if large == sweep {
+		k = -k
+	}
+	var (
+		cx1    = k * rx * y1 / ry
+		cy1    = -k * ry * x1 / rx
+		mid    = start.add(end).mul(0.5)
+		centre = Point{cos*cx1 - sin*cy1 + mid.X, sin*cx1 + cos*cy1 + mid.Y}
+		theta  = math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+		delta  = math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta
+	)
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// Each piece spans at most a quarter turn.
+	var (
+		pieces = int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+		step   = delta / float64(pieces)
+		alpha  = 4.0 / 3 * math.Tan(step/4)
+		at     = func(t float64) (Point, Point) {
+			var st, ct = math.Sincos(t)
+			return Point{
+				centre.X + rx*ct*cos - ry*st*sin,
+				centre.Y + rx*ct*sin + ry*st*cos,
+			}, Point{
+				-rx*st*cos - ry*ct*sin,
+				-rx*st*sin + ry*ct*cos,
+			}
+		}
+	)
+	for i := 0; i < pieces; i++ {
+		var (
+			t0, t1 = theta + float64(i)*step, theta + float64(i+1)*step
+			p0, d0 = at(t0)
+			p1, d1 = at(t1)
+			c1     = p0.add(d0.mul(alpha))
+			c2     = p1.sub(d1.mul(alpha))
+		)
+		if i == pieces-1 {
+			p1 = end
+		}
+		s.CubeTo(c1.X, c1.Y, c2.X, c2.Y, p1.X, p1.Y)
+	}
+}
+
+// ParseSVG creates a Shape from the path, rect, circle, ellipse, polygon and
+// polyline elements of an SVG document, returning it with the document's
+// viewBox, or the shape's bounds if it has none. All elements are filled with
+// the non-zero rule; styles and transforms are ignored.
+func ParseSVG(r io.Reader) (*Shape, Rect, error) {
+	var (
+		dec     = xml.NewDecoder(r)
+		s       = &Shape{}
+		viewBox Rect
+	)
+
+	for {
+		var tok, err = dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, Rect{}, fmt.Errorf("sdf.ParseSVG error: %v", err)
+		}
+
+		var el, ok = tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var (
+			attr = make(map[string]string)
+			num  = func(name string) float64 {
+				var v, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(attr[name]), "px"), 64)
+				return v
+			}
+		)
+		for _, a := range el.Attr {
+			attr[a.Name.Local] = a.Value
+		}
+
+		switch el.Name.Local {
+		case "svg":
+			var p = &pathScanner{s: attr["viewBox"]}
+			var v [4]float64
+			for i := range v {
+				if !p.more() {
+					break
+				}
+				if v[i], err = p.number(); err != nil {
+					return nil, Rect{}, fmt.Errorf("sdf.ParseSVG error: bad viewBox '%s'", attr["viewBox"])
+				}
+			}
+			viewBox = Rect{Point{v[0], v[1]}, Point{v[0] + v[2], v[1] + v[3]}}
+		case "path":
+			err = s.appendPath(attr["d"])
+		case "rect":
+			var x, y, w, h = num("x"), num("y"), num("width"), num("height")
+			if w > 0 && h > 0 {
+				s.MoveTo(x, y)
+				s.LineTo(x+w, y)
+				s.LineTo(x+w, y+h)
+				s.LineTo(x, y+h)
+				s.Close()
+			}
+		case "circle", "ellipse":
+			var cx, cy, rx, ry = num("cx"), num("cy"), num("rx"), num("ry")
+			if el.Name.Local == "circle" {
+				rx, ry = num("r"), num("r")
+			}
+			if rx > 0 && ry > 0 {
+				s.MoveTo(cx+rx, cy)
+				s.arcTo(rx, ry, 0, false, true, Point{cx - rx, cy})
+				s.arcTo(rx, ry, 0, false, true, Point{cx + rx, cy})
+				s.Close()
+			}
+		case "polygon", "polyline":
+			var p = &pathScanner{s: attr["points"]}
+			for i := 0; p.more(); i++ {
+				var x, y float64
+				if x, err = p.number(); err == nil {
+					y, err = p.number()
+				}
+				if err != nil {
+					break
+				}
+				if i == 0 {
+					s.MoveTo(x, y)
+				} else {
+					s.LineTo(x, y)
+				}
+			}
+			s.Close()
+		}
+		if err != nil {
+			return nil, Rect{}, fmt.Errorf("sdf.ParseSVG error: <%s>: %v", el.Name.Local, err)
+		}
+	}
+
+	if viewBox.Empty() {
+		viewBox = s.Bounds()
+	}
+
+	return s, viewBox, nil
+}
@@ -0,0 +1,306 @@
+package texfmt
+
+// bc7Mode describes the layout of one of the eight BC7 block modes.
+type bc7Mode struct {
+	subsets       int // number of subsets
+	partitionBits int
+	rotationBits  int
+	indexSelBits  int
+	colorBits     int // bits per colour channel of each endpoint
+	alphaBits     int // bits per alpha channel of each endpoint
+	endpointPBits int // whether each endpoint has a unique p-bit
+	sharedPBits   int // whether each subset has a shared p-bit
+	indexBits     int
+	indexBits2    int // bits of the secondary index, if any
+}
+
+var bc7Modes = [8]bc7Mode{
+	{3, 4, 0, 0, 4, 0, 1, 0, 3, 0},
+	{2, 6, 0, 0, 6, 0, 0, 1, 3, 0},
+	{3, 6, 0, 0, 5, 0, 0, 0, 2, 0},
+	{2, 6, 0, 0, 7, 0, 1, 0, 2, 0},
+	{1, 0, 2, 1, 5, 6, 0, 0, 2, 3},
+	{1, 0, 2, 0, 7, 8, 0, 0, 2, 2},
+	{1, 0, 0, 0, 7, 7, 1, 0, 4, 0},
+	{2, 6, 0, 0, 5, 5, 1, 0, 2, 0},
+}
+
+// bptcWeights are the interpolation weights for 2, 3 and 4 bit indices.
+var bptcWeights = [5][]int{
+	2: {0, 21, 43, 64},
+	3: {0, 9, 18, 27, 37, 46, 55, 64},
+	4: {0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64},
+}
+
+// bptcPartitions2 holds the two subset partitions as bit masks, where bit i is
+// set if pixel i belongs to the second subset.
+var bptcPartitions2 = [64]uint16{
+	0xcccc, 0x8888, 0xeeee, 0xecc8, 0xc880, 0xfeec, 0xfec8, 0xec80,
+	0xc800, 0xffec, 0xfe80, 0xe800, 0xffe8, 0xff00, 0xfff0, 0xf000,
+	0xf710, 0x008e, 0x7100, 0x08ce, 0x008c, 0x7310, 0x3100, 0x8cce,
+	0x088c, 0x3110, 0x6666, 0x366c, 0x17e8, 0x0ff0, 0x718e, 0x399c,
+	0xaaaa, 0xf0f0, 0x5a5a, 0x33cc, 0x3c3c, 0x55aa, 0x9696, 0xa55a,
+	0x73ce, 0x13c8, 0x324c, 0x3bdc, 0x6996, 0xc33c, 0x9966, 0x0660,
+	0x0272, 0x04e4, 0x4e40, 0x2720, 0xc936, 0x936c, 0x39c6, 0x639c,
+	0x9336, 0x9cc6, 0x817e, 0xe718, 0xccf0, 0x0fcc, 0x7744, 0xee22,
+}
+
+// bptcPartitions3 holds the subset of each pixel for the three subset
+// partitions.
+var bptcPartitions3 = [64][16]uint8{
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 1, 2, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 2, 0, 0, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 1, 0, 1, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2},
+	{0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0, 2, 2, 2, 0},
+	{0, 0, 0, 1, 0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2},
+	{0, 1, 1, 1, 0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0},
+	{0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2, 0, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 0, 1, 2, 2, 2, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 0, 0, 1, 1, 0, 0, 2, 2, 1, 0, 2, 2, 1, 0},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1, 0, 0, 0, 0},
+	{0, 0, 1, 2, 0, 0, 1, 2, 1, 1, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1, 0, 1, 1, 0},
+	{0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1},
+	{0, 0, 2, 2, 1, 1, 0, 2, 1, 1, 0, 2, 0, 0, 2, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 0, 0, 2, 2, 2, 2, 2},
+	{0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 0, 0, 2, 0, 0, 0, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 2, 0, 0, 2, 2, 0, 2, 2, 2},
+	{0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0},
+	{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0},
+	{0, 1, 2, 0, 2, 0, 1, 2, 1, 2, 0, 1, 0, 1, 2, 0},
+	{0, 0, 1, 1, 2, 2, 0, 0, 1, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0, 1, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 0, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 1, 1},
+	{0, 2, 2, 0, 1, 2, 2, 1, 0, 2, 2, 0, 1, 2, 2, 1},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 0, 1, 0, 1},
+	{0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 2, 2, 2, 0, 1, 1, 1},
+	{0, 0, 0, 2, 1, 1, 1, 2, 0, 0, 0, 2, 1, 1, 1, 2},
+	{0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2},
+	{0, 0, 0, 2, 1, 1, 1, 2, 1, 1, 1, 2, 0, 0, 0, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2},
+	{0, 0, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2},
+	{0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 1},
+	{0, 2, 2, 2, 1, 2, 2, 2, 0, 2, 2, 2, 1, 2, 2, 2},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 1, 2, 0, 1, 1, 2, 2, 0, 1, 2, 2, 2, 0},
+}
+
+// bptcAnchors2 is the anchor pixel of the second subset of each two subset
+// partition.
+var bptcAnchors2 = [64]uint8{
+	15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15,
+	15, 2, 8, 2, 2, 8, 8, 15, 2, 8, 2, 2, 8, 8, 2, 2,
+	15, 15, 6, 8, 2, 8, 15, 15, 2, 8, 2, 2, 2, 15, 15, 6,
+	6, 2, 6, 8, 15, 15, 2, 2, 15, 15, 15, 15, 15, 2, 2, 15,
+}
+
+// bptcAnchors3 are the anchor pixels of the second and third subsets of each
+// three subset partition.
+var bptcAnchors3 = [2][64]uint8{
+	{
+		3, 3, 15, 15, 8, 3, 15, 15, 8, 8, 6, 6, 6, 5, 3, 3,
+		3, 3, 8, 15, 3, 3, 6, 10, 5, 8, 8, 6, 8, 5, 15, 15,
+		8, 15, 3, 5, 6, 10, 8, 15, 15, 3, 15, 5, 15, 15, 15, 15,
+		3, 15, 5, 5, 5, 8, 5, 10, 5, 10, 8, 13, 15, 12, 3, 3,
+	},
+	{
+		15, 8, 8, 3, 15, 15, 3, 8, 15, 15, 15, 15, 15, 15, 15, 8,
+		15, 8, 15, 3, 15, 8, 15, 8, 3, 15, 6, 10, 15, 15, 10, 8,
+		15, 3, 15, 10, 10, 8, 9, 10, 6, 15, 8, 15, 3, 6, 6, 8,
+		15, 3, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 3, 15, 15, 8,
+	},
+}
+
+// bptcSubset returns the subset of pixel 'i' for the given partition.
+func bptcSubset(subsets, partition, i int) int {
+	switch subsets {
+	case 2:
+		return int(bptcPartitions2[partition]>>uint(i)) & 1
+	case 3:
+		return int(bptcPartitions3[partition][i])
+	}
+	return 0
+}
+
+// bptcIsAnchor reports whether pixel 'i' is the anchor of its subset, whose
+// index is stored with one bit fewer.
+func bptcIsAnchor(subsets, partition, i int) bool {
+	switch {
+	case i == 0:
+		return true
+	case subsets == 2:
+		return i == int(bptcAnchors2[partition])
+	case subsets == 3:
+		return i == int(bptcAnchors3[0][partition]) || i == int(bptcAnchors3[1][partition])
+	}
+	return false
+}
+
+// bitReader reads little-endian bit fields from a block.
+type bitReader struct {
+	data []byte
+	pos  uint
+}
+
+func (br *bitReader) read(n int) int {
+	var v int
+	for i := 0; i < n; i++ {
+		var bit = int(br.data[br.pos>>3]>>(br.pos&7)) & 1
+		v |= bit << uint(i)
+		br.pos++
+	}
+	return v
+}
+
+// bptcInterp interpolates between two endpoints using a BPTC weight.
+func bptcInterp(e0, e1, w int) int {
+	return ((64-w)*e0 + w*e1 + 32) >> 6
+}
+
+// decodeBC7 decodes a BC7 block.
+func decodeBC7(block []byte, px *[16][4]uint8) {
+	var (
+		br   = bitReader{data: block}
+		mode = 0
+	)
+
+	for mode < 8 && br.read(1) == 0 {
+		mode++
+	}
+	if mode == 8 {
+		*px = [16][4]uint8{}
+		return
+	}
+
+	var (
+		m         = bc7Modes[mode]
+		partition = br.read(m.partitionBits)
+		rotation  = br.read(m.rotationBits)
+		indexSel  = br.read(m.indexSelBits)
+
+		endpoints [6][4]int
+		channels  = 3
+	)
+
+	if m.alphaBits > 0 {
+		channels = 4
+	}
+
+	for c := 0; c < channels; c++ {
+		var bits = m.colorBits
+		if c == 3 {
+			bits = m.alphaBits
+		}
+		for e := 0; e < m.subsets*2; e++ {
+			endpoints[e][c] = br.read(bits)
+		}
+	}
+
+	// Apply p-bits and expand each endpoint to 8 bits per channel.
+	var pbits [6]int
+	switch {
+	case m.endpointPBits > 0:
+		for e := 0; e < m.subsets*2; e++ {
+			pbits[e] = br.read(1)
+		}
+	case m.sharedPBits > 0:
+		for s := 0; s < m.subsets; s++ {
+			pbits[s*2] = br.read(1)
+			pbits[s*2+1] = pbits[s*2]
+		}
+	}
+	for e := 0; e < m.subsets*2; e++ {
+		for c := 0; c < 4; c++ {
+			var bits = m.colorBits
+			if c == 3 {
+				bits = m.alphaBits
+			}
+			if c == 3 && bits == 0 {
+				endpoints[e][c] = 0xff
+				continue
+			}
+			if m.endpointPBits > 0 || m.sharedPBits > 0 {
+				endpoints[e][c] = endpoints[e][c]<<1 | pbits[e]
+				bits++
+			}
+			endpoints[e][c] = endpoints[e][c]<<uint(8-bits) | endpoints[e][c]>>uint(2*bits-8)
+		}
+	}
+
+	var indices, indices2 [16]int
+	for i := range indices {
+		var bits = m.indexBits
+		if bptcIsAnchor(m.subsets, partition, i) {
+			bits--
+		}
+		indices[i] = br.read(bits)
+	}
+	if m.indexBits2 > 0 {
+		for i := range indices2 {
+			var bits = m.indexBits2
+			if i == 0 {
+				bits--
+			}
+			indices2[i] = br.read(bits)
+		}
+	}
+
+	for i := range px {
+		var (
+			s      = bptcSubset(m.subsets, partition, i)
+			e0, e1 = endpoints[s*2], endpoints[s*2+1]
+			cw     = bptcWeights[m.indexBits][indices[i]]
+			aw     = cw
+		)
+
+		if m.indexBits2 > 0 {
+			aw = bptcWeights[m.indexBits2][indices2[i]]
+			if indexSel == 1 {
+				cw, aw = aw, cw
+			}
+		}
+
+		for c := 0; c < 3; c++ {
+			px[i][c] = uint8(bptcInterp(e0[c], e1[c], cw))
+		}
+		px[i][3] = uint8(bptcInterp(e0[3], e1[3], aw))
+
+		switch rotation {
+		case 1:
+			px[i][0], px[i][3] = px[i][3], px[i][0]
+		case 2:
+			px[i][1], px[i][3] = px[i][3], px[i][1]
+		case 3:
+			px[i][2], px[i][3] = px[i][3], px[i][2]
+		}
+	}
+}
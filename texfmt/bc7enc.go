@@ -0,0 +1,290 @@
+package texfmt
+
+import (
+	"math"
+	"sort"
+)
+
+// bitWriter writes little-endian bit fields into a block.
+type bitWriter struct {
+	data []byte
+	pos  uint
+}
+
+func (bw *bitWriter) write(v, n int) {
+	for i := 0; i < n; i++ {
+		if v>>uint(i)&1 == 1 {
+			bw.data[bw.pos>>3] |= 1 << (bw.pos & 7)
+		}
+		bw.pos++
+	}
+}
+
+// bc7Subset is the encoding of one subset of a BC7 block.
+type bc7Subset struct {
+	q       [2][4]int // quantized endpoints, without p-bits
+	p       [2]int    // p-bit of each endpoint
+	indices [16]int   // index of each pixel in the subset
+	err     float64
+}
+
+// bc7Fit encodes the pixels of one subset. 'quant' converts an endpoint and
+// p-bit to its quantized and decoded values; 'shared' forces both endpoints to
+// use the same p-bit.
+func bc7Fit(px []vec4, members []int, channels, indexBits int, shared bool, quant func(c float64, p int) (int, int)) bc7Subset {
+	var (
+		sub     = make([]vec4, len(members))
+		weights = bptcWeights[indexBits]
+		best    = bc7Subset{err: math.Inf(1)}
+	)
+
+	for i, m := range members {
+		sub[i] = px[m]
+	}
+
+	var e0, e1 = fitEndpoints(sub, channels)
+
+	for iter := 0; iter < 3; iter++ {
+		for pbits := 0; pbits < 4; pbits++ {
+			var p0, p1 = pbits & 1, pbits >> 1
+			if shared && p0 != p1 {
+				continue
+			}
+
+			var (
+				s      = bc7Subset{p: [2]int{p0, p1}}
+				d0, d1 vec4
+			)
+			for c := 0; c < channels; c++ {
+				s.q[0][c], d0[c] = quantPair(quant, e0[c], p0)
+				s.q[1][c], d1[c] = quantPair(quant, e1[c], p1)
+			}
+			for c := channels; c < 4; c++ {
+				d0[c], d1[c] = 255, 255
+			}
+
+			for i, m := range members {
+				var bestIdx, bestErr = 0, math.Inf(1)
+				for j, w := range weights {
+					var e float64
+					for c := 0; c < 4; c++ {
+						var d = float64(bptcInterp(int(d0[c]), int(d1[c]), w)) - px[m][c]
+						e += d * d
+					}
+					if e < bestErr {
+						bestIdx, bestErr = j, e
+					}
+				}
+				s.indices[i] = bestIdx
+				s.err += bestErr
+			}
+
+			if s.err < best.err {
+				best = s
+			}
+		}
+
+		if best.err == 0 {
+			break
+		}
+
+		var w = make([]float64, len(members))
+		for i := range members {
+			w[i] = float64(weights[best.indices[i]]) / 64
+		}
+		var ok bool
+		if e0, e1, ok = refineEndpoints(sub, w, channels); !ok {
+			break
+		}
+	}
+
+	return best
+}
+
+// quantPair calls 'quant' and returns its results as an int and a float.
+func quantPair(quant func(c float64, p int) (int, int), c float64, p int) (int, float64) {
+	var q, d = quant(c, p)
+	return q, float64(d)
+}
+
+// quantMode6 quantizes a channel to 7 bits plus a p-bit.
+func quantMode6(c float64, p int) (int, int) {
+	var q = int(clampf(math.Floor((c-float64(p))/2+0.5), 0, 127))
+	return q, q<<1 | p
+}
+
+// quantMode1 quantizes a channel to 6 bits plus a p-bit, expanded to 8 bits.
+func quantMode1(c float64, p int) (int, int) {
+	var (
+		q = int(clampf(math.Floor((c*127/255-float64(p))/2+0.5), 0, 63))
+		v = q<<1 | p
+	)
+	return q, v<<1 | v>>6
+}
+
+// fixAnchor swaps the endpoints of a subset if the index of its anchor pixel
+// has its most significant bit set, so that the bit can be omitted.
+func (s *bc7Subset) fixAnchor(anchor, indexBits int) {
+	var top = 1 << uint(indexBits)
+	if s.indices[anchor] < top/2 {
+		return
+	}
+	s.q[0], s.q[1] = s.q[1], s.q[0]
+	s.p[0], s.p[1] = s.p[1], s.p[0]
+	for i := range s.indices {
+		s.indices[i] = top - 1 - s.indices[i]
+	}
+}
+
+// encodeBC7Mode6 encodes a block with a single RGBA subset.
+func encodeBC7Mode6(px []vec4, block []byte) float64 {
+	var members = make([]int, 16)
+	for i := range members {
+		members[i] = i
+	}
+
+	var s = bc7Fit(px, members, 4, 4, false, quantMode6)
+	s.fixAnchor(0, 4)
+
+	for i := range block {
+		block[i] = 0
+	}
+	var bw = bitWriter{data: block}
+	bw.write(1<<6, 7)
+	for c := 0; c < 4; c++ {
+		bw.write(s.q[0][c], 7)
+		bw.write(s.q[1][c], 7)
+	}
+	bw.write(s.p[0], 1)
+	bw.write(s.p[1], 1)
+	for i, idx := range s.indices {
+		if i == 0 {
+			bw.write(idx, 3)
+		} else {
+			bw.write(idx, 4)
+		}
+	}
+
+	return s.err
+}
+
+// encodeBC7Mode1 encodes an opaque block with two RGB subsets, choosing the
+// partition whose unquantized fit has the least error.
+func encodeBC7Mode1(px []vec4, block []byte) float64 {
+	type candidate struct {
+		partition int
+		err       float64
+	}
+
+	var candidates = make([]candidate, 64)
+	for p := range candidates {
+		candidates[p] = candidate{p, 0}
+		for s := 0; s < 2; s++ {
+			var sub []vec4
+			for i := range px {
+				if bptcSubset(2, p, i) == s {
+					sub = append(sub, px[i])
+				}
+			}
+			var mean, axis = principalAxis(sub, 3)
+			for _, v := range sub {
+				var (
+					d = v.sub(mean)
+					t = d.dot(axis, 3)
+				)
+				candidates[p].err += d.dot(d, 3) - t*t
+			}
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].err < candidates[b].err })
+
+	var (
+		bestErr       = math.Inf(1)
+		bestPartition int
+		bestSubsets   [2]bc7Subset
+		bestMembers   [2][]int
+	)
+
+	for _, cand := range candidates[:4] {
+		var (
+			members [2][]int
+			subsets [2]bc7Subset
+			total   float64
+		)
+		for i := range px {
+			var s = bptcSubset(2, cand.partition, i)
+			members[s] = append(members[s], i)
+		}
+		for s := range subsets {
+			subsets[s] = bc7Fit(px, members[s], 3, 3, true, quantMode1)
+			total += subsets[s].err
+		}
+		if total < bestErr {
+			bestErr, bestPartition, bestSubsets, bestMembers = total, cand.partition, subsets, members
+		}
+	}
+
+	// Map each pixel to its subset and position within it.
+	var indices [16]int
+	for s := range bestSubsets {
+		var anchor = 0
+		for j, m := range bestMembers[s] {
+			if s == 1 && m == int(bptcAnchors2[bestPartition]) {
+				anchor = j
+			}
+		}
+		bestSubsets[s].fixAnchor(anchor, 3)
+		for j, m := range bestMembers[s] {
+			indices[m] = bestSubsets[s].indices[j]
+		}
+	}
+
+	for i := range block {
+		block[i] = 0
+	}
+	var bw = bitWriter{data: block}
+	bw.write(2, 2)
+	bw.write(bestPartition, 6)
+	for c := 0; c < 3; c++ {
+		for s := 0; s < 2; s++ {
+			bw.write(bestSubsets[s].q[0][c], 6)
+			bw.write(bestSubsets[s].q[1][c], 6)
+		}
+	}
+	bw.write(bestSubsets[0].p[0], 1)
+	bw.write(bestSubsets[1].p[0], 1)
+	for i, idx := range indices {
+		if bptcIsAnchor(2, bestPartition, i) {
+			bw.write(idx, 2)
+		} else {
+			bw.write(idx, 3)
+		}
+	}
+
+	return bestErr
+}
+
+// encodeBC7 encodes a block using mode 6, or mode 1 if the block is opaque and
+// two subsets represent it better.
+func encodeBC7(px *[16][4]uint8, block []byte) {
+	var (
+		pixels = toVec4(px)
+		opaque = true
+	)
+
+	for _, p := range px {
+		if p[3] != 255 {
+			opaque = false
+		}
+	}
+
+	var err = encodeBC7Mode6(pixels, block)
+	if !opaque || err == 0 {
+		return
+	}
+
+	var alt [16]byte
+	if encodeBC7Mode1(pixels, alt[:]) < err {
+		copy(block, alt[:])
+	}
+}
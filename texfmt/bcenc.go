@@ -0,0 +1,374 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// vec4 is a pixel or endpoint with channels in [0, 255].
+type vec4 [4]float64
+
+func (a vec4) sub(b vec4) vec4 { return vec4{a[0] - b[0], a[1] - b[1], a[2] - b[2], a[3] - b[3]} }
+
+func (a vec4) dot(b vec4, channels int) float64 {
+	var d float64
+	for c := 0; c < channels; c++ {
+		d += a[c] * b[c]
+	}
+	return d
+}
+
+// principalAxis returns the mean of the pixels and the direction of greatest
+// variance over the first 'channels' channels, found by power iteration.
+func principalAxis(px []vec4, channels int) (mean, axis vec4) {
+	for _, p := range px {
+		for c := 0; c < channels; c++ {
+			mean[c] += p[c]
+		}
+	}
+	for c := 0; c < channels; c++ {
+		mean[c] /= float64(len(px))
+	}
+
+	var cov [4][4]float64
+	for _, p := range px {
+		var d = p.sub(mean)
+		for i := 0; i < channels; i++ {
+			for j := 0; j < channels; j++ {
+				cov[i][j] += d[i] * d[j]
+			}
+		}
+	}
+
+	// Start from the covariance of the channel varying most, which is never
+	// orthogonal to the axis, as a fixed start such as grey can be.
+	var top = 0
+	for c := 1; c < channels; c++ {
+		if cov[c][c] > cov[top][top] {
+			top = c
+		}
+	}
+	for c := 0; c < channels; c++ {
+		axis[c] = cov[c][top]
+	}
+	if cov[top][top] == 0 {
+		axis[0] = 1
+	}
+	for iter := 0; iter < 8; iter++ {
+		var next vec4
+		for i := 0; i < channels; i++ {
+			for j := 0; j < channels; j++ {
+				next[i] += cov[i][j] * axis[j]
+			}
+		}
+		var l = math.Sqrt(next.dot(next, channels))
+		if l < 1e-9 {
+			break
+		}
+		for c := 0; c < channels; c++ {
+			axis[c] = next[c] / l
+		}
+	}
+
+	return mean, axis
+}
+
+// fitEndpoints returns the extremes of the pixels projected onto their
+// principal axis.
+func fitEndpoints(px []vec4, channels int) (e0, e1 vec4) {
+	var (
+		mean, axis = principalAxis(px, channels)
+		lo, hi     = math.Inf(1), math.Inf(-1)
+	)
+
+	for _, p := range px {
+		var t = p.sub(mean).dot(axis, channels)
+		lo, hi = math.Min(lo, t), math.Max(hi, t)
+	}
+
+	for c := 0; c < channels; c++ {
+		e0[c] = clampf(mean[c]+axis[c]*lo, 0, 255)
+		e1[c] = clampf(mean[c]+axis[c]*hi, 0, 255)
+	}
+
+	return e0, e1
+}
+
+// refineEndpoints solves for the endpoints that minimize the squared error of
+// the pixels given their interpolation weights in [0, 1]. It returns false if
+// the system is degenerate.
+func refineEndpoints(px []vec4, weights []float64, channels int) (e0, e1 vec4, ok bool) {
+	var (
+		aa, ab, bb float64
+		ax, bx     vec4
+	)
+
+	for i, p := range px {
+		var a, b = 1 - weights[i], weights[i]
+		aa += a * a
+		ab += a * b
+		bb += b * b
+		for c := 0; c < channels; c++ {
+			ax[c] += a * p[c]
+			bx[c] += b * p[c]
+		}
+	}
+
+	var det = aa*bb - ab*ab
+	if math.Abs(det) < 1e-9 {
+		return e0, e1, false
+	}
+
+	for c := 0; c < channels; c++ {
+		e0[c] = clampf((bb*ax[c]-ab*bx[c])/det, 0, 255)
+		e1[c] = clampf((aa*bx[c]-ab*ax[c])/det, 0, 255)
+	}
+
+	return e0, e1, true
+}
+
+func clampf(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// toVec4 converts a block of pixels for encoding.
+func toVec4(px *[16][4]uint8) []vec4 {
+	var out = make([]vec4, 16)
+	for i, p := range px {
+		out[i] = vec4{float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])}
+	}
+	return out
+}
+
+// pack565 quantizes a colour to 5:6:5.
+func pack565(c vec4) uint16 {
+	var (
+		r = uint16(clampf(math.Floor(c[0]*31/255+0.5), 0, 31))
+		g = uint16(clampf(math.Floor(c[1]*63/255+0.5), 0, 63))
+		b = uint16(clampf(math.Floor(c[2]*31/255+0.5), 0, 31))
+	)
+	return r<<11 | g<<5 | b
+}
+
+// bc1Palette returns the palette decoded from two 5:6:5 endpoints.
+func bc1Palette(c0, c1 uint16, fourColor bool) (pal [4]vec4) {
+	var p0, p1 = unpack565(c0), unpack565(c1)
+	for c := 0; c < 3; c++ {
+		var a, b = float64(p0[c]), float64(p1[c])
+		pal[0][c], pal[1][c] = a, b
+		if fourColor {
+			pal[2][c] = math.Floor((2*a + b + 1) / 3)
+			pal[3][c] = math.Floor((a + 2*b + 1) / 3)
+		} else {
+			pal[2][c] = math.Floor((a + b) / 2)
+		}
+	}
+	return pal
+}
+
+// bc1Indices assigns each pixel its nearest palette entry, returning the
+// packed indices and the total squared error. Transparent pixels are assigned
+// index 3 in three colour mode.
+func bc1Indices(px []vec4, pal [4]vec4, fourColor bool, transparent []bool) (uint32, float64) {
+	var (
+		indices uint32
+		total   float64
+		entries = 4
+	)
+
+	if !fourColor {
+		entries = 3
+	}
+
+	for i, p := range px {
+		if transparent != nil && transparent[i] {
+			indices |= 3 << (2 * uint(i))
+			continue
+		}
+
+		var best, bestErr = 0, math.Inf(1)
+		for j := 0; j < entries; j++ {
+			var d = p.sub(pal[j])
+			if e := d.dot(d, 3); e < bestErr {
+				best, bestErr = j, e
+			}
+		}
+		indices |= uint32(best) << (2 * uint(i))
+		total += bestErr
+	}
+
+	return indices, total
+}
+
+// encodeColorBlock encodes the colour block of BC1, BC2 and BC3. If
+// 'allowAlpha' is set, pixels with alpha below 128 are encoded as transparent
+// using three colour mode.
+func encodeColorBlock(px *[16][4]uint8, block []byte, allowAlpha bool) {
+	var (
+		pixels      = toVec4(px)
+		transparent []bool
+		opaque      = pixels[:0:0]
+	)
+
+	if allowAlpha {
+		transparent = make([]bool, 16)
+		for i, p := range px {
+			if p[3] < 128 {
+				transparent[i] = true
+			} else {
+				opaque = append(opaque, pixels[i])
+			}
+		}
+		if len(opaque) == 16 {
+			transparent = nil
+		}
+	} else {
+		opaque = pixels
+	}
+
+	var le = binary.LittleEndian
+	if len(opaque) == 0 {
+		le.PutUint16(block[0:], 0)
+		le.PutUint16(block[2:], 0)
+		le.PutUint32(block[4:], 0xffffffff)
+		return
+	}
+
+	var (
+		fourColor = transparent == nil
+		e0, e1    = fitEndpoints(opaque, 3)
+		weights   = [4]float64{0, 1, 1.0 / 3, 2.0 / 3}
+
+		bestC0, bestC1 uint16
+		bestIdx        uint32
+		bestErr        = math.Inf(1)
+	)
+	if !fourColor {
+		weights[2] = 0.5
+	}
+
+	for iter := 0; iter < 3; iter++ {
+		var c0, c1 = pack565(e0), pack565(e1)
+
+		// Four colour mode requires c0 > c1 and three colour mode c0 <= c1.
+		if (fourColor && c0 < c1) || (!fourColor && c0 > c1) {
+			c0, c1 = c1, c0
+		}
+
+		var (
+			pal      = bc1Palette(c0, c1, fourColor || c0 > c1)
+			idx, err = bc1Indices(pixels, pal, fourColor || c0 > c1, transparent)
+		)
+		if err < bestErr {
+			bestC0, bestC1, bestIdx, bestErr = c0, c1, idx, err
+		}
+		if err == 0 {
+			break
+		}
+
+		var (
+			w   = make([]float64, 0, 16)
+			fit = make([]vec4, 0, 16)
+		)
+		for i := range pixels {
+			if transparent != nil && transparent[i] {
+				continue
+			}
+			w = append(w, weights[idx>>(2*uint(i))&3])
+			fit = append(fit, pixels[i])
+		}
+
+		// The refined endpoints replace c0 and c1 respectively, and are
+		// reordered by the next iteration if necessary.
+		var ok bool
+		if e0, e1, ok = refineEndpoints(fit, w, 3); !ok {
+			break
+		}
+	}
+
+	// In four colour mode equal endpoints would select three colour mode,
+	// so all pixels use index 0.
+	if fourColor && bestC0 == bestC1 {
+		bestIdx = 0
+	}
+
+	le.PutUint16(block[0:], bestC0)
+	le.PutUint16(block[2:], bestC1)
+	le.PutUint32(block[4:], bestIdx)
+}
+
+// encodeAlphaBlock encodes channel 'c' of a block as a BC4 block. Both the
+// eight value mode spanning the whole range and the six value mode, which
+// spans the values other than 0 and 255, are tried.
+func encodeAlphaBlock(px *[16][4]uint8, c int, block []byte) {
+	var (
+		lo, hi         = 255, 0
+		inner0, inner1 = 255, 0
+	)
+
+	for _, p := range px {
+		var v = int(p[c])
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+		if v > 0 && v < inner0 {
+			inner0 = v
+		}
+		if v < 255 && v > inner1 {
+			inner1 = v
+		}
+	}
+	if inner0 > inner1 {
+		inner0, inner1 = lo, hi
+	}
+
+	var try = func(a0, a1 int) (indices uint64, total int) {
+		var pal = bc4Palette(a0, a1)
+		for i, p := range px {
+			var best, bestErr = 0, 1 << 30
+			for j, v := range pal {
+				var d = int(p[c]) - int(v)
+				if d*d < bestErr {
+					best, bestErr = j, d*d
+				}
+			}
+			indices |= uint64(best) << (3 * uint(i))
+			total += bestErr
+		}
+		return indices, total
+	}
+
+	var (
+		a0, a1       = hi, lo
+		indices, err = try(a0, a1)
+	)
+	if idx6, err6 := try(inner0, inner1); err6 < err {
+		a0, a1, indices = inner0, inner1, idx6
+	}
+
+	block[0], block[1] = byte(a0), byte(a1)
+	for i := 0; i < 6; i++ {
+		block[2+i] = byte(indices >> (8 * uint(i)))
+	}
+}
+
+func encodeBC1(px *[16][4]uint8, block []byte) {
+	encodeColorBlock(px, block, true)
+}
+
+func encodeBC3(px *[16][4]uint8, block []byte) {
+	encodeAlphaBlock(px, 3, block[:8])
+	encodeColorBlock(px, block[8:], false)
+}
+
+func encodeBC4(px *[16][4]uint8, block []byte) {
+	encodeAlphaBlock(px, 0, block)
+}
+
+func encodeBC5(px *[16][4]uint8, block []byte) {
+	encodeAlphaBlock(px, 0, block[:8])
+	encodeAlphaBlock(px, 1, block[8:])
+}
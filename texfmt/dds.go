@@ -0,0 +1,240 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const ddsMagic = "DDS "
+
+// DDS header flags.
+const (
+	ddpfAlphaPixels = 0x1
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+	ddpfLuminance   = 0x20000
+
+	ddsCaps2Cubemap    = 0x200
+	ddsCaps2Volume     = 0x200000
+	ddsResourceMiscCub = 0x4
+
+	ddsHeaderSize = 124
+	dx10HeaderLen = 20
+)
+
+// dxgiFormats maps DXGI_FORMAT values to Formats.
+var dxgiFormats = map[uint32]Format{
+	2:  RGBA32F,
+	6:  RGB32F,
+	10: RGBA16F,
+	28: RGBA8, 29: RGBA8SRGB,
+	49: RG8,
+	61: R8,
+	71: BC1, 72: BC1SRGB,
+	74: BC2, 75: BC2SRGB,
+	77: BC3, 78: BC3SRGB,
+	80: BC4, 81: BC4S,
+	83: BC5, 84: BC5S,
+	95: BC6HU, 96: BC6HS,
+	98: BC7, 99: BC7SRGB,
+}
+
+// fourCCFormats maps legacy DDS FourCC codes to Formats.
+var fourCCFormats = map[string]Format{
+	"DXT1": BC1,
+	"DXT2": BC2, "DXT3": BC2,
+	"DXT4": BC3, "DXT5": BC3,
+	"ATI1": BC4, "BC4U": BC4, "BC4S": BC4S,
+	"ATI2": BC5, "BC5U": BC5, "BC5S": BC5S,
+}
+
+// d3dFormats maps the numeric D3DFORMAT values stored in the FourCC field.
+var d3dFormats = map[uint32]Format{
+	113: RGBA16F,
+	116: RGBA32F,
+}
+
+// ddsPixelFormat is the DDS_PIXELFORMAT structure.
+type ddsPixelFormat struct {
+	Flags                      uint32
+	FourCC                     uint32
+	RGBBitCount                uint32
+	RMask, GMask, BMask, AMask uint32
+}
+
+// format determines the Format of a legacy pixel format description. If the
+// data is uncompressed but not stored as RGBA8, 'convert' reorders it.
+func (pf *ddsPixelFormat) format() (f Format, convert func([]byte) []byte, err error) {
+	if pf.Flags&ddpfFourCC != 0 {
+		var cc = string([]byte{byte(pf.FourCC), byte(pf.FourCC >> 8), byte(pf.FourCC >> 16), byte(pf.FourCC >> 24)})
+		if f, ok := fourCCFormats[cc]; ok {
+			return f, nil, nil
+		}
+		if f, ok := d3dFormats[pf.FourCC]; ok {
+			return f, nil, nil
+		}
+		return FormatUnknown, nil, fmt.Errorf("texfmt.decodeDDS error: unsupported FourCC %q", cc)
+	}
+
+	if pf.Flags&(ddpfRGB|ddpfLuminance) == 0 || (pf.RGBBitCount != 24 && pf.RGBBitCount != 32) {
+		return FormatUnknown, nil, errors.New("texfmt.decodeDDS error: unsupported pixel format")
+	}
+
+	var (
+		bpp   = int(pf.RGBBitCount / 8)
+		masks = [4]uint32{pf.RMask, pf.GMask, pf.BMask, pf.AMask}
+	)
+	if pf.Flags&ddpfLuminance != 0 {
+		masks[1], masks[2] = pf.RMask, pf.RMask
+	}
+	if pf.Flags&ddpfAlphaPixels == 0 {
+		masks[3] = 0
+	}
+	if bpp == 4 && masks == [4]uint32{0xff, 0xff00, 0xff0000, 0xff000000} {
+		return RGBA8, nil, nil
+	}
+
+	convert = func(src []byte) []byte {
+		var dst = make([]byte, len(src)/bpp*4)
+		for i, j := 0, 0; i+bpp <= len(src); i, j = i+bpp, j+4 {
+			var px uint32
+			for k := 0; k < bpp; k++ {
+				px |= uint32(src[i+k]) << (8 * uint(k))
+			}
+			for c, mask := range masks {
+				if mask == 0 {
+					dst[j+c] = 0xff
+					continue
+				}
+				var shift uint
+				for mask>>shift&1 == 0 {
+					shift++
+				}
+				dst[j+c] = byte((px & mask) >> shift)
+			}
+		}
+		return dst
+	}
+
+	return RGBA8, convert, nil
+}
+
+// decodeDDS parses a DirectDraw Surface file, with or without the DX10
+// extended header.
+func decodeDDS(data []byte) (*Image, error) {
+	if len(data) < 4+ddsHeaderSize {
+		return nil, errors.New("texfmt.decodeDDS error: file is truncated")
+	}
+
+	var (
+		le     = binary.LittleEndian
+		hdr    = data[4 : 4+ddsHeaderSize]
+		offset = 4 + ddsHeaderSize
+
+		flags  = le.Uint32(hdr[4:])
+		height = int(le.Uint32(hdr[8:]))
+		width  = int(le.Uint32(hdr[12:]))
+		mips   = int(le.Uint32(hdr[24:]))
+		caps2  = le.Uint32(hdr[108:])
+
+		pf = ddsPixelFormat{
+			Flags:       le.Uint32(hdr[76:]),
+			FourCC:      le.Uint32(hdr[80:]),
+			RGBBitCount: le.Uint32(hdr[84:]),
+			RMask:       le.Uint32(hdr[88:]),
+			GMask:       le.Uint32(hdr[92:]),
+			BMask:       le.Uint32(hdr[96:]),
+			AMask:       le.Uint32(hdr[100:]),
+		}
+
+		img = &Image{Width: width, Height: height, Layers: 1, Faces: 1}
+
+		convert func([]byte) []byte
+		err     error
+	)
+
+	if le.Uint32(hdr[0:]) != ddsHeaderSize {
+		return nil, errors.New("texfmt.decodeDDS error: invalid header size")
+	}
+	if caps2&ddsCaps2Volume != 0 {
+		return nil, errors.New("texfmt.decodeDDS error: volume textures are not supported")
+	}
+	if flags&0x20000 == 0 || mips < 1 {
+		mips = 1
+	}
+	if caps2&ddsCaps2Cubemap != 0 {
+		if caps2&0xfc00 != 0xfc00 {
+			return nil, errors.New("texfmt.decodeDDS error: partial cube maps are not supported")
+		}
+		img.Faces = 6
+	}
+
+	if pf.Flags&ddpfFourCC != 0 && string(hdr[80:84]) == "DX10" {
+		if len(data) < offset+dx10HeaderLen {
+			return nil, errors.New("texfmt.decodeDDS error: file is truncated")
+		}
+		var (
+			dx10   = data[offset : offset+dx10HeaderLen]
+			dxgi   = le.Uint32(dx10[0:])
+			layers = int(le.Uint32(dx10[12:]))
+			ok     bool
+		)
+		offset += dx10HeaderLen
+
+		if img.Format, ok = dxgiFormats[dxgi]; !ok {
+			return nil, fmt.Errorf("texfmt.decodeDDS error: unsupported DXGI format %d", dxgi)
+		}
+		if le.Uint32(dx10[4:]) == 4 {
+			return nil, errors.New("texfmt.decodeDDS error: volume textures are not supported")
+		}
+		if le.Uint32(dx10[8:])&ddsResourceMiscCub != 0 {
+			img.Faces = 6
+		}
+		if layers > 1 {
+			img.Layers = layers
+			img.Array = true
+		}
+	} else if img.Format, convert, err = pf.format(); err != nil {
+		return nil, err
+	}
+
+	if err = checkHeader("texfmt.decodeDDS", width, height, img.Layers, mips); err != nil {
+		return nil, err
+	}
+
+	var srcSize = img.Format.BlockSize()
+	if convert != nil {
+		srcSize = int(pf.RGBBitCount / 8)
+	}
+
+	// DDS stores each layer and face with its complete mip chain, whereas
+	// Image stores each level with all of its layers and faces.
+	img.Levels = make([][]byte, mips)
+	for layer := 0; layer < img.Layers; layer++ {
+		for face := 0; face < img.Faces; face++ {
+			for level := 0; level < mips; level++ {
+				var (
+					w, h = img.LevelSize(level)
+					size = img.Format.DataSize(w, h) / img.Format.BlockSize() * srcSize
+				)
+				if size > len(data)-offset {
+					return nil, errors.New("texfmt.decodeDDS error: file is truncated")
+				}
+
+				var src = data[offset : offset+size]
+				if convert != nil {
+					src = convert(src)
+				}
+				img.Levels[level] = append(img.Levels[level], src...)
+				offset += size
+			}
+		}
+	}
+
+	if err = img.validate(); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
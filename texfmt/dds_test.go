@@ -0,0 +1,88 @@
+package texfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// writeDDS returns a DDS file with a legacy header and a single level.
+func writeDDS(pfFlags uint32, fourCC string, bits uint32, masks [4]uint32, width, height int, data []byte) []byte {
+	var (
+		le  = binary.LittleEndian
+		hdr = make([]byte, 4+ddsHeaderSize)
+	)
+	copy(hdr, ddsMagic)
+	le.PutUint32(hdr[4:], ddsHeaderSize)
+	le.PutUint32(hdr[12:], uint32(height))
+	le.PutUint32(hdr[16:], uint32(width))
+	le.PutUint32(hdr[80:], pfFlags)
+	copy(hdr[84:88], fourCC)
+	le.PutUint32(hdr[88:], bits)
+	for i, m := range masks {
+		le.PutUint32(hdr[92+4*i:], m)
+	}
+	return append(hdr, data...)
+}
+
+func TestDecodeDDSFourCC(t *testing.T) {
+	var block = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	var img, err = Decode(bytes.NewReader(writeDDS(ddpfFourCC, "DXT1", 0, [4]uint32{}, 4, 4, block)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != BC1 || img.Width != 4 || img.Height != 4 || len(img.Levels) != 1 {
+		t.Fatalf("got %v %dx%d with %d levels", img.Format, img.Width, img.Height, len(img.Levels))
+	}
+	if !bytes.Equal(img.Levels[0], block) {
+		t.Errorf("got %v, want %v", img.Levels[0], block)
+	}
+}
+
+func TestDecodeDDSBGR(t *testing.T) {
+	// Two 24-bit BGR pixels are converted to RGBA8 with opaque alpha.
+	var (
+		masks = [4]uint32{0xff0000, 0xff00, 0xff, 0}
+		file  = writeDDS(ddpfRGB, "", 24, masks, 2, 1, []byte{1, 2, 3, 4, 5, 6})
+		want  = []byte{3, 2, 1, 0xff, 6, 5, 4, 0xff}
+	)
+	var img, err = Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != RGBA8 || !bytes.Equal(img.Levels[0], want) {
+		t.Errorf("got %v %v, want RGBA8 %v", img.Format, img.Levels[0], want)
+	}
+}
+
+func TestDecodeDDSTruncated(t *testing.T) {
+	var file = writeDDS(ddpfFourCC, "DXT1", 0, [4]uint32{}, 8, 8, make([]byte, 31))
+	if _, err := Decode(bytes.NewReader(file)); err == nil {
+		t.Error("truncated file was decoded")
+	}
+}
+
+func TestDecodeDDSInvalidHeader(t *testing.T) {
+	var le = binary.LittleEndian
+	var tests = []struct {
+		name          string
+		width, height uint32
+		mips          uint32
+	}{
+		{"huge mip count", 8, 8, 0x7fffffff},
+		{"too many mips", 8, 8, 5},
+		{"huge size", 0xffffffff, 0xffffffff, 1},
+		{"too wide", 65537, 1, 1},
+		{"empty", 0, 4, 1},
+	}
+	for _, tt := range tests {
+		var file = writeDDS(ddpfFourCC, "DXT1", 0, [4]uint32{}, 8, 8, make([]byte, 64))
+		le.PutUint32(file[8:], le.Uint32(file[8:])|0x20000)
+		le.PutUint32(file[12:], tt.height)
+		le.PutUint32(file[16:], tt.width)
+		le.PutUint32(file[28:], tt.mips)
+		if _, err := Decode(bytes.NewReader(file)); err == nil {
+			t.Errorf("%s: invalid header was decoded", tt.name)
+		}
+	}
+}
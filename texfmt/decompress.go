@@ -0,0 +1,287 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ldrDecoder decodes one 4x4 block into RGBA8 pixels in row-major order.
+type ldrDecoder func(block []byte, px *[16][4]uint8)
+
+// floatDecoder decodes one 4x4 block into RGBA32F pixels in row-major order.
+type floatDecoder func(block []byte, px *[16][4]float32)
+
+var ldrDecoders = map[Format]ldrDecoder{
+	BC1: decodeBC1, BC1SRGB: decodeBC1,
+	BC2: decodeBC2, BC2SRGB: decodeBC2,
+	BC3: decodeBC3, BC3SRGB: decodeBC3,
+	BC4: decodeBC4, BC5: decodeBC5,
+	BC7: decodeBC7, BC7SRGB: decodeBC7,
+	ETC2RGB8: decodeETC2RGB, ETC2RGB8SRGB: decodeETC2RGB,
+	ETC2RGB8A1: decodeETC2RGBA1, ETC2RGB8A1SRGB: decodeETC2RGBA1,
+	ETC2RGBA8: decodeETC2RGBA, ETC2RGBA8SRGB: decodeETC2RGBA,
+}
+
+var floatDecoders = map[Format]floatDecoder{
+	BC4S: decodeBC4S, BC5S: decodeBC5S,
+	EACR11: decodeEACR11, EACR11S: decodeEACR11S,
+	EACRG11: decodeEACRG11, EACRG11S: decodeEACRG11S,
+}
+
+// CanDecompress reports whether Decompress supports the format.
+func CanDecompress(f Format) bool {
+	var _, ldr = ldrDecoders[f]
+	var _, flt = floatDecoders[f]
+	return ldr || flt
+}
+
+// Decompress decodes every level, layer and face of a block compressed image.
+// Unsigned formats with at most 8 bits per channel decode to RGBA8 or
+// RGBA8SRGB; signed and 11-bit formats decode to RGBA32F. BC6H is not
+// supported, since every OpenGL 4.2 implementation can sample it directly.
+func Decompress(img *Image) (*Image, error) {
+	if !img.Format.Compressed() {
+		return img, nil
+	}
+
+	var (
+		ldr, isLDR = ldrDecoders[img.Format]
+		flt, isFlt = floatDecoders[img.Format]
+		out        = &Image{
+			Width:  img.Width,
+			Height: img.Height,
+			Layers: img.Layers,
+			Faces:  img.Faces,
+			Array:  img.Array,
+			Levels: make([][]byte, len(img.Levels)),
+		}
+	)
+
+	switch {
+	case isLDR && img.Format.SRGB():
+		out.Format = RGBA8SRGB
+	case isLDR:
+		out.Format = RGBA8
+	case isFlt:
+		out.Format = RGBA32F
+	default:
+		return nil, fmt.Errorf("texfmt.Decompress error: no decoder for %v", img.Format)
+	}
+
+	for level := range img.Levels {
+		var w, h = img.LevelSize(level)
+		for layer := 0; layer < img.Layers; layer++ {
+			for face := 0; face < img.Faces; face++ {
+				var (
+					src = img.Slice(level, layer, face)
+					dst []byte
+				)
+				if isLDR {
+					dst = decodeLDR(ldr, img.Format.BlockSize(), w, h, src)
+				} else {
+					dst = decodeFloat(flt, img.Format.BlockSize(), w, h, src)
+				}
+				out.Levels[level] = append(out.Levels[level], dst...)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// decodeLDR decodes a w by h surface of blocks into RGBA8 pixels.
+func decodeLDR(dec ldrDecoder, blockSize, w, h int, src []byte) []byte {
+	var (
+		dst = make([]byte, w*h*4)
+		px  [16][4]uint8
+	)
+
+	for by := 0; by < (h+3)/4; by++ {
+		for bx := 0; bx < (w+3)/4; bx++ {
+			dec(src[:blockSize], &px)
+			src = src[blockSize:]
+
+			for i, p := range px {
+				var x, y = bx*4 + i%4, by*4 + i/4
+				if x < w && y < h {
+					copy(dst[(y*w+x)*4:], p[:])
+				}
+			}
+		}
+	}
+
+	return dst
+}
+
+// decodeFloat decodes a w by h surface of blocks into RGBA32F pixels.
+func decodeFloat(dec floatDecoder, blockSize, w, h int, src []byte) []byte {
+	var (
+		dst = make([]byte, w*h*16)
+		px  [16][4]float32
+	)
+
+	for by := 0; by < (h+3)/4; by++ {
+		for bx := 0; bx < (w+3)/4; bx++ {
+			dec(src[:blockSize], &px)
+			src = src[blockSize:]
+
+			for i, p := range px {
+				var x, y = bx*4 + i%4, by*4 + i/4
+				if x >= w || y >= h {
+					continue
+				}
+				for c, v := range p {
+					binary.LittleEndian.PutUint32(dst[(y*w+x)*16+c*4:], math.Float32bits(v))
+				}
+			}
+		}
+	}
+
+	return dst
+}
+
+// unpack565 expands a 5:6:5 colour to 8 bits per channel.
+func unpack565(c uint16) [4]uint8 {
+	var r, g, b = uint8(c>>11) & 0x1f, uint8(c>>5) & 0x3f, uint8(c) & 0x1f
+	return [4]uint8{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 0xff}
+}
+
+// decodeColorBlock decodes the 8 byte colour block shared by BC1, BC2 and BC3.
+// 'opaque' forces four colour mode, as BC2 and BC3 require.
+func decodeColorBlock(block []byte, px *[16][4]uint8, opaque bool) {
+	var (
+		c0      = binary.LittleEndian.Uint16(block[0:])
+		c1      = binary.LittleEndian.Uint16(block[2:])
+		indices = binary.LittleEndian.Uint32(block[4:])
+		pal     [4][4]uint8
+	)
+
+	pal[0], pal[1] = unpack565(c0), unpack565(c1)
+	if c0 > c1 || opaque {
+		for c := 0; c < 3; c++ {
+			pal[2][c] = uint8((2*int(pal[0][c]) + int(pal[1][c]) + 1) / 3)
+			pal[3][c] = uint8((int(pal[0][c]) + 2*int(pal[1][c]) + 1) / 3)
+		}
+		pal[2][3], pal[3][3] = 0xff, 0xff
+	} else {
+		for c := 0; c < 3; c++ {
+			pal[2][c] = uint8((int(pal[0][c]) + int(pal[1][c])) / 2)
+		}
+		pal[2][3] = 0xff
+	}
+
+	for i := range px {
+		px[i] = pal[indices>>(2*uint(i))&3]
+	}
+}
+
+// bc4Palette returns the eight values selected by the indices of a BC4 block
+// with endpoints a0 and a1.
+func bc4Palette(a0, a1 int) (pal [8]uint8) {
+	pal[0], pal[1] = uint8(a0), uint8(a1)
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			pal[i+1] = uint8(((7-i)*a0 + i*a1 + 3) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			pal[i+1] = uint8(((5-i)*a0 + i*a1 + 2) / 5)
+		}
+		pal[6], pal[7] = 0, 0xff
+	}
+	return pal
+}
+
+// decodeAlphaBlock decodes the 8 byte interpolated block of BC3, BC4 and BC5
+// into the channel 'c' of each pixel.
+func decodeAlphaBlock(block []byte, px *[16][4]uint8, c int) {
+	var (
+		pal     = bc4Palette(int(block[0]), int(block[1]))
+		indices = uint64(binary.LittleEndian.Uint16(block[2:])) | uint64(binary.LittleEndian.Uint32(block[4:]))<<16
+	)
+
+	for i := range px {
+		px[i][c] = pal[indices>>(3*uint(i))&7]
+	}
+}
+
+// decodeSignedBlock decodes a signed BC4 block into the channel 'c' of each
+// pixel, in the range [-1, 1].
+func decodeSignedBlock(block []byte, px *[16][4]float32, c int) {
+	var (
+		a0, a1  = int(int8(block[0])), int(int8(block[1]))
+		indices = uint64(binary.LittleEndian.Uint16(block[2:])) | uint64(binary.LittleEndian.Uint32(block[4:]))<<16
+		pal     [8]float32
+	)
+
+	if a0 == -128 {
+		a0 = -127
+	}
+	if a1 == -128 {
+		a1 = -127
+	}
+
+	pal[0], pal[1] = float32(a0), float32(a1)
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			pal[i+1] = float32((7-i)*a0+i*a1) / 7
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			pal[i+1] = float32((5-i)*a0+i*a1) / 5
+		}
+		pal[6], pal[7] = -127, 127
+	}
+
+	for i := range px {
+		px[i][c] = pal[indices>>(3*uint(i))&7] / 127
+	}
+}
+
+func decodeBC1(block []byte, px *[16][4]uint8) {
+	decodeColorBlock(block, px, false)
+}
+
+func decodeBC2(block []byte, px *[16][4]uint8) {
+	decodeColorBlock(block[8:], px, true)
+	for i := range px {
+		var a = block[i/2] >> (4 * uint(i%2)) & 0xf
+		px[i][3] = a<<4 | a
+	}
+}
+
+func decodeBC3(block []byte, px *[16][4]uint8) {
+	decodeColorBlock(block[8:], px, true)
+	decodeAlphaBlock(block, px, 3)
+}
+
+func decodeBC4(block []byte, px *[16][4]uint8) {
+	decodeAlphaBlock(block, px, 0)
+	for i := range px {
+		px[i][1], px[i][2], px[i][3] = 0, 0, 0xff
+	}
+}
+
+func decodeBC5(block []byte, px *[16][4]uint8) {
+	decodeAlphaBlock(block, px, 0)
+	decodeAlphaBlock(block[8:], px, 1)
+	for i := range px {
+		px[i][2], px[i][3] = 0, 0xff
+	}
+}
+
+func decodeBC4S(block []byte, px *[16][4]float32) {
+	decodeSignedBlock(block, px, 0)
+	for i := range px {
+		px[i][1], px[i][2], px[i][3] = 0, 0, 1
+	}
+}
+
+func decodeBC5S(block []byte, px *[16][4]float32) {
+	decodeSignedBlock(block, px, 0)
+	decodeSignedBlock(block[8:], px, 1)
+	for i := range px {
+		px[i][2], px[i][3] = 0, 1
+	}
+}
@@ -0,0 +1,122 @@
+package texfmt
+
+import "testing"
+
+func TestDecodeBC1(t *testing.T) {
+	// Red and blue endpoints in four colour mode, then the same in three
+	// colour mode, where index 3 is transparent black.
+	var tests = []struct {
+		block []byte
+		want  [4][4]uint8 // pixels with indices 0 to 3
+	}{
+		{
+			[]byte{0x00, 0xf8, 0x1f, 0x00, 0xe4, 0, 0, 0},
+			[4][4]uint8{{255, 0, 0, 255}, {0, 0, 255, 255}, {170, 0, 85, 255}, {85, 0, 170, 255}},
+		},
+		{
+			[]byte{0x1f, 0x00, 0x00, 0xf8, 0xe4, 0, 0, 0},
+			[4][4]uint8{{0, 0, 255, 255}, {255, 0, 0, 255}, {127, 0, 127, 255}, {0, 0, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		var px [16][4]uint8
+		decodeBC1(tt.block, &px)
+		for i, want := range tt.want {
+			if px[i] != want {
+				t.Errorf("block %v pixel %d: got %v, want %v", tt.block, i, px[i], want)
+			}
+		}
+	}
+}
+
+func TestDecodeBC4(t *testing.T) {
+	// Indices 0, 1 and 2 of pixels 0 to 2 select a0, a1 and the first
+	// interpolated value.
+	var (
+		block = []byte{210, 70, 0x88, 0, 0, 0, 0, 0}
+		px    [16][4]uint8
+		want  = []uint8{210, 70, 190}
+	)
+	decodeBC4(block, &px)
+	for i, w := range want {
+		if px[i][0] != w {
+			t.Errorf("pixel %d: got %d, want %d", i, px[i][0], w)
+		}
+	}
+}
+
+func TestDecodeETC1(t *testing.T) {
+	// An individual mode block of base colour (136, 68, 34) with modifier
+	// table 0. Pixel (0, 0) has index 3, for the large negative modifier, and
+	// the others index 0, for the small positive one.
+	var (
+		block = []byte{0x88, 0x44, 0x22, 0x00, 0x00, 0x01, 0x00, 0x01}
+		px    [16][4]uint8
+	)
+	decodeETC2RGB(block, &px)
+	if want := [4]uint8{128, 60, 26, 255}; px[0] != want {
+		t.Errorf("pixel 0: got %v, want %v", px[0], want)
+	}
+	for i := 1; i < 16; i++ {
+		if want := [4]uint8{138, 70, 36, 255}; px[i] != want {
+			t.Errorf("pixel %d: got %v, want %v", i, px[i], want)
+		}
+	}
+}
+
+func TestDecodeEACAlpha(t *testing.T) {
+	// Base 100 with multiplier 2 and modifier table 0, whose first entry is
+	// -3, followed by the colour block of TestDecodeETC1.
+	var (
+		block = []byte{100, 0x20, 0, 0, 0, 0, 0, 0, 0x88, 0x44, 0x22, 0x00, 0x00, 0x01, 0x00, 0x01}
+		px    [16][4]uint8
+	)
+	decodeETC2RGBA(block, &px)
+	for i := range px {
+		if px[i][3] != 94 {
+			t.Errorf("pixel %d: alpha %d, want 94", i, px[i][3])
+		}
+	}
+	if px[1][0] != 138 {
+		t.Errorf("pixel 1: got %v", px[1])
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	// A 6x2 BC1 image is two blocks wide; the decoded pixels are cropped.
+	var img = &Image{
+		Format: BC1,
+		Width:  6, Height: 2,
+		Layers: 1, Faces: 1,
+		Levels: [][]byte{{
+			0x00, 0xf8, 0x00, 0xf8, 0, 0, 0, 0,
+			0x1f, 0x00, 0x1f, 0x00, 0, 0, 0, 0,
+		}},
+	}
+	var out, err = Decompress(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Format != RGBA8 || len(out.Levels[0]) != 6*2*4 {
+		t.Fatalf("got %v with %d bytes", out.Format, len(out.Levels[0]))
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 6; x++ {
+			var (
+				p    = out.Levels[0][(y*6+x)*4:]
+				want = [4]byte{255, 0, 0, 255}
+			)
+			if x >= 4 {
+				want = [4]byte{0, 0, 255, 255}
+			}
+			if [4]byte{p[0], p[1], p[2], p[3]} != want {
+				t.Errorf("pixel (%d, %d): got %v, want %v", x, y, p[:4], want)
+			}
+		}
+	}
+
+	img.Format = BC1SRGB
+	if out, _ = Decompress(img); out.Format != RGBA8SRGB {
+		t.Errorf("sRGB image decompressed to %v", out.Format)
+	}
+}
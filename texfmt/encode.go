@@ -0,0 +1,186 @@
+package texfmt
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// encoder compresses one 4x4 block of RGBA8 pixels in row-major order.
+type encoder func(px *[16][4]uint8, block []byte)
+
+var encoders = map[Format]encoder{
+	BC1: encodeBC1, BC1SRGB: encodeBC1,
+	BC3: encodeBC3, BC3SRGB: encodeBC3,
+	BC4: encodeBC4, BC5: encodeBC5,
+	BC7: encodeBC7, BC7SRGB: encodeBC7,
+}
+
+// CanCompress reports whether Compress supports the format.
+func CanCompress(f Format) bool {
+	var _, ok = encoders[f]
+	return ok || f == RGBA8 || f == RGBA8SRGB
+}
+
+// CompressOptions controls how Compress encodes an image.
+type CompressOptions struct {
+	Mipmaps   bool // generate a complete mip chain
+	Normalize bool // renormalize mip levels of tangent space normal maps
+}
+
+// Compress encodes an image into the given format, generating mip levels if
+// requested. For sRGB formats the mip levels are filtered in linear space.
+// RGBA8 and RGBA8SRGB are accepted to store uncompressed data.
+func Compress(src image.Image, f Format, opts *CompressOptions) (*Image, error) {
+	if !CanCompress(f) {
+		return nil, fmt.Errorf("texfmt.Compress error: cannot encode %v", f)
+	}
+	if opts == nil {
+		opts = &CompressOptions{}
+	}
+
+	var (
+		bounds = src.Bounds()
+		level  = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		img    = &Image{
+			Format: f,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Layers: 1,
+			Faces:  1,
+		}
+	)
+
+	if img.Width == 0 || img.Height == 0 {
+		return nil, fmt.Errorf("texfmt.Compress error: image is empty")
+	}
+
+	draw.Draw(level, level.Bounds(), src, bounds.Min, draw.Src)
+
+	for {
+		img.Levels = append(img.Levels, CompressRGBA(f, level.Rect.Dx(), level.Rect.Dy(), level.Pix))
+
+		if !opts.Mipmaps || (level.Rect.Dx() == 1 && level.Rect.Dy() == 1) {
+			break
+		}
+		level = Downsample(level, f.SRGB(), opts.Normalize)
+	}
+
+	return img, nil
+}
+
+// CompressRGBA encodes a w by h surface of tightly packed RGBA8 pixels into the
+// given format, which must be supported by Compress.
+func CompressRGBA(f Format, w, h int, pix []byte) []byte {
+	var enc, ok = encoders[f]
+	if !ok {
+		return append([]byte(nil), pix[:w*h*4]...)
+	}
+
+	var (
+		size = f.BlockSize()
+		dst  = make([]byte, f.DataSize(w, h))
+		px   [16][4]uint8
+		i    int
+	)
+
+	for by := 0; by < (h+3)/4; by++ {
+		for bx := 0; bx < (w+3)/4; bx++ {
+			// Edge blocks repeat the last row and column.
+			for j := range px {
+				var x, y = bx*4 + j%4, by*4 + j/4
+				if x >= w {
+					x = w - 1
+				}
+				if y >= h {
+					y = h - 1
+				}
+				copy(px[j][:], pix[(y*w+x)*4:])
+			}
+
+			enc(&px, dst[i:i+size])
+			i += size
+		}
+	}
+
+	return dst
+}
+
+// srgbToLinear converts an 8-bit sRGB value to linear [0, 1].
+func srgbToLinear(v uint8) float64 {
+	var c = float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear [0, 1] value to 8-bit sRGB.
+func linearToSRGB(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return uint8(math.Max(0, math.Min(255, c*255+0.5)))
+}
+
+// Downsample halves the size of an image with a box filter, rounding down as
+// OpenGL does for mip levels. If 'srgb' is set, colour channels are averaged in
+// linear space. If 'normalize' is set, the RGB channels are treated as a unit
+// vector and renormalized.
+func Downsample(src *image.NRGBA, srgb, normalize bool) *image.NRGBA {
+	var (
+		sw, sh = src.Rect.Dx(), src.Rect.Dy()
+		dw, dh = max(sw/2, 1), max(sh/2, 1)
+		dst    = image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	)
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var (
+				sum [4]float64
+				n   float64
+			)
+			// Odd sizes fold the last row or column into the final texel.
+			for sy := y * sh / dh; sy < (y+1)*sh/dh; sy++ {
+				for sx := x * sw / dw; sx < (x+1)*sw/dw; sx++ {
+					var p = src.Pix[src.PixOffset(src.Rect.Min.X+sx, src.Rect.Min.Y+sy):]
+					for c := 0; c < 4; c++ {
+						if srgb && c < 3 {
+							sum[c] += srgbToLinear(p[c])
+						} else {
+							sum[c] += float64(p[c]) / 255
+						}
+					}
+					n++
+				}
+			}
+
+			for c := range sum {
+				sum[c] /= n
+			}
+			if normalize {
+				var v = [3]float64{sum[0]*2 - 1, sum[1]*2 - 1, sum[2]*2 - 1}
+				var l = math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+				if l > 0 {
+					for c := 0; c < 3; c++ {
+						sum[c] = (v[c]/l + 1) / 2
+					}
+				}
+			}
+
+			var d = dst.Pix[dst.PixOffset(x, y):]
+			for c := 0; c < 4; c++ {
+				if srgb && c < 3 {
+					d[c] = linearToSRGB(sum[c])
+				} else {
+					d[c] = uint8(math.Max(0, math.Min(255, sum[c]*255+0.5)))
+				}
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,112 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// testImage returns an image of a diagonal gradient, in which the colours of
+// each block lie along a line, as block compression requires to be accurate.
+// Alpha stays above BC1's threshold of transparency.
+func testImage(w, h int) *image.NRGBA {
+	var img = image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v = (x + y) * 255 / (w + h - 2)
+			img.SetNRGBA(x, y, color.NRGBA{uint8(v), uint8(v / 2), uint8(255 - v), uint8(255 - v/2)})
+		}
+	}
+	return img
+}
+
+// rmse returns the root mean square error of channels 0 to 'channels'-1 of two
+// RGBA8 surfaces.
+func rmse(a, b []byte, channels int) float64 {
+	var sum float64
+	for i := 0; i < len(a); i += 4 {
+		for c := 0; c < channels; c++ {
+			var d = float64(a[i+c]) - float64(b[i+c])
+			sum += d * d
+		}
+	}
+	return math.Sqrt(sum / float64(len(a)/4*channels))
+}
+
+func TestCompress(t *testing.T) {
+	var tests = []struct {
+		format   Format
+		channels int // channels compared
+		maxRMSE  float64
+	}{
+		{BC1, 3, 5},
+		{BC3, 4, 4},
+		{BC4, 1, 2.5},
+		{BC5, 2, 2.5},
+		{BC7, 4, 1},
+	}
+	var src = testImage(16, 16)
+	for _, tt := range tests {
+		var img, err = Compress(src, tt.format, nil)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.format, err)
+		}
+		if len(img.Levels) != 1 || len(img.Levels[0]) != tt.format.DataSize(16, 16) {
+			t.Fatalf("%v: got %d levels", tt.format, len(img.Levels))
+		}
+		var out *Image
+		if out, err = Decompress(img); err != nil {
+			t.Fatalf("%v: %v", tt.format, err)
+		}
+		var pix = out.Levels[0]
+		if out.Format == RGBA32F {
+			pix = toRGBA8(pix)
+		}
+		if e := rmse(src.Pix, pix, tt.channels); e > tt.maxRMSE {
+			t.Errorf("%v: RMSE %.2f, want at most %g", tt.format, e, tt.maxRMSE)
+		}
+	}
+}
+
+func TestCompressMipmaps(t *testing.T) {
+	// A non-square, non-power-of-two image halves down to 1x1.
+	var img, err = Compress(testImage(10, 4), BC1, &CompressOptions{Mipmaps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(img.Levels) != 4 {
+		t.Fatalf("got %d levels, want 4", len(img.Levels))
+	}
+	for i, level := range img.Levels {
+		if len(level) != img.ImageSize(i) {
+			t.Errorf("level %d has %d bytes, want %d", i, len(level), img.ImageSize(i))
+		}
+	}
+}
+
+func TestDownsampleSRGB(t *testing.T) {
+	// Black and white average to linear grey, which is brighter than 128 in
+	// sRGB.
+	var src = image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 255})
+
+	if got := Downsample(src, false, false).Pix[0]; got < 127 || got > 128 {
+		t.Errorf("linear: got %d, want 127 or 128", got)
+	}
+	if got := Downsample(src, true, false).Pix[0]; got < 186 || got > 188 {
+		t.Errorf("sRGB: got %d, want 187", got)
+	}
+}
+
+// toRGBA8 converts RGBA32F pixels to RGBA8.
+func toRGBA8(src []byte) []byte {
+	var dst = make([]byte, len(src)/4)
+	for i := range dst {
+		var v = math.Float32frombits(binary.LittleEndian.Uint32(src[4*i:]))
+		dst[i] = uint8(clampf(float64(v), 0, 1)*255 + 0.5)
+	}
+	return dst
+}
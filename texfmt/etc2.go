@@ -0,0 +1,337 @@
+package texfmt
+
+import "encoding/binary"
+
+// etcModifiers are the intensity modifier tables of ETC1 and ETC2, ordered as
+// {-large, -small, +small, +large}.
+var etcModifiers = [8][4]int{
+	{-8, -2, 2, 8},
+	{-17, -5, 5, 17},
+	{-29, -9, 9, 29},
+	{-42, -13, 13, 42},
+	{-60, -18, 18, 60},
+	{-80, -24, 24, 80},
+	{-106, -33, 33, 106},
+	{-183, -47, 47, 183},
+}
+
+// etcIndexOrder maps a pixel index to its position in etcModifiers.
+var etcIndexOrder = [4]int{2, 3, 1, 0}
+
+// etcDistances are the paint colour distances of the T and H modes.
+var etcDistances = [8]int{3, 6, 11, 16, 23, 32, 41, 64}
+
+// eacModifiers are the modifier tables of EAC alpha and R11/RG11 blocks.
+var eacModifiers = [16][8]int{
+	{-3, -6, -9, -15, 2, 5, 8, 14},
+	{-3, -7, -10, -13, 2, 6, 9, 12},
+	{-2, -5, -8, -13, 1, 4, 7, 12},
+	{-2, -4, -6, -13, 1, 3, 5, 12},
+	{-3, -6, -8, -12, 2, 5, 7, 11},
+	{-3, -7, -9, -11, 2, 6, 8, 10},
+	{-4, -7, -8, -11, 3, 6, 7, 10},
+	{-3, -5, -8, -11, 2, 4, 7, 10},
+	{-2, -6, -8, -10, 1, 5, 7, 9},
+	{-2, -5, -8, -10, 1, 4, 7, 9},
+	{-2, -4, -8, -10, 1, 3, 7, 9},
+	{-2, -5, -7, -10, 1, 4, 6, 9},
+	{-3, -4, -7, -10, 2, 3, 6, 9},
+	{-1, -2, -3, -10, 0, 1, 2, 9},
+	{-4, -6, -8, -9, 3, 5, 7, 8},
+	{-3, -5, -7, -9, 2, 4, 6, 8},
+}
+
+func clamp255(v int) uint8 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	}
+	return uint8(v)
+}
+
+// bits extracts 'n' bits of 'v' starting at bit 'lo'.
+func bits(v uint64, lo, n uint) int {
+	return int(v>>lo) & (1<<n - 1)
+}
+
+// extend4 and friends expand an n-bit colour channel to 8 bits.
+func extend4(v int) int { return v<<4 | v }
+func extend5(v int) int { return v<<3 | v>>2 }
+func extend6(v int) int { return v<<2 | v>>4 }
+func extend7(v int) int { return v<<1 | v>>6 }
+
+// decodeETC2Color decodes an ETC2 colour block. With 'punchthrough' set, the
+// differential bit instead indicates an opaque block, and transparent pixels
+// are returned with zero alpha.
+func decodeETC2Color(block []byte, px *[16][4]uint8, punchthrough bool) {
+	var (
+		v      = binary.BigEndian.Uint64(block)
+		diff   = bits(v, 33, 1) == 1
+		opaque = true
+		msb    = bits(v, 16, 16)
+		lsb    = bits(v, 0, 16)
+	)
+
+	if punchthrough {
+		opaque = diff
+		diff = true
+	}
+
+	// index returns the two bit index of pixel (x, y).
+	var index = func(x, y int) int {
+		var i = uint(x*4 + y)
+		return (msb>>i&1)<<1 | lsb>>i&1
+	}
+
+	var setPaint = func(paint [4][3]int) {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				var (
+					i = index(x, y)
+					p = &px[y*4+x]
+				)
+				if !opaque && i == 2 {
+					*p = [4]uint8{}
+					continue
+				}
+				*p = [4]uint8{clamp255(paint[i][0]), clamp255(paint[i][1]), clamp255(paint[i][2]), 0xff}
+			}
+		}
+	}
+
+	var base [2][3]int
+	if diff {
+		var r, g, b = bits(v, 59, 5), bits(v, 51, 5), bits(v, 43, 5)
+		var dr, dg, db = bits(v, 56, 3), bits(v, 48, 3), bits(v, 40, 3)
+		if dr >= 4 {
+			dr -= 8
+		}
+		if dg >= 4 {
+			dg -= 8
+		}
+		if db >= 4 {
+			db -= 8
+		}
+
+		switch {
+		case r+dr < 0 || r+dr > 31:
+			// T mode
+			var (
+				c0 = [3]int{
+					extend4(bits(v, 59, 2)<<2 | bits(v, 56, 2)),
+					extend4(bits(v, 52, 4)),
+					extend4(bits(v, 48, 4)),
+				}
+				c1 = [3]int{extend4(bits(v, 44, 4)), extend4(bits(v, 40, 4)), extend4(bits(v, 36, 4))}
+				d  = etcDistances[bits(v, 34, 2)<<1|bits(v, 32, 1)]
+			)
+			setPaint([4][3]int{
+				c0,
+				{c1[0] + d, c1[1] + d, c1[2] + d},
+				c1,
+				{c1[0] - d, c1[1] - d, c1[2] - d},
+			})
+			return
+
+		case g+dg < 0 || g+dg > 31:
+			// H mode
+			var (
+				r0 = bits(v, 59, 4)
+				g0 = bits(v, 56, 3)<<1 | bits(v, 52, 1)
+				b0 = bits(v, 51, 1)<<3 | bits(v, 47, 3)
+				r1 = bits(v, 43, 4)
+				g1 = bits(v, 39, 4)
+				b1 = bits(v, 35, 4)
+				di = bits(v, 34, 1)<<2 | bits(v, 32, 1)<<1
+			)
+			if r0<<8|g0<<4|b0 >= r1<<8|g1<<4|b1 {
+				di |= 1
+			}
+			var (
+				c0 = [3]int{extend4(r0), extend4(g0), extend4(b0)}
+				c1 = [3]int{extend4(r1), extend4(g1), extend4(b1)}
+				d  = etcDistances[di]
+			)
+			setPaint([4][3]int{
+				{c0[0] + d, c0[1] + d, c0[2] + d},
+				{c0[0] - d, c0[1] - d, c0[2] - d},
+				{c1[0] + d, c1[1] + d, c1[2] + d},
+				{c1[0] - d, c1[1] - d, c1[2] - d},
+			})
+			return
+
+		case b+db < 0 || b+db > 31:
+			// Planar mode, which is always opaque.
+			var (
+				o = [3]int{
+					extend6(bits(v, 57, 6)),
+					extend7(bits(v, 56, 1)<<6 | bits(v, 49, 6)),
+					extend6(bits(v, 48, 1)<<5 | bits(v, 43, 2)<<3 | bits(v, 39, 3)),
+				}
+				h = [3]int{
+					extend6(bits(v, 34, 5)<<1 | bits(v, 32, 1)),
+					extend7(bits(v, 25, 7)),
+					extend6(bits(v, 19, 6)),
+				}
+				vv = [3]int{extend6(bits(v, 13, 6)), extend7(bits(v, 6, 7)), extend6(bits(v, 0, 6))}
+			)
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					var p = &px[y*4+x]
+					for c := 0; c < 3; c++ {
+						p[c] = clamp255((x*(h[c]-o[c]) + y*(vv[c]-o[c]) + 4*o[c] + 2) >> 2)
+					}
+					p[3] = 0xff
+				}
+			}
+			return
+		}
+
+		base[0] = [3]int{extend5(r), extend5(g), extend5(b)}
+		base[1] = [3]int{extend5(r + dr), extend5(g + dg), extend5(b + db)}
+	} else {
+		base[0] = [3]int{extend4(bits(v, 60, 4)), extend4(bits(v, 52, 4)), extend4(bits(v, 44, 4))}
+		base[1] = [3]int{extend4(bits(v, 56, 4)), extend4(bits(v, 48, 4)), extend4(bits(v, 40, 4))}
+	}
+
+	var (
+		flip   = bits(v, 32, 1) == 1
+		tables = [2]int{bits(v, 37, 3), bits(v, 34, 3)}
+	)
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			var (
+				sub = x / 2
+				i   = index(x, y)
+				p   = &px[y*4+x]
+			)
+			if flip {
+				sub = y / 2
+			}
+
+			var mod = etcModifiers[tables[sub]][etcIndexOrder[i]]
+			if !opaque {
+				if i == 2 {
+					*p = [4]uint8{}
+					continue
+				}
+				if i == 0 {
+					mod = 0
+				}
+			}
+
+			var c = base[sub]
+			*p = [4]uint8{clamp255(c[0] + mod), clamp255(c[1] + mod), clamp255(c[2] + mod), 0xff}
+		}
+	}
+}
+
+// decodeEAC decodes the 16 values of an EAC block, returning them in
+// row-major order. Unsigned values are in the range [0, 2047] and signed
+// values in [-1023, 1023]. With 'alpha' set, the block is decoded as an
+// 8-bit ETC2 alpha block instead.
+func decodeEAC(block []byte, signed, alpha bool) (vals [16]int) {
+	var (
+		v     = binary.BigEndian.Uint64(block)
+		base  = bits(v, 56, 8)
+		mult  = bits(v, 52, 4)
+		table = eacModifiers[bits(v, 48, 4)]
+	)
+
+	if signed {
+		base = int(int8(base))
+		if base == -128 {
+			base = -127
+		}
+	}
+
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			var (
+				i   = uint(x*4 + y)
+				mod = table[bits(v, 45-3*i, 3)]
+				val int
+			)
+
+			switch {
+			case alpha:
+				val = base + mod*mult
+				if val < 0 {
+					val = 0
+				} else if val > 255 {
+					val = 255
+				}
+			case signed:
+				if mult == 0 {
+					val = base*8 + mod
+				} else {
+					val = base*8 + mod*mult*8
+				}
+				if val < -1023 {
+					val = -1023
+				} else if val > 1023 {
+					val = 1023
+				}
+			default:
+				if mult == 0 {
+					val = base*8 + 4 + mod
+				} else {
+					val = base*8 + 4 + mod*mult*8
+				}
+				if val < 0 {
+					val = 0
+				} else if val > 2047 {
+					val = 2047
+				}
+			}
+
+			vals[y*4+x] = val
+		}
+	}
+
+	return vals
+}
+
+func decodeETC2RGB(block []byte, px *[16][4]uint8) {
+	decodeETC2Color(block, px, false)
+}
+
+func decodeETC2RGBA1(block []byte, px *[16][4]uint8) {
+	decodeETC2Color(block, px, true)
+}
+
+func decodeETC2RGBA(block []byte, px *[16][4]uint8) {
+	decodeETC2Color(block[8:], px, false)
+	for i, a := range decodeEAC(block, false, true) {
+		px[i][3] = uint8(a)
+	}
+}
+
+func decodeEACR11(block []byte, px *[16][4]float32) {
+	for i, r := range decodeEAC(block, false, false) {
+		px[i] = [4]float32{float32(r) / 2047, 0, 0, 1}
+	}
+}
+
+func decodeEACR11S(block []byte, px *[16][4]float32) {
+	for i, r := range decodeEAC(block, true, false) {
+		px[i] = [4]float32{float32(r) / 1023, 0, 0, 1}
+	}
+}
+
+func decodeEACRG11(block []byte, px *[16][4]float32) {
+	var r, g = decodeEAC(block, false, false), decodeEAC(block[8:], false, false)
+	for i := range px {
+		px[i] = [4]float32{float32(r[i]) / 2047, float32(g[i]) / 2047, 0, 1}
+	}
+}
+
+func decodeEACRG11S(block []byte, px *[16][4]float32) {
+	var r, g = decodeEAC(block, true, false), decodeEAC(block[8:], true, false)
+	for i := range px {
+		px[i] = [4]float32{float32(r[i]) / 1023, float32(g[i]) / 1023, 0, 1}
+	}
+}
@@ -0,0 +1,194 @@
+package texfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	hdrMagic     = "#?RADIANCE"
+	hdrMagicRGBE = "#?RGBE"
+)
+
+// isHDR reports whether the data begins with a Radiance header.
+func isHDR(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(hdrMagic)) || bytes.HasPrefix(data, []byte(hdrMagicRGBE))
+}
+
+// decodeHDR parses a Radiance RGBE file into a single RGB32F level. Both flat
+// and run-length encoded scanlines are supported. Images stored bottom-up are
+// flipped so that the first row is the top of the image.
+func decodeHDR(data []byte) (*Image, error) {
+	var line string
+
+	// Header lines are terminated by an empty line.
+	for {
+		var i = bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil, errors.New("texfmt.decodeHDR error: header is truncated")
+		}
+		line, data = strings.TrimSpace(string(data[:i])), data[i+1:]
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("texfmt.decodeHDR error: unsupported %s", line)
+		}
+	}
+
+	var i = bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, errors.New("texfmt.decodeHDR error: missing resolution")
+	}
+	line, data = string(data[:i]), data[i+1:]
+
+	var (
+		yDir, xDir    string
+		width, height int
+	)
+	if _, err := fmt.Sscanf(line, "%s %d %s %d", &yDir, &height, &xDir, &width); err != nil {
+		return nil, fmt.Errorf("texfmt.decodeHDR error: invalid resolution %q", line)
+	}
+	if (yDir != "-Y" && yDir != "+Y") || xDir != "+X" {
+		return nil, fmt.Errorf("texfmt.decodeHDR error: unsupported orientation %q", line)
+	}
+	if width < 1 || height < 1 || width > maxDimension || height > maxDimension || height > math.MaxInt/12/width {
+		return nil, fmt.Errorf("texfmt.decodeHDR error: invalid resolution %q", line)
+	}
+
+	// The output grows as scanlines are decoded, so that a header claiming a
+	// huge image cannot allocate more than the pixel data can fill.
+	var (
+		out      []byte
+		scanline = make([]byte, width*4)
+		rowSize  = width * 12
+		err      error
+	)
+
+	for y := 0; y < height; y++ {
+		if data, err = readScanline(data, scanline); err != nil {
+			return nil, err
+		}
+
+		var dst = len(out)
+		out = append(out, make([]byte, rowSize)...)
+		for x := 0; x < width; x++ {
+			var rgbe = scanline[x*4 : x*4+4]
+			if rgbe[3] == 0 {
+				continue
+			}
+			var scale = math.Ldexp(1, int(rgbe[3])-136)
+			for c := 0; c < 3; c++ {
+				binary.LittleEndian.PutUint32(out[dst+x*12+c*4:], math.Float32bits(float32(float64(rgbe[c])*scale)))
+			}
+		}
+	}
+
+	if yDir == "+Y" {
+		var tmp = make([]byte, rowSize)
+		for a, b := 0, height-1; a < b; a, b = a+1, b-1 {
+			var ra, rb = out[a*rowSize : (a+1)*rowSize], out[b*rowSize : (b+1)*rowSize]
+			copy(tmp, ra)
+			copy(ra, rb)
+			copy(rb, tmp)
+		}
+	}
+
+	return &Image{
+		Format: RGB32F,
+		Width:  width,
+		Height: height,
+		Layers: 1,
+		Faces:  1,
+		Levels: [][]byte{out},
+	}, nil
+}
+
+// readScanline decodes one scanline of RGBE pixels into 'dst' and returns the
+// remaining data.
+func readScanline(data, dst []byte) ([]byte, error) {
+	var (
+		width     = len(dst) / 4
+		truncated = errors.New("texfmt.decodeHDR error: pixel data is truncated")
+	)
+
+	// Flat scanlines, and widths the newer encoding cannot represent.
+	if width < 8 || width > 0x7fff || len(data) < 4 || data[0] != 2 || data[1] != 2 || data[2]&0x80 != 0 {
+		return readFlatScanline(data, dst)
+	}
+
+	if int(data[2])<<8|int(data[3]) != width {
+		return nil, errors.New("texfmt.decodeHDR error: scanline width mismatch")
+	}
+	data = data[4:]
+
+	// Each component is run-length encoded separately.
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			if len(data) < 2 {
+				return nil, truncated
+			}
+			var n = int(data[0])
+			if n > 128 {
+				n -= 128
+				if x+n > width {
+					return nil, errors.New("texfmt.decodeHDR error: run overflows scanline")
+				}
+				for ; n > 0; n-- {
+					dst[x*4+c] = data[1]
+					x++
+				}
+				data = data[2:]
+			} else {
+				if n == 0 || x+n > width || len(data) < 1+n {
+					return nil, errors.New("texfmt.decodeHDR error: invalid run")
+				}
+				for i := 0; i < n; i++ {
+					dst[x*4+c] = data[1+i]
+					x++
+				}
+				data = data[1+n:]
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// readFlatScanline decodes a scanline stored uncompressed or with the original
+// Radiance run-length encoding, in which (1, 1, 1, n) repeats the previous
+// pixel.
+func readFlatScanline(data, dst []byte) ([]byte, error) {
+	var shift uint
+
+	for x := 0; x < len(dst)/4; {
+		if len(data) < 4 {
+			return nil, errors.New("texfmt.decodeHDR error: pixel data is truncated")
+		}
+		if data[0] == 1 && data[1] == 1 && data[2] == 1 {
+			if x == 0 {
+				return nil, errors.New("texfmt.decodeHDR error: run without a preceding pixel")
+			}
+			var n = int(data[3]) << shift
+			if x+n > len(dst)/4 {
+				return nil, errors.New("texfmt.decodeHDR error: run overflows scanline")
+			}
+			for ; n > 0; n-- {
+				copy(dst[x*4:x*4+4], dst[x*4-4:x*4])
+				x++
+			}
+			shift += 8
+		} else {
+			copy(dst[x*4:x*4+4], data[:4])
+			x++
+			shift = 0
+		}
+		data = data[4:]
+	}
+
+	return data, nil
+}
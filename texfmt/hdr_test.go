@@ -0,0 +1,89 @@
+package texfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// hdrPixel returns the float RGB of pixel 'i' of an RGB32F level.
+func hdrPixel(level []byte, i int) [3]float32 {
+	var p [3]float32
+	for c := range p {
+		p[c] = math.Float32frombits(binary.LittleEndian.Uint32(level[i*12+c*4:]))
+	}
+	return p
+}
+
+func TestDecodeHDRFlat(t *testing.T) {
+	// A 2x2 image stored bottom-up, so the first scanline is the bottom row.
+	// RGBE (128, 64, 32, 129) is (1, 0.5, 0.25), and a zero exponent black.
+	var file = "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n+Y 2 +X 2\n" +
+		"\x80\x40\x20\x81\x00\x00\x00\x00" +
+		"\x80\x80\x80\x82\x80\x80\x80\x80"
+
+	var img, err = Decode(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != RGB32F || img.Width != 2 || img.Height != 2 {
+		t.Fatalf("got %v %dx%d", img.Format, img.Width, img.Height)
+	}
+	var want = [4][3]float32{{2, 2, 2}, {0.5, 0.5, 0.5}, {1, 0.5, 0.25}, {0, 0, 0}}
+	for i, w := range want {
+		if p := hdrPixel(img.Levels[0], i); p != w {
+			t.Errorf("pixel %d: got %v, want %v", i, p, w)
+		}
+	}
+}
+
+func TestDecodeHDRRunLength(t *testing.T) {
+	// A scanline of 8 pixels with each component run-length encoded: red as
+	// a run of 8, green as 8 literals, and blue and exponent as runs.
+	var buf bytes.Buffer
+	buf.WriteString("#?RADIANCE\n\n-Y 1 +X 8\n")
+	buf.Write([]byte{2, 2, 0, 8})
+	buf.Write([]byte{128 + 8, 128})
+	buf.Write([]byte{8, 0, 16, 32, 48, 64, 80, 96, 112})
+	buf.Write([]byte{128 + 8, 0})
+	buf.Write([]byte{128 + 8, 128})
+
+	var img, err = Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 8; x++ {
+		var want = [3]float32{0.5, float32(x*16) / 256, 0}
+		if p := hdrPixel(img.Levels[0], x); p != want {
+			t.Errorf("pixel %d: got %v, want %v", x, p, want)
+		}
+	}
+}
+
+func TestDecodeHDRInvalid(t *testing.T) {
+	for _, file := range []string{
+		"#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x80\x80\x80\x80",
+		"#?RADIANCE\n\n-Y 1 -X 1\n\x80\x80\x80\x80",
+		"#?RADIANCE\n\n-Y 2 +X 1\n\x80\x80\x80\x80",
+		"#?RADIANCE\n\n-Y 1 +X 2\n\x01\x01\x01\x01\x80\x80\x80\x80",
+	} {
+		if _, err := Decode(strings.NewReader(file)); err == nil {
+			t.Errorf("%q was decoded", file)
+		}
+	}
+}
+
+func TestDecodeHDRTruncatedHeader(t *testing.T) {
+	for _, file := range []string{
+		"#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n",
+		"#?RADIANCE\n\n",
+		"#?RADIANCE\n\n-Y 100000 +X 100000\n",
+		"#?RADIANCE\n\n-Y 60000 +X 60000\n\x80\x80\x80\x80",
+	} {
+		if _, err := Decode(strings.NewReader(file)); err == nil {
+			t.Errorf("%q was decoded", file)
+		}
+	}
+}
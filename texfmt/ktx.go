@@ -0,0 +1,236 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	ktx1Magic = "\xabKTX 11\xbb\r\n\x1a\n"
+	ktx2Magic = "\xabKTX 20\xbb\r\n\x1a\n"
+)
+
+// glFormats maps OpenGL internal formats, as stored in KTX files, to Formats.
+var glFormats = map[uint32]Format{
+	0x8058: RGBA8, 0x8c43: RGBA8SRGB,
+	0x822b: RG8, 0x8229: R8,
+	0x881a: RGBA16F, 0x8815: RGB32F, 0x8814: RGBA32F,
+	0x83f1: BC1, 0x8c4d: BC1SRGB,
+	0x83f2: BC2, 0x8c4e: BC2SRGB,
+	0x83f3: BC3, 0x8c4f: BC3SRGB,
+	0x8dbb: BC4, 0x8dbc: BC4S,
+	0x8dbd: BC5, 0x8dbe: BC5S,
+	0x8e8f: BC6HU, 0x8e8e: BC6HS,
+	0x8e8c: BC7, 0x8e8d: BC7SRGB,
+	0x8d64: ETC2RGB8, // ETC1 is a subset of ETC2
+	0x9274: ETC2RGB8, 0x9275: ETC2RGB8SRGB,
+	0x9276: ETC2RGB8A1, 0x9277: ETC2RGB8A1SRGB,
+	0x9278: ETC2RGBA8, 0x9279: ETC2RGBA8SRGB,
+	0x9270: EACR11, 0x9271: EACR11S,
+	0x9272: EACRG11, 0x9273: EACRG11S,
+}
+
+// vkFormats maps Vulkan formats, as stored in KTX2 files, to Formats.
+var vkFormats = map[uint32]Format{
+	37: RGBA8, 43: RGBA8SRGB,
+	16: RG8, 9: R8,
+	97: RGBA16F, 106: RGB32F, 109: RGBA32F,
+	131: BC1, 132: BC1SRGB, 133: BC1, 134: BC1SRGB,
+	135: BC2, 136: BC2SRGB,
+	137: BC3, 138: BC3SRGB,
+	139: BC4, 140: BC4S,
+	141: BC5, 142: BC5S,
+	143: BC6HU, 144: BC6HS,
+	145: BC7, 146: BC7SRGB,
+	147: ETC2RGB8, 148: ETC2RGB8SRGB,
+	149: ETC2RGB8A1, 150: ETC2RGB8A1SRGB,
+	151: ETC2RGBA8, 152: ETC2RGBA8SRGB,
+	153: EACR11, 154: EACR11S,
+	155: EACRG11, 156: EACRG11S,
+}
+
+// decodeKTX parses a KTX version 1 file.
+func decodeKTX(data []byte) (*Image, error) {
+	if len(data) < 64 {
+		return nil, errors.New("texfmt.decodeKTX error: file is truncated")
+	}
+
+	var bo binary.ByteOrder = binary.LittleEndian
+	switch binary.LittleEndian.Uint32(data[12:]) {
+	case 0x04030201:
+	case 0x01020304:
+		bo = binary.BigEndian
+	default:
+		return nil, errors.New("texfmt.decodeKTX error: invalid endianness")
+	}
+
+	var (
+		typeSize = int(bo.Uint32(data[20:]))
+		internal = bo.Uint32(data[28:])
+		width    = int(bo.Uint32(data[36:]))
+		height   = int(bo.Uint32(data[40:]))
+		depth    = int(bo.Uint32(data[44:]))
+		layers   = int(bo.Uint32(data[48:]))
+		faces    = int(bo.Uint32(data[52:]))
+		levels   = int(bo.Uint32(data[56:]))
+		kvdLen   = int(bo.Uint32(data[60:]))
+		offset   = 64 + kvdLen
+
+		img = &Image{Width: width, Height: height, Layers: 1, Faces: faces}
+		ok  bool
+	)
+
+	if img.Format, ok = glFormats[internal]; !ok {
+		return nil, fmt.Errorf("texfmt.decodeKTX error: unsupported internal format 0x%x", internal)
+	}
+	if depth > 1 {
+		return nil, errors.New("texfmt.decodeKTX error: volume textures are not supported")
+	}
+	if height == 0 {
+		img.Height = 1
+	}
+	if faces != 1 && faces != 6 {
+		return nil, fmt.Errorf("texfmt.decodeKTX error: invalid face count %d", faces)
+	}
+	if layers > 0 {
+		img.Layers = layers
+		img.Array = true
+	}
+	if levels < 1 {
+		levels = 1
+	}
+	if err := checkHeader("texfmt.decodeKTX", img.Width, img.Height, img.Layers, levels); err != nil {
+		return nil, err
+	}
+
+	img.Levels = make([][]byte, levels)
+	for level := range img.Levels {
+		if len(data) < offset+4 {
+			return nil, errors.New("texfmt.decodeKTX error: file is truncated")
+		}
+		offset += 4
+
+		// imageSize counts a single face of non-array cube maps, and the
+		// whole level otherwise. Rows of uncompressed data and each face
+		// are padded to four bytes; compressed data is stored in rows of
+		// blocks, which are whole words already.
+		var (
+			w, h   = img.LevelSize(level)
+			row    = img.Format.DataSize(w, 1)
+			pad    = row
+			size   = img.ImageSize(level)
+			stored = size
+		)
+		if !img.Format.Compressed() {
+			pad = (row + 3) &^ 3
+			stored = pad * h
+		}
+		for i := 0; i < img.Layers*img.Faces; i++ {
+			if stored > len(data)-offset {
+				return nil, errors.New("texfmt.decodeKTX error: file is truncated")
+			}
+			if pad == row {
+				img.Levels[level] = append(img.Levels[level], data[offset:offset+size]...)
+			} else {
+				for y := 0; y < h; y++ {
+					img.Levels[level] = append(img.Levels[level], data[offset+y*pad:offset+y*pad+row]...)
+				}
+			}
+			offset += (stored + 3) &^ 3
+		}
+		offset = (offset + 3) &^ 3
+
+		if bo == binary.BigEndian && typeSize > 1 {
+			swapEndian(img.Levels[level], typeSize)
+		}
+	}
+
+	if err := img.validate(); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// swapEndian reverses the byte order of each 'size' byte word in 'data'.
+func swapEndian(data []byte, size int) {
+	for i := 0; i+size <= len(data); i += size {
+		for a, b := i, i+size-1; a < b; a, b = a+1, b-1 {
+			data[a], data[b] = data[b], data[a]
+		}
+	}
+}
+
+// decodeKTX2 parses a KTX version 2 file. Supercompressed files are not
+// supported.
+func decodeKTX2(data []byte) (*Image, error) {
+	if len(data) < 80 {
+		return nil, errors.New("texfmt.decodeKTX2 error: file is truncated")
+	}
+
+	var (
+		le = binary.LittleEndian
+
+		vkFormat = le.Uint32(data[12:])
+		width    = int(le.Uint32(data[20:]))
+		height   = int(le.Uint32(data[24:]))
+		depth    = int(le.Uint32(data[28:]))
+		layers   = int(le.Uint32(data[32:]))
+		faces    = int(le.Uint32(data[36:]))
+		levels   = int(le.Uint32(data[40:]))
+		scheme   = le.Uint32(data[44:])
+
+		img = &Image{Width: width, Height: height, Layers: 1, Faces: faces}
+		ok  bool
+	)
+
+	if img.Format, ok = vkFormats[vkFormat]; !ok {
+		return nil, fmt.Errorf("texfmt.decodeKTX2 error: unsupported Vulkan format %d", vkFormat)
+	}
+	if scheme != 0 {
+		return nil, fmt.Errorf("texfmt.decodeKTX2 error: unsupported supercompression scheme %d", scheme)
+	}
+	if depth > 1 {
+		return nil, errors.New("texfmt.decodeKTX2 error: volume textures are not supported")
+	}
+	if height == 0 {
+		img.Height = 1
+	}
+	if faces != 1 && faces != 6 {
+		return nil, fmt.Errorf("texfmt.decodeKTX2 error: invalid face count %d", faces)
+	}
+	if layers > 0 {
+		img.Layers = layers
+		img.Array = true
+	}
+	if levels < 1 {
+		levels = 1
+	}
+	if err := checkHeader("texfmt.decodeKTX2", img.Width, img.Height, img.Layers, levels); err != nil {
+		return nil, err
+	}
+	if len(data) < 80+levels*24 {
+		return nil, errors.New("texfmt.decodeKTX2 error: file is truncated")
+	}
+
+	img.Levels = make([][]byte, levels)
+	for level := range img.Levels {
+		var (
+			index  = data[80+level*24:]
+			offset = le.Uint64(index[0:])
+			length = le.Uint64(index[8:])
+			n      = uint64(len(data))
+		)
+		if offset > n || length > n-offset {
+			return nil, errors.New("texfmt.decodeKTX2 error: file is truncated")
+		}
+		img.Levels[level] = data[offset : offset+length]
+	}
+
+	if err := img.validate(); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
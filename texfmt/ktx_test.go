@@ -0,0 +1,103 @@
+package texfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// writeKTX returns a little-endian KTX1 file holding 'levels', each given as
+// the image data of a single face, padded as the format requires.
+func writeKTX(internal uint32, typeSize, width, height int, levels [][]byte) []byte {
+	var (
+		buf    bytes.Buffer
+		le     = binary.LittleEndian
+		header = make([]byte, 64)
+	)
+	copy(header, ktx1Magic)
+	le.PutUint32(header[12:], 0x04030201)
+	le.PutUint32(header[20:], uint32(typeSize))
+	le.PutUint32(header[28:], internal)
+	le.PutUint32(header[36:], uint32(width))
+	le.PutUint32(header[40:], uint32(height))
+	le.PutUint32(header[52:], 1)
+	le.PutUint32(header[56:], uint32(len(levels)))
+	buf.Write(header)
+
+	for _, level := range levels {
+		binary.Write(&buf, le, uint32(len(level)))
+		buf.Write(level)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeKTXCompressed(t *testing.T) {
+	// An 8x8 BC1 image is 2x2 blocks of 8 bytes, and its 4x4 mip one block.
+	var levels = [][]byte{make([]byte, 32), make([]byte, 8)}
+	for _, level := range levels {
+		for i := range level {
+			level[i] = byte(i + len(level))
+		}
+	}
+
+	var img, err = Decode(bytes.NewReader(writeKTX(0x83f1, 1, 8, 8, levels)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != BC1 || img.Width != 8 || img.Height != 8 || len(img.Levels) != 2 {
+		t.Fatalf("got %v %dx%d with %d levels", img.Format, img.Width, img.Height, len(img.Levels))
+	}
+	for i := range levels {
+		if !bytes.Equal(img.Levels[i], levels[i]) {
+			t.Errorf("level %d: got %v, want %v", i, img.Levels[i], levels[i])
+		}
+	}
+}
+
+func TestDecodeKTXRowPadding(t *testing.T) {
+	// Rows of a 3x2 RG8 image are 6 bytes, padded to 8 in the file.
+	var file = writeKTX(0x822b, 1, 3, 2, [][]byte{{
+		1, 2, 3, 4, 5, 6, 0, 0,
+		7, 8, 9, 10, 11, 12, 0, 0,
+	}})
+
+	var img, err = Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	if !bytes.Equal(img.Levels[0], want) {
+		t.Errorf("got %v, want %v", img.Levels[0], want)
+	}
+}
+
+func TestDecodeKTXTruncated(t *testing.T) {
+	var file = writeKTX(0x83f1, 1, 8, 8, [][]byte{make([]byte, 32)})
+	if _, err := Decode(bytes.NewReader(file[:len(file)-1])); err == nil {
+		t.Error("truncated file was decoded")
+	}
+}
+
+func TestDecodeKTXInvalidHeader(t *testing.T) {
+	var le = binary.LittleEndian
+	var tests = []struct {
+		name   string
+		offset int
+		value  uint32
+	}{
+		{"huge level count", 56, 0x7fffffff},
+		{"too many levels", 56, 5},
+		{"huge width", 36, 0xffffffff},
+		{"huge layer count", 48, 0xffffffff},
+	}
+	for _, tt := range tests {
+		var file = writeKTX(0x83f1, 1, 8, 8, [][]byte{make([]byte, 32)})
+		le.PutUint32(file[tt.offset:], tt.value)
+		if _, err := Decode(bytes.NewReader(file)); err == nil {
+			t.Errorf("%s: invalid header was decoded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package texfmt reads and writes texture container files, including Radiance
+// HDR images, and encodes and decodes the block compressed formats they hold.
+// It has no dependency on OpenGL so that tools which only manipulate texture
+// data need not link against it.
+package texfmt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format identifies the pixel or block layout of texture data.
+type Format int
+
+// Supported texture formats. Uncompressed formats are listed first, followed
+// by the block compressed formats, which always use 4x4 blocks.
+const (
+	FormatUnknown Format = iota
+
+	RGBA8     // 8-bit unsigned normalized RGBA
+	RGBA8SRGB // 8-bit sRGB RGB with linear alpha
+	RG8       // 8-bit unsigned normalized RG
+	R8        // 8-bit unsigned normalized R
+	RGBA16F   // 16-bit float RGBA
+	RGB32F    // 32-bit float RGB
+	RGBA32F   // 32-bit float RGBA
+
+	BC1     // DXT1, RGB with optional 1-bit alpha
+	BC1SRGB // DXT1, sRGB
+	BC2     // DXT3, RGB with explicit 4-bit alpha
+	BC2SRGB // DXT3, sRGB
+	BC3     // DXT5, RGB with interpolated alpha
+	BC3SRGB // DXT5, sRGB
+	BC4     // RGTC1, single unsigned channel
+	BC4S    // RGTC1, single signed channel
+	BC5     // RGTC2, two unsigned channels
+	BC5S    // RGTC2, two signed channels
+	BC6HU   // BPTC, unsigned half float RGB
+	BC6HS   // BPTC, signed half float RGB
+	BC7     // BPTC, RGBA
+	BC7SRGB // BPTC, sRGB
+
+	ETC2RGB8       // ETC2 RGB, also decodes ETC1
+	ETC2RGB8SRGB   // ETC2 sRGB
+	ETC2RGB8A1     // ETC2 RGB with punch-through alpha
+	ETC2RGB8A1SRGB // ETC2 sRGB with punch-through alpha
+	ETC2RGBA8      // ETC2 RGB with EAC alpha
+	ETC2RGBA8SRGB  // ETC2 sRGB with EAC alpha
+	EACR11         // EAC single unsigned channel
+	EACR11S        // EAC single signed channel
+	EACRG11        // EAC two unsigned channels
+	EACRG11S       // EAC two signed channels
+)
+
+var formatNames = map[Format]string{
+	RGBA8: "RGBA8", RGBA8SRGB: "RGBA8_SRGB", RG8: "RG8", R8: "R8",
+	RGBA16F: "RGBA16F", RGB32F: "RGB32F", RGBA32F: "RGBA32F",
+	BC1: "BC1", BC1SRGB: "BC1_SRGB", BC2: "BC2", BC2SRGB: "BC2_SRGB",
+	BC3: "BC3", BC3SRGB: "BC3_SRGB", BC4: "BC4", BC4S: "BC4_SNORM",
+	BC5: "BC5", BC5S: "BC5_SNORM", BC6HU: "BC6H_UF16", BC6HS: "BC6H_SF16",
+	BC7: "BC7", BC7SRGB: "BC7_SRGB",
+	ETC2RGB8: "ETC2_RGB8", ETC2RGB8SRGB: "ETC2_SRGB8",
+	ETC2RGB8A1: "ETC2_RGB8A1", ETC2RGB8A1SRGB: "ETC2_SRGB8A1",
+	ETC2RGBA8: "ETC2_RGBA8", ETC2RGBA8SRGB: "ETC2_SRGB8_ALPHA8",
+	EACR11: "EAC_R11", EACR11S: "EAC_R11_SNORM",
+	EACRG11: "EAC_RG11", EACRG11S: "EAC_RG11_SNORM",
+}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// Compressed reports whether the format is block compressed.
+func (f Format) Compressed() bool {
+	return f >= BC1
+}
+
+// SRGB reports whether the colour channels of the format are sRGB encoded.
+func (f Format) SRGB() bool {
+	switch f {
+	case RGBA8SRGB, BC1SRGB, BC2SRGB, BC3SRGB, BC7SRGB,
+		ETC2RGB8SRGB, ETC2RGB8A1SRGB, ETC2RGBA8SRGB:
+		return true
+	}
+	return false
+}
+
+// ToSRGB returns the sRGB encoded counterpart of the format, or the format
+// itself if it has none.
+func (f Format) ToSRGB() Format {
+	switch f {
+	case RGBA8:
+		return RGBA8SRGB
+	case BC1:
+		return BC1SRGB
+	case BC2:
+		return BC2SRGB
+	case BC3:
+		return BC3SRGB
+	case BC7:
+		return BC7SRGB
+	case ETC2RGB8:
+		return ETC2RGB8SRGB
+	case ETC2RGB8A1:
+		return ETC2RGB8A1SRGB
+	case ETC2RGBA8:
+		return ETC2RGBA8SRGB
+	}
+	return f
+}
+
+// BlockSize returns the size in bytes of a 4x4 block for compressed formats,
+// or of a single pixel for uncompressed formats.
+func (f Format) BlockSize() int {
+	switch f {
+	case RGBA8, RGBA8SRGB:
+		return 4
+	case RG8:
+		return 2
+	case R8:
+		return 1
+	case RGBA16F:
+		return 8
+	case RGB32F:
+		return 12
+	case RGBA32F:
+		return 16
+	case BC1, BC1SRGB, BC4, BC4S,
+		ETC2RGB8, ETC2RGB8SRGB, ETC2RGB8A1, ETC2RGB8A1SRGB,
+		EACR11, EACR11S:
+		return 8
+	case BC2, BC2SRGB, BC3, BC3SRGB, BC5, BC5S, BC6HU, BC6HS, BC7, BC7SRGB,
+		ETC2RGBA8, ETC2RGBA8SRGB, EACRG11, EACRG11S:
+		return 16
+	}
+	return 0
+}
+
+// DataSize returns the number of bytes needed to store a w by h image.
+func (f Format) DataSize(w, h int) int {
+	if f.Compressed() {
+		return ((w + 3) / 4) * ((h + 3) / 4) * f.BlockSize()
+	}
+	return w * h * f.BlockSize()
+}
+
+// Image is a complete texture payload: every mip level of every array layer
+// and cube face. The data for each level holds its layers in order, and
+// within each layer its faces in the order +X, -X, +Y, -Y, +Z, -Z.
+type Image struct {
+	Format        Format
+	Width, Height int      // size of the base level in pixels
+	Layers        int      // number of array layers, at least 1
+	Faces         int      // 6 for cube maps, otherwise 1
+	Array         bool     // whether the texture is an array texture
+	Levels        [][]byte // data for each mip level, largest first
+}
+
+// LevelSize returns the dimensions of mip level 'level'.
+func (img *Image) LevelSize(level int) (w, h int) {
+	w, h = img.Width>>uint(level), img.Height>>uint(level)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// ImageSize returns the size in bytes of a single layer and face of mip level
+// 'level'.
+func (img *Image) ImageSize(level int) int {
+	var w, h = img.LevelSize(level)
+	return img.Format.DataSize(w, h)
+}
+
+// Slice returns the data of one layer and face of mip level 'level'.
+func (img *Image) Slice(level, layer, face int) []byte {
+	var (
+		size = img.ImageSize(level)
+		i    = (layer*img.Faces + face) * size
+	)
+	return img.Levels[level][i : i+size]
+}
+
+// validate checks that the image dimensions are sane and that each level holds
+// the expected amount of data.
+func (img *Image) validate() error {
+	if img.Width < 1 || img.Height < 1 {
+		return fmt.Errorf("texfmt error: invalid image size %dx%d", img.Width, img.Height)
+	}
+	if img.Format.BlockSize() == 0 {
+		return fmt.Errorf("texfmt error: unsupported format %v", img.Format)
+	}
+	if len(img.Levels) == 0 {
+		return errors.New("texfmt error: image has no levels")
+	}
+	for i, level := range img.Levels {
+		if len(level) != img.ImageSize(i)*img.Layers*img.Faces {
+			return fmt.Errorf("texfmt error: level %d has %d bytes, expected %d", i, len(level), img.ImageSize(i)*img.Layers*img.Faces)
+		}
+	}
+	return nil
+}
+
+// Limits on the sizes read from file headers, well above what GPUs support,
+// which keep the sizes of the data computed from them from overflowing.
+const (
+	maxDimension = 65536
+	maxLayers    = 65536
+)
+
+// checkHeader returns an error, reported as from 'fn', if the size, number of
+// array layers or number of mip levels read from a file header are out of
+// range. A mip chain ends at 1x1, so it has at most floor(log2(max(w, h))) + 1
+// levels.
+func checkHeader(fn string, width, height, layers, levels int) error {
+	var chain = 1
+	for s := max(width, height); s > 1; s >>= 1 {
+		chain++
+	}
+	switch {
+	case width < 1 || height < 1 || width > maxDimension || height > maxDimension:
+		return fmt.Errorf("%s error: invalid image size %dx%d", fn, width, height)
+	case layers < 1 || layers > maxLayers:
+		return fmt.Errorf("%s error: invalid layer count %d", fn, layers)
+	case levels < 1 || levels > chain:
+		return fmt.Errorf("%s error: invalid level count %d for a %dx%d image", fn, levels, width, height)
+	}
+	return nil
+}
+
+// Decode reads a DDS, KTX, KTX2 or Radiance HDR file, determined by its magic
+// number.
+func Decode(r io.Reader) (*Image, error) {
+	var data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(data) >= 4 && string(data[:4]) == ddsMagic:
+		return decodeDDS(data)
+	case len(data) >= 12 && string(data[:12]) == ktx1Magic:
+		return decodeKTX(data)
+	case len(data) >= 12 && string(data[:12]) == ktx2Magic:
+		return decodeKTX2(data)
+	case isHDR(data):
+		return decodeHDR(data)
+	}
+
+	return nil, errors.New("texfmt.Decode error: unrecognized file format")
+}
@@ -0,0 +1,313 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// reverse returns the inverse of a format table. When several keys map to the
+// same format the smallest is used, unless one of them is listed in 'prefer'.
+func reverse(table map[uint32]Format, prefer ...uint32) map[Format]uint32 {
+	var out = make(map[Format]uint32)
+	for k, f := range table {
+		if old, ok := out[f]; !ok || k < old {
+			out[f] = k
+		}
+	}
+	for _, k := range prefer {
+		out[table[k]] = k
+	}
+	return out
+}
+
+var (
+	dxgiByFormat = reverse(dxgiFormats)
+	vkByFormat   = reverse(vkFormats, 133, 134) // BC1 with alpha
+)
+
+// EncodeDDS writes an image as a DDS file with the DX10 extended header.
+func EncodeDDS(w io.Writer, img *Image) error {
+	if err := img.validate(); err != nil {
+		return err
+	}
+
+	var dxgi, ok = dxgiByFormat[img.Format]
+	if !ok {
+		return fmt.Errorf("texfmt.EncodeDDS error: %v cannot be stored in DDS", img.Format)
+	}
+
+	var (
+		le   = binary.LittleEndian
+		hdr  = make([]byte, 4+ddsHeaderSize+dx10HeaderLen)
+		h    = hdr[4:]
+		dx10 = hdr[4+ddsHeaderSize:]
+
+		flags = uint32(0x1 | 0x2 | 0x4 | 0x1000) // caps, height, width, pixel format
+		caps  = uint32(0x1000)                   // texture
+		caps2 uint32
+		misc  uint32
+		pitch = img.ImageSize(0)
+	)
+
+	if img.Format.Compressed() {
+		flags |= 0x80000 // linear size
+	} else {
+		flags |= 0x8 // pitch
+		pitch = img.Format.DataSize(img.Width, 1)
+	}
+	if len(img.Levels) > 1 {
+		flags |= 0x20000 // mip map count
+		caps |= 0x400008 // mip map, complex
+	}
+	if img.Faces == 6 {
+		caps |= 0x8
+		caps2 = ddsCaps2Cubemap | 0xfc00
+		misc = ddsResourceMiscCub
+	}
+
+	copy(hdr, ddsMagic)
+	le.PutUint32(h[0:], ddsHeaderSize)
+	le.PutUint32(h[4:], flags)
+	le.PutUint32(h[8:], uint32(img.Height))
+	le.PutUint32(h[12:], uint32(img.Width))
+	le.PutUint32(h[16:], uint32(pitch))
+	le.PutUint32(h[24:], uint32(len(img.Levels)))
+	le.PutUint32(h[72:], 32)
+	le.PutUint32(h[76:], ddpfFourCC)
+	copy(h[80:], "DX10")
+	le.PutUint32(h[104:], caps)
+	le.PutUint32(h[108:], caps2)
+
+	le.PutUint32(dx10[0:], dxgi)
+	le.PutUint32(dx10[4:], 3) // 2D texture
+	le.PutUint32(dx10[8:], misc)
+	le.PutUint32(dx10[12:], uint32(img.Layers))
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+
+	for layer := 0; layer < img.Layers; layer++ {
+		for face := 0; face < img.Faces; face++ {
+			for level := range img.Levels {
+				if _, err := w.Write(img.Slice(level, layer, face)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// KTX2 data format descriptor constants.
+const (
+	dfdModelRGBSDA = 1
+	dfdModelBC1A   = 128
+	dfdModelBC2    = 129
+	dfdModelBC3    = 130
+	dfdModelBC4    = 131
+	dfdModelBC5    = 132
+	dfdModelBC6H   = 133
+	dfdModelBC7    = 134
+	dfdModelETC2   = 161
+
+	dfdChannelSigned = 0x40
+	dfdChannelFloat  = 0x80
+)
+
+// dfdSample is one sample of a basic data format descriptor.
+type dfdSample struct {
+	offset, length int // in bits
+	channel        uint8
+}
+
+// dataFormatDescriptor builds the basic data format descriptor required by
+// KTX2 for the given format.
+func dataFormatDescriptor(f Format) ([]byte, error) {
+	var (
+		model   uint8
+		samples []dfdSample
+		signed  = f == BC4S || f == BC5S || f == BC6HS || f == EACR11S || f == EACRG11S
+		block   = uint8(3) // block dimension minus one
+	)
+
+	switch f {
+	case RGBA8, RGBA8SRGB:
+		model, block = dfdModelRGBSDA, 0
+		samples = []dfdSample{{0, 8, 0}, {8, 8, 1}, {16, 8, 2}, {24, 8, 15}}
+	case RG8:
+		model, block = dfdModelRGBSDA, 0
+		samples = []dfdSample{{0, 8, 0}, {8, 8, 1}}
+	case R8:
+		model, block = dfdModelRGBSDA, 0
+		samples = []dfdSample{{0, 8, 0}}
+	case BC1, BC1SRGB:
+		model, samples = dfdModelBC1A, []dfdSample{{0, 64, 1}}
+	case BC2, BC2SRGB:
+		model, samples = dfdModelBC2, []dfdSample{{0, 64, 15}, {64, 64, 0}}
+	case BC3, BC3SRGB:
+		model, samples = dfdModelBC3, []dfdSample{{0, 64, 15}, {64, 64, 0}}
+	case BC4, BC4S:
+		model, samples = dfdModelBC4, []dfdSample{{0, 64, 0}}
+	case BC5, BC5S:
+		model, samples = dfdModelBC5, []dfdSample{{0, 64, 0}, {64, 64, 1}}
+	case BC6HU, BC6HS:
+		model, samples = dfdModelBC6H, []dfdSample{{0, 128, dfdChannelFloat}}
+	case BC7, BC7SRGB:
+		model, samples = dfdModelBC7, []dfdSample{{0, 128, 0}}
+	case ETC2RGB8, ETC2RGB8SRGB, ETC2RGB8A1, ETC2RGB8A1SRGB:
+		model, samples = dfdModelETC2, []dfdSample{{0, 64, 2}}
+	case ETC2RGBA8, ETC2RGBA8SRGB:
+		model, samples = dfdModelETC2, []dfdSample{{0, 64, 15}, {64, 64, 2}}
+	case EACR11, EACR11S:
+		model, samples = dfdModelETC2, []dfdSample{{0, 64, 0}}
+	case EACRG11, EACRG11S:
+		model, samples = dfdModelETC2, []dfdSample{{0, 64, 0}, {64, 64, 1}}
+	default:
+		return nil, fmt.Errorf("texfmt.EncodeKTX2 error: no data format descriptor for %v", f)
+	}
+
+	var (
+		le        = binary.LittleEndian
+		blockSize = 24 + 16*len(samples)
+		dfd       = make([]byte, 4+blockSize)
+		b         = dfd[4:]
+		transfer  = uint8(1) // linear
+	)
+
+	if f.SRGB() {
+		transfer = 2
+	}
+
+	le.PutUint32(dfd[0:], uint32(len(dfd)))
+	le.PutUint32(b[0:], 0)                           // vendor and descriptor type
+	le.PutUint32(b[4:], 2|uint32(blockSize)<<16)     // version and block size
+	b[8], b[9], b[10], b[11] = model, 1, transfer, 0 // BT.709 primaries
+	b[12], b[13] = block, block
+	b[16] = uint8(f.BlockSize())
+
+	for i, s := range samples {
+		var (
+			sb           = b[24+16*i:]
+			lower, upper = uint32(0), uint32(0xffffffff)
+			channel      = s.channel
+		)
+		switch {
+		case channel&dfdChannelFloat != 0:
+			upper = math.Float32bits(1)
+			if signed {
+				channel |= dfdChannelSigned
+				lower = math.Float32bits(-1)
+			}
+		case signed:
+			channel |= dfdChannelSigned
+			lower, upper = 0x80000000, 0x7fffffff
+		case block == 0:
+			upper = 0xff
+		}
+		le.PutUint16(sb[0:], uint16(s.offset))
+		sb[2] = uint8(s.length - 1)
+		sb[3] = channel
+		le.PutUint32(sb[8:], lower)
+		le.PutUint32(sb[12:], upper)
+	}
+
+	return dfd, nil
+}
+
+// EncodeKTX2 writes an image as an uncompressed KTX2 file. Mip levels are
+// stored smallest first, as the specification recommends.
+func EncodeKTX2(w io.Writer, img *Image) error {
+	if err := img.validate(); err != nil {
+		return err
+	}
+
+	var vk, ok = vkByFormat[img.Format]
+	if !ok {
+		return fmt.Errorf("texfmt.EncodeKTX2 error: %v cannot be stored in KTX2", img.Format)
+	}
+
+	var dfd, err = dataFormatDescriptor(img.Format)
+	if err != nil {
+		return err
+	}
+
+	var (
+		le       = binary.LittleEndian
+		levels   = len(img.Levels)
+		indexEnd = 80 + levels*24
+		dataPos  = indexEnd + len(dfd)
+		hdr      = make([]byte, indexEnd)
+		layers   = uint32(0)
+		typeSize = uint32(1)
+		align    = 16
+		offsets  = make([]int, levels)
+	)
+
+	if img.Array {
+		layers = uint32(img.Layers)
+	}
+	switch img.Format {
+	case RGBA16F:
+		typeSize = 2
+	case RGB32F, RGBA32F:
+		typeSize = 4
+	}
+
+	for level := levels - 1; level >= 0; level-- {
+		dataPos = (dataPos + align - 1) / align * align
+		offsets[level] = dataPos
+		dataPos += len(img.Levels[level])
+	}
+
+	copy(hdr, ktx2Magic)
+	le.PutUint32(hdr[12:], vk)
+	le.PutUint32(hdr[16:], typeSize)
+	le.PutUint32(hdr[20:], uint32(img.Width))
+	le.PutUint32(hdr[24:], uint32(img.Height))
+	le.PutUint32(hdr[32:], layers)
+	le.PutUint32(hdr[36:], uint32(img.Faces))
+	le.PutUint32(hdr[40:], uint32(levels))
+	le.PutUint32(hdr[48:], uint32(indexEnd))
+	le.PutUint32(hdr[52:], uint32(len(dfd)))
+
+	for level := 0; level < levels; level++ {
+		var index = hdr[80+level*24:]
+		le.PutUint64(index[0:], uint64(offsets[level]))
+		le.PutUint64(index[8:], uint64(len(img.Levels[level])))
+		le.PutUint64(index[16:], uint64(len(img.Levels[level])))
+	}
+
+	var pos int
+	var write = func(data []byte) error {
+		var n, err = w.Write(data)
+		pos += n
+		return err
+	}
+
+	if err = write(hdr); err != nil {
+		return err
+	}
+	if err = write(dfd); err != nil {
+		return err
+	}
+	for level := levels - 1; level >= 0; level-- {
+		if pad := offsets[level] - pos; pad > 0 {
+			if err = write(make([]byte, pad)); err != nil {
+				return err
+			}
+		}
+		if err = write(img.Levels[level]); err != nil {
+			return err
+		}
+	}
+	if pos != dataPos {
+		return errors.New("texfmt.EncodeKTX2 error: short write")
+	}
+
+	return nil
+}
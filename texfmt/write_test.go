@@ -0,0 +1,60 @@
+package texfmt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	var mips, err = Compress(testImage(8, 8), BC7SRGB, &CompressOptions{Mipmaps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A cube map array of two layers, with each face a different value.
+	var cube = &Image{Format: RGBA8, Width: 2, Height: 2, Layers: 2, Faces: 6, Array: true}
+	cube.Levels = [][]byte{make([]byte, 2*6*RGBA8.DataSize(2, 2)), make([]byte, 2*6*RGBA8.DataSize(1, 1))}
+	for _, level := range cube.Levels {
+		for i := range level {
+			level[i] = byte(i * 7)
+		}
+	}
+
+	var encoders = []struct {
+		name   string
+		encode func(w io.Writer, img *Image) error
+	}{
+		{"DDS", EncodeDDS},
+		{"KTX2", EncodeKTX2},
+	}
+	for _, enc := range encoders {
+		for _, img := range []*Image{mips, cube} {
+			var buf bytes.Buffer
+			if err := enc.encode(&buf, img); err != nil {
+				t.Fatalf("%s %v: %v", enc.name, img.Format, err)
+			}
+			var got, err = Decode(&buf)
+			if err != nil {
+				t.Fatalf("%s %v: %v", enc.name, img.Format, err)
+			}
+			if got.Format != img.Format || got.Width != img.Width || got.Height != img.Height ||
+				got.Layers != img.Layers || got.Faces != img.Faces || got.Array != img.Array || len(got.Levels) != len(img.Levels) {
+				t.Errorf("%s: got %+v, want %+v", enc.name, *got, *img)
+				continue
+			}
+			for i := range img.Levels {
+				if !bytes.Equal(got.Levels[i], img.Levels[i]) {
+					t.Errorf("%s %v: level %d differs", enc.name, img.Format, i)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	var img = &Image{Format: ETC2RGB8, Width: 4, Height: 4, Layers: 1, Faces: 1, Levels: [][]byte{make([]byte, 8)}}
+	if err := EncodeDDS(io.Discard, img); err == nil {
+		t.Error("ETC2 was stored in DDS")
+	}
+}